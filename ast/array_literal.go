@@ -9,6 +9,9 @@ import (
 type ArrayLiteral struct {
 	Token    token.Token
 	Elements []Expression
+	// Rbracket is the closing bracket, used by tools that need to know
+	// where the literal ends.
+	Rbracket token.Token
 }
 
 func (al *ArrayLiteral) expressionNode()      {}
@@ -21,6 +21,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment
 }
 
 func (p *Program) TokenLiteral() string {
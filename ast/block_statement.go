@@ -8,6 +8,9 @@ import (
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	// Rbrace is the closing brace, used by tools that need to know where
+	// the block ends.
+	Rbrace token.Token
 }
 
 func (bs *BlockStatement) statementNode()       {}
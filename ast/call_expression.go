@@ -10,6 +10,9 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+	// Rparen is the closing parenthesis, used by tools that need to know
+	// where the call ends.
+	Rparen token.Token
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -0,0 +1,42 @@
+package ast
+
+import (
+	"strings"
+	"zumbra/token"
+)
+
+type Comment struct {
+	Token token.Token
+}
+
+func (c *Comment) Text() string {
+	text := c.Token.Literal
+
+	switch {
+	case strings.HasPrefix(text, "///"):
+		text = strings.TrimPrefix(text, "///")
+	case strings.HasPrefix(text, "//"):
+		text = strings.TrimPrefix(text, "//")
+	case strings.HasPrefix(text, "/*"):
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/"))
+	}
+
+	return strings.TrimPrefix(text, " ")
+}
+
+type CommentGroup struct {
+	List []*Comment
+}
+
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+
+	lines := []string{}
+	for _, c := range g.List {
+		lines = append(lines, c.Text())
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -9,6 +9,11 @@ import (
 type DictLiteral struct {
 	Token token.Token
 	Pairs map[Expression]Expression
+	// Keys keeps the keys in source order.
+	Keys []Expression
+	// Rbrace is the closing brace, used by tools that need to know where
+	// the literal ends.
+	Rbrace token.Token
 }
 
 func (dl *DictLiteral) expressionNode()      {}
@@ -18,8 +23,14 @@ func (dl *DictLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range dl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	if len(dl.Keys) == len(dl.Pairs) {
+		for _, key := range dl.Keys {
+			pairs = append(pairs, key.String()+":"+dl.Pairs[key].String())
+		}
+	} else {
+		for key, value := range dl.Pairs {
+			pairs = append(pairs, key.String()+":"+value.String())
+		}
 	}
 
 	out.WriteString("{")
@@ -0,0 +1,33 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+	"zumbra/token"
+)
+
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Variants []*Identifier
+	Doc      *CommentGroup
+}
+
+func (es *EnumStatement) statementNode()       {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	out.WriteString("enum ")
+	out.WriteString(es.Name.String())
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(variants, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
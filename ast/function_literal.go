@@ -9,6 +9,7 @@ import (
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	ReturnType TypeNode
 	Body       *BlockStatement
 	Name       string
 }
@@ -20,7 +21,11 @@ func (fl *FunctionLiteral) String() string {
 
 	params := []string{}
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		if p.Type != nil {
+			params = append(params, p.String()+": "+p.Type.String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -29,7 +34,11 @@ func (fl *FunctionLiteral) String() string {
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String())
+	}
+	out.WriteString(" ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -5,6 +5,7 @@ import "zumbra/token"
 type Identifier struct {
 	Token token.Token
 	Value string
+	Type  TypeNode
 }
 
 func (i *Identifier) expressionNode()      {}
@@ -1,10 +1,15 @@
 package ast
 
-import "zumbra/token"
+import (
+	"math/big"
+	"zumbra/token"
+)
 
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	// Big holds literals that do not fit in an int64.
+	Big *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode()      {}
@@ -0,0 +1,44 @@
+package ast
+
+import (
+	"bytes"
+	"zumbra/token"
+)
+
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm runs Body when the subject equals Pattern. A nil Pattern is the
+// `else` arm.
+type MatchArm struct {
+	Pattern Expression
+	Body    *BlockStatement
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+
+	for _, arm := range me.Arms {
+		if arm.Pattern == nil {
+			out.WriteString("else")
+		} else {
+			out.WriteString(arm.Pattern.String())
+		}
+		out.WriteString(" => ")
+		out.WriteString(arm.Body.String())
+		out.WriteString(" ")
+	}
+
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,32 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+	"zumbra/token"
+)
+
+type SetLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	// Rbrace is the closing brace, used by tools that need to know where
+	// the literal ends.
+	Rbrace token.Token
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
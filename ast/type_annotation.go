@@ -0,0 +1,86 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+	"zumbra/token"
+)
+
+type TypeNode interface {
+	Node
+	typeNode()
+}
+
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+type ArrayType struct {
+	Token   token.Token
+	Element TypeNode
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string       { return "[" + at.Element.String() + "]" }
+
+type DictType struct {
+	Token token.Token
+	Key   TypeNode
+	Value TypeNode
+}
+
+func (dt *DictType) typeNode()            {}
+func (dt *DictType) TokenLiteral() string { return dt.Token.Literal }
+func (dt *DictType) String() string {
+	return "{" + dt.Key.String() + ": " + dt.Value.String() + "}"
+}
+
+type FunctionType struct {
+	Token      token.Token
+	Parameters []TypeNode
+	ReturnType TypeNode
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ft.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fct(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+
+	if ft.ReturnType != nil {
+		out.WriteString(": ")
+		out.WriteString(ft.ReturnType.String())
+	}
+
+	return out.String()
+}
+
+type UnionType struct {
+	Token token.Token
+	Types []TypeNode
+}
+
+func (ut *UnionType) typeNode()            {}
+func (ut *UnionType) TokenLiteral() string { return ut.Token.Literal }
+func (ut *UnionType) String() string {
+	types := []string{}
+	for _, t := range ut.Types {
+		types = append(types, t.String())
+	}
+
+	return strings.Join(types, " | ")
+}
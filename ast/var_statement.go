@@ -8,7 +8,9 @@ import (
 type VarStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  TypeNode
 	Value Expression
+	Doc   *CommentGroup
 }
 
 func (ls *VarStatement) statementNode()       {}
@@ -18,6 +20,9 @@ func (ls *VarStatement) String() string {
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -0,0 +1,270 @@
+package ast
+
+import (
+	"sort"
+	"zumbra/token"
+)
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for every node. Children are skipped when f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Inspect(n.Expression, f)
+		}
+	case *VarStatement:
+		Inspect(n.Name, f)
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+	case *AssignStatement:
+		Inspect(n.Name, f)
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+	case *ReturnStatement:
+		if n.ReturnValue != nil {
+			Inspect(n.ReturnValue, f)
+		}
+	case *YieldStatement:
+		if n.Value != nil {
+			Inspect(n.Value, f)
+		}
+	case *WhileStatement:
+		if n.Condition != nil {
+			Inspect(n.Condition, f)
+		}
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+	case *ImportStatement:
+		if n.Path != nil {
+			Inspect(n.Path, f)
+		}
+	case *Identifier:
+		if n.Type != nil {
+			Inspect(n.Type, f)
+		}
+	case *PrefixExpression:
+		if n.Right != nil {
+			Inspect(n.Right, f)
+		}
+	case *InfixExpression:
+		if n.Left != nil {
+			Inspect(n.Left, f)
+		}
+		if n.Right != nil {
+			Inspect(n.Right, f)
+		}
+	case *IfExpression:
+		if n.Condition != nil {
+			Inspect(n.Condition, f)
+		}
+		if n.Consequence != nil {
+			Inspect(n.Consequence, f)
+		}
+		if n.Alternative != nil {
+			Inspect(n.Alternative, f)
+		}
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		if n.ReturnType != nil {
+			Inspect(n.ReturnType, f)
+		}
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+	case *CallExpression:
+		if n.Function != nil {
+			Inspect(n.Function, f)
+		}
+		for _, a := range n.Arguments {
+			Inspect(a, f)
+		}
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *DictLiteral:
+		for _, key := range SortedKeys(n) {
+			Inspect(key, f)
+			Inspect(n.Pairs[key], f)
+		}
+	case *IndexExpression:
+		if n.Left != nil {
+			Inspect(n.Left, f)
+		}
+		if n.Index != nil {
+			Inspect(n.Index, f)
+		}
+	case *AttributeAccess:
+		if n.Object != nil {
+			Inspect(n.Object, f)
+		}
+		if n.Property != nil {
+			Inspect(n.Property, f)
+		}
+	case *EnumStatement:
+		Inspect(n.Name, f)
+		for _, v := range n.Variants {
+			Inspect(v, f)
+		}
+	case *MatchExpression:
+		if n.Subject != nil {
+			Inspect(n.Subject, f)
+		}
+		for _, arm := range n.Arms {
+			if arm.Pattern != nil {
+				Inspect(arm.Pattern, f)
+			}
+			if arm.Body != nil {
+				Inspect(arm.Body, f)
+			}
+		}
+	case *SetLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *ForStatement:
+		Inspect(n.Variable, f)
+		if n.Iterable != nil {
+			Inspect(n.Iterable, f)
+		}
+		if n.Body != nil {
+			Inspect(n.Body, f)
+		}
+	case *ArrayType:
+		Inspect(n.Element, f)
+	case *DictType:
+		Inspect(n.Key, f)
+		Inspect(n.Value, f)
+	case *FunctionType:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		if n.ReturnType != nil {
+			Inspect(n.ReturnType, f)
+		}
+	case *UnionType:
+		for _, t := range n.Types {
+			Inspect(t, f)
+		}
+	}
+}
+
+// SortedKeys returns the keys of a dict literal in source order.
+func SortedKeys(dl *DictLiteral) []Expression {
+	keys := []Expression{}
+	for k := range dl.Pairs {
+		keys = append(keys, k)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := StartToken(keys[i]), StartToken(keys[j])
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return keys[i].String() < keys[j].String()
+	})
+
+	return keys
+}
+
+// StartToken returns the first token of node in the source, which is where
+// tools report positions for it.
+func StartToken(node Node) token.Token {
+	switch n := node.(type) {
+	case *Program:
+		if len(n.Statements) > 0 {
+			return StartToken(n.Statements[0])
+		}
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			return StartToken(n.Expression)
+		}
+		return n.Token
+	case *AssignStatement:
+		return n.Name.Token
+	case *InfixExpression:
+		return StartToken(n.Left)
+	case *CallExpression:
+		return StartToken(n.Function)
+	case *IndexExpression:
+		return StartToken(n.Left)
+	case *AttributeAccess:
+		return StartToken(n.Object)
+	case *VarStatement:
+		return n.Token
+	case *ReturnStatement:
+		return n.Token
+	case *YieldStatement:
+		return n.Token
+	case *WhileStatement:
+		return n.Token
+	case *ImportStatement:
+		return n.Token
+	case *BlockStatement:
+		return n.Token
+	case *Identifier:
+		return n.Token
+	case *IntegerLiteral:
+		return n.Token
+	case *FloatLiteral:
+		return n.Token
+	case *StringLiteral:
+		return n.Token
+	case *Boolean:
+		return n.Token
+	case *PrefixExpression:
+		return n.Token
+	case *IfExpression:
+		return n.Token
+	case *FunctionLiteral:
+		return n.Token
+	case *ArrayLiteral:
+		return n.Token
+	case *DictLiteral:
+		return n.Token
+	case *EnumStatement:
+		return n.Token
+	case *MatchExpression:
+		return n.Token
+	case *SetLiteral:
+		return n.Token
+	case *ForStatement:
+		return n.Token
+	case *NamedType:
+		return n.Token
+	case *ArrayType:
+		return n.Token
+	case *DictType:
+		return n.Token
+	case *FunctionType:
+		return n.Token
+	case *UnionType:
+		return n.Token
+	}
+
+	return token.Token{}
+}
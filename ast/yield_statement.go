@@ -0,0 +1,27 @@
+package ast
+
+import (
+	"bytes"
+	"zumbra/token"
+)
+
+type YieldStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ys.TokenLiteral() + " ")
+
+	if ys.Value != nil {
+		out.WriteString(ys.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
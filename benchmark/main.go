@@ -1,8 +1,14 @@
+// Benchmark times Zumbra programs on the VM or the evaluator. Run it with
+// -compare to see how much faster the VM runs them optimized:
+//
+//	go run ./benchmark -compare -count 5
 package main
 
 import (
 	"flag"
 	"fmt"
+	"os"
+	"regexp"
 	"time"
 	"zumbra/compiler"
 	"zumbra/evaluator"
@@ -13,8 +19,18 @@ import (
 )
 
 var engine = flag.String("engine", "vm", "use 'vm' or 'eval'")
+var optimization = flag.Int("O", compiler.MaxOptimization, "optimization level of the compiled programs, from 0 to 2")
+var compare = flag.Bool("compare", false, "run the vm at -O 0 and at the -O level and show the speedup")
+var run = flag.String("run", "", "only run the benchmarks whose name matches this regexp")
+var count = flag.Int("count", 3, "runs of each benchmark; the fastest is reported")
 
-var input = `
+type benchmark struct {
+	name  string
+	input string
+}
+
+var benchmarks = []benchmark{
+	{"fib", `
 var fibonacci << fct(x) {
 	if (x == 0) {
 		0
@@ -26,49 +42,154 @@ var fibonacci << fct(x) {
 		}
 	}
 };
-fibonacci(35);
-`
+fibonacci(30);
+`},
+	{"loop", `
+var i << 0;
+var total << 0;
+while (i < 3000000) {
+	total << total + i;
+	i << i + 1;
+}
+total;
+`},
+	{"calls", `
+var square << fct(x) { x * x };
+var add << fct(a, b) { a + b };
+var sumSquares << fct(n) {
+	var i << 0;
+	var total << 0;
+	while (i < n) {
+		total << add(total, square(i));
+		i << i + 1;
+	}
+	total
+};
+sumSquares(500000);
+`},
+	{"closures", `
+var counter << fct() {
+	var count << 0;
+	fct(step) { count + step }
+};
+var next << counter();
+var i << 0;
+var total << 0;
+while (i < 1000000) {
+	total << total + next(i);
+	i << i + 1;
+}
+total;
+`},
+	{"arrays", `
+var numbers << [];
+var i << 0;
+while (i < 2000) {
+	numbers << addToArrayEnd(numbers, i);
+	i << i + 1;
+}
+var total << 0;
+var round << 0;
+while (round < 200) {
+	for (n in numbers) {
+		total << total + n % 7;
+	}
+	round << round + 1;
+}
+total;
+`},
+}
 
-func main() {
-	flag.Parse()
+// measure runs input on the engine and returns the result of its last
+// expression and how long the program ran, without parsing and compiling.
+func measure(input, engine string, level int) (object.Object, time.Duration, error) {
+	program := parser.New(lexer.New(input)).ParseProgram()
 
-	var duration time.Duration
-	var result object.Object
+	if engine == "eval" {
+		env := object.NewEnvironment()
+		start := time.Now()
+		result := evaluator.Eval(program, env)
+		return result, time.Since(start), nil
+	}
 
-	l := lexer.New(input)
-	p := parser.New(l)
-	program := p.ParseProgram()
+	comp := compiler.New()
+	if err := comp.SetOptimization(level); err != nil {
+		return nil, 0, err
+	}
+	if err := comp.Compile(program); err != nil {
+		return nil, 0, fmt.Errorf("compiler error: %s", err)
+	}
+
+	machine := vm.New(comp.Bytecode())
+	start := time.Now()
+	if err := machine.Run(); err != nil {
+		return nil, 0, fmt.Errorf("vm error: %s", err)
+	}
+	return machine.LastPoppedStackElem(), time.Since(start), nil
+}
 
-	if *engine == "vm" {
-		comp := compiler.New()
-		err := comp.Compile(program)
+// fastest runs a benchmark count times and keeps the fastest run, which is
+// the least disturbed by the rest of the machine.
+func fastest(b benchmark, engine string, level int) (object.Object, time.Duration, error) {
+	var best time.Duration
+	var result object.Object
+	for i := 0; i < *count; i++ {
+		r, d, err := measure(b.input, engine, level)
 		if err != nil {
-			fmt.Printf("compiler error: %s", err)
-			return
+			return nil, 0, err
+		}
+		if i == 0 || d < best {
+			best, result = d, r
 		}
+	}
+	return result, best, nil
+}
 
-		machine := vm.New(comp.Bytecode())
-		start := time.Now()
+func main() {
+	flag.Parse()
+
+	filter, err := regexp.Compile(*run)
+	if err != nil {
+		fmt.Printf("bad -run: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *compare {
+		fmt.Printf("%-10s %12s %12s %8s\n", "benchmark", "-O 0", fmt.Sprintf("-O %d", *optimization), "speedup")
+	}
 
-		err = machine.Run()
+	for _, b := range benchmarks {
+		if !filter.MatchString(b.name) {
+			continue
+		}
+
+		result, duration, err := fastest(b, *engine, *optimization)
 		if err != nil {
-			fmt.Printf("vm error: %s", err)
-			return
+			fmt.Printf("%s: %s\n", b.name, err)
+			os.Exit(1)
 		}
 
-		duration = time.Since(start)
-		result = machine.LastPoppedStackElem()
-	} else {
+		if !*compare {
+			fmt.Printf(
+				"engine=%s, benchmark=%s, result=%s, duration=%s\n",
+				*engine,
+				b.name,
+				result.Inspect(),
+				duration)
+			continue
+		}
 
-		env := object.NewEnvironment()
-		start := time.Now()
-		result = evaluator.Eval(program, env)
-		duration = time.Since(start)
+		baseResult, baseDuration, err := fastest(b, "vm", compiler.O0)
+		if err != nil {
+			fmt.Printf("%s: %s\n", b.name, err)
+			os.Exit(1)
+		}
+		if baseResult.Inspect() != result.Inspect() {
+			fmt.Printf("%s: results differ: %s at -O 0, %s at -O %d\n", b.name, baseResult.Inspect(), result.Inspect(), *optimization)
+			os.Exit(1)
+		}
+		fmt.Printf("%-10s %12s %12s %7.2fx\n", b.name,
+			baseDuration.Round(time.Millisecond), duration.Round(time.Millisecond),
+			float64(baseDuration)/float64(duration))
 	}
-	fmt.Printf(
-		"engine=%s, result=%s, duration=%s\n",
-		*engine,
-		result.Inspect(),
-		duration)
-
 }
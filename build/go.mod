@@ -1,12 +0,0 @@
-module zumbra-generated
-
-go 1.21.0
-
-toolchain go1.24.2
-
-require (
-	github.com/go-sql-driver/mysql v1.9.2
-	github.com/golang-jwt/jwt/v5 v5.2.2
-)
-
-require filippo.io/edwards25519 v1.1.0 // indirect
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"zumbra/bundle"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/parser"
+	"zumbra/transpiler"
+)
+
+// buildCommand implements `zumbra build [-o output] [-work dir] file.zum`
+// and `zumbra build -bundle [-target os/arch] [-stub file] [-O level] [-o
+// output] file.zum`. The first generates a Go program from file.zum and
+// builds it with the Go toolchain; the second appends the compiled program
+// to a zumbra executable, which then runs it on the VM.
+func buildCommand(args []string) bool {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	output := flags.String("o", "", "executable to write, by default the file name without .zum")
+	work := flags.String("work", "", "directory to write the generated Go module to and keep")
+	bundled := flags.Bool("bundle", false, "bundle the compiled program with a zumbra executable instead of generating Go")
+	target := flags.String("target", "", "os/arch to bundle for, by default the one zumbra runs on")
+	stub := flags.String("stub", "", "zumbra executable to bundle with, instead of the one for the target")
+	flags.IntVar(&optimization, "O", compiler.DefaultOptimization, "optimization level of the bundled program, from 0 to 2")
+	files := parseInterspersed(flags, args)
+
+	if len(files) != 1 || (!*bundled && (*target != "" || *stub != "")) || (*bundled && *work != "") {
+		fmt.Println("usage: zumbra build [-o output] [-work dir] file.zum")
+		fmt.Println("       zumbra build -bundle [-target os/arch] [-stub file] [-O level] [-o output] file.zum")
+		return false
+	}
+	filename := files[0]
+
+	comp, _ := compileFile(filename)
+	if comp == nil {
+		return false
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filename, ".zum")
+		if *output == filename {
+			*output += ".out"
+		}
+		goos := runtime.GOOS
+		if *bundled && *target != "" {
+			goos, _, _ = strings.Cut(*target, "/")
+		}
+		if goos == "windows" {
+			*output += ".exe"
+		}
+	}
+
+	if *bundled {
+		data, err := comp.Bytecode().MarshalBinary()
+		if err != nil {
+			fmt.Printf("Error when trying to serialize the bytecode: %s\n", err)
+			return false
+		}
+
+		if *stub == "" {
+			*stub, err = bundle.Stub(*target)
+			if err != nil {
+				fmt.Printf("Build error: %s\n", err)
+				return false
+			}
+		}
+		if err := bundle.Create(*output, *stub, data); err != nil {
+			fmt.Printf("Build error: %s\n", err)
+			return false
+		}
+		return true
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error when trying to read the file: %s\n", err)
+		return false
+	}
+	program := parser.New(lexer.New(string(data))).ParseProgram()
+
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		fmt.Printf("Path error: %s\n", err)
+		return false
+	}
+	opts := transpiler.Options{Dir: filepath.Dir(absPath), Source: filepath.Base(filename)}
+	if m := projectFor(filename); m != nil {
+		opts.ImportPaths = m.ImportPaths()
+	}
+
+	code, err := transpiler.Generate(program, opts)
+	if err != nil {
+		fmt.Printf("Build error: %s\n", err)
+		return false
+	}
+
+	dir := *work
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "zumbra-build-")
+		if err != nil {
+			fmt.Printf("Build error: %s\n", err)
+			return false
+		}
+		defer os.RemoveAll(dir)
+	}
+	if err := transpiler.WriteModule(dir, map[string][]byte{".": code}); err != nil {
+		fmt.Printf("Build error: %s\n", err)
+		return false
+	}
+
+	executable, err := filepath.Abs(*output)
+	if err != nil {
+		fmt.Printf("Path error: %s\n", err)
+		return false
+	}
+
+	cmd := exec.Command("go", "build", "-o", executable, ".")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("Error when building the Go program: %s\n", err)
+		return false
+	}
+	return true
+}
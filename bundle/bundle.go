@@ -0,0 +1,148 @@
+// Package bundle makes standalone executables out of compiled programs. A
+// bundle is a zumbra executable, the stub, with the program's bytecode
+// appended to it: when the stub starts and finds a program at its end, it
+// runs that program instead of the zumbra command line.
+package bundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// A bundle ends with a trailer: the length of the program as a
+// little-endian uint64, then the magic bytes.
+const magic = "\x00ZUMBRA-BUNDLE\x00"
+
+const trailerSize = 8 + len(magic)
+
+// StubsEnv names the environment variable pointing at the directory with
+// the stubs for other targets.
+const StubsEnv = "ZUMBRA_STUBS"
+
+// Write writes stub, without the program it may already carry, followed
+// by program to w.
+func Write(w io.Writer, stub []byte, program []byte) error {
+	if n, ok := programSize(stub); ok && n <= uint64(len(stub)-trailerSize) {
+		stub = stub[:len(stub)-trailerSize-int(n)]
+	}
+
+	var trailer bytes.Buffer
+	binary.Write(&trailer, binary.LittleEndian, uint64(len(program)))
+	trailer.WriteString(magic)
+
+	for _, part := range [][]byte{stub, program, trailer.Bytes()} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create writes the bundle of program with the stub at stubPath to path,
+// as an executable.
+func Create(path, stubPath string, program []byte) error {
+	stub, err := os.ReadFile(stubPath)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, stub, program); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0755)
+}
+
+// Read returns the program bundled in the executable at path, or nil when
+// there is none. An executable that cannot be read carries no program as
+// far as Read is concerned: it only fails when the executable ends with a
+// trailer but the program is corrupt.
+func Read(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil
+	}
+	size := info.Size()
+	if size < int64(trailerSize) {
+		return nil, nil
+	}
+
+	trailer := make([]byte, trailerSize)
+	if _, err := f.ReadAt(trailer, size-int64(trailerSize)); err != nil {
+		return nil, nil
+	}
+	n, ok := programSize(trailer)
+	if !ok {
+		return nil, nil
+	}
+	if n > uint64(size-int64(trailerSize)) {
+		return nil, errors.New("the bundled program is truncated")
+	}
+
+	program := make([]byte, n)
+	if _, err := f.ReadAt(program, size-int64(trailerSize)-int64(n)); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// programSize returns the size of the program data ends with, if it ends
+// with a trailer.
+func programSize(data []byte) (uint64, bool) {
+	if len(data) < trailerSize || !bytes.HasSuffix(data, []byte(magic)) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(data[len(data)-trailerSize:]), true
+}
+
+// Stub returns the zumbra executable to bundle programs for target, an
+// "os/arch" pair like "linux/arm64". An empty target, or the one zumbra
+// runs on, uses the running executable. Other targets use
+// zumbra-<os>-<arch> from the directory named by ZUMBRA_STUBS, or from the
+// stubs directory next to the running executable.
+func Stub(target string) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if target == "" || target == runtime.GOOS+"/"+runtime.GOARCH {
+		return self, nil
+	}
+
+	goos, goarch, ok := strings.Cut(target, "/")
+	if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+		return "", fmt.Errorf("invalid target %q, want os/arch", target)
+	}
+
+	dir := os.Getenv(StubsEnv)
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(self), "stubs")
+	}
+	stub := filepath.Join(dir, StubName(goos, goarch))
+	if _, err := os.Stat(stub); err != nil {
+		return "", fmt.Errorf("no zumbra executable for %s: build one with `GOOS=%s GOARCH=%s go build -o %s` or set %s", target, goos, goarch, stub, StubsEnv)
+	}
+	return stub, nil
+}
+
+// StubName is the file name of the stub for goos and goarch.
+func StubName(goos, goarch string) string {
+	name := "zumbra-" + goos + "-" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
@@ -0,0 +1,105 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAndRead(t *testing.T) {
+	dir := t.TempDir()
+	stub := filepath.Join(dir, "stub")
+	if err := os.WriteFile(stub, []byte("executable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	program, err := Read(stub)
+	if err != nil || program != nil {
+		t.Fatalf("stub should carry no program. got=%q, %v", program, err)
+	}
+
+	app := filepath.Join(dir, "app")
+	if err := Create(app, stub, []byte("first program")); err != nil {
+		t.Fatal(err)
+	}
+	program, err = Read(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(program) != "first program" {
+		t.Errorf("wrong program. got=%q", program)
+	}
+
+	// Bundling with a bundle replaces its program.
+	again := filepath.Join(dir, "again")
+	if err := Create(again, app, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("executablesecond")) {
+		t.Errorf("old program kept. got=%q", data)
+	}
+	program, err = Read(again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(program) != "second" {
+		t.Errorf("wrong program. got=%q", program)
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	var out bytes.Buffer
+	if err := Write(&out, []byte("stub"), []byte("program")); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(path, out.Bytes()[6:], 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Read(path)
+	if err == nil || err.Error() != "the bundled program is truncated" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+func TestReadUnreadable(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, path := range []string{filepath.Join(dir, "missing"), dir} {
+		program, err := Read(path)
+		if err != nil || program != nil {
+			t.Errorf("%s should carry no program. got=%q, %v", path, program, err)
+		}
+	}
+}
+
+func TestStub(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(StubsEnv, dir)
+
+	if _, err := Stub("linux"); err == nil || err.Error() != `invalid target "linux", want os/arch` {
+		t.Errorf("wrong error. got=%v", err)
+	}
+	if _, err := Stub("plan9/mips"); err == nil {
+		t.Errorf("expected an error for a missing stub")
+	}
+
+	want := filepath.Join(dir, "zumbra-windows-arm64.exe")
+	if err := os.WriteFile(want, []byte("stub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Stub("windows/arm64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("wrong stub. want=%s, got=%s", want, got)
+	}
+}
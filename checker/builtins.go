@@ -0,0 +1,104 @@
+package checker
+
+import (
+	"strings"
+	"zumbra/lexer"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+)
+
+func (c *Checker) builtinType(signature string) Type {
+	open := strings.Index(signature, "(")
+	close := matchingParen(signature, open)
+	if open < 0 || close < 0 {
+		return &Function{Unknown: true, Return: Any}
+	}
+
+	fct := &Function{Return: Null}
+
+	for _, param := range splitTopLevel(signature[open+1 : close]) {
+		name, typ, _ := strings.Cut(param, ":")
+		name = strings.TrimSpace(name)
+
+		if strings.HasPrefix(name, "...") {
+			fct.Variadic = true
+		} else if !strings.HasSuffix(name, "?") {
+			fct.Required++
+		}
+
+		fct.Parameters = append(fct.Parameters, c.parseTypeString(typ))
+	}
+
+	if rest := strings.TrimSpace(signature[close+1:]); strings.HasPrefix(rest, ":") {
+		fct.Return = c.parseTypeString(strings.TrimPrefix(rest, ":"))
+	}
+
+	return fct
+}
+
+func (c *Checker) parseTypeString(input string) Type {
+	p := parser.New(lexer.New(strings.TrimSpace(input)))
+	node := p.ParseType()
+	if node == nil || len(p.Errors()) != 0 {
+		return Any
+	}
+	return c.typeFromNode(node)
+}
+
+func (c *Checker) defineBuiltins(s *scope) {
+	for _, b := range builtins.Builtins {
+		signature, ok := builtins.Signatures[b.Name]
+		if !ok {
+			s.define(b.Name, &Function{Unknown: true, Return: Any}, false)
+			continue
+		}
+		s.define(b.Name, c.builtinType(signature), false)
+	}
+}
+
+func matchingParen(s string, open int) int {
+	if open < 0 {
+		return -1
+	}
+
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func splitTopLevel(s string) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if strings.TrimSpace(s[start:]) != "" {
+		parts = append(parts, s[start:])
+	}
+
+	return parts
+}
@@ -0,0 +1,662 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+	"zumbra/ast"
+)
+
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type symbol struct {
+	typ      Type
+	declared bool
+}
+
+type scope struct {
+	outer   *scope
+	symbols map[string]*symbol
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, symbols: map[string]*symbol{}}
+}
+
+func (s *scope) define(name string, t Type, declared bool) *symbol {
+	sym := &symbol{typ: t, declared: declared}
+	s.symbols[name] = sym
+	return sym
+}
+
+func (s *scope) resolve(name string) (*symbol, bool) {
+	sym, ok := s.symbols[name]
+	if !ok && s.outer != nil {
+		return s.outer.resolve(name)
+	}
+	return sym, ok
+}
+
+type Checker struct {
+	errors     []Error
+	scope      *scope
+	reassigned map[string]bool
+	returns    []*frame
+	builtins   *scope
+	imports    bool
+	enums      map[string]*Enum
+}
+
+type frame struct {
+	expected  *Function
+	returned  []Type
+	generator bool
+}
+
+func New() *Checker {
+	c := &Checker{reassigned: map[string]bool{}, enums: map[string]*Enum{}}
+	c.builtins = newScope(nil)
+	c.defineBuiltins(c.builtins)
+	c.scope = newScope(c.builtins)
+	return c
+}
+
+// Check infers the types of program and reports every mismatch it can prove
+// without running it. Untyped code is only checked where the types of the
+// values involved are known.
+func Check(program *ast.Program) []Error {
+	return New().Check(program)
+}
+
+func (c *Checker) Check(program *ast.Program) []Error {
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStatement:
+			c.reassigned[node.Name.Value] = true
+		case *ast.ImportStatement:
+			c.imports = true
+		}
+		return true
+	})
+
+	for _, stmt := range program.Statements {
+		c.checkStatement(stmt)
+	}
+
+	return c.errors
+}
+
+// Annotated reports whether program declares any type.
+func Annotated(program *ast.Program) bool {
+	found := false
+	ast.Inspect(program, func(node ast.Node) bool {
+		if _, ok := node.(ast.TypeNode); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func (c *Checker) errorf(node ast.Node, format string, a ...interface{}) {
+	tok := ast.StartToken(node)
+	c.errors = append(c.errors, Error{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (c *Checker) checkStatement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			return Null
+		}
+		return c.checkExpression(stmt.Expression)
+
+	case *ast.VarStatement:
+		c.checkVar(stmt)
+
+	case *ast.AssignStatement:
+		valueType := c.checkExpression(stmt.Value)
+
+		if sym, ok := c.scope.resolve(stmt.Name.Value); ok && sym.declared && !assignable(valueType, sym.typ) {
+			c.errorf(stmt.Value, "cannot assign %s to %s of type %s", valueType, stmt.Name.Value, sym.typ)
+		}
+
+	case *ast.ReturnStatement:
+		valueType := c.checkExpression(stmt.ReturnValue)
+
+		if len(c.returns) > 0 {
+			f := c.returns[len(c.returns)-1]
+			f.returned = append(f.returned, valueType)
+			if f.expected != nil && !f.generator && !assignable(valueType, f.expected.Return) {
+				c.errorf(stmt.ReturnValue, "cannot return %s from function returning %s", valueType, f.expected.Return)
+			}
+		}
+
+	case *ast.YieldStatement:
+		c.checkExpression(stmt.Value)
+		if len(c.returns) == 0 {
+			c.errorf(stmt, "yield outside of function")
+		}
+
+	case *ast.WhileStatement:
+		c.checkExpression(stmt.Condition)
+		c.checkBlock(stmt.Body)
+
+	case *ast.ForStatement:
+		c.checkFor(stmt)
+
+	case *ast.EnumStatement:
+		enum := &Enum{Name: stmt.Name.Value}
+		for _, v := range stmt.Variants {
+			if enum.has(v.Value) {
+				c.errorf(v, "duplicate variant %s in enum %s", v.Value, enum.Name)
+				continue
+			}
+			enum.Variants = append(enum.Variants, v.Value)
+		}
+		c.enums[enum.Name] = enum
+		c.scope.define(enum.Name, enum, true)
+
+	case *ast.BlockStatement:
+		return c.checkBlock(stmt)
+	}
+
+	return Null
+}
+
+func (c *Checker) checkVar(stmt *ast.VarStatement) {
+	var declared Type
+	if stmt.Type != nil {
+		declared = c.typeFromNode(stmt.Type)
+	}
+
+	var sym *symbol
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && declared == nil {
+		// Defining the name first lets recursive calls see the signature.
+		sym = c.scope.define(stmt.Name.Value, c.signature(fl), false)
+	}
+
+	valueType := c.checkExpression(stmt.Value)
+
+	switch {
+	case declared != nil:
+		if !assignable(valueType, declared) {
+			c.errorf(stmt.Value, "cannot assign %s to %s of type %s", valueType, stmt.Name.Value, declared)
+		}
+		c.scope.define(stmt.Name.Value, declared, true)
+	case c.reassigned[stmt.Name.Value]:
+		c.scope.define(stmt.Name.Value, Any, false)
+	case sym != nil:
+		sym.typ = valueType
+	default:
+		c.scope.define(stmt.Name.Value, valueType, false)
+	}
+}
+
+func (c *Checker) checkBlock(block *ast.BlockStatement) Type {
+	if block == nil {
+		return Null
+	}
+
+	var last Type = Null
+	for _, stmt := range block.Statements {
+		last = c.checkStatement(stmt)
+		if _, ok := stmt.(*ast.ExpressionStatement); !ok {
+			last = Null
+		}
+	}
+
+	return last
+}
+
+func (c *Checker) signature(fl *ast.FunctionLiteral) *Function {
+	fct := &Function{Return: Any, Required: len(fl.Parameters)}
+
+	for _, p := range fl.Parameters {
+		if p.Type != nil {
+			fct.Parameters = append(fct.Parameters, c.typeFromNode(p.Type))
+		} else {
+			fct.Parameters = append(fct.Parameters, Any)
+		}
+	}
+
+	if fl.ReturnType != nil {
+		fct.Return = c.typeFromNode(fl.ReturnType)
+	}
+
+	return fct
+}
+
+func (c *Checker) checkFunction(fl *ast.FunctionLiteral) Type {
+	fct := c.signature(fl)
+
+	c.scope = newScope(c.scope)
+	for i, p := range fl.Parameters {
+		c.scope.define(p.Value, fct.Parameters[i], p.Type != nil)
+	}
+
+	f := &frame{generator: isGenerator(fl)}
+	if fl.ReturnType != nil {
+		f.expected = fct
+	}
+	c.returns = append(c.returns, f)
+
+	last := c.checkBlock(fl.Body)
+
+	c.returns = c.returns[:len(c.returns)-1]
+	c.scope = c.scope.outer
+
+	if f.generator {
+		if f.expected != nil && !assignable(Iterator, fct.Return) {
+			c.errorf(fl.ReturnType, "generator function must return iterator, not %s", fct.Return)
+		}
+		fct.Return = Iterator
+		return fct
+	}
+
+	if f.expected != nil {
+		if n := len(fl.Body.Statements); n > 0 {
+			if es, ok := fl.Body.Statements[n-1].(*ast.ExpressionStatement); ok && !assignable(last, fct.Return) {
+				c.errorf(es, "cannot return %s from function returning %s", last, fct.Return)
+			}
+		}
+		return fct
+	}
+
+	returned := f.returned
+	if n := len(fl.Body.Statements); n == 0 || !isReturn(fl.Body.Statements[n-1]) {
+		returned = append(returned, last)
+	}
+
+	fct.Return = join(returned)
+	if _, ok := fct.Return.(*Union); ok {
+		fct.Return = Any
+	}
+
+	return fct
+}
+
+// isGenerator reports whether fl yields, ignoring functions nested in it.
+func isGenerator(fl *ast.FunctionLiteral) bool {
+	found := false
+	ast.Inspect(fl.Body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.YieldStatement:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+func isReturn(stmt ast.Statement) bool {
+	_, ok := stmt.(*ast.ReturnStatement)
+	return ok
+}
+
+func (c *Checker) checkExpression(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case nil:
+		return Any
+
+	case *ast.IntegerLiteral:
+		if exp.Big != nil {
+			return BigInt
+		}
+		return Int
+
+	case *ast.FloatLiteral:
+		return Float
+
+	case *ast.StringLiteral:
+		return String
+
+	case *ast.Boolean:
+		return Bool
+
+	case *ast.Identifier:
+		sym, ok := c.scope.resolve(exp.Value)
+		if !ok {
+			return Any
+		}
+		// Imported files are not followed, so any builtin may be shadowed.
+		if c.imports && c.builtins.symbols[exp.Value] == sym {
+			return Any
+		}
+		return sym.typ
+
+	case *ast.ArrayLiteral:
+		elements := []Type{}
+		for _, el := range exp.Elements {
+			elements = append(elements, c.checkExpression(el))
+		}
+		return &Array{Element: join(elements)}
+
+	case *ast.DictLiteral:
+		keys, values := []Type{}, []Type{}
+		for _, key := range ast.SortedKeys(exp) {
+			keyType := c.checkExpression(key)
+			if !assignable(keyType, &Union{Types: []Type{String, Int, Bool}}) {
+				c.errorf(key, "unusable as dict key: %s", keyType)
+			}
+			keys = append(keys, keyType)
+			values = append(values, c.checkExpression(exp.Pairs[key]))
+		}
+		return &Dict{Key: join(keys), Value: join(values)}
+
+	case *ast.PrefixExpression:
+		right := c.checkExpression(exp.Right)
+
+		switch exp.Operator {
+		case "!":
+			return Bool
+		case "-":
+			if isDynamic(right) {
+				return Any
+			}
+			if !isNumeric(right) {
+				c.errorf(exp, "unsupported type for negation: %s", right)
+				return Any
+			}
+			return right
+		}
+		return Any
+
+	case *ast.InfixExpression:
+		return c.checkInfix(exp)
+
+	case *ast.IfExpression:
+		c.checkExpression(exp.Condition)
+		consequence := c.checkBlock(exp.Consequence)
+		if exp.Alternative == nil {
+			return consequence
+		}
+		alternative := c.checkBlock(exp.Alternative)
+		if consequence.String() == alternative.String() {
+			return consequence
+		}
+		return Any
+
+	case *ast.FunctionLiteral:
+		return c.checkFunction(exp)
+
+	case *ast.CallExpression:
+		return c.checkCall(exp)
+
+	case *ast.IndexExpression:
+		return c.checkIndex(exp)
+
+	case *ast.AttributeAccess:
+		return c.checkAttribute(exp)
+
+	case *ast.SetLiteral:
+		for _, el := range exp.Elements {
+			elType := c.checkExpression(el)
+			if !assignable(elType, &Union{Types: []Type{String, Int, Bool}}) {
+				if _, ok := elType.(*Variant); !ok {
+					c.errorf(el, "unusable as set element: %s", elType)
+				}
+			}
+		}
+		return Set
+
+	case *ast.MatchExpression:
+		return c.checkMatch(exp)
+	}
+
+	return Any
+}
+
+func (c *Checker) checkInfix(exp *ast.InfixExpression) Type {
+	left := c.checkExpression(exp.Left)
+	right := c.checkExpression(exp.Right)
+
+	switch exp.Operator {
+	case "==", "!=", "and", "or":
+		return Bool
+	}
+
+	if isDynamic(left) || isDynamic(right) {
+		switch exp.Operator {
+		case "<", ">", "<=", ">=":
+			return Bool
+		}
+		return Any
+	}
+
+	switch exp.Operator {
+	case "+", "-", "*", "/", "%":
+		if isNumeric(left) && isNumeric(right) {
+			result := numericResult(left, right)
+			if result == Decimal && exp.Operator == "%" {
+				break
+			}
+			return result
+		}
+		if exp.Operator == "+" && left == String && right == String {
+			return String
+		}
+
+	case "<", ">", "<=", ">=":
+		if isNumeric(left) && isNumeric(right) {
+			return Bool
+		}
+
+	default:
+		return Any
+	}
+
+	c.errorf(exp, "unsupported types for %s: %s and %s", exp.Operator, left, right)
+	return Any
+}
+
+func (c *Checker) checkCall(exp *ast.CallExpression) Type {
+	callee := c.checkExpression(exp.Function)
+
+	args := []Type{}
+	for _, a := range exp.Arguments {
+		args = append(args, c.checkExpression(a))
+	}
+
+	if isDynamic(callee) {
+		return Any
+	}
+
+	fct, ok := callee.(*Function)
+	if !ok {
+		c.errorf(exp, "cannot call value of type %s", callee)
+		return Any
+	}
+
+	if fct.Unknown {
+		return fct.Return
+	}
+
+	name := exp.Function.String()
+
+	if len(args) < fct.Required || (!fct.Variadic && len(args) > len(fct.Parameters)) {
+		want := fmt.Sprintf("%d", fct.Required)
+		switch {
+		case fct.Variadic:
+			want = fmt.Sprintf("at least %d", fct.Required)
+		case fct.Required != len(fct.Parameters):
+			want = fmt.Sprintf("%d to %d", fct.Required, len(fct.Parameters))
+		}
+		c.errorf(exp, "wrong number of arguments to `%s`. got=%d, want=%s", name, len(args), want)
+		return fct.Return
+	}
+
+	for i, arg := range args {
+		var param Type
+		if i < len(fct.Parameters) {
+			param = fct.Parameters[i]
+		} else {
+			param = fct.Parameters[len(fct.Parameters)-1]
+		}
+
+		if !assignable(arg, param) {
+			c.errorf(exp.Arguments[i], "argument %d to `%s` has type %s, want %s", i+1, name, arg, param)
+		}
+	}
+
+	return fct.Return
+}
+
+func (c *Checker) checkIndex(exp *ast.IndexExpression) Type {
+	left := c.checkExpression(exp.Left)
+	index := c.checkExpression(exp.Index)
+
+	if isDynamic(left) {
+		return Any
+	}
+
+	switch left := left.(type) {
+	case *Array:
+		if !isDynamic(index) && index != Int {
+			c.errorf(exp.Index, "array index must be int, got %s", index)
+		}
+		return left.Element
+	case *Dict:
+		if !assignable(index, left.Key) {
+			c.errorf(exp.Index, "dict key must be %s, got %s", left.Key, index)
+		}
+		return left.Value
+	}
+
+	c.errorf(exp, "index operator not supported: %s", left)
+	return Any
+}
+
+var dateAttributes = map[string]Type{
+	"hour":     Int,
+	"minute":   Int,
+	"day":      Int,
+	"second":   Int,
+	"month":    Int,
+	"year":     Int,
+	"fullDate": String,
+}
+
+func (c *Checker) checkAttribute(exp *ast.AttributeAccess) Type {
+	object := c.checkExpression(exp.Object)
+
+	if isDynamic(object) {
+		return Any
+	}
+
+	if enum, ok := object.(*Enum); ok {
+		if !enum.has(exp.Property.Value) {
+			c.errorf(exp.Property, "unknown variant %s for enum %s", exp.Property.Value, enum.Name)
+			return Any
+		}
+		return &Variant{Enum: enum}
+	}
+
+	if object == Date {
+		if t, ok := dateAttributes[exp.Property.Value]; ok {
+			return t
+		}
+		c.errorf(exp.Property, "unknown attribute %s for date", exp.Property.Value)
+		return Any
+	}
+
+	c.errorf(exp, "type %s has no attributes", object)
+	return Any
+}
+
+func (c *Checker) checkFor(stmt *ast.ForStatement) {
+	iterable := c.checkExpression(stmt.Iterable)
+
+	var element Type = Any
+	switch t := iterable.(type) {
+	case *Array:
+		element = t.Element
+	case *Dict:
+		element = t.Key
+	case Basic:
+		switch t {
+		case String:
+			element = String
+		case Any, Set, Iterator:
+		default:
+			c.errorf(stmt.Iterable, "cannot iterate over %s", t)
+		}
+	case *Union:
+	default:
+		c.errorf(stmt.Iterable, "cannot iterate over %s", t)
+	}
+
+	c.scope.define(stmt.Variable.Value, element, false)
+	if c.reassigned[stmt.Variable.Value] {
+		c.scope.define(stmt.Variable.Value, Any, false)
+	}
+
+	c.checkBlock(stmt.Body)
+}
+
+func (c *Checker) checkMatch(exp *ast.MatchExpression) Type {
+	subject := c.checkExpression(exp.Subject)
+
+	var enum *Enum
+	if v, ok := subject.(*Variant); ok {
+		enum = v.Enum
+	}
+
+	covered := map[string]bool{}
+	hasElse := false
+	results := []Type{}
+
+	for _, arm := range exp.Arms {
+		if arm.Pattern == nil {
+			hasElse = true
+		} else {
+			pattern := c.checkExpression(arm.Pattern)
+
+			if v, ok := pattern.(*Variant); ok {
+				if enum == nil && isDynamic(subject) {
+					enum = v.Enum
+				}
+				if access, ok := arm.Pattern.(*ast.AttributeAccess); ok && v.Enum == enum {
+					covered[access.Property.Value] = true
+				}
+			}
+
+			if !isDynamic(subject) && !isDynamic(pattern) && !assignable(pattern, subject) {
+				c.errorf(arm.Pattern, "cannot match %s against %s", pattern, subject)
+			}
+		}
+
+		results = append(results, c.checkBlock(arm.Body))
+	}
+
+	if enum != nil && !hasElse {
+		missing := []string{}
+		for _, v := range enum.Variants {
+			if !covered[v] {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) > 0 {
+			c.errorf(exp, "match on %s is not exhaustive, missing: %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	result := join(results)
+	if _, ok := result.(*Union); ok || len(results) == 0 {
+		return Any
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"testing"
+	"zumbra/ast"
+	"zumbra/lexer"
+	"zumbra/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestCheckerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var x: int << "a";`, []string{"1:15: cannot assign string to x of type int"}},
+		{`var x: float << 1;`, nil},
+		{`var xs: [int] << [1, "a"];`, []string{"1:18: cannot assign [int | string] to xs of type [int]"}},
+		{`var x: int | string << "a"; x << 2;`, nil},
+		{`var x: int << 1; x << "a";`, []string{"1:23: cannot assign string to x of type int"}},
+		{`1 + {"a": 1}`, []string{"1:1: unsupported types for +: int and {string: int}"}},
+		{`"a" - "b"`, []string{"1:1: unsupported types for -: string and string"}},
+		{`"a" + "b"`, nil},
+		{`-"a"`, []string{"1:1: unsupported type for negation: string"}},
+		{`sizeOf({"a": 1})`, []string{"1:8: argument 1 to `sizeOf` has type {string: int}, want string | [any] | set | iterator"}},
+		{`sizeOf("a", "b")`, []string{"1:1: wrong number of arguments to `sizeOf`. got=2, want=1"}},
+		{`toString([1])`, []string{"1:10: argument 1 to `toString` has type [int], want int | float | bool | bigint | decimal"}},
+		{`var add << fct(a: int, b: int): int { a + b }; add(1, "2");`, []string{"1:55: argument 2 to `add` has type string, want int"}},
+		{`var f << fct(a: int): string { a };`, []string{"1:32: cannot return int from function returning string"}},
+		{`var f << fct(a: int): string { return a; };`, []string{"1:39: cannot return int from function returning string"}},
+		{`var f << fct() { 1 }; var s: string << f();`, []string{"1:40: cannot assign int to s of type string"}},
+		{`var x << 1; x()`, []string{"1:13: cannot call value of type int"}},
+		{`[1, 2]["a"]`, []string{"1:8: array index must be int, got string"}},
+		{`1[0]`, []string{"1:1: index operator not supported: int"}},
+		{`date().hour + 1`, nil},
+		{`"a".hour`, []string{"1:1: type string has no attributes"}},
+		{`var t: strin << 1;`, []string{"1:8: unknown type strin"}},
+		{`var m: float << max([1.5, 2.5]); var n: decimal << min([decimal("1"), 2]);`, nil},
+		{`max(["a"])`, []string{"1:5: argument 1 to `max` has type [string], want [int | bigint | float | decimal] | iterator"}},
+	}
+
+	for _, tt := range tests {
+		errors := Check(parse(t, tt.input))
+
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%v, got=%v", tt.input, tt.expected, errors)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, msg, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestUntypedPrograms(t *testing.T) {
+	inputs := []string{
+		`var x << 1; x << "a"; x + 1;`,
+		`var f << fct(n) { if (n < 2) { return n; }; f(n - 1) + f(n - 2) }; f(10);`,
+		`var arr << [1, "a", true]; arr[0] + 1;`,
+		`var d << {"a": 1}; d["a"] * 2;`,
+		"import \"lib.zum\"\nsum(1);",
+	}
+
+	for _, input := range inputs {
+		if errors := Check(parse(t, input)); len(errors) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, errors)
+		}
+	}
+}
+
+func TestEnumsAndSets(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`enum S { A, B } var f << fct(s: S) { match (s) { S.A => 1 } };`, []string{"1:38: match on S is not exhaustive, missing: B"}},
+		{`enum S { A, B } var f << fct(s: S) { match (s) { S.A => 1, else => 2 } };`, nil},
+		{`enum S { A, B } S.C`, []string{"1:19: unknown variant C for enum S"}},
+		{`enum S { A } var s: S << 1;`, []string{"1:26: cannot assign int to s of type S"}},
+		{`enum S { A } var s: S << S.A;`, nil},
+		{`sizeOf({1, 2})`, nil},
+		{`{[1]}`, []string{"1:2: unusable as set element: [int]"}},
+		{`for (x in 1) { x }`, []string{"1:11: cannot iterate over int"}},
+		{`for (x in ["a"]) { x - 1 }`, []string{"1:20: unsupported types for -: string and int"}},
+	}
+
+	for _, tt := range tests {
+		errors := Check(parse(t, tt.input))
+
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%v, got=%v", tt.input, tt.expected, errors)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, msg, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestExactNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var d: decimal << decimal("1.5") + 1;`, nil},
+		{`var d: decimal << 1;`, nil},
+		{`var b: bigint << 99999999999999999999;`, nil},
+		{`var i: int << 99999999999999999999;`, []string{"1:15: cannot assign bigint to i of type int"}},
+		{`var f: float << decimal("1.5") * 2.0;`, []string{"1:17: cannot assign decimal to f of type float"}},
+		{`decimal("1.5") % 2`, []string{"1:1: unsupported types for %: decimal and int"}},
+	}
+
+	for _, tt := range tests {
+		errors := Check(parse(t, tt.input))
+
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%v, got=%v", tt.input, tt.expected, errors)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, msg, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var gen << fct() { yield 1; }; for (x in gen()) { x }`, nil},
+		{`var gen << fct(): iterator { yield 1; return 2; };`, nil},
+		{`var gen << fct(): int { yield 1; };`, []string{"1:19: generator function must return iterator, not int"}},
+		{`var gen << fct() { yield 1; }; var n: int << gen();`, []string{"1:46: cannot assign iterator to n of type int"}},
+		{`yield 1;`, []string{"1:1: yield outside of function"}},
+		{`var gen << fct() { yield 1; }; sum(gen())`, nil},
+	}
+
+	for _, tt := range tests {
+		errors := Check(parse(t, tt.input))
+
+		if len(errors) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors. want=%v, got=%v", tt.input, tt.expected, errors)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, msg, errors[i].Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package checker
+
+import (
+	"strings"
+	"zumbra/ast"
+)
+
+type Type interface {
+	String() string
+}
+
+type Basic string
+
+const (
+	Any      Basic = "any"
+	Int      Basic = "int"
+	Float    Basic = "float"
+	BigInt   Basic = "bigint"
+	Decimal  Basic = "decimal"
+	Iterator Basic = "iterator"
+	String   Basic = "string"
+	Bool     Basic = "bool"
+	Null     Basic = "null"
+	Date     Basic = "date"
+	Set      Basic = "set"
+)
+
+func (b Basic) String() string { return string(b) }
+
+type Array struct {
+	Element Type
+}
+
+func (a *Array) String() string { return "[" + a.Element.String() + "]" }
+
+type Dict struct {
+	Key   Type
+	Value Type
+}
+
+func (d *Dict) String() string {
+	if d.Key == Any && d.Value == Any {
+		return "dict"
+	}
+	return "{" + d.Key.String() + ": " + d.Value.String() + "}"
+}
+
+type Function struct {
+	Parameters []Type
+	Required   int
+	Variadic   bool
+	Return     Type
+	Unknown    bool
+}
+
+func (f *Function) String() string {
+	if f.Unknown {
+		return "fct"
+	}
+
+	params := []string{}
+	for i, p := range f.Parameters {
+		switch {
+		case f.Variadic && i == len(f.Parameters)-1:
+			params = append(params, "..."+p.String())
+		case i >= f.Required:
+			params = append(params, p.String()+"?")
+		default:
+			params = append(params, p.String())
+		}
+	}
+
+	return "fct(" + strings.Join(params, ", ") + "): " + f.Return.String()
+}
+
+type Enum struct {
+	Name     string
+	Variants []string
+}
+
+func (e *Enum) String() string { return "enum " + e.Name }
+
+func (e *Enum) has(variant string) bool {
+	for _, v := range e.Variants {
+		if v == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// Variant is the type of the values of an enum.
+type Variant struct {
+	Enum *Enum
+}
+
+func (v *Variant) String() string { return v.Enum.Name }
+
+type Union struct {
+	Types []Type
+}
+
+func (u *Union) String() string {
+	types := []string{}
+	for _, t := range u.Types {
+		types = append(types, t.String())
+	}
+	return strings.Join(types, " | ")
+}
+
+var namedTypes = map[string]Type{
+	"any":      Any,
+	"int":      Int,
+	"float":    Float,
+	"bigint":   BigInt,
+	"decimal":  Decimal,
+	"string":   String,
+	"bool":     Bool,
+	"null":     Null,
+	"date":     Date,
+	"set":      Set,
+	"iterator": Iterator,
+	"array":    &Array{Element: Any},
+	"dict":     &Dict{Key: Any, Value: Any},
+	"fct":      &Function{Unknown: true, Return: Any},
+}
+
+func isNumeric(t Type) bool {
+	return t == Int || t == Float || t == BigInt || t == Decimal
+}
+
+// numericResult mirrors the VM: decimals absorb every other number, floats
+// absorb integers and a bigint absorbs an int.
+func numericResult(left, right Type) Type {
+	for _, t := range []Type{Decimal, Float, BigInt} {
+		if left == t || right == t {
+			return t
+		}
+	}
+	return Int
+}
+
+func isDynamic(t Type) bool {
+	if t == Any {
+		return true
+	}
+	_, ok := t.(*Union)
+	return ok
+}
+
+// join merges the given types into a single type, collapsing duplicates.
+func join(types []Type) Type {
+	distinct := []Type{}
+	seen := map[string]bool{}
+
+	for _, t := range types {
+		if t == Any {
+			return Any
+		}
+
+		members := []Type{t}
+		if u, ok := t.(*Union); ok {
+			members = u.Types
+		}
+
+		for _, m := range members {
+			if !seen[m.String()] {
+				seen[m.String()] = true
+				distinct = append(distinct, m)
+			}
+		}
+	}
+
+	switch len(distinct) {
+	case 0:
+		return Any
+	case 1:
+		return distinct[0]
+	default:
+		return &Union{Types: distinct}
+	}
+}
+
+func assignable(src, dst Type) bool {
+	if src == Any || dst == Any || src == Null {
+		return true
+	}
+
+	if u, ok := src.(*Union); ok {
+		for _, t := range u.Types {
+			if !assignable(t, dst) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch dst := dst.(type) {
+	case *Union:
+		for _, t := range dst.Types {
+			if assignable(src, t) {
+				return true
+			}
+		}
+		return false
+
+	case Basic:
+		switch dst {
+		case Float, BigInt:
+			if src == Int {
+				return true
+			}
+		case Decimal:
+			if src == Int || src == BigInt {
+				return true
+			}
+		}
+		return src == dst
+
+	case *Array:
+		s, ok := src.(*Array)
+		return ok && assignable(s.Element, dst.Element)
+
+	case *Dict:
+		s, ok := src.(*Dict)
+		return ok && assignable(s.Key, dst.Key) && assignable(s.Value, dst.Value)
+
+	case *Function:
+		_, ok := src.(*Function)
+		return ok
+
+	case *Variant:
+		s, ok := src.(*Variant)
+		return ok && s.Enum == dst.Enum
+
+	case *Enum:
+		return src == dst
+	}
+
+	return false
+}
+
+func (c *Checker) typeFromNode(node ast.TypeNode) Type {
+	switch node := node.(type) {
+	case *ast.NamedType:
+		if t, ok := namedTypes[node.Name]; ok {
+			return t
+		}
+		if e, ok := c.enums[node.Name]; ok {
+			return &Variant{Enum: e}
+		}
+		c.errorf(node, "unknown type %s", node.Name)
+		return Any
+
+	case *ast.ArrayType:
+		return &Array{Element: c.typeFromNode(node.Element)}
+
+	case *ast.DictType:
+		return &Dict{Key: c.typeFromNode(node.Key), Value: c.typeFromNode(node.Value)}
+
+	case *ast.FunctionType:
+		fct := &Function{Return: Any, Required: len(node.Parameters)}
+		for _, p := range node.Parameters {
+			fct.Parameters = append(fct.Parameters, c.typeFromNode(p))
+		}
+		if node.ReturnType != nil {
+			fct.Return = c.typeFromNode(node.ReturnType)
+		}
+		return fct
+
+	case *ast.UnionType:
+		types := []Type{}
+		for _, t := range node.Types {
+			types = append(types, c.typeFromNode(t))
+		}
+		return join(types)
+	}
+
+	return Any
+}
@@ -49,6 +49,41 @@ const (
 	OpAnd = iota
 	OpOr
 	OpGetAttr
+	OpSet
+	OpDup
+	OpIterInit
+	OpIterNext
+	OpYield
+	// OpAddImmediate adds the signed 16-bit integer in its operand to the
+	// value on top of the stack.
+	OpAddImmediate
+	// OpLocalCompareJump compares a local with a constant using the
+	// comparison opcode in its third operand and jumps to its fourth
+	// operand when the result is not truthy, as OpGetLocal, OpConstant,
+	// the comparison and OpJumpNotTruthy would.
+	OpLocalCompareJump
+	// OpCall0 to OpCall3 are OpCall with 0 to 3 arguments.
+	OpCall0
+	OpCall1
+	OpCall2
+	OpCall3
+	// The wide opcodes are the ones above with 4-byte operands, for
+	// indexes, counts and offsets that do not fit in the narrow ones: past
+	// 65535, or past 255 for locals and free variables. Wide says which
+	// opcode has which wide variant.
+	OpConstantWide
+	OpJumpNotTruthyWide
+	OpJumpWide
+	OpSetGlobalWide
+	OpGetGlobalWide
+	OpArrayWide
+	OpDictWide
+	OpClosureWide
+	OpSetWide
+	OpIterNextWide
+	OpSetLocalWide
+	OpGetLocalWide
+	OpGetFreeWide
 )
 
 type Definition struct {
@@ -95,6 +130,79 @@ var definitions = map[Opcode]*Definition{
 	OpAnd:                {"OpAnd", []int{}},
 	OpOr:                 {"OpOr", []int{}},
 	OpGetAttr:            {"OpGetAttr", []int{}},
+	OpSet:                {"OpSet", []int{2}},
+	OpDup:                {"OpDup", []int{}},
+	OpIterInit:           {"OpIterInit", []int{}},
+	OpIterNext:           {"OpIterNext", []int{2}},
+	OpYield:              {"OpYield", []int{}},
+	OpAddImmediate:       {"OpAddImmediate", []int{2}},
+	OpLocalCompareJump:   {"OpLocalCompareJump", []int{1, 2, 1, 2}},
+	OpCall0:              {"OpCall0", []int{}},
+	OpCall1:              {"OpCall1", []int{}},
+	OpCall2:              {"OpCall2", []int{}},
+	OpCall3:              {"OpCall3", []int{}},
+	OpConstantWide:       {"OpConstantWide", []int{4}},
+	OpJumpNotTruthyWide:  {"OpJumpNotTruthyWide", []int{4}},
+	OpJumpWide:           {"OpJumpWide", []int{4}},
+	OpSetGlobalWide:      {"OpSetGlobalWide", []int{4}},
+	OpGetGlobalWide:      {"OpGetGlobalWide", []int{4}},
+	OpArrayWide:          {"OpArrayWide", []int{4}},
+	OpDictWide:           {"OpDictWide", []int{4}},
+	OpClosureWide:        {"OpClosureWide", []int{4, 4}},
+	OpSetWide:            {"OpSetWide", []int{4}},
+	OpIterNextWide:       {"OpIterNextWide", []int{4}},
+	OpSetLocalWide:       {"OpSetLocalWide", []int{4}},
+	OpGetLocalWide:       {"OpGetLocalWide", []int{4}},
+	OpGetFreeWide:        {"OpGetFreeWide", []int{4}},
+}
+
+var wideOpcodes = map[Opcode]Opcode{
+	OpConstant:      OpConstantWide,
+	OpJumpNotTruthy: OpJumpNotTruthyWide,
+	OpJump:          OpJumpWide,
+	OpSetGlobal:     OpSetGlobalWide,
+	OpGetGlobal:     OpGetGlobalWide,
+	OpArray:         OpArrayWide,
+	OpDict:          OpDictWide,
+	OpClosure:       OpClosureWide,
+	OpSet:           OpSetWide,
+	OpIterNext:      OpIterNextWide,
+	OpSetLocal:      OpSetLocalWide,
+	OpGetLocal:      OpGetLocalWide,
+	OpGetFree:       OpGetFreeWide,
+}
+
+// Wide returns the variant of op whose operands are 4 bytes wide, if op
+// has one.
+func Wide(op Opcode) (Opcode, bool) {
+	wide, ok := wideOpcodes[op]
+	return wide, ok
+}
+
+// Fits reports whether operands fit in the operands of op.
+func Fits(op Opcode, operands ...int) bool {
+	def, ok := definitions[op]
+	if !ok {
+		return true
+	}
+	for i, o := range operands {
+		if i < len(def.OperandWidths) && def.OperandWidths[i] < 4 && o >= 1<<(8*def.OperandWidths[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// JumpOperand returns which operand of op holds the offset it jumps to, for
+// the opcodes that jump.
+func JumpOperand(op Opcode) (int, bool) {
+	switch op {
+	case OpJump, OpJumpNotTruthy, OpIterNext, OpJumpWide, OpJumpNotTruthyWide, OpIterNextWide:
+		return 0, true
+	case OpLocalCompareJump:
+		return 3, true
+	}
+	return 0, false
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -123,6 +231,8 @@ func Make(op Opcode, operands ...int) []byte {
 	for i, o := range operands {
 		width := def.OperandWidths[i]
 		switch width {
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(o))
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
 		case 1:
@@ -142,6 +252,7 @@ func (ins Instructions) String() string {
 		def, err := Lookup(ins[i])
 		if err != nil {
 			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
 			continue
 		}
 
@@ -161,16 +272,11 @@ func (ins Instructions) FmtInstruction(def *Definition, operands []int) string {
 		return fmt.Sprintf("ERROR: operand len mismatch: %d vs %d", len(operands), operandCount)
 	}
 
-	switch operandCount {
-	case 0:
-		return def.Name
-	case 1:
-		return fmt.Sprintf("%s %d", def.Name, operands[0])
-	case 2:
-		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	text := def.Name
+	for _, operand := range operands {
+		text += fmt.Sprintf(" %d", operand)
 	}
-
-	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+	return text
 }
 
 func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
@@ -179,6 +285,8 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 
 	for i, width := range def.OperandWidths {
 		switch width {
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
 		case 1:
@@ -197,3 +305,7 @@ func ReadUint8(ins Instructions) uint8 {
 func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
+
+func ReadUint32(ins Instructions) uint32 {
+	return binary.BigEndian.Uint32(ins)
+}
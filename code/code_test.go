@@ -14,6 +14,11 @@ func TestMake(t *testing.T) {
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
 		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
 		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+		{OpAddImmediate, []int{-2}, []byte{byte(OpAddImmediate), 255, 254}},
+		{OpLocalCompareJump, []int{1, 2, int(OpLessThan), 300}, []byte{byte(OpLocalCompareJump), 1, 0, 2, byte(OpLessThan), 1, 44}},
+		{OpConstantWide, []int{65536}, []byte{byte(OpConstantWide), 0, 1, 0, 0}},
+		{OpClosureWide, []int{70000, 300}, []byte{byte(OpClosureWide), 0, 1, 17, 112, 0, 0, 1, 44}},
+		{OpGetLocalWide, []int{256}, []byte{byte(OpGetLocalWide), 0, 0, 1, 0}},
 	}
 	for _, tt := range tests {
 		instruction := Make(tt.op, tt.operands...)
@@ -37,6 +42,7 @@ func TestInstructionsString(t *testing.T) {
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
 		Make(OpClosure, 65535, 255),
+		Make(OpLocalCompareJump, 0, 1, int(OpEqual), 20),
 	}
 
 	expected := `0000 OpAdd
@@ -44,6 +50,7 @@ func TestInstructionsString(t *testing.T) {
 0003 OpConstant 2
 0006 OpConstant 65535
 0009 OpClosure 65535 255
+0013 OpLocalCompareJump 0 1 9 20
 `
 
 	concatted := Instructions{}
@@ -66,6 +73,10 @@ func TestReadOperands(t *testing.T) {
 		{OpConstant, []int{65535}, 2},
 		{OpGetLocal, []int{255}, 1},
 		{OpClosure, []int{65535, 255}, 3},
+		{OpLocalCompareJump, []int{255, 65535, int(OpGreaterThan), 65535}, 6},
+		{OpJumpWide, []int{1 << 24}, 4},
+		{OpClosureWide, []int{65536, 256}, 8},
+		{OpGetFreeWide, []int{300}, 4},
 	}
 
 	for _, tt := range tests {
@@ -88,3 +99,67 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestLineTable(t *testing.T) {
+	var table LineTable
+	table = table.Add(0, "", 1)
+	table = table.Add(3, "", 1)
+	table = table.Add(5, "", 2)
+	table = table.Add(9, "lib.zum", 1)
+	// Instructions from offset 9 on were removed and replaced.
+	table = table.Add(9, "", 3)
+
+	expected := LineTable{{0, "", 1}, {5, "", 2}, {9, "", 3}}
+	if len(table) != len(expected) {
+		t.Fatalf("wrong table. want=%v, got=%v", expected, table)
+	}
+	for i := range expected {
+		if table[i] != expected[i] {
+			t.Errorf("entry %d wrong. want=%v, got=%v", i, expected[i], table[i])
+		}
+	}
+
+	if line, ok := table.Lookup(7); !ok || line.Line != 2 {
+		t.Errorf("Lookup(7) = %v, %t", line, ok)
+	}
+	if _, ok := table.StartsAt(7); ok {
+		t.Errorf("StartsAt(7) found an entry")
+	}
+	if line, ok := table.StartsAt(5); !ok || line.Line != 2 {
+		t.Errorf("StartsAt(5) = %v, %t", line, ok)
+	}
+	if trimmed := table.Trim(9); len(trimmed) != 2 {
+		t.Errorf("Trim(9) left %d entries", len(trimmed))
+	}
+}
+
+func TestWide(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		fits     bool
+		wide     Opcode
+	}{
+		{OpConstant, []int{65535}, true, OpConstantWide},
+		{OpConstant, []int{65536}, false, OpConstantWide},
+		{OpClosure, []int{65536, 2}, false, OpClosureWide},
+		{OpClosure, []int{1, 256}, false, OpClosureWide},
+		{OpGetLocal, []int{255}, true, OpGetLocalWide},
+		{OpSetLocal, []int{256}, false, OpSetLocalWide},
+		{OpGetFree, []int{300}, false, OpGetFreeWide},
+		{OpJumpNotTruthy, []int{70000}, false, OpJumpNotTruthyWide},
+		{OpSetGlobal, []int{1}, true, OpSetGlobalWide},
+		{OpAddImmediate, []int{-1}, true, 0},
+		{OpConstantWide, []int{1 << 30}, true, 0},
+	}
+
+	for _, tt := range tests {
+		if fits := Fits(tt.op, tt.operands...); fits != tt.fits {
+			t.Errorf("Fits(%d, %v) = %t, want %t", tt.op, tt.operands, fits, tt.fits)
+		}
+		wide, ok := Wide(tt.op)
+		if ok != (tt.wide != 0) || wide != tt.wide {
+			t.Errorf("Wide(%d) = %d, %t, want %d", tt.op, wide, ok, tt.wide)
+		}
+	}
+}
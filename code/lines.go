@@ -0,0 +1,67 @@
+package code
+
+// Line records that the instructions from Offset on were compiled from Line
+// of File. File is empty for the file given to the compiler and holds the
+// path of imported files.
+type Line struct {
+	Offset int
+	File   string
+	Line   int
+}
+
+// LineTable maps instruction offsets to source lines. Entries are sorted by
+// offset and each one covers the instructions up to the next.
+type LineTable []Line
+
+// Add records that the instructions emitted from offset on come from
+// file:line. Entries at or past offset belonged to instructions that were
+// removed and are dropped.
+func (t LineTable) Add(offset int, file string, line int) LineTable {
+	for len(t) > 0 && t[len(t)-1].Offset >= offset {
+		t = t[:len(t)-1]
+	}
+
+	if len(t) > 0 && t[len(t)-1].File == file && t[len(t)-1].Line == line {
+		return t
+	}
+
+	return append(t, Line{Offset: offset, File: file, Line: line})
+}
+
+// Trim drops entries past the end of instructions of the given length.
+func (t LineTable) Trim(length int) LineTable {
+	for len(t) > 0 && t[len(t)-1].Offset >= length {
+		t = t[:len(t)-1]
+	}
+	return t
+}
+
+// Lookup returns the entry covering the instruction at offset.
+func (t LineTable) Lookup(offset int) (Line, bool) {
+	found := -1
+	lo, hi := 0, len(t)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if t[mid].Offset <= offset {
+			found = mid
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	if found < 0 {
+		return Line{}, false
+	}
+	return t[found], true
+}
+
+// StartsAt returns the entry that begins exactly at offset, which is where
+// the code of a new source line starts.
+func (t LineTable) StartsAt(offset int) (Line, bool) {
+	line, ok := t.Lookup(offset)
+	if !ok || line.Offset != offset {
+		return Line{}, false
+	}
+	return line, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"zumbra/compiler"
+)
+
+// compileCommand implements `zumbra compile file.zum [-o file.zbc] [-O
+// level]`, which writes the compiled program for `zumbra run file.zbc`.
+func compileCommand(args []string) bool {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	output := flags.String("o", "", "output file, by default the source name with a .zbc extension")
+	flags.IntVar(&optimization, "O", compiler.DefaultOptimization, "optimization level, from 0 to 2")
+	files := parseInterspersed(flags, args)
+
+	if len(files) != 1 {
+		fmt.Println("usage: zumbra compile file.zum [-o file.zbc] [-O level]")
+		return false
+	}
+	filename := files[0]
+
+	comp, _ := compileFile(filename)
+	if comp == nil {
+		return false
+	}
+
+	data, err := comp.Bytecode().MarshalBinary()
+	if err != nil {
+		fmt.Printf("Error when trying to serialize the bytecode: %s\n", err)
+		return false
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filename, ".zum") + ".zbc"
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Printf("Error when trying to write %s: %s\n", *output, err)
+		return false
+	}
+	return true
+}
+
+// parseInterspersed parses flags that may come after the positional
+// arguments, as in `zumbra compile file.zum -o out.zbc`, and returns the
+// positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		flags.Parse(args)
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// Cache keeps compiled programs on disk. An entry is keyed by the compiler
+// Version, the program's path and a hash of its source, and also records
+// the hashes of the files it imported: the compiler puts imported globals
+// in the importing program's scope, so an entry is only valid while none of
+// them changed.
+type Cache struct {
+	Dir string
+}
+
+// DefaultCache returns the cache in the user's cache directory, or in the
+// directory named by $ZUMBRA_CACHE. It returns nil when ZUMBRA_CACHE is
+// "off" or no directory is available.
+func DefaultCache() *Cache {
+	dir := os.Getenv("ZUMBRA_CACHE")
+	if dir == "off" {
+		return nil
+	}
+	if dir == "" {
+		userDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(userDir, "zumbra")
+	}
+	return &Cache{Dir: dir}
+}
+
+func (c *Cache) entry(file string, source []byte) string {
+	h := sha256.New()
+	h.Write([]byte(Version + "\x00" + file + "\x00"))
+	h.Write(source)
+	return filepath.Join(c.Dir, hex.EncodeToString(h.Sum(nil))+".zbc")
+}
+
+func hashFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Load returns the cached compilation of file, whose content is source.
+func (c *Cache) Load(file string, source []byte) (*Bytecode, bool) {
+	data, err := os.ReadFile(c.entry(file, source))
+	if err != nil {
+		return nil, false
+	}
+
+	d := &decoder{data: data}
+	imports := d.length()
+	for i := 0; i < imports && d.err == nil; i++ {
+		path, hash := d.string(), d.string()
+		if current, err := hashFile(path); err != nil || current != hash {
+			return nil, false
+		}
+	}
+	if d.err != nil {
+		return nil, false
+	}
+
+	bytecode, err := UnmarshalBytecode(d.data)
+	if err != nil {
+		return nil, false
+	}
+	return bytecode, true
+}
+
+// Store saves the compilation of file along with the files it imported.
+func (c *Cache) Store(file string, source []byte, bytecode *Bytecode, imports []string) error {
+	e := &encoder{}
+	e.uvarint(uint64(len(imports)))
+	for _, path := range imports {
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		e.string(path)
+		e.string(hash)
+	}
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	e.buf.Write(data)
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a concurrent run never reads
+	// a partial entry.
+	tmp, err := os.CreateTemp(c.Dir, "entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(e.buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.entry(file, source))
+}
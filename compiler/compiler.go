@@ -5,8 +5,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"zumbra/ast"
 	"zumbra/code"
+	"zumbra/imports"
 	"zumbra/lexer"
 	"zumbra/object"
 	"zumbra/object/builtins"
@@ -17,6 +19,12 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	// generator is set once a `yield` is compiled in this scope.
+	generator bool
+	lines     code.LineTable
+	// farJumps holds the targets, by position, of the jumps whose target
+	// does not fit in their operand, until widenJumps makes room for them.
+	farJumps map[int]int
 }
 
 type Compiler struct {
@@ -27,6 +35,21 @@ type Compiler struct {
 	scopeIndex          int
 	importedFiles       map[string]bool
 	currentDir          string
+	// importPaths are searched, in order, for imports that are not found
+	// next to the importing file.
+	importPaths []string
+	// noImports makes import statements an error, for sandboxed programs
+	// that may not read files.
+	noImports bool
+	// currentFile is the imported file being compiled, empty for the
+	// program given to Compile.
+	currentFile string
+	enums       map[string]*object.Enum
+	// optimization is the level set with SetOptimization.
+	optimization int
+	// constantIndex finds constants equal to a new one, so it can be
+	// shared from O1 on.
+	constantIndex map[constantKey]int
 }
 
 func New() *Compiler {
@@ -50,6 +73,7 @@ func New() *Compiler {
 		scopeIndex:    0,
 		importedFiles: map[string]bool{},
 		currentDir:    cwd,
+		enums:         map[string]*object.Enum{},
 	}
 }
 
@@ -67,10 +91,14 @@ func NewWithStateAndDir(s *SymbolTable, constants []object.Object, baseDir strin
 		scopeIndex:    0,
 		importedFiles: map[string]bool{},
 		currentDir:    baseDir,
+		enums:         map[string]*object.Enum{},
 	}
 }
 
 func (c *Compiler) Compile(node ast.Node) error {
+	if stmt, ok := node.(ast.Statement); ok {
+		c.markLine(stmt)
+	}
 
 	switch node := node.(type) {
 	case *ast.Program:
@@ -89,6 +117,17 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop)
 
 	case *ast.InfixExpression:
+		if value, ok := c.constantValue(node); ok {
+			c.emitConstant(value)
+			break
+		}
+		if immediate, ok := c.addImmediate(node); ok {
+			if err := c.Compile(node.Left); err != nil {
+				return err
+			}
+			c.emit(code.OpAddImmediate, immediate)
+			break
+		}
 
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -131,6 +170,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.PrefixExpression:
+		if value, ok := c.constantValue(node); ok {
+			c.emitConstant(value)
+			break
+		}
+
 		err := c.Compile(node.Right)
 		if err != nil {
 			return err
@@ -145,7 +189,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
 	case *ast.IntegerLiteral:
-		integer := &object.Integer{Value: node.Value}
+		var integer object.Object = &object.Integer{Value: node.Value}
+		if node.Big != nil {
+			integer = &object.BigInt{Value: node.Big}
+		}
 		c.emit(code.OpConstant, c.addConstant(integer))
 
 	case *ast.FloatLiteral:
@@ -164,13 +211,15 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.IfExpression:
-		err := c.Compile(node.Condition)
+		if value, ok := c.constantValue(node.Condition); ok {
+			return c.compileConstantIf(node, truthy(value))
+		}
+
+		jumpNotTruthyPos, err := c.compileJumpNotTruthy(node.Condition)
 		if err != nil {
 			return err
 		}
 
-		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
-
 		err = c.Compile(node.Consequence)
 		if err != nil {
 			return err
@@ -183,7 +232,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		jumpPos := c.emit(code.OpJump, 9999)
 
 		afterConsequencePos := len(c.currentInstructions())
-		c.changeOperand(jumpNotTruthyPos, afterConsequencePos)
+		c.changeJumpTarget(jumpNotTruthyPos, afterConsequencePos)
 
 		if node.Alternative == nil {
 			c.emit(code.OpNull)
@@ -199,14 +248,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		afterAlternativePos := len(c.currentInstructions())
-		c.changeOperand(jumpPos, afterAlternativePos)
+		c.changeJumpTarget(jumpPos, afterAlternativePos)
 
 	case *ast.BlockStatement:
-		for _, statement := range node.Statements {
+		for i, statement := range node.Statements {
 			err := c.Compile(statement)
 			if err != nil {
 				return err
 			}
+
+			// Nothing after a return runs.
+			if _, ok := statement.(*ast.ReturnStatement); ok && c.optimization >= O1 && c.scopeIndex > 0 {
+				return c.compileUnreachable(node.Statements[i+1:]...)
+			}
 		}
 
 	case *ast.VarStatement:
@@ -265,6 +319,16 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		c.emit(code.OpDict, len(node.Pairs)*2)
 
+	case *ast.SetLiteral:
+		for _, el := range node.Elements {
+			err := c.Compile(el)
+			if err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpSet, len(node.Elements))
+
 	case *ast.IndexExpression:
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -300,11 +364,23 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if !c.lastInstructionIs(code.OpReturnValue) {
 			c.emit(code.OpReturn)
 		}
+		c.widenJumps()
+		if c.optimization >= O2 {
+			threadJumps(c.currentInstructions())
+		}
 
 		freeSymbols := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numDefinitions
+		localNames := c.symbolTable.Names()
+		generator := c.scopes[c.scopeIndex].generator
+		lines := c.scopes[c.scopeIndex].lines
 		instructions := c.leaveScope()
 
+		freeNames := make([]string, len(freeSymbols))
+		for i, s := range freeSymbols {
+			freeNames[i] = s.Name
+		}
+
 		for _, s := range freeSymbols {
 			c.loadSymbol(s)
 		}
@@ -313,6 +389,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 			Instructions:  instructions,
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
+			Generator:     generator,
+			Name:          node.Name,
+			Lines:         lines.Trim(len(instructions)),
+			LocalNames:    localNames,
+			FreeNames:     freeNames,
 		}
 		fnIndex := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIndex, len(freeSymbols))
@@ -325,6 +406,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		c.emit(code.OpReturnValue)
 
+	case *ast.YieldStatement:
+		if c.scopeIndex == 0 {
+			return fmt.Errorf("yield outside of function")
+		}
+
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+
+		c.emit(code.OpYield)
+		c.scopes[c.scopeIndex].generator = true
+
 	case *ast.CallExpression:
 		err := c.Compile(node.Function)
 		if err != nil {
@@ -338,7 +432,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
-		c.emit(code.OpCall, len(node.Arguments))
+		if c.optimization >= O2 && len(node.Arguments) <= 3 {
+			c.emit(code.OpCall0 + code.Opcode(len(node.Arguments)))
+		} else {
+			c.emit(code.OpCall, len(node.Arguments))
+		}
 
 	case *ast.WhileStatement:
 		err := c.compileWhile(node)
@@ -355,6 +453,15 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.ImportStatement:
 		return c.compileImport(node)
 
+	case *ast.EnumStatement:
+		return c.compileEnum(node)
+
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
+
+	case *ast.ForStatement:
+		return c.compileFor(node)
+
 	case *ast.AttributeAccess:
 		if err := c.Compile(node.Object); err != nil {
 			return err
@@ -369,23 +476,75 @@ func (c *Compiler) Compile(node ast.Node) error {
 }
 
 func (c *Compiler) Bytecode() *Bytecode {
+	c.widenJumps()
+	if c.optimization >= O2 {
+		threadJumps(c.currentInstructions())
+	}
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Lines:        c.scopes[c.scopeIndex].lines.Trim(len(c.currentInstructions())),
+	}
+}
+
+// Imports returns the files imported while compiling, directly or not.
+func (c *Compiler) Imports() []string {
+	files := make([]string, 0, len(c.importedFiles))
+	for file := range c.importedFiles {
+		files = append(files, file)
 	}
+	sort.Strings(files)
+	return files
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	// Lines maps Instructions back to the source, like the Lines of every
+	// compiled function among Constants.
+	Lines code.LineTable
+}
+
+// markLine records that the code emitted next belongs to stmt's line.
+// Blocks are skipped so their first statement is marked instead.
+func (c *Compiler) markLine(stmt ast.Statement) {
+	if _, ok := stmt.(*ast.BlockStatement); ok {
+		return
+	}
+
+	tok := ast.StartToken(stmt)
+	if tok.Line == 0 {
+		return
+	}
+
+	scope := &c.scopes[c.scopeIndex]
+	scope.lines = scope.lines.Add(len(scope.instructions), c.currentFile, tok.Line)
 }
 
 func (c *Compiler) addConstant(obj object.Object) int {
+	key, shared := constantKeyOf(obj)
+	shared = shared && c.optimization >= O1
+	if index, ok := c.constantIndex[key]; shared && ok {
+		return index
+	}
+
 	c.constants = append(c.constants, obj)
-	return len(c.constants) - 1
+	index := len(c.constants) - 1
+	if shared {
+		if c.constantIndex == nil {
+			c.constantIndex = map[constantKey]int{}
+		}
+		c.constantIndex[key] = index
+	}
+	return index
 }
 
+// emit appends an instruction, using the wide variant of op when the
+// operands do not fit in op's.
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	if wide, ok := code.Wide(op); ok && !code.Fits(op, operands...) {
+		op = wide
+	}
 	instruction := code.Make(op, operands...)
 	pos := c.addInstruction(instruction)
 	c.setLastInstruction(op, pos)
@@ -432,10 +591,35 @@ func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
 	}
 }
 
-func (c *Compiler) changeOperand(pos int, operand int) {
-	op := code.Opcode(c.currentInstructions()[pos])
-	newInstruction := code.Make(op, operand)
-	c.replaceInstruction(pos, newInstruction)
+// changeJumpTarget points the jump at pos to target, whichever operand of
+// the jump holds it. Targets the jump cannot hold are kept for widenJumps.
+func (c *Compiler) changeJumpTarget(pos int, target int) {
+	scope := &c.scopes[c.scopeIndex]
+	if !setJumpTarget(scope.instructions, pos, target) {
+		if scope.farJumps == nil {
+			scope.farJumps = map[int]int{}
+		}
+		scope.farJumps[pos] = target
+	}
+}
+
+// setJumpTarget points the jump at pos to target and reports whether the
+// target fit in the jump's operand.
+func setJumpTarget(ins code.Instructions, pos int, target int) bool {
+	op := code.Opcode(ins[pos])
+	def, err := code.Lookup(byte(op))
+	index, ok := code.JumpOperand(op)
+	if err != nil || !ok {
+		return true
+	}
+
+	operands, _ := code.ReadOperands(def, ins[pos+1:])
+	operands[index] = target
+	if !code.Fits(op, operands...) {
+		return false
+	}
+	copy(ins[pos:], code.Make(op, operands...))
+	return true
 }
 
 func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
@@ -502,13 +686,53 @@ func (c *Compiler) loadSymbol(s Symbol) {
 }
 
 func (c *Compiler) compileWhile(stmt *ast.WhileStatement) error {
+	// A constant condition either never lets the body run or never needs
+	// to be checked.
+	value, constant := c.constantValue(stmt.Condition)
+	if constant && !truthy(value) {
+		return c.compileUnreachable(stmt.Body)
+	}
+
 	loopStartPos := len(c.currentInstructions())
 
-	if err := c.Compile(stmt.Condition); err != nil {
+	jumpNotTruthyPos := -1
+	if !constant {
+		var err error
+		jumpNotTruthyPos, err = c.compileJumpNotTruthy(stmt.Condition)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := c.Compile(stmt.Body); err != nil {
 		return err
 	}
 
-	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+	c.emit(code.OpJump, loopStartPos)
+
+	if jumpNotTruthyPos >= 0 {
+		afterLoopPos := len(c.currentInstructions())
+		c.changeJumpTarget(jumpNotTruthyPos, afterLoopPos)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileFor(stmt *ast.ForStatement) error {
+	if err := c.Compile(stmt.Iterable); err != nil {
+		return err
+	}
+
+	c.emit(code.OpIterInit)
+
+	loopStartPos := c.emit(code.OpIterNext, 9999)
+
+	symbol := c.symbolTable.Define(stmt.Variable.Value)
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
 
 	if err := c.Compile(stmt.Body); err != nil {
 		return err
@@ -517,7 +741,161 @@ func (c *Compiler) compileWhile(stmt *ast.WhileStatement) error {
 	c.emit(code.OpJump, loopStartPos)
 
 	afterLoopPos := len(c.currentInstructions())
-	c.changeOperand(jumpNotTruthyPos, afterLoopPos)
+	c.changeJumpTarget(loopStartPos, afterLoopPos)
+
+	return nil
+}
+
+func (c *Compiler) compileEnum(stmt *ast.EnumStatement) error {
+	variants := []string{}
+	seen := map[string]bool{}
+
+	for _, v := range stmt.Variants {
+		if seen[v.Value] {
+			return fmt.Errorf("duplicate variant %s in enum %s", v.Value, stmt.Name.Value)
+		}
+		seen[v.Value] = true
+		variants = append(variants, v.Value)
+	}
+
+	enum := object.NewEnum(stmt.Name.Value, variants)
+	c.enums[enum.Name] = enum
+
+	symbol := c.symbolTable.Define(stmt.Name.Value)
+	c.emit(code.OpConstant, c.addConstant(enum))
+
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	if err := c.checkExhaustive(node); err != nil {
+		return err
+	}
+
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+
+	var alternative *ast.MatchArm
+	jumpPositions := []int{}
+
+	for _, arm := range node.Arms {
+		if arm.Pattern == nil {
+			alternative = arm
+			continue
+		}
+
+		c.emit(code.OpDup)
+		if err := c.Compile(arm.Pattern); err != nil {
+			return err
+		}
+		c.emit(code.OpEqual)
+
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+		c.emit(code.OpPop)
+		if err := c.compileArmBody(arm.Body); err != nil {
+			return err
+		}
+		jumpPositions = append(jumpPositions, c.emit(code.OpJump, 9999))
+
+		c.changeJumpTarget(jumpNotTruthyPos, len(c.currentInstructions()))
+	}
+
+	c.emit(code.OpPop)
+	if alternative != nil {
+		if err := c.compileArmBody(alternative.Body); err != nil {
+			return err
+		}
+	} else {
+		c.emit(code.OpNull)
+	}
+
+	afterMatchPos := len(c.currentInstructions())
+	for _, pos := range jumpPositions {
+		c.changeJumpTarget(pos, afterMatchPos)
+	}
+
+	return nil
+}
+
+// compileArmBody leaves the value of the arm's last expression on the stack,
+// or null when the arm does not end in an expression.
+func (c *Compiler) compileArmBody(body *ast.BlockStatement) error {
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+
+	n := len(body.Statements)
+	if n > 0 {
+		if _, ok := body.Statements[n-1].(*ast.ExpressionStatement); ok {
+			c.removeLastPop()
+			return nil
+		}
+	}
+
+	c.emit(code.OpNull)
+	return nil
+}
+
+// checkExhaustive makes sure a match over the variants of a known enum without
+// an else arm handles every variant.
+func (c *Compiler) checkExhaustive(node *ast.MatchExpression) error {
+	var enum *object.Enum
+	covered := map[string]bool{}
+	onlyVariants := true
+	hasElse := false
+
+	for _, arm := range node.Arms {
+		if arm.Pattern == nil {
+			hasElse = true
+			continue
+		}
+
+		access, ok := arm.Pattern.(*ast.AttributeAccess)
+		if !ok {
+			onlyVariants = false
+			continue
+		}
+
+		ident, ok := access.Object.(*ast.Identifier)
+		if !ok || c.enums[ident.Value] == nil {
+			onlyVariants = false
+			continue
+		}
+
+		e := c.enums[ident.Value]
+		if _, ok := e.Variant(access.Property.Value); !ok {
+			return fmt.Errorf("unknown variant %s for enum %s", access.Property.Value, e.Name)
+		}
+
+		if enum != nil && enum != e {
+			onlyVariants = false
+		}
+		enum = e
+		covered[access.Property.Value] = true
+	}
+
+	if hasElse || !onlyVariants || enum == nil {
+		return nil
+	}
+
+	missing := []string{}
+	for _, v := range enum.Variants {
+		if !covered[v.Name] {
+			missing = append(missing, v.Name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("match on %s is not exhaustive, missing: %s", enum.Name, strings.Join(missing, ", "))
+	}
 
 	return nil
 }
@@ -544,15 +922,32 @@ func (c *Compiler) compileAssign(stmt *ast.AssignStatement) error {
 	return nil
 }
 
+// SetImportPaths sets the directories searched for imports that are not
+// found relative to the importing file.
+func (c *Compiler) SetImportPaths(paths []string) {
+	c.importPaths = paths
+}
+
+// DisallowImports makes import statements a compile error.
+func (c *Compiler) DisallowImports() {
+	c.noImports = true
+}
+
+func (c *Compiler) resolveImport(path string) string {
+	return imports.Resolve(c.currentDir, path, c.importPaths)
+}
+
 func (c *Compiler) compileImport(stmt *ast.ImportStatement) error {
 	path := stmt.Path.Value
+	if c.noImports {
+		return fmt.Errorf("imports are not allowed: %s", path)
+	}
 
 	if c.importedFiles == nil {
 		c.importedFiles = make(map[string]bool)
 	}
 
-	importFullPath := filepath.Join(c.currentDir, path)
-	importFullPath = filepath.Clean(importFullPath)
+	importFullPath := c.resolveImport(path)
 
 	if c.importedFiles[importFullPath] {
 		return nil
@@ -573,11 +968,12 @@ func (c *Compiler) compileImport(stmt *ast.ImportStatement) error {
 		return fmt.Errorf("could not parse imported file: %s", path)
 	}
 
-	oldDir := c.currentDir
+	oldDir, oldFile := c.currentDir, c.currentFile
 	c.currentDir = filepath.Dir(importFullPath)
+	c.currentFile = importFullPath
 
 	err = c.Compile(program)
-	c.currentDir = oldDir
+	c.currentDir, c.currentFile = oldDir, oldFile
 
 	return err
 }
@@ -2,11 +2,15 @@ package compiler
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"zumbra/ast"
 	"zumbra/code"
 	"zumbra/lexer"
 	"zumbra/object"
+	"zumbra/object/builtins"
 	"zumbra/parser"
 )
 
@@ -82,10 +86,18 @@ func TestIntegerArithmetic(t *testing.T) {
 }
 
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
+	t.Helper()
+	runOptimizedCompilerTests(t, O0, tests)
+}
+
+func runOptimizedCompilerTests(t *testing.T, level int, tests []compilerTestCase) {
 	t.Helper()
 	for _, tt := range tests {
 		program := parse(tt.input)
 		compiler := New()
+		if err := compiler.SetOptimization(level); err != nil {
+			t.Fatal(err)
+		}
 		err := compiler.Compile(program)
 		if err != nil {
 			t.Fatalf("compiler error: %s", err)
@@ -102,6 +114,15 @@ func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	}
 }
 
+func builtinIndex(name string) int {
+	for i, b := range builtins.Builtins {
+		if b.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -831,7 +852,7 @@ func TestBuiltins(t *testing.T) {
 			input: `fct() { sizeOf([]) }`,
 			expectedConstants: []interface{}{
 				[]code.Instructions{
-					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpGetBuiltin, builtinIndex("sizeOf")),
 					code.Make(code.OpArray, 0),
 					code.Make(code.OpCall, 1),
 					code.Make(code.OpReturnValue),
@@ -1049,7 +1070,7 @@ func TestCompileAttributeAccess(t *testing.T) {
 				"hour",
 			},
 			expectedInstructions: []code.Instructions{
-				code.Make(code.OpGetBuiltin, 18),
+				code.Make(code.OpGetBuiltin, builtinIndex("date")),
 				code.Make(code.OpCall, 0),
 				code.Make(code.OpSetGlobal, 0),
 				code.Make(code.OpGetGlobal, 0),
@@ -1062,3 +1083,714 @@ func TestCompileAttributeAccess(t *testing.T) {
 
 	runCompilerTests(t, tests)
 }
+
+func TestSetLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{1, 2}",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSet, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `match (1) { 2 => 3, else => 4 }`,
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpDup),
+				// 0004
+				code.Make(code.OpConstant, 1),
+				// 0007
+				code.Make(code.OpEqual),
+				// 0008
+				code.Make(code.OpJumpNotTruthy, 18),
+				// 0011
+				code.Make(code.OpPop),
+				// 0012
+				code.Make(code.OpConstant, 2),
+				// 0015
+				code.Make(code.OpJump, 22),
+				// 0018
+				code.Make(code.OpPop),
+				// 0019
+				code.Make(code.OpConstant, 3),
+				// 0022
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `for (x in [1]) { x; }`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIterInit),
+				// 0007
+				code.Make(code.OpIterNext, 20),
+				// 0010
+				code.Make(code.OpSetGlobal, 0),
+				// 0013
+				code.Make(code.OpGetGlobal, 0),
+				// 0016
+				code.Make(code.OpPop),
+				// 0017
+				code.Make(code.OpJump, 7),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatchExhaustiveness(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`enum S { A, B, C } match (S.A) { S.A => 1 }`,
+			"match on S is not exhaustive, missing: B, C",
+		},
+		{
+			`enum S { A } match (S.A) { S.B => 1, else => 2 }`,
+			"unknown variant B for enum S",
+		},
+		{
+			`enum S { A, A }`,
+			"duplicate variant A in enum S",
+		},
+		{`enum S { A, B } match (S.A) { S.A => 1, S.B => 2 }`, ""},
+		{`enum S { A, B } match (S.A) { S.A => 1, else => 2 }`, ""},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("%q: unexpected compiler error: %s", tt.input, err)
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestYieldStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fct() { yield 1; }`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpYield),
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	program := parse(`fct() { yield 1; }`)
+	compiler := New()
+	compiler.Compile(program)
+	fn := compiler.Bytecode().Constants[1].(*object.CompiledFunction)
+	if !fn.Generator {
+		t.Errorf("function with yield is not marked as a generator")
+	}
+
+	program = parse(`fct() { fct() { yield 1; } }`)
+	compiler = New()
+	compiler.Compile(program)
+	fn = compiler.Bytecode().Constants[2].(*object.CompiledFunction)
+	if fn.Generator {
+		t.Errorf("yield in a nested function marked the outer function as a generator")
+	}
+
+	if err := New().Compile(parse(`yield 1;`)); err == nil || err.Error() != "yield outside of function" {
+		t.Errorf("expected yield outside of function error, got %v", err)
+	}
+}
+
+func TestLineTables(t *testing.T) {
+	input := `var x << 1;
+var f << fct(a) {
+    var b << a + x;
+    if (b > 2) {
+        b
+    } else {
+        0
+    }
+};
+f(2);`
+
+	compiler := New()
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecode := compiler.Bytecode()
+
+	lines := func(table code.LineTable) []int {
+		result := []int{}
+		for _, entry := range table {
+			result = append(result, entry.Line)
+		}
+		return result
+	}
+
+	if got := lines(bytecode.Lines); fmt.Sprint(got) != "[1 2 10]" {
+		t.Errorf("wrong main lines: %v", got)
+	}
+
+	var fn *object.CompiledFunction
+	for _, c := range bytecode.Constants {
+		if f, ok := c.(*object.CompiledFunction); ok {
+			fn = f
+		}
+	}
+	if fn == nil {
+		t.Fatalf("no compiled function among the constants")
+	}
+	if got := lines(fn.Lines); fmt.Sprint(got) != "[3 4 5 7]" {
+		t.Errorf("wrong function lines: %v", got)
+	}
+	if fn.Name != "f" || fmt.Sprint(fn.LocalNames) != "[a b]" || fmt.Sprint(fn.FreeNames) != "[]" {
+		t.Errorf("wrong names: %q %v %v", fn.Name, fn.LocalNames, fn.FreeNames)
+	}
+
+	for _, entry := range fn.Lines {
+		if entry.Offset >= len(fn.Instructions) {
+			t.Errorf("entry past the instructions: %+v", entry)
+		}
+	}
+}
+
+func TestImportPaths(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"main/lib.zum":     `var local << 1;`,
+		"src/lib.zum":      `var shadowed << 2;`,
+		"src/util.zum":     `var fromSrc << 3;`,
+		"modules/util.zum": `var fromModules << 4;`,
+		"modules/dep.zum":  `var fromDep << 5;`,
+	} {
+		path := filepath.Join(dir, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	symbolTable := NewSymbolTable()
+	comp := NewWithStateAndDir(symbolTable, []object.Object{}, filepath.Join(dir, "main"))
+	comp.SetImportPaths([]string{filepath.Join(dir, "src"), filepath.Join(dir, "modules")})
+
+	err := comp.Compile(parse(`import "lib.zum" import "util.zum" import "dep.zum"`))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	want := "local fromSrc fromDep"
+	if got := strings.Join(symbolTable.Names(), " "); got != want {
+		t.Errorf("wrong globals. want=%q got=%q", want, got)
+	}
+
+	err = comp.Compile(parse(`import "missing.zum"`))
+	if err == nil || err.Error() != "could not read imported file: missing.zum" {
+		t.Errorf("wrong error for a missing import: %v", err)
+	}
+}
+
+func TestImportPackages(t *testing.T) {
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"zumbra_modules/strutil/shout.zum":   `import "helpers.zum" var shout << 1;`,
+		"zumbra_modules/strutil/helpers.zum": `var helper << 2;`,
+		"src/deep/main.zum":                  ``,
+	} {
+		path := filepath.Join(dir, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	symbolTable := NewSymbolTable()
+	comp := NewWithStateAndDir(symbolTable, []object.Object{}, filepath.Join(dir, "src", "deep"))
+	if err := comp.Compile(parse(`import "strutil/shout.zum"`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	if got := strings.Join(symbolTable.Names(), " "); got != "helper shout" {
+		t.Errorf("wrong globals: %q", got)
+	}
+}
+
+func TestConstantFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "1 + 2 * 3",
+			expectedConstants: []interface{}{7},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"zum" + "bra"`,
+			expectedConstants: []interface{}{"zumbra"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "-(1 + 2)",
+			expectedConstants: []interface{}{-3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 < 2 and !false",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "var x << 2; x * (3 + 4)",
+			expectedConstants: []interface{}{2, 7},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// Errors are left for the VM to report.
+			input:             "1 / 0",
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpDiv),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, O1, tests)
+}
+
+func TestConstantDeduplication(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `var a << 1; var b << 1; var c << "one"; var d << "one"; fct() { 1 }`,
+			expectedConstants: []interface{}{
+				1,
+				"one",
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 2),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 3),
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, O1, tests)
+}
+
+func TestUnreachableCode(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 } else { 20 }; 30",
+			expectedConstants: []interface{}{10, 30},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "if (1 > 2) { 10 }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// y keeps its slot, as it does when the branch is compiled.
+			input:             "if (false) { var y << 1; } var z << 2; z",
+			expectedConstants: []interface{}{2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fct() { return 1; 2 }",
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "var x << 0; while (false) { x << 1; }",
+			expectedConstants: []interface{}{0},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			input:             "var x << 0; while (true) { x << x + 1; }",
+			expectedConstants: []interface{}{0, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpConstant, 1),
+				// 0012
+				code.Make(code.OpAdd),
+				// 0013
+				code.Make(code.OpSetGlobal, 0),
+				// 0016
+				code.Make(code.OpJump, 6),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, O1, tests)
+}
+
+func TestUnreachableCodeErrors(t *testing.T) {
+	compiler := New()
+	compiler.SetOptimization(O1)
+	err := compiler.Compile(parse("if (false) { missing }"))
+	if err == nil || err.Error() != "undefined variable missing" {
+		t.Fatalf("wrong error. want=%q, got=%v", "undefined variable missing", err)
+	}
+}
+
+func TestJumpThreading(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "var a << true; var b << true; if (a) { if (b) { 1 } else { 2 } } else { 3 }",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpSetGlobal, 0),
+				// 0004
+				code.Make(code.OpTrue),
+				// 0005
+				code.Make(code.OpSetGlobal, 1),
+				// 0008
+				code.Make(code.OpGetGlobal, 0),
+				// 0011
+				code.Make(code.OpJumpNotTruthy, 32),
+				// 0014
+				code.Make(code.OpGetGlobal, 1),
+				// 0017
+				code.Make(code.OpJumpNotTruthy, 26),
+				// 0020
+				code.Make(code.OpConstant, 0),
+				// 0023: jumped to the outer if's OpJump at 0029 before
+				code.Make(code.OpJump, 35),
+				// 0026
+				code.Make(code.OpConstant, 1),
+				// 0029
+				code.Make(code.OpJump, 35),
+				// 0032
+				code.Make(code.OpConstant, 2),
+				// 0035
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, O2, tests)
+}
+
+func TestSetOptimization(t *testing.T) {
+	compiler := New()
+	for _, level := range []int{O0, O1, O2} {
+		if err := compiler.SetOptimization(level); err != nil {
+			t.Errorf("level %d: %s", level, err)
+		}
+	}
+	for _, level := range []int{-1, MaxOptimization + 1} {
+		if err := compiler.SetOptimization(level); err == nil {
+			t.Errorf("level %d: expected an error", level)
+		}
+	}
+}
+
+func TestSpecialisedInstructions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "var x << 1; x + 2; x - 3; x + 40000",
+			expectedConstants: []interface{}{1, 40000},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpAddImmediate, 2),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpAddImmediate, -3),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fct(n) { if (n < 2) { n } else { 0 } }",
+			expectedConstants: []interface{}{
+				2,
+				0,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpLocalCompareJump, 0, 0, int(code.OpLessThan), 12),
+					// 0007
+					code.Make(code.OpGetLocal, 0),
+					// 0009
+					code.Make(code.OpJump, 15),
+					// 0012
+					code.Make(code.OpConstant, 1),
+					// 0015
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// Only locals compared with constants are fused.
+			input: "var limit << 2; fct(n) { while (n < limit) { n } }",
+			expectedConstants: []interface{}{
+				2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpGetLocal, 0),
+					// 0002
+					code.Make(code.OpGetGlobal, 0),
+					// 0005
+					code.Make(code.OpLessThan),
+					// 0006
+					code.Make(code.OpJumpNotTruthy, 15),
+					// 0009
+					code.Make(code.OpGetLocal, 0),
+					// 0011
+					code.Make(code.OpPop),
+					// 0012
+					code.Make(code.OpJump, 0),
+					// 0015
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "sizeOf([]); sizeOf([], 1, 2, 3); sizeOf([], 1, 2, 3, 4)",
+			expectedConstants: []interface{}{1, 2, 3, 4},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpGetBuiltin, builtinIndex("sizeOf")),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpCall1),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetBuiltin, builtinIndex("sizeOf")),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpCall, 4),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetBuiltin, builtinIndex("sizeOf")),
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpCall, 5),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runOptimizedCompilerTests(t, O2, tests)
+}
+
+func TestWideOperands(t *testing.T) {
+	var globals strings.Builder
+	for i := 0; i <= 65536; i++ {
+		fmt.Fprintf(&globals, "var g%d << 1;\n", i)
+	}
+	globals.WriteString("g65536; g1;")
+
+	tests := []struct {
+		input string
+		level int
+		tail  []code.Instructions
+	}{
+		{
+			input: strings.Repeat("1; ", 65536) + "2;",
+			level: O0,
+			tail: []code.Instructions{
+				code.Make(code.OpConstantWide, 65536),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: globals.String(),
+			level: O1,
+			tail: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobalWide, 65536),
+				code.Make(code.OpGetGlobalWide, 65536),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetGlobal, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		compiler := New()
+		compiler.SetOptimization(tt.level)
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		ins := compiler.Bytecode().Instructions
+		tail := concatInstructions(tt.tail)
+		if len(ins) < len(tail) {
+			t.Fatalf("too few instructions: %d", len(ins))
+		}
+		if got := code.Instructions(ins[len(ins)-len(tail):]); got.String() != tail.String() {
+			t.Errorf("wrong instructions at the end.\nwant=%q\ngot=%q", tail, got)
+		}
+	}
+}
+
+func TestWidenJumps(t *testing.T) {
+	// The body of the if is longer than a narrow jump can skip.
+	body := strings.Repeat("n; ", 25000)
+	input := "fct(n) { if (n < 1) { " + body + "n } else { 5 } }"
+
+	compiler := New()
+	compiler.SetOptimization(O2)
+	if err := compiler.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	fn, ok := compiler.Bytecode().Constants[2].(*object.CompiledFunction)
+	if !ok {
+		t.Fatalf("constant 2 is not a function")
+	}
+	ins := fn.Instructions
+
+	// The OpLocalCompareJump goes back to the instructions it fuses.
+	afterConsequence := 11 + 25000*3 + 2 + 5
+	head := concatInstructions([]code.Instructions{
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpConstant, 0),
+		code.Make(code.OpLessThan),
+		code.Make(code.OpJumpNotTruthyWide, afterConsequence),
+	})
+	if got := ins[:len(head)]; got.String() != head.String() {
+		t.Errorf("wrong jump.\nwant=%q\ngot=%q", head, got)
+	}
+
+	tail := concatInstructions([]code.Instructions{
+		code.Make(code.OpGetLocal, 0),
+		code.Make(code.OpJumpWide, afterConsequence+3),
+		code.Make(code.OpConstant, 1),
+		code.Make(code.OpReturnValue),
+	})
+	if got := ins[afterConsequence-7:]; got.String() != tail.String() {
+		t.Errorf("wrong instructions at the end.\nwant=%q\ngot=%q", tail, got)
+	}
+
+	// Line table entries move with their instructions.
+	if len(fn.Lines) == 0 || fn.Lines[0].Offset != 0 {
+		t.Errorf("wrong line table: %+v", fn.Lines)
+	}
+}
@@ -0,0 +1,464 @@
+package compiler
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"zumbra/ast"
+	"zumbra/code"
+	"zumbra/object"
+)
+
+// Optimization levels for SetOptimization. Each level does what the ones
+// below it do.
+const (
+	// O0 compiles the program as it is written.
+	O0 = iota
+	// O1 folds expressions over literals into constants, shares equal
+	// constants and drops code that can never run.
+	O1
+	// O2 also points jumps that land on another jump at the end of the
+	// chain, and emits the VM's specialised instructions: OpAddImmediate
+	// for adding or subtracting small integer literals,
+	// OpLocalCompareJump for conditions comparing a local with a constant
+	// and OpCall0 to OpCall3 for calls with few arguments.
+	O2
+)
+
+// MaxOptimization is the highest optimization level.
+const MaxOptimization = O2
+
+// DefaultOptimization is the level the zumbra commands compile at unless
+// told otherwise with -O.
+const DefaultOptimization = O2
+
+// SetOptimization sets how much the code the compiler emits is optimized.
+// Compilers start at O0. Optimizations never change what a program does,
+// so the levels only differ in the bytecode they produce.
+func (c *Compiler) SetOptimization(level int) error {
+	if level < O0 || level > MaxOptimization {
+		return fmt.Errorf("unknown optimization level %d, want 0 to %d", level, MaxOptimization)
+	}
+	c.optimization = level
+	return nil
+}
+
+type constantKey struct {
+	objectType object.ObjectType
+	value      string
+}
+
+// constantKeyOf returns the key under which obj is shared with equal
+// constants. Only immutable values are shared.
+func constantKeyOf(obj object.Object) (constantKey, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return constantKey{obj.Type(), strconv.FormatInt(obj.Value, 10)}, true
+	case *object.BigInt:
+		return constantKey{obj.Type(), obj.Value.String()}, true
+	case *object.Float:
+		return constantKey{obj.Type(), strconv.FormatUint(math.Float64bits(obj.Value), 16)}, true
+	case *object.String:
+		return constantKey{obj.Type(), obj.Value}, true
+	}
+	return constantKey{}, false
+}
+
+// constantValue returns the value of expr when it is made of literals only,
+// computed as the VM would. Expressions the VM would fail on are left for it
+// to report at run time.
+func (c *Compiler) constantValue(expr ast.Expression) (object.Object, bool) {
+	if c.optimization < O1 {
+		return nil, false
+	}
+
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		if expr.Big != nil {
+			return &object.BigInt{Value: expr.Big}, true
+		}
+		return &object.Integer{Value: expr.Value}, true
+
+	case *ast.FloatLiteral:
+		return &object.Float{Value: expr.Value}, true
+
+	case *ast.StringLiteral:
+		return &object.String{Value: expr.Value}, true
+
+	case *ast.Boolean:
+		return &object.Boolean{Value: expr.Value}, true
+
+	case *ast.PrefixExpression:
+		right, ok := c.constantValue(expr.Right)
+		if !ok {
+			return nil, false
+		}
+		switch expr.Operator {
+		case "!":
+			b, isBool := right.(*object.Boolean)
+			return &object.Boolean{Value: isBool && !b.Value}, true
+		case "-":
+			return object.Negate(right)
+		}
+
+	case *ast.InfixExpression:
+		left, ok := c.constantValue(expr.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := c.constantValue(expr.Right)
+		if !ok {
+			return nil, false
+		}
+		switch expr.Operator {
+		case "+", "-", "*", "/", "%":
+			return foldArithmetic(expr.Operator, left, right)
+		case "==", "!=", "<", ">", "<=", ">=":
+			return foldComparison(expr.Operator, left, right)
+		case "and":
+			return &object.Boolean{Value: truthy(left) && truthy(right)}, true
+		case "or":
+			return &object.Boolean{Value: truthy(left) || truthy(right)}, true
+		}
+	}
+
+	return nil, false
+}
+
+func foldArithmetic(operator string, left, right object.Object) (object.Object, bool) {
+	if object.IsExactNumber(left) || object.IsExactNumber(right) {
+		result, err := object.Arithmetic(operator, left, right)
+		return result, err == nil
+	}
+
+	switch left := left.(type) {
+	case *object.Integer:
+		switch right := right.(type) {
+		case *object.Integer:
+			return foldIntegers(operator, left, right)
+		case *object.Float:
+			return foldFloats(operator, float64(left.Value), right.Value)
+		}
+	case *object.Float:
+		switch right := right.(type) {
+		case *object.Integer:
+			return foldFloats(operator, left.Value, float64(right.Value))
+		case *object.Float:
+			return foldFloats(operator, left.Value, right.Value)
+		}
+	case *object.String:
+		if right, ok := right.(*object.String); ok && operator == "+" {
+			return &object.String{Value: left.Value + right.Value}, true
+		}
+	}
+	return nil, false
+}
+
+func foldIntegers(operator string, left, right *object.Integer) (object.Object, bool) {
+	var result int64
+	switch operator {
+	case "+":
+		result = left.Value + right.Value
+	case "-":
+		result = left.Value - right.Value
+	case "*":
+		result = left.Value * right.Value
+	case "/", "%":
+		if right.Value == 0 {
+			return nil, false
+		}
+		if operator == "/" {
+			result = left.Value / right.Value
+		} else {
+			result = left.Value % right.Value
+		}
+	}
+
+	if object.IntegerOverflows(operator, left.Value, right.Value, result) {
+		value, err := object.Arithmetic(operator, left, right)
+		return value, err == nil
+	}
+	return &object.Integer{Value: result}, true
+}
+
+func foldFloats(operator string, left, right float64) (object.Object, bool) {
+	switch operator {
+	case "+":
+		return &object.Float{Value: left + right}, true
+	case "-":
+		return &object.Float{Value: left - right}, true
+	case "*":
+		return &object.Float{Value: left * right}, true
+	case "/":
+		return &object.Float{Value: left / right}, true
+	}
+	return nil, false
+}
+
+func foldComparison(operator string, left, right object.Object) (object.Object, bool) {
+	var result int
+	switch {
+	case isNumber(left) && isNumber(right):
+		// Compare says NaN equals itself, where the VM says it does not.
+		if isNaN(left) || isNaN(right) {
+			return nil, false
+		}
+		var ok bool
+		result, ok = object.Compare(left, right)
+		if !ok {
+			return nil, false
+		}
+
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		// The VM only compares strings for equality.
+		if operator != "==" {
+			return nil, false
+		}
+		return &object.Boolean{Value: left.(*object.String).Value == right.(*object.String).Value}, true
+
+	case left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ:
+		equal := left.(*object.Boolean).Value == right.(*object.Boolean).Value
+		switch operator {
+		case "==":
+			return &object.Boolean{Value: equal}, true
+		case "!=":
+			return &object.Boolean{Value: !equal}, true
+		}
+		return nil, false
+
+	default:
+		return nil, false
+	}
+
+	switch operator {
+	case "==":
+		return &object.Boolean{Value: result == 0}, true
+	case "!=":
+		return &object.Boolean{Value: result != 0}, true
+	case "<":
+		return &object.Boolean{Value: result < 0}, true
+	case ">":
+		return &object.Boolean{Value: result > 0}, true
+	case "<=":
+		return &object.Boolean{Value: result <= 0}, true
+	case ">=":
+		return &object.Boolean{Value: result >= 0}, true
+	}
+	return nil, false
+}
+
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Integer, *object.Float, *object.BigInt, *object.Decimal:
+		return true
+	}
+	return false
+}
+
+func isNaN(obj object.Object) bool {
+	f, ok := obj.(*object.Float)
+	return ok && math.IsNaN(f.Value)
+}
+
+// truthy matches the VM's idea of truth for the constants the compiler
+// folds.
+func truthy(obj object.Object) bool {
+	if b, ok := obj.(*object.Boolean); ok {
+		return b.Value
+	}
+	return true
+}
+
+func (c *Compiler) emitConstant(obj object.Object) {
+	if b, ok := obj.(*object.Boolean); ok {
+		if b.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+		return
+	}
+	c.emit(code.OpConstant, c.addConstant(obj))
+}
+
+// compileConstantIf compiles an if whose condition is known to be truthy or
+// not: only the branch that runs is kept.
+func (c *Compiler) compileConstantIf(node *ast.IfExpression, condition bool) error {
+	if condition {
+		if err := c.compileBranch(node.Consequence); err != nil {
+			return err
+		}
+		if node.Alternative != nil {
+			return c.compileUnreachable(node.Alternative)
+		}
+		return nil
+	}
+
+	if err := c.compileUnreachable(node.Consequence); err != nil {
+		return err
+	}
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+		return nil
+	}
+	return c.compileBranch(node.Alternative)
+}
+
+// compileBranch leaves the value of an if branch on the stack, as the
+// branches of a regular if do.
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	start := len(c.currentInstructions())
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+	if c.lastInstructionIs(code.OpPop) && c.scopes[c.scopeIndex].lastInstruction.Pos >= start {
+		c.removeLastPop()
+	}
+	return nil
+}
+
+// compileUnreachable compiles statements that can never run and drops what
+// they emitted. Compiling them still defines their symbols and reports
+// their errors, as it does when they are kept.
+func (c *Compiler) compileUnreachable(stmts ...ast.Statement) error {
+	scope := c.scopes[c.scopeIndex]
+	pos := len(scope.instructions)
+	lines := slices.Clone(scope.lines)
+	constants := len(c.constants)
+	importedFiles := maps.Clone(c.importedFiles)
+
+	for _, s := range stmts {
+		if err := c.Compile(s); err != nil {
+			return err
+		}
+	}
+
+	current := &c.scopes[c.scopeIndex]
+	current.instructions = current.instructions[:pos]
+	current.lastInstruction = scope.lastInstruction
+	current.previousInstruction = scope.previousInstruction
+	current.lines = lines
+	for jump := range current.farJumps {
+		if jump >= pos {
+			delete(current.farJumps, jump)
+		}
+	}
+
+	c.constants = c.constants[:constants]
+	for key, index := range c.constantIndex {
+		if index >= constants {
+			delete(c.constantIndex, key)
+		}
+	}
+	c.importedFiles = importedFiles
+
+	return nil
+}
+
+// threadJumps points jumps that land on an OpJump at where the chain of
+// jumps ends. Instructions keep their offsets, so line tables stay valid.
+func threadJumps(ins code.Instructions) {
+	for i := 0; i < len(ins); {
+		op := code.Opcode(ins[i])
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+
+		if index, ok := code.JumpOperand(op); ok {
+			target := operands[index]
+			// Chains are short; the limit only stops loops like
+			// `while (true) {}`, which jump to themselves.
+			for hops := 0; hops < 16 && target < len(ins); hops++ {
+				next, ok := jumpTarget(ins, target)
+				if !ok {
+					break
+				}
+				target = next
+			}
+			if target != operands[index] {
+				setJumpTarget(ins, i, target)
+			}
+		}
+
+		i += 1 + read
+	}
+}
+
+// jumpTarget returns where the instruction at pos jumps to when it is an
+// unconditional jump.
+func jumpTarget(ins code.Instructions, pos int) (int, bool) {
+	switch code.Opcode(ins[pos]) {
+	case code.OpJump:
+		return int(code.ReadUint16(ins[pos+1:])), true
+	case code.OpJumpWide:
+		return int(code.ReadUint32(ins[pos+1:])), true
+	}
+	return 0, false
+}
+
+// addImmediate returns the operand of the OpAddImmediate that computes node
+// when node adds or subtracts an integer literal that fits in it. Adding the
+// negated literal gives the same result as subtracting it for every type the
+// VM can subtract from, and fails with the same errors for the others.
+func (c *Compiler) addImmediate(node *ast.InfixExpression) (int, bool) {
+	if c.optimization < O2 {
+		return 0, false
+	}
+
+	literal, ok := node.Right.(*ast.IntegerLiteral)
+	if !ok || literal.Big != nil || literal.Value > math.MaxInt16 {
+		return 0, false
+	}
+
+	switch node.Operator {
+	case "+":
+		return int(literal.Value), true
+	case "-":
+		return -int(literal.Value), true
+	}
+	return 0, false
+}
+
+var comparisonOpcodes = map[string]code.Opcode{
+	"==": code.OpEqual,
+	"!=": code.OpNotEqual,
+	">":  code.OpGreaterThan,
+	"<":  code.OpLessThan,
+	">=": code.OpGreaterThanOrEqual,
+	"<=": code.OpLessThanOrEqual,
+}
+
+// compileJumpNotTruthy compiles condition and a jump, still to be pointed
+// at the code for when the condition does not hold, and returns where the
+// jump is. From O2 on, comparing a local with a constant is fused with the
+// jump into an OpLocalCompareJump.
+func (c *Compiler) compileJumpNotTruthy(condition ast.Expression) (int, error) {
+	if infix, ok := condition.(*ast.InfixExpression); ok && c.optimization >= O2 {
+		ident, isIdent := infix.Left.(*ast.Identifier)
+		comparison, isComparison := comparisonOpcodes[infix.Operator]
+		value, isConstant := c.constantValue(infix.Right)
+		// Booleans are not constants: the VM compares them by identity
+		// with its own True and False.
+		_, isBool := value.(*object.Boolean)
+
+		if isIdent && isComparison && isConstant && !isBool {
+			symbol, ok := c.symbolTable.Resolve(ident.Value)
+			// The local's operand is a single byte.
+			if ok && symbol.Scope == LocalScope && symbol.Index <= math.MaxUint8 {
+				if constant := c.addConstant(value); constant <= math.MaxUint16 {
+					return c.emit(code.OpLocalCompareJump, symbol.Index, constant, int(comparison), 9999), nil
+				}
+			}
+		}
+	}
+
+	if err := c.Compile(condition); err != nil {
+		return 0, err
+	}
+	return c.emit(code.OpJumpNotTruthy, 9999), nil
+}
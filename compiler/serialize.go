@@ -0,0 +1,447 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"zumbra/code"
+	"zumbra/object"
+)
+
+// Version identifies the code this compiler emits. Change it whenever the
+// same source would compile to different bytecode, so .zbc files and cached
+// compilations from older compilers are no longer used.
+const Version = "0.1.0-6"
+
+// FormatVersion is the version of the .zbc layout written by
+// MarshalBinary.
+const FormatVersion = 2
+
+var zbcMagic = []byte("ZBC\x00")
+
+// ErrVersion is returned for .zbc files written by another compiler or in
+// another format.
+var ErrVersion = errors.New("bytecode was written by another compiler version")
+
+// Constant tags. Their values are part of the format.
+const (
+	tagInteger byte = iota + 1
+	tagFloat
+	tagString
+	tagBoolean
+	tagNull
+	tagFunction
+	tagEnum
+	tagEnumValue
+	tagArray
+	tagDict
+	tagSet
+	tagBigInt
+	tagDecimal
+)
+
+// A .zbc file is the magic bytes, FormatVersion, Version and the bytecode:
+// instructions, line table and tagged constants. Integers are varints,
+// strings and byte slices are length-prefixed.
+
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.buf.Write(zbcMagic)
+	e.uvarint(FormatVersion)
+	e.string(Version)
+
+	e.bytes(b.Instructions)
+	e.lines(b.Lines)
+	e.uvarint(uint64(len(b.Constants)))
+	for _, c := range b.Constants {
+		if err := e.object(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBytecode reads bytecode written by MarshalBinary.
+func UnmarshalBytecode(data []byte) (*Bytecode, error) {
+	if !bytes.HasPrefix(data, zbcMagic) {
+		return nil, fmt.Errorf("not a Zumbra bytecode file")
+	}
+
+	d := &decoder{data: data[len(zbcMagic):]}
+	if d.uvarint() != FormatVersion || d.string() != Version {
+		if d.err != nil {
+			return nil, d.err
+		}
+		return nil, ErrVersion
+	}
+
+	b := &Bytecode{}
+	b.Instructions = code.Instructions(d.bytes())
+	b.Lines = d.lines()
+
+	count := d.length()
+	b.Constants = make([]object.Object, 0, count)
+	for i := 0; i < count && d.err == nil; i++ {
+		b.Constants = append(b.Constants, d.object())
+	}
+
+	if d.err == nil && len(d.data) > 0 {
+		d.err = fmt.Errorf("%d unexpected bytes at the end", len(d.data))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid bytecode: %w", d.err)
+	}
+	return b, nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) uvarint(x uint64) {
+	e.buf.Write(binary.AppendUvarint(nil, x))
+}
+
+func (e *encoder) varint(x int64) {
+	e.buf.Write(binary.AppendVarint(nil, x))
+}
+
+func (e *encoder) bytes(data []byte) {
+	e.uvarint(uint64(len(data)))
+	e.buf.Write(data)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.buf.WriteByte(1)
+	} else {
+		e.buf.WriteByte(0)
+	}
+}
+
+func (e *encoder) strings(list []string) {
+	e.uvarint(uint64(len(list)))
+	for _, s := range list {
+		e.string(s)
+	}
+}
+
+// bigInt writes x as its sign and the bytes of its absolute value.
+func (e *encoder) bigInt(x *big.Int) {
+	e.bool(x.Sign() < 0)
+	e.bytes(x.Bytes())
+}
+
+func (e *encoder) lines(table code.LineTable) {
+	e.uvarint(uint64(len(table)))
+	for _, entry := range table {
+		e.uvarint(uint64(entry.Offset))
+		e.string(entry.File)
+		e.uvarint(uint64(entry.Line))
+	}
+}
+
+func (e *encoder) object(obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		e.buf.WriteByte(tagInteger)
+		e.varint(obj.Value)
+
+	case *object.BigInt:
+		e.buf.WriteByte(tagBigInt)
+		e.bigInt(obj.Value)
+
+	case *object.Decimal:
+		e.buf.WriteByte(tagDecimal)
+		e.bigInt(obj.Unscaled)
+		e.varint(int64(obj.Scale))
+
+	case *object.Float:
+		e.buf.WriteByte(tagFloat)
+		e.uvarint(math.Float64bits(obj.Value))
+
+	case *object.String:
+		e.buf.WriteByte(tagString)
+		e.string(obj.Value)
+
+	case *object.Boolean:
+		e.buf.WriteByte(tagBoolean)
+		e.bool(obj.Value)
+
+	case *object.Null:
+		e.buf.WriteByte(tagNull)
+
+	case *object.CompiledFunction:
+		e.buf.WriteByte(tagFunction)
+		e.bytes(obj.Instructions)
+		e.uvarint(uint64(obj.NumLocals))
+		e.uvarint(uint64(obj.NumParameters))
+		e.bool(obj.Generator)
+		e.string(obj.Name)
+		e.lines(obj.Lines)
+		e.strings(obj.LocalNames)
+		e.strings(obj.FreeNames)
+
+	case *object.Enum:
+		e.buf.WriteByte(tagEnum)
+		e.string(obj.Name)
+		variants := []string{}
+		for _, v := range obj.Variants {
+			variants = append(variants, v.Name)
+		}
+		e.strings(variants)
+
+	case *object.EnumValue:
+		e.buf.WriteByte(tagEnumValue)
+		e.string(obj.Enum)
+		e.string(obj.Name)
+		e.uvarint(uint64(obj.Ordinal))
+
+	case *object.Array:
+		e.buf.WriteByte(tagArray)
+		return e.objects(obj.Elements)
+
+	case *object.Dict:
+		e.buf.WriteByte(tagDict)
+		keys := make([]object.DictKey, 0, len(obj.Pairs))
+		for k := range obj.Pairs {
+			keys = append(keys, k)
+		}
+		sortKeys(keys)
+
+		e.uvarint(uint64(len(keys)))
+		for _, k := range keys {
+			if err := e.object(obj.Pairs[k].Key); err != nil {
+				return err
+			}
+			if err := e.object(obj.Pairs[k].Value); err != nil {
+				return err
+			}
+		}
+
+	case *object.Set:
+		e.buf.WriteByte(tagSet)
+		return e.objects(obj.Values())
+
+	default:
+		return fmt.Errorf("cannot serialize constant of type %s", obj.Type())
+	}
+
+	return nil
+}
+
+func (e *encoder) objects(list []object.Object) error {
+	e.uvarint(uint64(len(list)))
+	for _, obj := range list {
+		if err := e.object(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortKeys(keys []object.DictKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].Value < keys[j].Value
+	})
+}
+
+// decoder reads until the first error, after which every read returns a
+// zero value and err says what went wrong.
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+	d.data = nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("truncated data")
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	x, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail("truncated data")
+		return 0
+	}
+	d.data = d.data[n:]
+	return x
+}
+
+// length reads a count and checks that the remaining data could hold that
+// many items of at least one byte, so corrupt files cannot make the decoder
+// allocate huge slices.
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail("length %d past the end of the data", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) byte() byte {
+	if len(d.data) == 0 {
+		d.fail("truncated data")
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.length()
+	data := append([]byte{}, d.data[:n]...)
+	d.data = d.data[n:]
+	return data
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() == 1
+}
+
+func (d *decoder) strings() []string {
+	n := d.length()
+	list := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		list = append(list, d.string())
+	}
+	return list
+}
+
+func (d *decoder) bigInt() *big.Int {
+	negative := d.bool()
+	x := new(big.Int).SetBytes(d.bytes())
+	if negative {
+		x.Neg(x)
+	}
+	return x
+}
+
+func (d *decoder) lines() code.LineTable {
+	n := d.length()
+	if n == 0 {
+		return nil
+	}
+	table := make(code.LineTable, 0, n)
+	for i := 0; i < n; i++ {
+		offset := int(d.uvarint())
+		file := d.string()
+		line := int(d.uvarint())
+		table = append(table, code.Line{Offset: offset, File: file, Line: line})
+	}
+	return table
+}
+
+func (d *decoder) object() object.Object {
+	switch tag := d.byte(); tag {
+	case tagInteger:
+		return &object.Integer{Value: d.varint()}
+
+	case tagBigInt:
+		return &object.BigInt{Value: d.bigInt()}
+
+	case tagDecimal:
+		unscaled := d.bigInt()
+		return &object.Decimal{Unscaled: unscaled, Scale: int32(d.varint())}
+
+	case tagFloat:
+		return &object.Float{Value: math.Float64frombits(d.uvarint())}
+
+	case tagString:
+		return &object.String{Value: d.string()}
+
+	case tagBoolean:
+		return &object.Boolean{Value: d.bool()}
+
+	case tagNull:
+		return &object.Null{}
+
+	case tagFunction:
+		fn := &object.CompiledFunction{}
+		fn.Instructions = code.Instructions(d.bytes())
+		fn.NumLocals = int(d.uvarint())
+		fn.NumParameters = int(d.uvarint())
+		fn.Generator = d.bool()
+		fn.Name = d.string()
+		fn.Lines = d.lines()
+		fn.LocalNames = d.strings()
+		fn.FreeNames = d.strings()
+		return fn
+
+	case tagEnum:
+		name := d.string()
+		return object.NewEnum(name, d.strings())
+
+	case tagEnumValue:
+		return &object.EnumValue{Enum: d.string(), Name: d.string(), Ordinal: int(d.uvarint())}
+
+	case tagArray:
+		return &object.Array{Elements: d.objects()}
+
+	case tagDict:
+		n := d.length()
+		dict := &object.Dict{Pairs: map[object.DictKey]object.DictPair{}}
+		for i := 0; i < n && d.err == nil; i++ {
+			key, value := d.object(), d.object()
+			hashable, ok := key.(object.Dictable)
+			if !ok {
+				d.fail("unusable as dict key: %s", key.Type())
+				break
+			}
+			dict.Pairs[hashable.DictKey()] = object.DictPair{Key: key, Value: value}
+		}
+		return dict
+
+	case tagSet:
+		set := object.NewSet()
+		for _, el := range d.objects() {
+			if err := set.Add(el); err != nil {
+				d.fail("%s", err)
+			}
+		}
+		return set
+
+	default:
+		d.fail("unknown constant tag %d", tag)
+		return &object.Null{}
+	}
+}
+
+func (d *decoder) objects() []object.Object {
+	n := d.length()
+	list := make([]object.Object, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		list = append(list, d.object())
+	}
+	return list
+}
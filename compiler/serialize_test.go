@@ -0,0 +1,185 @@
+package compiler
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"zumbra/object"
+)
+
+func compileForTest(t *testing.T, input string) *Bytecode {
+	t.Helper()
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return comp.Bytecode()
+}
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	input := `
+enum Color { Red, Green }
+var scale << 1.5;
+var greet << fct(name) { "hello " + name };
+var adder << fct(x) { fct(y) { x + y } };
+var counter << fct() { yield 1; yield 2; };
+show(greet("zumbra"), adder(1)(2), scale, Color.Green);
+show(match (Color.Red) { Color.Red => { true } Color.Green => { false } });
+`
+	bytecode := compileForTest(t, input)
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %s", err)
+	}
+	decoded, err := UnmarshalBytecode(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBytecode: %s", err)
+	}
+
+	if !reflect.DeepEqual(bytecode.Instructions, decoded.Instructions) {
+		t.Errorf("instructions differ:\nwant=%s\ngot=%s", bytecode.Instructions, decoded.Instructions)
+	}
+	if !reflect.DeepEqual(bytecode.Lines, decoded.Lines) {
+		t.Errorf("lines differ: want=%v got=%v", bytecode.Lines, decoded.Lines)
+	}
+	if len(bytecode.Constants) != len(decoded.Constants) {
+		t.Fatalf("wrong number of constants. want=%d got=%d", len(bytecode.Constants), len(decoded.Constants))
+	}
+
+	for i, want := range bytecode.Constants {
+		got := decoded.Constants[i]
+		fn, ok := want.(*object.CompiledFunction)
+		if !ok {
+			if want.Type() != got.Type() || want.Inspect() != got.Inspect() {
+				t.Errorf("constant %d differs: want=%s got=%s", i, want.Inspect(), got.Inspect())
+			}
+			continue
+		}
+
+		gotFn, ok := got.(*object.CompiledFunction)
+		if !ok {
+			t.Errorf("constant %d is not a function. got=%T", i, got)
+			continue
+		}
+		if !reflect.DeepEqual(fn.Instructions, gotFn.Instructions) ||
+			fn.NumLocals != gotFn.NumLocals ||
+			fn.NumParameters != gotFn.NumParameters ||
+			fn.Generator != gotFn.Generator ||
+			fn.Name != gotFn.Name ||
+			!reflect.DeepEqual(fn.Lines, gotFn.Lines) ||
+			len(fn.LocalNames) != len(gotFn.LocalNames) ||
+			len(fn.FreeNames) != len(gotFn.FreeNames) {
+			t.Errorf("function constant %d differs: want=%+v got=%+v", i, fn, gotFn)
+		}
+	}
+}
+
+func TestBigNumberConstantsRoundTrip(t *testing.T) {
+	bytecode := compileForTest(t, `var x << 99999999999999999999; show(x, -123456789012345678901234567890, 0);`)
+
+	huge, _ := new(big.Int).SetString("-98765432109876543210", 10)
+	bytecode.Constants = append(bytecode.Constants,
+		&object.BigInt{Value: big.NewInt(0)},
+		&object.Decimal{Unscaled: big.NewInt(-12345), Scale: 3},
+		&object.Decimal{Unscaled: huge, Scale: 16},
+		&object.Decimal{Unscaled: big.NewInt(0), Scale: 0},
+	)
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %s", err)
+	}
+	decoded, err := UnmarshalBytecode(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBytecode: %s", err)
+	}
+
+	bigInts := 0
+	for i, want := range bytecode.Constants {
+		got := decoded.Constants[i]
+		if want.Type() != got.Type() || want.Inspect() != got.Inspect() {
+			t.Errorf("constant %d differs: want=%s %s got=%s %s", i, want.Type(), want.Inspect(), got.Type(), got.Inspect())
+		}
+		if want, ok := want.(*object.Decimal); ok && want.Scale != got.(*object.Decimal).Scale {
+			t.Errorf("constant %d has scale %d, want %d", i, got.(*object.Decimal).Scale, want.Scale)
+		}
+		if _, ok := want.(*object.BigInt); ok {
+			bigInts++
+		}
+	}
+	if bigInts < 2 {
+		t.Errorf("the program compiled to %d BigInt constants, want at least 2", bigInts)
+	}
+}
+
+func TestUnmarshalBytecodeErrors(t *testing.T) {
+	data, err := compileForTest(t, `var x << [1, "two", 3.0]; show(x);`).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %s", err)
+	}
+
+	if _, err := UnmarshalBytecode([]byte("package main")); err == nil {
+		t.Errorf("expected an error for a file that is not bytecode")
+	}
+
+	for n := len(zbcMagic); n < len(data); n++ {
+		if _, err := UnmarshalBytecode(data[:n]); err == nil {
+			t.Errorf("expected an error for data truncated to %d bytes", n)
+		}
+	}
+
+	other := &encoder{}
+	other.buf.Write(zbcMagic)
+	other.uvarint(FormatVersion)
+	other.string("0.0.0")
+	if _, err := UnmarshalBytecode(other.buf.Bytes()); !errors.Is(err, ErrVersion) {
+		t.Errorf("expected ErrVersion for another compiler version, got %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := &Cache{Dir: filepath.Join(dir, "cache")}
+
+	lib := filepath.Join(dir, "lib.zum")
+	if err := os.WriteFile(lib, []byte(`var two << 2;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(dir, "main.zum")
+	source := []byte(`show(1 + 1);`)
+	bytecode := compileForTest(t, string(source))
+
+	if _, ok := cache.Load(file, source); ok {
+		t.Fatalf("expected a miss on an empty cache")
+	}
+	if err := cache.Store(file, source, bytecode, []string{lib}); err != nil {
+		t.Fatalf("Store: %s", err)
+	}
+
+	cached, ok := cache.Load(file, source)
+	if !ok {
+		t.Fatalf("expected a hit after Store")
+	}
+	if !reflect.DeepEqual(bytecode.Instructions, cached.Instructions) {
+		t.Errorf("cached instructions differ:\nwant=%s\ngot=%s", bytecode.Instructions, cached.Instructions)
+	}
+
+	if _, ok := cache.Load(file, []byte(`show(1 + 2);`)); ok {
+		t.Errorf("expected a miss for changed source")
+	}
+	if _, ok := cache.Load(filepath.Join(dir, "other.zum"), source); ok {
+		t.Errorf("expected a miss for another file")
+	}
+
+	if err := os.WriteFile(lib, []byte(`var two << 3;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Load(file, source); ok {
+		t.Errorf("expected a miss after an imported file changed")
+	}
+}
@@ -22,6 +22,8 @@ type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+	// names holds the name of every Define call, indexed like the symbols.
+	names []string
 }
 
 func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
@@ -49,10 +51,33 @@ func (s *SymbolTable) Define(name string) Symbol {
 
 	s.store[name] = symbol
 	s.numDefinitions++
+	s.names = append(s.names, name)
 
 	return symbol
 }
 
+// Names returns the names defined in this table by index. A name defined
+// twice appears at both indexes.
+func (s *SymbolTable) Names() []string {
+	return append([]string{}, s.names...)
+}
+
+// Copy returns a table with the same symbols, whose definitions do not
+// change s.
+func (s *SymbolTable) Copy() *SymbolTable {
+	c := &SymbolTable{
+		Outer:          s.Outer,
+		store:          make(map[string]Symbol, len(s.store)),
+		numDefinitions: s.numDefinitions,
+		FreeSymbols:    append([]Symbol{}, s.FreeSymbols...),
+		names:          append([]string{}, s.names...),
+	}
+	for name, symbol := range s.store {
+		c.store[name] = symbol
+	}
+	return c
+}
+
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
 	if !ok && s.Outer != nil {
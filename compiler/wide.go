@@ -0,0 +1,78 @@
+package compiler
+
+import "zumbra/code"
+
+// widenJumps makes every jump of the current scope wide once the target of
+// one of them does not fit in its operand, which only happens in code past
+// 64 KiB. Jump targets are only all known once the scope is complete, so
+// jumps are emitted narrow and widened here, when the scope is done.
+//
+// Widening moves the instructions after each jump, so the targets, the
+// line table and the positions of the last instructions move with them.
+// An OpLocalCompareJump has no wide variant and goes back to the
+// instructions it fuses.
+func (c *Compiler) widenJumps() {
+	scope := &c.scopes[c.scopeIndex]
+	if len(scope.farJumps) == 0 {
+		return
+	}
+
+	old := scope.instructions
+	ins := make(code.Instructions, 0, len(old)+len(old)/4)
+	// moved maps the offset of every instruction to its new offset.
+	moved := map[int]int{}
+	// jumps maps the new offset of every jump to its old target.
+	jumps := map[int]int{}
+
+	for i := 0; i < len(old); {
+		op := code.Opcode(old[i])
+		def, err := code.Lookup(old[i])
+		if err != nil {
+			return
+		}
+		operands, read := code.ReadOperands(def, old[i+1:])
+		moved[i] = len(ins)
+
+		index, isJump := code.JumpOperand(op)
+		if !isJump {
+			ins = append(ins, old[i:i+1+read]...)
+			i += 1 + read
+			continue
+		}
+
+		target, far := scope.farJumps[i]
+		if !far {
+			target = operands[index]
+		}
+		if op == code.OpLocalCompareJump {
+			ins = append(ins, code.Make(code.OpGetLocal, operands[0])...)
+			ins = append(ins, code.Make(code.OpConstant, operands[1])...)
+			ins = append(ins, code.Make(code.Opcode(operands[2]))...)
+			op = code.OpJumpNotTruthy
+		}
+		if wide, ok := code.Wide(op); ok {
+			op = wide
+		}
+		jumps[len(ins)] = target
+		ins = append(ins, code.Make(op, 0)...)
+		i += 1 + read
+	}
+	moved[len(old)] = len(ins)
+
+	for pos, target := range jumps {
+		setJumpTarget(ins, pos, moved[target])
+	}
+
+	lines := code.LineTable{}
+	for _, line := range scope.lines {
+		if offset, ok := moved[line.Offset]; ok {
+			lines = lines.Add(offset, line.File, line.Line)
+		}
+	}
+
+	scope.instructions = ins
+	scope.lines = lines
+	scope.lastInstruction.Pos = moved[scope.lastInstruction.Pos]
+	scope.previousInstruction.Pos = moved[scope.previousInstruction.Pos]
+	scope.farJumps = nil
+}
@@ -0,0 +1,214 @@
+// Package conformance runs Zumbra programs on each of the language's
+// engines: the VM, the tree-walking evaluator and the Go programs `zumbra
+// build` generates. Its tests hold every engine to the same expected
+// output, so the engines cannot drift apart unnoticed.
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"zumbra/ast"
+	"zumbra/compiler"
+	"zumbra/evaluator"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/transpiler"
+	"zumbra/vm"
+)
+
+// Result is what a program printed and the message of the runtime error
+// that stopped it, if one did.
+type Result struct {
+	Stdout string
+	Error  string
+}
+
+// ErrUnsupported is returned for programs an engine cannot run at all.
+var ErrUnsupported = errors.New("not supported by this engine")
+
+// Engine runs programs. The VM and the evaluator run in process and leave
+// the working directory alone; programs that read files relative to it
+// need the caller to change into their directory.
+type Engine interface {
+	Name() string
+	Run(path string) (Result, error)
+}
+
+// VM compiles programs at the given optimization level and runs them on
+// the VM.
+type VM struct {
+	Optimization int
+}
+
+func (v VM) Name() string {
+	if v.Optimization == compiler.O0 {
+		return "vm"
+	}
+	return fmt.Sprintf("vm -O%d", v.Optimization)
+}
+
+// Run runs the program as `zumbra run` does.
+func (v VM) Run(path string) (result Result, err error) {
+	program, err := parseFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var out bytes.Buffer
+	symbolTable := compiler.NewSymbolTable()
+	for i, b := range builtins.Builtins {
+		symbolTable.DefineBuiltin(i, b.Name)
+	}
+	globals := make([]object.Object, vm.GlobalSize)
+	globals[symbolTable.Define("show").Index] = builtins.ShowTo(&out)
+
+	comp := compiler.NewWithStateAndDir(symbolTable, []object.Object{}, filepath.Dir(path))
+	if err := comp.SetOptimization(v.Optimization); err != nil {
+		return Result{}, err
+	}
+	if err := comp.Compile(program); err != nil {
+		return Result{}, fmt.Errorf("compilation error: %s", err)
+	}
+
+	defer recoverPanic(&err)
+	machine := vm.NewWithGlobalsStore(comp.Bytecode(), globals)
+	if err := machine.Run(); err != nil {
+		return Result{Stdout: out.String(), Error: err.Error()}, nil
+	}
+	return Result{Stdout: out.String()}, nil
+}
+
+type Evaluator struct{}
+
+func (Evaluator) Name() string { return "evaluator" }
+
+// Run evaluates the program. The error value a program stops with is its
+// runtime error.
+func (Evaluator) Run(path string) (result Result, err error) {
+	program, err := parseFile(path)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetDir(filepath.Dir(path))
+	env.Set("show", builtins.ShowTo(&out))
+
+	defer recoverPanic(&err)
+	if e, ok := evaluator.Eval(program, env).(*object.Error); ok {
+		return Result{Stdout: out.String(), Error: e.Message}, nil
+	}
+	return Result{Stdout: out.String()}, nil
+}
+
+// Transpiled runs the executables `zumbra build` makes of programs. They
+// are all built up front, by NewTranspiled, in one Go module.
+type Transpiled struct {
+	executables map[string]string
+	unsupported map[string]error
+}
+
+// NewTranspiled builds the programs in paths under dir. It needs the Go
+// toolchain.
+func NewTranspiled(dir string, paths []string) (*Transpiled, error) {
+	t := &Transpiled{executables: map[string]string{}, unsupported: map[string]error{}}
+
+	mains := map[string][]byte{}
+	for i, path := range paths {
+		program, err := parseFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		code, err := transpiler.Generate(program, transpiler.Options{Dir: filepath.Dir(path), Source: filepath.Base(path)})
+		var unsupported *transpiler.UnsupportedError
+		if errors.As(err, &unsupported) {
+			t.unsupported[path] = err
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		pkg := fmt.Sprintf("p%d", i)
+		mains[pkg] = code
+		t.executables[path] = filepath.Join(dir, "bin", pkg)
+	}
+
+	if err := transpiler.WriteModule(dir, mains); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("go", "build", "-trimpath", "-o", filepath.Join(dir, "bin")+string(filepath.Separator), "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("go build: %s\n%s", err, out)
+	}
+	return t, nil
+}
+
+func (t *Transpiled) Name() string { return "transpiler" }
+
+// Run runs the program's executable in the program's directory.
+func (t *Transpiled) Run(path string) (Result, error) {
+	if err, ok := t.unsupported[path]; ok {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+	executable, ok := t.executables[path]
+	if !ok {
+		return Result{}, fmt.Errorf("%s was not built", path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(executable)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return Result{Stdout: stdout.String()}, nil
+	}
+
+	// A runtime error is the last line printed, as `zumbra run` prints it.
+	const prefix = "Error on VM execution: "
+	out := strings.TrimSuffix(stdout.String(), "\n")
+	last := out[strings.LastIndex(out, "\n")+1:]
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && strings.HasPrefix(last, prefix) {
+		return Result{
+			Stdout: strings.TrimSuffix(out, last),
+			Error:  strings.TrimPrefix(last, prefix),
+		}, nil
+	}
+	return Result{}, fmt.Errorf("%s\n%s", err, stderr.String())
+}
+
+func parseFile(path string) (*ast.Program, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parsing errors: %s", strings.Join(p.Errors(), "; "))
+	}
+	return program, nil
+}
+
+// recoverPanic turns a panic of an engine into an error.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
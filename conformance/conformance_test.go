@@ -0,0 +1,220 @@
+package conformance
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zumbra/compiler"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected results with the VM's")
+
+// knownDivergences are the programs an engine is known to get wrong, by
+// engine and program, with the reason. A divergence that goes away fails
+// the test, so the list stays accurate.
+var knownDivergences = map[string]string{
+	"evaluator closures.zum":                 "closures see later assignments to the variables they capture",
+	"evaluator generators.zum":               "yield is not supported",
+	"evaluator code_examples/generators.zum": "yield is not supported",
+	"evaluator loops.zum":                    "var cannot declare a variable twice, as a loop body does",
+	"evaluator runtime_error.zum":            "runtime errors are worded differently",
+	"evaluator code_examples/arrays/sum.zum": "an error returned by a builtin stops the program",
+}
+
+// skippedExamples are the code examples that cannot run in a test, with
+// the reason.
+var skippedExamples = map[string]string{
+	"date.zum":                  "prints the current time",
+	"dicts/addToADict.zum":      "prints a dict with several keys, in random order",
+	"dicts/dictKeys.zum":        "prints the keys of a dict, in random order",
+	"dicts/dictValues.zum":      "prints the values of a dict, in random order",
+	"extras/sendEmail.zum":      "needs a mail account",
+	"extras/sendWhatsapp.zum":   "needs a WhatsApp account",
+	"input.zum":                 "reads stdin",
+	"numbers/randomNumbers.zum": "prints random numbers",
+	"testing/math_test.zum":     "runs under zumbra test",
+}
+
+var skippedExampleDirs = map[string]string{
+	"http":  "starts servers and needs the network",
+	"jwt":   "prints tokens that depend on the current time",
+	"mysql": "needs a database",
+}
+
+type program struct {
+	// name identifies the program in test names and knownDivergences.
+	name string
+	path string
+	// expected is the path of its .out and .err files, without the
+	// extension.
+	expected string
+}
+
+func programs(t *testing.T) []program {
+	t.Helper()
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples, err := filepath.Abs(filepath.Join("..", "code_examples"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	programs := []program{}
+	paths, err := filepath.Glob(filepath.Join(testdata, "*.zum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		programs = append(programs, program{
+			name:     name,
+			path:     path,
+			expected: strings.TrimSuffix(path, ".zum"),
+		})
+	}
+
+	err = filepath.WalkDir(examples, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(examples, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if _, ok := skippedExampleDirs[rel]; ok {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := skippedExamples[rel]; ok || filepath.Ext(path) != ".zum" {
+			return nil
+		}
+
+		programs = append(programs, program{
+			name:     "code_examples/" + rel,
+			path:     path,
+			expected: filepath.Join(testdata, "code_examples", strings.TrimSuffix(rel, ".zum")),
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return programs
+}
+
+func engines(t *testing.T, programs []program) []Engine {
+	t.Helper()
+	engines := []Engine{VM{}, VM{Optimization: compiler.MaxOptimization}, Evaluator{}}
+
+	if testing.Short() {
+		t.Log("skipping the transpiler in short mode")
+		return engines
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Log("skipping the transpiler: go is not installed")
+		return engines
+	}
+
+	paths := []string{}
+	for _, p := range programs {
+		paths = append(paths, p.path)
+	}
+	transpiled, err := NewTranspiled(t.TempDir(), paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(engines, transpiled)
+}
+
+func readExpected(t *testing.T, p program) Result {
+	t.Helper()
+	stdout, err := os.ReadFile(p.expected + ".out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := os.ReadFile(p.expected + ".err")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return Result{Stdout: string(stdout), Error: strings.TrimSuffix(string(message), "\n")}
+}
+
+func writeExpected(t *testing.T, p program, r Result) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p.expected), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.expected+".out", []byte(r.Stdout), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Error == "" {
+		if err := os.Remove(p.expected + ".err"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := os.WriteFile(p.expected+".err", []byte(r.Error+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	programs := programs(t)
+	engines := engines(t, programs)
+
+	for _, p := range programs {
+		t.Run(p.name, func(t *testing.T) {
+			// Programs run from their own directory, as `zumbra file.zum`
+			// run there would.
+			t.Chdir(filepath.Dir(p.path))
+
+			if *update {
+				got, err := VM{}.Run(p.path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				writeExpected(t, p, got)
+			}
+			want := readExpected(t, p)
+
+			for _, e := range engines {
+				got, err := e.Run(p.path)
+				if errors.Is(err, ErrUnsupported) {
+					t.Logf("%s: %s", e.Name(), err)
+					continue
+				}
+
+				divergence := ""
+				switch {
+				case err != nil:
+					divergence = fmt.Sprintf("failed: %s", err)
+				case got != want:
+					divergence = fmt.Sprintf("wrong result.\nwant stdout=%q error=%q\ngot stdout=%q error=%q",
+						want.Stdout, want.Error, got.Stdout, got.Error)
+				}
+
+				reason, known := knownDivergences[e.Name()+" "+p.name]
+				switch {
+				case known && divergence == "":
+					t.Errorf("%s no longer diverges; remove it from knownDivergences", e.Name())
+				case known:
+					t.Logf("%s diverges as known, %s", e.Name(), reason)
+				case divergence != "":
+					t.Errorf("%s: %s", e.Name(), divergence)
+				}
+			}
+		})
+	}
+}
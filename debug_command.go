@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"zumbra/debugger"
+)
+
+const debugHelp = `Commands:
+  break [file:]line, b   set a breakpoint
+  delete [file:]line     remove a breakpoint
+  continue, c            run until a breakpoint or the end
+  next, n                step over the current line
+  step, s                step into calls on the current line
+  out, o                 run until the current function returns
+  backtrace, bt          show the call stack
+  locals                 show the current frame's locals and free variables
+  globals                show the globals
+  print name, p name     show a variable
+  frame n, f n           select the frame that locals and print look at
+  quit, q                stop debugging`
+
+// debugCommand implements `zumbra debug [-dap] file.zum`.
+func debugCommand(args []string) bool {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	dap := flags.Bool("dap", false, "speak the Debug Adapter Protocol on stdin and stdout")
+	flags.Parse(args)
+
+	if *dap {
+		if err := debugger.NewAdapter(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		return true
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Println("usage: zumbra debug [-dap] file.zum")
+		return false
+	}
+
+	d, err := debugger.New(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	debugSession(d, os.Stdin, os.Stdout)
+	return true
+}
+
+func debugSession(d *debugger.Debugger, in io.Reader, out io.Writer) {
+	sources := map[string][]string{}
+	source := func(file string, line int) string {
+		if _, ok := sources[file]; !ok {
+			data, _ := os.ReadFile(file)
+			sources[file] = strings.Split(string(data), "\n")
+		}
+		if line < 1 || line > len(sources[file]) {
+			return ""
+		}
+		return strings.TrimSpace(sources[file][line-1])
+	}
+
+	report := func(event debugger.Event) {
+		switch event.Reason {
+		case debugger.Exited:
+			fmt.Fprintln(out, "program exited")
+		case debugger.Failed:
+			fmt.Fprintf(out, "program failed: %s\n", event.Err)
+		default:
+			fmt.Fprintf(out, "%s at %s:%d\n  %d\t%s\n", event.Reason, event.File, event.Line, event.Line, source(event.File, event.Line))
+		}
+	}
+
+	showVariables := func(variables []debugger.Variable) {
+		for _, v := range variables {
+			fmt.Fprintf(out, "  %s = %s\n", v.Name, debugger.Describe(v.Value))
+		}
+	}
+
+	event := d.Start(true)
+	report(event)
+	frame := 0
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "(zdb) ")
+		if !scanner.Scan() {
+			d.Stop()
+			return
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, arg := fields[0], strings.Join(fields[1:], " ")
+
+		running := event.Running()
+		switch command {
+		case "help", "h":
+			fmt.Fprintln(out, debugHelp)
+
+		case "break", "b", "delete":
+			file, line, ok := parseLocation(arg)
+			if !ok {
+				fmt.Fprintln(out, "expected [file:]line")
+				continue
+			}
+			if command == "delete" {
+				d.ClearBreakpoint(file, line)
+			} else if !d.SetBreakpoint(file, line) {
+				fmt.Fprintf(out, "no code on line %d, the breakpoint may never be hit\n", line)
+			}
+
+		case "continue", "c", "next", "n", "step", "s", "out", "o":
+			if !running {
+				fmt.Fprintln(out, "the program is not running")
+				continue
+			}
+			switch command {
+			case "continue", "c":
+				event = d.Continue()
+			case "next", "n":
+				event = d.StepOver()
+			case "step", "s":
+				event = d.StepIn()
+			default:
+				event = d.StepOut()
+			}
+			frame = 0
+			report(event)
+
+		case "backtrace", "bt":
+			for i, sf := range d.Stack() {
+				marker := " "
+				if i == frame {
+					marker = "*"
+				}
+				fmt.Fprintf(out, "%s %d %s at %s:%d\n", marker, i, sf.Function, sf.File, sf.Line)
+			}
+
+		case "frame", "f":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 || n >= len(d.Stack()) {
+				fmt.Fprintln(out, "no such frame")
+				continue
+			}
+			frame = n
+
+		case "locals":
+			showVariables(d.Locals(frame))
+			showVariables(d.Free(frame))
+
+		case "globals":
+			showVariables(d.Globals())
+
+		case "print", "p":
+			value, ok := d.Lookup(arg, frame)
+			if !ok {
+				fmt.Fprintf(out, "%s is not defined here\n", arg)
+				continue
+			}
+			fmt.Fprintln(out, debugger.Describe(value))
+
+		case "quit", "q":
+			d.Stop()
+			return
+
+		default:
+			fmt.Fprintf(out, "unknown command %s, try help\n", command)
+		}
+	}
+}
+
+func parseLocation(arg string) (string, int, bool) {
+	file := ""
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, arg = arg[:i], arg[i+1:]
+	}
+
+	line, err := strconv.Atoi(arg)
+	return file, line, err == nil && line > 0
+}
@@ -0,0 +1,417 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The Debug Adapter Protocol messages the adapter speaks. There is a single
+// thread, and variable references encode the scope: globals use 1, and a
+// frame's locals and free variables use frameScopes plus twice its index.
+
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    bool            `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Body       interface{}     `json:"body,omitempty"`
+}
+
+const (
+	threadID        = 1
+	globalsScope    = 1
+	frameScopes     = 1000
+	maxVariableSize = 200
+)
+
+type source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"`
+}
+
+type Adapter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	mu      sync.Mutex
+	seq     int
+	started bool
+	running bool
+
+	debugger    *Debugger
+	stopOnEntry bool
+
+	stdout *os.File
+	output chan struct{}
+}
+
+func NewAdapter(in io.Reader, out io.Writer) *Adapter {
+	return &Adapter{in: bufio.NewReader(in), out: out}
+}
+
+// Serve handles requests until the client disconnects or closes the input.
+func (a *Adapter) Serve() error {
+	for {
+		req, err := a.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !a.handle(req) {
+			return nil
+		}
+	}
+}
+
+func (a *Adapter) read() (*message, error) {
+	length := -1
+	for {
+		line, err := a.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(a.in, body); err != nil {
+		return nil, err
+	}
+
+	var req message
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (a *Adapter) send(msg message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seq++
+	msg.Seq = a.seq
+	body, _ := json.Marshal(msg)
+	fmt.Fprintf(a.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (a *Adapter) respond(req *message, body interface{}) {
+	a.send(message{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: true, Body: body})
+}
+
+func (a *Adapter) fail(req *message, format string, args ...interface{}) {
+	a.send(message{Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: fmt.Sprintf(format, args...)})
+}
+
+func (a *Adapter) event(name string, body interface{}) {
+	a.send(message{Type: "event", Event: name, Body: body})
+}
+
+// handle answers a request and returns false once the client disconnects.
+func (a *Adapter) handle(req *message) bool {
+	if req.Type != "request" {
+		return true
+	}
+
+	switch req.Command {
+	case "initialize":
+		a.respond(req, map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		})
+
+	case "launch":
+		var args struct {
+			Program     string `json:"program"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil || args.Program == "" {
+			a.fail(req, "launch needs a program")
+			return true
+		}
+
+		d, err := New(args.Program)
+		if err != nil {
+			a.fail(req, "%s", err)
+			return true
+		}
+		a.debugger = d
+		a.stopOnEntry = args.StopOnEntry
+		a.respond(req, nil)
+		a.event("initialized", nil)
+
+	case "disconnect":
+		if a.debugger != nil && a.paused() {
+			a.debugger.Stop()
+			a.finish()
+		}
+		a.respond(req, nil)
+		return false
+
+	default:
+		if a.debugger == nil {
+			a.fail(req, "no program launched")
+			return true
+		}
+		a.handleLaunched(req)
+	}
+
+	return true
+}
+
+func (a *Adapter) handleLaunched(req *message) {
+	d := a.debugger
+
+	switch req.Command {
+	case "setBreakpoints":
+		var args struct {
+			Source      source `json:"source"`
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			a.fail(req, "%s", err)
+			return
+		}
+
+		d.ClearBreakpoints(args.Source.Path)
+		breakpoints := []map[string]interface{}{}
+		for _, bp := range args.Breakpoints {
+			verified := d.SetBreakpoint(args.Source.Path, bp.Line)
+			breakpoints = append(breakpoints, map[string]interface{}{"verified": verified, "line": bp.Line})
+		}
+		a.respond(req, map[string]interface{}{"breakpoints": breakpoints})
+
+	case "configurationDone":
+		a.respond(req, nil)
+		a.captureOutput()
+		a.mu.Lock()
+		a.started = true
+		a.mu.Unlock()
+		a.resume(func() Event { return d.Start(a.stopOnEntry) })
+
+	case "threads":
+		a.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "main"}},
+		})
+
+	case "continue", "next", "stepIn", "stepOut":
+		if !a.paused() {
+			a.fail(req, "the program is not paused")
+			return
+		}
+
+		step := map[string]func() Event{
+			"continue": d.Continue,
+			"next":     d.StepOver,
+			"stepIn":   d.StepIn,
+			"stepOut":  d.StepOut,
+		}[req.Command]
+		if req.Command == "continue" {
+			a.respond(req, map[string]interface{}{"allThreadsContinued": true})
+		} else {
+			a.respond(req, nil)
+		}
+		a.resume(step)
+
+	case "stackTrace":
+		if !a.paused() {
+			a.fail(req, "the program is not paused")
+			return
+		}
+
+		frames := []map[string]interface{}{}
+		for i, sf := range d.Stack() {
+			frames = append(frames, map[string]interface{}{
+				"id":     i,
+				"name":   sf.Function,
+				"source": source{Name: filepath.Base(sf.File), Path: sf.File},
+				"line":   sf.Line,
+				"column": 1,
+			})
+		}
+		a.respond(req, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+
+	case "scopes":
+		var args struct {
+			FrameID int `json:"frameId"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+
+		a.respond(req, map[string]interface{}{"scopes": []map[string]interface{}{
+			{"name": "Locals", "variablesReference": frameScopes + 2*args.FrameID, "expensive": false},
+			{"name": "Closure", "variablesReference": frameScopes + 2*args.FrameID + 1, "expensive": false},
+			{"name": "Globals", "variablesReference": globalsScope, "expensive": false},
+		}})
+
+	case "variables":
+		if !a.paused() {
+			a.fail(req, "the program is not paused")
+			return
+		}
+
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+
+		var variables []Variable
+		switch ref := args.VariablesReference; {
+		case ref == globalsScope:
+			variables = d.Globals()
+		case ref >= frameScopes && (ref-frameScopes)%2 == 0:
+			variables = d.Locals((ref - frameScopes) / 2)
+		case ref >= frameScopes:
+			variables = d.Free((ref - frameScopes) / 2)
+		}
+
+		result := []map[string]interface{}{}
+		for _, v := range variables {
+			result = append(result, map[string]interface{}{
+				"name":               v.Name,
+				"value":              truncate(Describe(v.Value)),
+				"type":               string(v.Value.Type()),
+				"variablesReference": 0,
+			})
+		}
+		a.respond(req, map[string]interface{}{"variables": result})
+
+	case "evaluate":
+		var args struct {
+			Expression string `json:"expression"`
+			FrameID    int    `json:"frameId"`
+		}
+		json.Unmarshal(req.Arguments, &args)
+
+		if !a.paused() {
+			a.fail(req, "the program is not paused")
+			return
+		}
+		value, ok := d.Lookup(strings.TrimSpace(args.Expression), args.FrameID)
+		if !ok {
+			a.fail(req, "%s is not defined here", args.Expression)
+			return
+		}
+		a.respond(req, map[string]interface{}{"result": truncate(Describe(value)), "variablesReference": 0})
+
+	default:
+		a.fail(req, "unsupported request %s", req.Command)
+	}
+}
+
+func (a *Adapter) paused() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.started && !a.running && !a.debugger.stopped
+}
+
+// resume runs the program in the background and reports where it stops.
+func (a *Adapter) resume(run func() Event) {
+	a.mu.Lock()
+	a.running = true
+	a.mu.Unlock()
+
+	go func() {
+		event := run()
+
+		a.mu.Lock()
+		a.running = false
+		a.mu.Unlock()
+
+		switch event.Reason {
+		case Exited, Failed:
+			a.finish()
+			if event.Err != nil {
+				a.event("output", map[string]interface{}{"category": "stderr", "output": event.Err.Error() + "\n"})
+			}
+			exitCode := 0
+			if event.Err != nil {
+				exitCode = 1
+			}
+			a.event("exited", map[string]interface{}{"exitCode": exitCode})
+			a.event("terminated", nil)
+		default:
+			a.event("stopped", map[string]interface{}{
+				"reason":            string(event.Reason),
+				"threadId":          threadID,
+				"allThreadsStopped": true,
+			})
+		}
+	}()
+}
+
+// captureOutput sends what the program prints as output events, since
+// stdout carries the protocol.
+func (a *Adapter) captureOutput() {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return
+	}
+
+	a.stdout = os.Stdout
+	a.output = make(chan struct{})
+	os.Stdout = w
+
+	go func() {
+		defer close(a.output)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				a.event("output", map[string]interface{}{"category": "stdout", "output": line})
+			}
+			if err != nil {
+				r.Close()
+				return
+			}
+		}
+	}()
+}
+
+// finish restores stdout once the program ended and waits for the rest of
+// its output.
+func (a *Adapter) finish() {
+	if a.stdout == nil {
+		return
+	}
+
+	w := os.Stdout
+	os.Stdout = a.stdout
+	a.stdout = nil
+	w.Close()
+	<-a.output
+}
+
+func truncate(s string) string {
+	if len(s) > maxVariableSize {
+		return s[:maxVariableSize] + "..."
+	}
+	return s
+}
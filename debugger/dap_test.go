@@ -0,0 +1,221 @@
+package debugger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type client struct {
+	t      *testing.T
+	writer *io.PipeWriter
+	reader *bufio.Reader
+	seq    int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+
+	c := &client{t: t, writer: inWriter, reader: bufio.NewReader(outReader), done: make(chan error, 1)}
+	go func() {
+		err := NewAdapter(inReader, outWriter).Serve()
+		outWriter.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) request(command string, arguments interface{}) int {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return c.seq
+}
+
+type received struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func (c *client) next() received {
+	c.t.Helper()
+	header, err := c.reader.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	length, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+	c.reader.ReadString('\n')
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg received
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// call sends a request and decodes its response body, keeping the events
+// that arrive meanwhile.
+func (c *client) call(command string, arguments interface{}, body interface{}, events *[]received) {
+	c.t.Helper()
+	seq := c.request(command, arguments)
+	for {
+		msg := c.next()
+		if msg.Type == "event" {
+			*events = append(*events, msg)
+			continue
+		}
+		if msg.RequestSeq != seq {
+			c.t.Fatalf("unexpected response %+v", msg)
+		}
+		if !msg.Success {
+			c.t.Fatalf("%s failed: %s", command, msg.Message)
+		}
+		if body != nil {
+			json.Unmarshal(msg.Body, body)
+		}
+		return
+	}
+}
+
+// waitFor returns the events up to and including the named one.
+func (c *client) waitFor(name string, events []received) []received {
+	c.t.Helper()
+	for _, e := range events {
+		if e.Event == name {
+			return events
+		}
+	}
+	for {
+		msg := c.next()
+		events = append(events, msg)
+		if msg.Event == name {
+			return events
+		}
+	}
+}
+
+func TestAdapterSession(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.zum")
+	src := "var double << fct(n) {\n    var result << n * 2;\n    result\n};\nshow(double(21));\n"
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t)
+	events := []received{}
+
+	c.call("initialize", map[string]interface{}{"adapterID": "zumbra"}, nil, &events)
+	c.call("launch", map[string]interface{}{"program": file}, nil, &events)
+	events = c.waitFor("initialized", events)
+
+	var breakpoints struct {
+		Breakpoints []struct {
+			Verified bool `json:"verified"`
+			Line     int  `json:"line"`
+		} `json:"breakpoints"`
+	}
+	c.call("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": file},
+		"breakpoints": []map[string]int{{"line": 3}, {"line": 4}},
+	}, &breakpoints, &events)
+	if len(breakpoints.Breakpoints) != 2 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[1].Verified {
+		t.Errorf("wrong breakpoints: %+v", breakpoints)
+	}
+
+	c.call("configurationDone", nil, nil, &events)
+	events = c.waitFor("stopped", nil)
+
+	var stack struct {
+		StackFrames []struct {
+			Name string `json:"name"`
+			Line int    `json:"line"`
+		} `json:"stackFrames"`
+	}
+	c.call("stackTrace", map[string]int{"threadId": 1}, &stack, &events)
+	if len(stack.StackFrames) != 2 || stack.StackFrames[0].Name != "double" || stack.StackFrames[0].Line != 3 || stack.StackFrames[1].Line != 5 {
+		t.Errorf("wrong stack: %+v", stack)
+	}
+
+	var scopes struct {
+		Scopes []struct {
+			Name      string `json:"name"`
+			Reference int    `json:"variablesReference"`
+		} `json:"scopes"`
+	}
+	c.call("scopes", map[string]int{"frameId": 0}, &scopes, &events)
+
+	var variables struct {
+		Variables []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+	c.call("variables", map[string]int{"variablesReference": scopes.Scopes[0].Reference}, &variables, &events)
+	got := []string{}
+	for _, v := range variables.Variables {
+		got = append(got, v.Name+"="+v.Value)
+	}
+	if strings.Join(got, " ") != "n=21 result=42" {
+		t.Errorf("wrong locals: %q", got)
+	}
+
+	var evaluated struct {
+		Result string `json:"result"`
+	}
+	c.call("evaluate", map[string]interface{}{"expression": "double", "frameId": 1}, &evaluated, &events)
+	if evaluated.Result != "fct double(n)" {
+		t.Errorf("wrong evaluation: %q", evaluated.Result)
+	}
+
+	c.call("continue", map[string]int{"threadId": 1}, nil, &events)
+	events = c.waitFor("terminated", events)
+
+	output := ""
+	for _, e := range events {
+		if e.Event == "output" {
+			var body struct {
+				Output string `json:"output"`
+			}
+			json.Unmarshal(e.Body, &body)
+			output += body.Output
+		}
+	}
+	if output != "42\n" {
+		t.Errorf("wrong program output: %q", output)
+	}
+
+	c.call("disconnect", nil, nil, &events)
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdapterLaunchError(t *testing.T) {
+	c := newClient(t)
+	c.request("launch", map[string]string{"program": "missing.zum"})
+
+	msg := c.next()
+	if msg.Type != "response" || msg.Success {
+		t.Errorf("expected a failed launch, got %+v", msg)
+	}
+	c.writer.Close()
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
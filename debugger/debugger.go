@@ -0,0 +1,431 @@
+// Package debugger runs a Zumbra program on the VM and pauses it at line
+// breakpoints and steps. The program runs in a goroutine of its own; a VM
+// hook blocks it at the first instruction of every source line where it
+// should stop, until the controller resumes it.
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"zumbra/code"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/vm"
+)
+
+type Reason string
+
+const (
+	Entry      Reason = "entry"
+	Breakpoint Reason = "breakpoint"
+	Step       Reason = "step"
+	Exited     Reason = "exited"
+	Failed     Reason = "error"
+)
+
+// Event tells why the program stopped. Err is set when it failed.
+type Event struct {
+	Reason Reason
+	File   string
+	Line   int
+	Err    error
+}
+
+func (e Event) Running() bool {
+	return e.Reason != Exited && e.Reason != Failed
+}
+
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+
+	machine *vm.VM
+	frame   *vm.Frame
+}
+
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+type stepMode int
+
+const (
+	stepContinue stepMode = iota
+	stepOver
+	stepIn
+	stepOut
+)
+
+var errStopped = errors.New("debugger stopped the program")
+
+type Debugger struct {
+	File string
+
+	bytecode    *compiler.Bytecode
+	globalNames []string
+	globals     []object.Object
+
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool
+
+	events chan Event
+	resume chan stepMode
+	abort  chan struct{}
+
+	// Set while the program is paused.
+	paused  *vm.VM
+	stopped bool
+
+	// The step in progress and where it started.
+	mode       stepMode
+	startDepth int
+}
+
+// New compiles a program for debugging.
+func New(filename string) (*Debugger, error) {
+	file, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parsing errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range builtins.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	comp := compiler.NewWithStateAndDir(symbolTable, []object.Object{}, filepath.Dir(file))
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("compilation error: %s", err)
+	}
+
+	return &Debugger{
+		File:        file,
+		bytecode:    comp.Bytecode(),
+		globalNames: symbolTable.Names(),
+		globals:     make([]object.Object, vm.GlobalSize),
+		breakpoints: map[string]map[int]bool{},
+		events:      make(chan Event),
+		resume:      make(chan stepMode),
+		abort:       make(chan struct{}),
+	}, nil
+}
+
+// SetBreakpoint adds a breakpoint and reports whether code starts on that
+// line. Relative files are resolved against the program's directory.
+func (d *Debugger) SetBreakpoint(file string, line int) bool {
+	file = d.resolve(file)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.breakpoints[file] == nil {
+		d.breakpoints[file] = map[int]bool{}
+	}
+	d.breakpoints[file][line] = true
+
+	return d.hasCode(file, line)
+}
+
+func (d *Debugger) ClearBreakpoint(file string, line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints[d.resolve(file)], line)
+}
+
+func (d *Debugger) ClearBreakpoints(file string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, d.resolve(file))
+}
+
+func (d *Debugger) resolve(file string) string {
+	if file == "" {
+		return d.File
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(d.File), file)
+	}
+	return filepath.Clean(file)
+}
+
+func (d *Debugger) hasCode(file string, line int) bool {
+	tables := []code.LineTable{d.bytecode.Lines}
+	for _, c := range d.bytecode.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			tables = append(tables, fn.Lines)
+		}
+	}
+
+	for _, table := range tables {
+		for _, entry := range table {
+			if d.resolve(entry.File) == file && entry.Line == line {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Start runs the program until it first stops. With stopOnEntry it stops
+// at the first line.
+func (d *Debugger) Start(stopOnEntry bool) Event {
+	if stopOnEntry {
+		d.mode = stepIn
+	}
+
+	machine := vm.NewWithGlobalsStore(d.bytecode, d.globals)
+	machine.SetHook(d.hook)
+	d.globals = machine.Globals()
+
+	go func() {
+		err := machine.Run()
+		switch {
+		case err == errStopped:
+			close(d.events)
+		case err != nil:
+			d.events <- Event{Reason: Failed, Err: err}
+		default:
+			d.events <- Event{Reason: Exited}
+		}
+	}()
+
+	return d.wait()
+}
+
+func (d *Debugger) Continue() Event { return d.step(stepContinue) }
+func (d *Debugger) StepOver() Event { return d.step(stepOver) }
+func (d *Debugger) StepIn() Event   { return d.step(stepIn) }
+func (d *Debugger) StepOut() Event  { return d.step(stepOut) }
+
+func (d *Debugger) step(mode stepMode) Event {
+	if d.paused == nil {
+		return Event{Reason: Exited}
+	}
+	d.paused = nil
+	d.resume <- mode
+	return d.wait()
+}
+
+func (d *Debugger) wait() Event {
+	event, ok := <-d.events
+	if !ok || !event.Running() {
+		d.stopped = true
+	}
+	if !ok {
+		return Event{Reason: Exited}
+	}
+	return event
+}
+
+// Stop ends a paused program.
+func (d *Debugger) Stop() {
+	if d.paused == nil || d.stopped {
+		return
+	}
+	d.paused = nil
+	d.stopped = true
+	close(d.abort)
+	<-d.events
+}
+
+func (d *Debugger) hook(machine *vm.VM) error {
+	frames := machine.Frames()
+	frame := frames[len(frames)-1]
+
+	entry, ok := frame.Closure().Fn.Lines.StartsAt(frame.IP())
+	if !ok {
+		return nil
+	}
+
+	file := d.resolve(entry.File)
+	depth := depth(machine)
+
+	d.mu.Lock()
+	breakpoint := d.breakpoints[file][entry.Line]
+	d.mu.Unlock()
+
+	var reason Reason
+	switch {
+	case breakpoint:
+		reason = Breakpoint
+	case d.mode == stepIn:
+		reason = Step
+	case d.mode == stepOver && depth <= d.startDepth:
+		reason = Step
+	case d.mode == stepOut && depth < d.startDepth:
+		reason = Step
+	default:
+		return nil
+	}
+	if reason == Step && d.startDepth == 0 {
+		reason = Entry
+	}
+
+	d.paused = machine
+	d.events <- Event{Reason: reason, File: file, Line: entry.Line}
+
+	select {
+	case mode := <-d.resume:
+		d.mode = mode
+		d.startDepth = depth
+		return nil
+	case <-d.abort:
+		return errStopped
+	}
+}
+
+// depth counts the frames of machine and of the VMs that called it. The
+// empty frame a called VM starts with is not counted.
+func depth(machine *vm.VM) int {
+	n := 0
+	for m := machine; m != nil; m = m.Caller() {
+		for _, f := range m.Frames() {
+			if len(f.Instructions()) > 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Stack returns the frames of the paused program, innermost first.
+func (d *Debugger) Stack() []StackFrame {
+	stack := []StackFrame{}
+
+	for m := d.paused; m != nil; m = m.Caller() {
+		frames := m.Frames()
+		for i := len(frames) - 1; i >= 0; i-- {
+			fn := frames[i].Closure().Fn
+			if len(fn.Instructions) == 0 {
+				continue
+			}
+
+			sf := StackFrame{Function: fn.Name, machine: m, frame: frames[i]}
+			if sf.Function == "" {
+				sf.Function = "<anonymous>"
+			}
+			if m.Caller() == nil && i == 0 {
+				sf.Function = "<main>"
+			}
+			if entry, ok := fn.Lines.Lookup(frames[i].IP()); ok {
+				sf.File = d.resolve(entry.File)
+				sf.Line = entry.Line
+			}
+			stack = append(stack, sf)
+		}
+	}
+
+	return stack
+}
+
+func (d *Debugger) frame(index int) (StackFrame, bool) {
+	stack := d.Stack()
+	if index < 0 || index >= len(stack) {
+		return StackFrame{}, false
+	}
+	return stack[index], true
+}
+
+// Locals returns the variables of a frame that have been assigned. The main
+// program has none: its variables are globals.
+func (d *Debugger) Locals(frame int) []Variable {
+	sf, ok := d.frame(frame)
+	if !ok || sf.Function == "<main>" {
+		return nil
+	}
+
+	values := sf.machine.Locals(sf.frame)
+	return named(sf.frame.Closure().Fn.LocalNames, values)
+}
+
+// Free returns the variables a frame's closure captured.
+func (d *Debugger) Free(frame int) []Variable {
+	sf, ok := d.frame(frame)
+	if !ok {
+		return nil
+	}
+
+	cl := sf.frame.Closure()
+	return named(cl.Fn.FreeNames, cl.Free)
+}
+
+func (d *Debugger) Globals() []Variable {
+	return named(d.globalNames, d.globals)
+}
+
+// named pairs names with values. When a name was defined twice only the
+// latest definition is kept.
+func named(names []string, values []object.Object) []Variable {
+	variables := []Variable{}
+	index := map[string]int{}
+
+	for i, name := range names {
+		if i >= len(values) || values[i] == nil {
+			continue
+		}
+		if j, ok := index[name]; ok {
+			variables[j].Value = values[i]
+			continue
+		}
+		index[name] = len(variables)
+		variables = append(variables, Variable{Name: name, Value: values[i]})
+	}
+
+	return variables
+}
+
+// Lookup finds a name the way code in the frame would: locals first, then
+// free variables and globals.
+func (d *Debugger) Lookup(name string, frame int) (object.Object, bool) {
+	sf, ok := d.frame(frame)
+	if !ok {
+		return nil, false
+	}
+
+	for _, scope := range [][]Variable{d.Locals(frame), d.Free(frame), d.Globals()} {
+		for _, v := range scope {
+			if v.Name == name {
+				return v.Value, true
+			}
+		}
+	}
+
+	if sf.frame.Closure().Fn.Name == name {
+		return sf.frame.Closure(), true
+	}
+	return nil, false
+}
+
+// Describe shows a value the way the debugger prints it. Functions show
+// their name and parameters instead of an address.
+func Describe(obj object.Object) string {
+	cl, ok := obj.(*object.Closure)
+	if !ok {
+		return obj.Inspect()
+	}
+
+	params := cl.Fn.LocalNames
+	if len(params) > cl.Fn.NumParameters {
+		params = params[:cl.Fn.NumParameters]
+	}
+	return "fct " + cl.Fn.Name + "(" + strings.Join(params, ", ") + ")"
+}
@@ -0,0 +1,130 @@
+package debugger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newDebugger(t *testing.T, src string) *Debugger {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "main.zum")
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := New(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func expectStop(t *testing.T, event Event, reason Reason, line int) {
+	t.Helper()
+	if event.Reason != reason || event.Line != line {
+		t.Fatalf("wrong stop. want=%s at %d, got=%s at %d (%v)", reason, line, event.Reason, event.Line, event.Err)
+	}
+}
+
+func expectValue(t *testing.T, d *Debugger, name string, frame int, expected string) {
+	t.Helper()
+	value, ok := d.Lookup(name, frame)
+	if !ok {
+		t.Fatalf("%s not found", name)
+	}
+	if Describe(value) != expected {
+		t.Errorf("wrong value of %s. want=%s, got=%s", name, expected, Describe(value))
+	}
+}
+
+const program = `var add << fct(a, b) {
+    var sum << a + b;
+    sum
+};
+var x << 1;
+var y << add(x, 2);
+var z << add(y, 3);
+`
+
+func TestStepping(t *testing.T) {
+	d := newDebugger(t, program)
+
+	expectStop(t, d.Start(true), Entry, 1)
+	expectStop(t, d.StepOver(), Step, 5)
+	expectStop(t, d.StepOver(), Step, 6)
+	expectStop(t, d.StepIn(), Step, 2)
+
+	stack := d.Stack()
+	if len(stack) != 2 || stack[0].Function != "add" || stack[0].Line != 2 || stack[1].Function != "<main>" || stack[1].Line != 6 {
+		t.Fatalf("wrong stack: %+v", stack)
+	}
+	if locals := d.Locals(0); len(locals) != 2 {
+		t.Errorf("expected the parameters only, got %+v", locals)
+	}
+
+	expectStop(t, d.StepOver(), Step, 3)
+	expectValue(t, d, "sum", 0, "3")
+	expectValue(t, d, "x", 0, "1")
+	expectValue(t, d, "add", 1, "fct add(a, b)")
+
+	expectStop(t, d.StepOut(), Step, 7)
+	expectValue(t, d, "y", 0, "3")
+	expectStop(t, d.StepOver(), Exited, 0)
+}
+
+func TestBreakpoints(t *testing.T) {
+	d := newDebugger(t, program)
+
+	if !d.SetBreakpoint("main.zum", 3) {
+		t.Errorf("breakpoint on line 3 not verified")
+	}
+	if d.SetBreakpoint("", 4) {
+		t.Errorf("breakpoint on a line without code verified")
+	}
+
+	expectStop(t, d.Start(false), Breakpoint, 3)
+	expectValue(t, d, "a", 0, "1")
+	expectStop(t, d.Continue(), Breakpoint, 3)
+	expectValue(t, d, "a", 0, "3")
+
+	d.ClearBreakpoint("main.zum", 3)
+	expectStop(t, d.Continue(), Exited, 0)
+}
+
+func TestFreeVariables(t *testing.T) {
+	d := newDebugger(t, `var counter << fct(start) {
+    fct(step) {
+        start + step
+    }
+};
+var next << counter(10);
+next(1);
+`)
+	d.SetBreakpoint("", 3)
+
+	expectStop(t, d.Start(false), Breakpoint, 3)
+	free := d.Free(0)
+	if len(free) != 1 || free[0].Name != "start" || free[0].Value.Inspect() != "10" {
+		t.Errorf("wrong free variables: %+v", free)
+	}
+	expectValue(t, d, "step", 0, "1")
+	expectValue(t, d, "next", 0, "fct (step)")
+}
+
+func TestRuntimeError(t *testing.T) {
+	d := newDebugger(t, "var x << 1;\nx();\n")
+
+	event := d.Start(false)
+	if event.Reason != Failed || event.Err == nil {
+		t.Fatalf("expected an error, got %+v", event)
+	}
+}
+
+func TestStop(t *testing.T) {
+	d := newDebugger(t, program)
+
+	expectStop(t, d.Start(true), Entry, 1)
+	d.Stop()
+	expectStop(t, d.Continue(), Exited, 0)
+}
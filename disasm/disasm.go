@@ -0,0 +1,265 @@
+// Package disasm prints compiled bytecode for people: the main program and
+// every compiled function in the constant pool, with jump targets as
+// labels, the source line each group of instructions came from and the
+// names behind constant, variable and builtin operands.
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"zumbra/code"
+	"zumbra/compiler"
+	"zumbra/object"
+	"zumbra/object/builtins"
+)
+
+// comparisons are the operators of the comparison opcodes, for
+// OpLocalCompareJump.
+var comparisons = map[code.Opcode]string{
+	code.OpEqual:              "==",
+	code.OpNotEqual:           "!=",
+	code.OpGreaterThan:        ">",
+	code.OpLessThan:           "<",
+	code.OpGreaterThanOrEqual: ">=",
+	code.OpLessThanOrEqual:    "<=",
+}
+
+type printer struct {
+	w        *bufio.Writer
+	file     string
+	bytecode *compiler.Bytecode
+	globals  []string
+	sources  map[string][]string
+}
+
+// Write disassembles bytecode compiled from file. globals are the names of
+// the global variables by index, as the compiler's symbol table holds them;
+// they may be nil.
+func Write(w io.Writer, bytecode *compiler.Bytecode, file string, globals []string) error {
+	return write(w, bytecode, file, globals, map[string][]string{})
+}
+
+// WriteSource is like Write for code that was not read from a file: source
+// is what was compiled and name is shown in its place.
+func WriteSource(w io.Writer, bytecode *compiler.Bytecode, name, source string, globals []string) error {
+	return write(w, bytecode, name, globals, map[string][]string{name: strings.Split(source, "\n")})
+}
+
+func write(w io.Writer, bytecode *compiler.Bytecode, file string, globals []string, sources map[string][]string) error {
+	p := &printer{
+		w:        bufio.NewWriter(w),
+		file:     file,
+		bytecode: bytecode,
+		globals:  globals,
+		sources:  sources,
+	}
+
+	main := &object.CompiledFunction{Instructions: bytecode.Instructions, Lines: bytecode.Lines}
+	p.function("<main>", -1, main)
+
+	if len(bytecode.Constants) > 0 {
+		fmt.Fprintln(p.w, "\nconstants:")
+		for i, c := range bytecode.Constants {
+			fmt.Fprintf(p.w, "  %4d  %-21s %s\n", i, c.Type(), describe(c))
+		}
+	}
+
+	for i, c := range bytecode.Constants {
+		if fn, ok := c.(*object.CompiledFunction); ok {
+			fmt.Fprintln(p.w)
+			p.function(describe(fn), i, fn)
+		}
+	}
+
+	return p.w.Flush()
+}
+
+func (p *printer) function(title string, constant int, fn *object.CompiledFunction) {
+	if constant >= 0 {
+		title += fmt.Sprintf("  (constant %d)", constant)
+	}
+	fmt.Fprintln(p.w, title)
+
+	if constant >= 0 {
+		fmt.Fprintf(p.w, "  locals: %s\n", list(fn.LocalNames))
+		fmt.Fprintf(p.w, "  free:   %s\n", list(fn.FreeNames))
+		if fn.Generator {
+			fmt.Fprintln(p.w, "  generator")
+		}
+	}
+
+	labels := labels(fn.Instructions)
+	ins := fn.Instructions
+
+	for i := 0; i < len(ins); {
+		if label, ok := labels[i]; ok {
+			fmt.Fprintf(p.w, "%s:\n", label)
+		}
+		if entry, ok := fn.Lines.StartsAt(i); ok {
+			file := entry.File
+			if file == "" {
+				file = p.file
+			}
+			fmt.Fprintf(p.w, "  ; %s:%d  %s\n", filepath.Base(file), entry.Line, p.source(file, entry.Line))
+		}
+
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(p.w, "  %04d  ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		op := code.Opcode(ins[i])
+
+		target, jumps := code.JumpOperand(op)
+		text := def.Name
+		for j, operand := range operands {
+			if jumps && j == target {
+				text += " " + labels[operand]
+			} else {
+				text += fmt.Sprintf(" %d", operand)
+			}
+		}
+
+		if comment := p.comment(op, operands, fn); comment != "" {
+			fmt.Fprintf(p.w, "  %04d  %-24s ; %s\n", i, text, comment)
+		} else {
+			fmt.Fprintf(p.w, "  %04d  %s\n", i, text)
+		}
+
+		i += 1 + read
+	}
+
+	// Loops jump past the last instruction when they finish.
+	if label, ok := labels[len(ins)]; ok {
+		fmt.Fprintf(p.w, "%s:\n  %04d  end\n", label, len(ins))
+	}
+}
+
+// labels names the jump targets L1, L2... in the order they appear.
+func labels(ins code.Instructions) map[int]string {
+	targets := []int{}
+	seen := map[int]bool{}
+
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			i++
+			continue
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		if j, ok := code.JumpOperand(code.Opcode(ins[i])); ok && !seen[operands[j]] {
+			seen[operands[j]] = true
+			targets = append(targets, operands[j])
+		}
+		i += 1 + read
+	}
+
+	sort.Ints(targets)
+	labels := map[int]string{}
+	for i, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", i+1)
+	}
+	return labels
+}
+
+func (p *printer) comment(op code.Opcode, operands []int, fn *object.CompiledFunction) string {
+	switch op {
+	case code.OpConstant, code.OpConstantWide:
+		return p.constant(operands[0])
+	case code.OpClosure, code.OpClosureWide:
+		comment := p.constant(operands[0])
+		if operands[1] > 0 {
+			comment += fmt.Sprintf(", %d free", operands[1])
+		}
+		return comment
+	case code.OpGetGlobal, code.OpSetGlobal, code.OpGetGlobalWide, code.OpSetGlobalWide:
+		return name(p.globals, operands[0])
+	case code.OpGetLocal, code.OpSetLocal, code.OpGetLocalWide, code.OpSetLocalWide:
+		return name(fn.LocalNames, operands[0])
+	case code.OpGetFree, code.OpGetFreeWide:
+		return name(fn.FreeNames, operands[0])
+	case code.OpGetBuiltin:
+		if operands[0] < len(builtins.Builtins) {
+			return builtins.Builtins[operands[0]].Name
+		}
+	case code.OpCall:
+		if operands[0] == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", operands[0])
+	case code.OpCall0, code.OpCall1, code.OpCall2, code.OpCall3:
+		if op == code.OpCall1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", op-code.OpCall0)
+	case code.OpAddImmediate:
+		return fmt.Sprintf("%+d", int16(operands[0]))
+	case code.OpLocalCompareJump:
+		return fmt.Sprintf("unless %s %s %s, to %04d", name(fn.LocalNames, operands[0]),
+			comparisons[code.Opcode(operands[2])], p.constant(operands[1]), operands[3])
+	case code.OpJump, code.OpJumpNotTruthy, code.OpIterNext, code.OpJumpWide, code.OpJumpNotTruthyWide, code.OpIterNextWide:
+		return fmt.Sprintf("to %04d", operands[0])
+	}
+	return ""
+}
+
+func (p *printer) constant(index int) string {
+	if index >= len(p.bytecode.Constants) {
+		return "missing constant"
+	}
+	return describe(p.bytecode.Constants[index])
+}
+
+func (p *printer) source(file string, line int) string {
+	lines, ok := p.sources[file]
+	if !ok {
+		data, _ := os.ReadFile(file)
+		lines = strings.Split(string(data), "\n")
+		p.sources[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
+
+func describe(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return fmt.Sprintf("%q", obj.Value)
+	case *object.CompiledFunction:
+		params := obj.LocalNames
+		if len(params) > obj.NumParameters {
+			params = params[:obj.NumParameters]
+		}
+		name := obj.Name
+		if name == "" {
+			name = "<anonymous>"
+		}
+		return "fct " + name + "(" + strings.Join(params, ", ") + ")"
+	default:
+		return obj.Inspect()
+	}
+}
+
+func name(names []string, index int) string {
+	if index < len(names) {
+		return names[index]
+	}
+	return ""
+}
+
+func list(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,130 @@
+package disasm
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+)
+
+func disassemble(t *testing.T, src string) string {
+	t.Helper()
+	return disassembleAt(t, src, compiler.O0)
+}
+
+func disassembleAt(t *testing.T, src string, level int) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "main.zum")
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, b := range builtins.Builtins {
+		symbolTable.DefineBuiltin(i, b.Name)
+	}
+	comp := compiler.NewWithStateAndDir(symbolTable, []object.Object{}, filepath.Dir(file))
+	if err := comp.SetOptimization(level); err != nil {
+		t.Fatal(err)
+	}
+	if err := comp.Compile(parser.New(lexer.New(src)).ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, comp.Bytecode(), file, symbolTable.Names()); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestWrite(t *testing.T) {
+	output := disassemble(t, `var limit << 3;
+var counter << fct(step) {
+    fct(n) { n + step }
+};
+var i << 0;
+while (i < limit) { i << counter(1)(i); }`)
+
+	expected := `<main>
+  ; main.zum:1  var limit << 3;
+  0000  OpConstant 0             ; 3
+  0003  OpSetGlobal 0            ; limit
+  ; main.zum:2  var counter << fct(step) {
+  0006  OpClosure 2 0            ; fct counter(step)
+  0010  OpSetGlobal 1            ; counter
+  ; main.zum:5  var i << 0;
+  0013  OpConstant 3             ; 0
+  0016  OpSetGlobal 2            ; i
+L1:
+  ; main.zum:6  while (i < limit) { i << counter(1)(i); }
+  0019  OpGetGlobal 2            ; i
+  0022  OpGetGlobal 0            ; limit
+  0025  OpLessThan
+  0026  OpJumpNotTruthy L2       ; to 0048
+  0029  OpGetGlobal 1            ; counter
+  0032  OpConstant 4             ; 1
+  0035  OpCall 1                 ; 1 argument
+  0037  OpGetGlobal 2            ; i
+  0040  OpCall 1                 ; 1 argument
+  0042  OpSetGlobal 2            ; i
+  0045  OpJump L1                ; to 0019
+L2:
+  0048  end
+
+constants:
+     0  INTEGER               3
+     1  COMPILED_FUNCTION_OBJ fct <anonymous>(n)
+     2  COMPILED_FUNCTION_OBJ fct counter(step)
+     3  INTEGER               0
+     4  INTEGER               1
+
+fct <anonymous>(n)  (constant 1)
+  locals: n
+  free:   step
+  ; main.zum:3  fct(n) { n + step }
+  0000  OpGetLocal 0             ; n
+  0002  OpGetFree 0              ; step
+  0004  OpAdd
+  0005  OpReturnValue
+
+fct counter(step)  (constant 2)
+  locals: step
+  free:   none
+  ; main.zum:3  fct(n) { n + step }
+  0000  OpGetLocal 0             ; step
+  0002  OpClosure 1 1            ; fct <anonymous>(n), 1 free
+  0006  OpReturnValue
+`
+	if output != expected {
+		t.Errorf("wrong disassembly.\nwant:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestLoopEndLabel(t *testing.T) {
+	output := disassemble(t, "for (x in [1]) { show(x); }")
+
+	if !strings.HasSuffix(output, "  0021  OpJump L1                ; to 0007\nL2:\n  0024  end\n\nconstants:\n     0  INTEGER               1\n") {
+		t.Errorf("missing label after the loop:\n%s", output)
+	}
+}
+
+func TestSpecialisedInstructions(t *testing.T) {
+	output := disassembleAt(t, "fct(n) { if (n > 2) { n - 1 } else { 0 } }(3);", compiler.O2)
+
+	for _, line := range []string{
+		"0000  OpLocalCompareJump 0 0 11 L1 ; unless n > 2, to 0015",
+		"0009  OpAddImmediate 65535     ; -1",
+		"0007  OpCall1                  ; 1 argument",
+	} {
+		if !strings.Contains(output, line) {
+			t.Errorf("missing %q:\n%s", line, output)
+		}
+	}
+}
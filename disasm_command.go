@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"zumbra/compiler"
+	"zumbra/disasm"
+)
+
+// disasmCommand implements `zumbra disasm [-O level] file.zum`.
+func disasmCommand(args []string) bool {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	flags.IntVar(&optimization, "O", compiler.DefaultOptimization, "optimization level, from 0 to 2")
+	files := parseInterspersed(flags, args)
+
+	if len(files) != 1 {
+		fmt.Println("usage: zumbra disasm [-O level] file.zum")
+		return false
+	}
+
+	comp, symbolTable := compileFile(files[0])
+	if comp == nil {
+		return false
+	}
+
+	file, _ := filepath.Abs(files[0])
+	if err := disasm.Write(os.Stdout, comp.Bytecode(), file, symbolTable.Names()); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,16 @@
+package evaluator
+
+import (
+	"testing"
+
+	"zumbra/object"
+	"zumbra/object/builtins"
+)
+
+func TestBuiltinsMatchTheVM(t *testing.T) {
+	for _, b := range builtins.Builtins {
+		if _, ok := testEval(b.Name).(*object.Builtin); !ok {
+			t.Errorf("builtin %s is not defined", b.Name)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"zumbra/ast"
+	"zumbra/imports"
 	"zumbra/lexer"
 	"zumbra/object"
 	"zumbra/parser"
@@ -34,6 +36,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: value}
 
+	case *ast.YieldStatement:
+		return newError("yield is not supported by the evaluator")
+
 	case *ast.VarStatement:
 
 		if _, ok := env.Get(node.Name.Value); ok {
@@ -46,10 +51,22 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		env.Set(node.Name.Value, value)
 
+	case *ast.AssignStatement:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+		if !env.Assign(node.Name.Value, value) {
+			return newError("undefined variable %s", node.Name.Value)
+		}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
 
 	case *ast.Boolean:
@@ -143,6 +160,38 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ImportStatement:
 		return evalImportStatement(node, env)
+
+	case *ast.SetLiteral:
+		set := object.NewSet()
+		for _, el := range evalExpressions(node.Elements, env) {
+			if isError(el) {
+				return el
+			}
+			if err := set.Add(el); err != nil {
+				return newError("%s", err)
+			}
+		}
+		return set
+
+	case *ast.EnumStatement:
+		variants := []string{}
+		for _, v := range node.Variants {
+			variants = append(variants, v.Value)
+		}
+		env.Set(node.Name.Value, object.NewEnum(node.Name.Value, variants))
+
+	case *ast.AttributeAccess:
+		obj := Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalAttributeAccess(obj, node.Property.Value)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
 	}
 
 	return nil
@@ -215,18 +264,20 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
+	if right.Type() != object.INTEGER_OBJ && !object.IsExactNumber(right) {
 		return newError("unknown operator: -%s", right.Type())
 	}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+	negated, _ := object.Negate(right)
+	return negated
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case operator == "and" || operator == "or":
 		return evalLogicalInfixExpression(operator, left, right)
+	case object.IsExactNumber(left) || object.IsExactNumber(right):
+		return evalExactInfixExpression(operator, left, right)
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
@@ -282,15 +333,27 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if (operator == "/" || operator == "%") && rightVal == 0 {
+		return newError("division by zero")
+	}
+
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+	case "+", "-", "*", "/":
+		var result int64
+		switch operator {
+		case "+":
+			result = leftVal + rightVal
+		case "-":
+			result = leftVal - rightVal
+		case "*":
+			result = leftVal * rightVal
+		case "/":
+			result = leftVal / rightVal
+		}
+		if object.IntegerOverflows(operator, leftVal, rightVal, result) {
+			return evalExactInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -310,6 +373,41 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+func evalExactInfixExpression(operator string, left, right object.Object) object.Object {
+	switch operator {
+	case "+", "-", "*", "/", "%":
+		result, err := object.Arithmetic(operator, left, right)
+		if err != nil {
+			return newError("%s", err)
+		}
+		return result
+	}
+
+	cmp, ok := object.Compare(left, right)
+	if !ok {
+		if operator == "==" || operator == "!=" {
+			return nativeBoolToBooleanObject((operator == "==") == objectEquals(left, right))
+		}
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(cmp < 0)
+	case ">":
+		return nativeBoolToBooleanObject(cmp > 0)
+	case "==":
+		return nativeBoolToBooleanObject(cmp == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(cmp != 0)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(cmp >= 0)
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
 func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	leftVal := left.(*object.Float).Value
 	rightVal := right.(*object.Float).Value
@@ -467,12 +565,21 @@ func evalExpressions(
 func applyFunction(fct object.Object, args []object.Object) object.Object {
 	switch fct := fct.(type) {
 	case *object.Function:
+		if len(args) != len(fct.Parameters) {
+			return newError("wrong number of arguments: want=%d, got=%d", len(fct.Parameters), len(args))
+		}
 		extendedEnv := extendFunctionEnv(fct, args)
 		evaluated := Eval(fct.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		if result := fct.Fn(args...); result != nil {
+		var result object.Object
+		if fct.Callback != nil {
+			result = fct.Callback(callFunction, args...)
+		} else {
+			result = fct.Fn(args...)
+		}
+		if result != nil {
 			return result
 		}
 
@@ -484,6 +591,16 @@ func applyFunction(fct object.Object, args []object.Object) object.Object {
 
 }
 
+// callFunction lets builtins call functions; errors raised by the function
+// are returned as error.
+func callFunction(fct object.Object, args ...object.Object) (object.Object, error) {
+	result := applyFunction(fct, args)
+	if err, ok := result.(*object.Error); ok {
+		return nil, fmt.Errorf("%s", err.Message)
+	}
+	return result, nil
+}
+
 func extendFunctionEnv(fct *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fct.Env)
 
@@ -591,7 +708,8 @@ func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.
 		result = Eval(ws.Body, env)
 
 		if result != nil {
-			if result.Type() == object.RETURN_VALUE_OBJ {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
 				return result
 			}
 		}
@@ -601,7 +719,7 @@ func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.
 }
 
 func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
-	path := node.Path.Value
+	path := imports.Resolve(env.Dir(), node.Path.Value, nil)
 
 	if env.IsImported(path) {
 		return nil
@@ -611,7 +729,7 @@ func evalImportStatement(node *ast.ImportStatement, env *object.Environment) obj
 
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return newError("Could not read imported file: %s", path)
+		return newError("Could not read imported file: %s", node.Path.Value)
 	}
 
 	l := lexer.New(string(content))
@@ -619,8 +737,85 @@ func evalImportStatement(node *ast.ImportStatement, env *object.Environment) obj
 	program := p.ParseProgram()
 
 	if len(p.Errors()) != 0 {
-		return newError("Could not parse imported file: %s", path)
+		return newError("Could not parse imported file: %s", node.Path.Value)
 	}
 
+	// The imported file's own imports are relative to it.
+	dir := env.Dir()
+	env.SetDir(filepath.Dir(path))
+	defer env.SetDir(dir)
+
 	return Eval(program, env)
 }
+
+func evalAttributeAccess(obj object.Object, name string) object.Object {
+	attr, err := object.Attribute(obj, name)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return attr
+}
+
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		if arm.Pattern != nil {
+			pattern := Eval(arm.Pattern, env)
+			if isError(pattern) {
+				return pattern
+			}
+			if !objectEquals(subject, pattern) {
+				continue
+			}
+		}
+
+		result := Eval(arm.Body, env)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	return NULL
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	it, err := object.NewIterator(iterable)
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	var result object.Object
+
+	for {
+		el, ok, err := it.Next()
+		if err != nil {
+			return newError("%s", err)
+		}
+		if !ok {
+			break
+		}
+
+		env.Set(fs.Variable.Value, el)
+
+		result = Eval(fs.Body, env)
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+
+	return nil
+}
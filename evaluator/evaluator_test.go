@@ -241,6 +241,26 @@ if (10 > 1) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			"var f << fct(a, b) { a + b }; f(1);",
+			"wrong number of arguments: want=2, got=1",
+		},
+		{
+			"var i << 0; while (true) { i + true; }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
+		{
+			"missing << 1;",
+			"undefined variable missing",
+		},
+		{
+			"1 / 0",
+			"division by zero",
+		},
+		{
+			"var n << 0; 5 % n",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -524,20 +544,27 @@ func TestMinAndMaxBuiltins(t *testing.T) {
 		{`min([1])`, 1},
 		{`max([1, 2, 3, 4, 5])`, 5},
 		{`min([1, 2, 3, 4, 5])`, 1},
+		{`max([1, 2.5, 2])`, 2.5},
+		{`min([3, 0.5, bigint(1)])`, 0.5},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-		integer, ok := tt.expected.(int)
-		if ok {
-			testIntegerObject(t, evaluated, int64(integer))
-		} else {
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			f, ok := evaluated.(*object.Float)
+			if !ok || f.Value != expected {
+				t.Errorf("object is not Float %v. got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		default:
 			testNullObject(t, evaluated)
 		}
 	}
 }
 
-func VarAttribuite(t *testing.T) {
+func TestVarAssignment(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
@@ -545,6 +572,10 @@ func VarAttribuite(t *testing.T) {
 		{`var i << 0;
 i << 1;
 i;`, 1},
+		{`var i << 0;
+var set << fct() { i << 5; };
+set();
+i;`, 5},
 		{
 			`var i << 0;
 i << 1;
@@ -564,7 +595,7 @@ i;`, 2,
 	}
 }
 
-func WhileStatement(t *testing.T) {
+func TestWhileStatement(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected interface{}
@@ -727,3 +758,39 @@ func TestStringComparison(t *testing.T) {
 		}
 	}
 }
+
+func TestEnumsAndMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`enum S { A, B } match (S.B) { S.A => 1, S.B => 2 }`, 2},
+		{`match (3) { 1 => 10, else => 20 }`, 20},
+		{`var xs << []; for (x in {1, 2, 2}) { addToArrayEnd(xs, x); } sizeOf(xs)`, 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestExactNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-(9223372036854775807 + 1) - 1", "-9223372036854775809"},
+		{`decimal("0.1") + decimal("0.2")`, "0.3"},
+		{`decimal("10.00") / 4`, "2.50"},
+		{`decimal("1.50") == decimal("1.5")`, "true"},
+		{`99999999999999999999 > 1`, "true"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: got %s (%s), want %s", tt.input, evaluated.Inspect(), evaluated.Type(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/parser"
+)
+
+func TestImportsAreRelativeToTheImportingFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"lib/shapes.zum": "import \"units.zum\"\nvar area << fct(side) { side * side * scale };",
+		"lib/units.zum":  `var scale << 10;`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"import \"lib/shapes.zum\"\narea(2);", 40},
+		{`import "lib/missing.zum"`, "Could not read imported file: lib/missing.zum"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.SetDir(dir)
+		evaluated := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok || errObj.Message != expected {
+				t.Errorf("wrong error. want=%q, got=%T (%+v)", expected, evaluated, evaluated)
+			}
+		}
+	}
+}
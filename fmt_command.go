@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zumbra/format"
+)
+
+// formatCommand implements `zumbra fmt [-w] [-check] paths...`. It returns
+// false when a file could not be formatted or, with -check, when a file is
+// not already formatted.
+func formatCommand(args []string) bool {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with an error")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Println("usage: zumbra fmt [-w] [-check] files...")
+		return false
+	}
+
+	files, err := zumbraFiles(flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	ok := true
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Error when trying to read the file: %s\n", err)
+			ok = false
+			continue
+		}
+
+		out, err := format.Source(src)
+		if err != nil {
+			fmt.Printf("%s: %s\n", file, err)
+			ok = false
+			continue
+		}
+
+		changed := !bytes.Equal(src, out)
+
+		switch {
+		case *check:
+			if changed {
+				fmt.Println(file)
+				ok = false
+			}
+		case *write:
+			if changed {
+				if err := os.WriteFile(file, out, 0644); err != nil {
+					fmt.Printf("Error when trying to write the file: %s\n", err)
+					ok = false
+				}
+			}
+		default:
+			os.Stdout.Write(out)
+		}
+	}
+
+	return ok
+}
+
+// zumbraFiles expands directories into the .zum files they contain.
+func zumbraFiles(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(file, ".zum") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,565 @@
+// Package format prints Zumbra programs in the canonical layout used by
+// `zumbra fmt`: four-space indentation, `;` after simple statements, one
+// space around binary operators and `<<`, and opening braces on the same
+// line. Comments are kept, and so is a single blank line wherever the
+// source had one or more.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"zumbra/ast"
+	"zumbra/lexer"
+	"zumbra/parser"
+	"zumbra/token"
+)
+
+// Source parses src and returns it formatted.
+func Source(src []byte) ([]byte, error) {
+	p := parser.New(lexer.NewWithComments(string(src)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	return []byte(Program(program, string(src))), nil
+}
+
+// Program prints a parsed program. src is only used to find blank lines
+// and may be empty.
+func Program(program *ast.Program, src string) string {
+	p := &printer{
+		lines:    strings.Split(src, "\n"),
+		comments: program.Comments,
+	}
+
+	p.statements(program.Statements, token.Token{})
+	p.flushComments(token.Token{Line: 1 << 30})
+
+	out := strings.TrimLeft(p.out.String(), "\n")
+	if out == "" {
+		return ""
+	}
+	return strings.TrimRight(out, "\n") + "\n"
+}
+
+const indentation = "    "
+
+type printer struct {
+	out      bytes.Buffer
+	indent   int
+	lines    []string
+	comments []*ast.Comment
+	next     int
+	// lastLine is the source line of the last thing printed, used to
+	// preserve blank lines.
+	lastLine int
+}
+
+func (p *printer) write(s string) {
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.write("\n" + strings.Repeat(indentation, p.indent))
+}
+
+// separate starts a new line for something that begins at source line,
+// adding a blank line if the source had one there.
+func (p *printer) separate(line int) {
+	if p.lastLine > 0 && p.blankBetween(p.lastLine, line) {
+		p.write("\n")
+	}
+	p.newline()
+}
+
+func (p *printer) blankBetween(from, to int) bool {
+	for l := from + 1; l < to && l <= len(p.lines); l++ {
+		if strings.TrimSpace(p.lines[l-1]) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// flushComments prints, on lines of their own, the comments that come
+// before pos.
+func (p *printer) flushComments(pos token.Token) {
+	for p.next < len(p.comments) && before(p.comments[p.next].Token, pos) {
+		c := p.comments[p.next].Token
+		p.separate(c.Line)
+		p.write(strings.TrimRight(c.Literal, " \t"))
+		p.lastLine = c.Line + strings.Count(c.Literal, "\n")
+		p.next++
+	}
+}
+
+// trailingComments prints comments that sit on the last line of what was
+// just printed, stopping at limit when it is set.
+func (p *printer) trailingComments(line int, limit token.Token) {
+	for p.next < len(p.comments) && p.comments[p.next].Token.Line == line {
+		c := p.comments[p.next].Token
+		if limit.Line > 0 && !before(c, limit) {
+			return
+		}
+		p.write(" " + strings.TrimRight(c.Literal, " \t"))
+		p.lastLine = c.Line + strings.Count(c.Literal, "\n")
+		p.next++
+	}
+}
+
+// statements prints a list of statements. end is the closing brace of the
+// enclosing block, or the zero token at the top level.
+func (p *printer) statements(stmts []ast.Statement, end token.Token) {
+	for _, stmt := range stmts {
+		start := ast.StartToken(stmt)
+		p.flushComments(start)
+		p.separate(start.Line)
+		p.statement(stmt)
+
+		last := endLine(stmt)
+		p.lastLine = last
+		p.trailingComments(last, end)
+	}
+
+	if end.Line > 0 {
+		p.flushComments(end)
+	}
+}
+
+func (p *printer) block(b *ast.BlockStatement) {
+	if len(b.Statements) == 0 && !p.commentsBefore(b.Rbrace) {
+		p.write("{}")
+		return
+	}
+
+	first := b.Rbrace
+	if len(b.Statements) > 0 {
+		first = ast.StartToken(b.Statements[0])
+	}
+
+	p.write("{")
+	p.indent++
+	p.lastLine = 0
+	// Only the comments before the first statement: on a one-line block,
+	// the ones after it belong to the statement holding the block.
+	p.trailingComments(b.Token.Line, first)
+	p.lastLine = 0
+	p.statements(b.Statements, b.Rbrace)
+	p.indent--
+	p.newline()
+	p.write("}")
+	if b.Rbrace.Line > p.lastLine {
+		p.lastLine = b.Rbrace.Line
+	}
+}
+
+func (p *printer) commentsBefore(pos token.Token) bool {
+	return pos.Line > 0 && p.next < len(p.comments) && before(p.comments[p.next].Token, pos)
+}
+
+// commentsAmong reports whether comments sit between open and end outside
+// of the brackets within nodes.
+func (p *printer) commentsAmong(open, end token.Token, nodes []ast.Node) bool {
+	for _, c := range p.comments[p.next:] {
+		if end.Line == 0 || !before(c.Token, end) {
+			return false
+		}
+		if before(open, c.Token) && !nested(c.Token, nodes) {
+			return true
+		}
+	}
+	return false
+}
+
+// items prints the elements of a literal, call or parameter list that
+// starts at open and ends at end. starts holds where each element starts
+// and inner the nodes they are made of; print prints one and returns the
+// line it ends on. When comments sit among the elements, each element goes
+// on a line of its own so the comments keep their place.
+func (p *printer) items(open, end token.Token, starts []token.Token, inner []ast.Node, print func(i int) int) {
+	n := len(starts)
+	start := func(i int) token.Token { return starts[i] }
+
+	if !p.commentsAmong(open, end, inner) {
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.write(", ")
+			}
+			print(i)
+		}
+		return
+	}
+
+	first := end
+	if n > 0 {
+		first = start(0)
+	}
+
+	p.indent++
+	p.lastLine = 0
+	p.trailingComments(open.Line, first)
+	p.lastLine = 0
+	for i := 0; i < n; i++ {
+		s := start(i)
+		p.flushComments(s)
+		p.separate(s.Line)
+		last := print(i)
+		if i < n-1 {
+			p.write(",")
+		}
+
+		p.lastLine = last
+		// Comments between two elements on one line go with the second.
+		if i == n-1 || start(i+1).Line > last {
+			p.trailingComments(last, end)
+		}
+	}
+	p.flushComments(end)
+	p.indent--
+	p.newline()
+	p.lastLine = end.Line
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VarStatement:
+		p.write("var " + s.Name.Value)
+		if s.Type != nil {
+			p.write(": " + s.Type.String())
+		}
+		p.write(" << ")
+		p.expression(s.Value, lowest)
+		p.write(";")
+
+	case *ast.AssignStatement:
+		p.write(s.Name.Value + " << ")
+		p.expression(s.Value, lowest)
+		p.write(";")
+
+	case *ast.ReturnStatement:
+		p.write("return ")
+		p.expression(s.ReturnValue, lowest)
+		p.write(";")
+
+	case *ast.YieldStatement:
+		p.write("yield ")
+		p.expression(s.Value, lowest)
+		p.write(";")
+
+	case *ast.ExpressionStatement:
+		p.expression(s.Expression, lowest)
+		switch s.Expression.(type) {
+		case *ast.IfExpression, *ast.MatchExpression:
+		default:
+			p.write(";")
+		}
+
+	case *ast.WhileStatement:
+		p.write("while (")
+		p.expression(s.Condition, lowest)
+		p.write(") ")
+		p.block(s.Body)
+
+	case *ast.ForStatement:
+		p.write("for (" + s.Variable.Value + " in ")
+		p.expression(s.Iterable, lowest)
+		p.write(") ")
+		p.block(s.Body)
+
+	case *ast.ImportStatement:
+		p.write("import " + quote(s.Path.Value))
+
+	case *ast.EnumStatement:
+		variants := []string{}
+		for _, v := range s.Variants {
+			variants = append(variants, v.Value)
+		}
+		p.write("enum " + s.Name.Value + " { " + strings.Join(variants, ", ") + " }")
+
+	default:
+		p.write(stmt.String())
+	}
+}
+
+const (
+	lowest = iota
+	or
+	and
+	equals
+	lessGreater
+	sum
+	product
+	prefix
+	call
+)
+
+var precedences = map[string]int{
+	"or":  or,
+	"and": and,
+	"==":  equals,
+	"!=":  equals,
+	"<":   lessGreater,
+	">":   lessGreater,
+	"<=":  lessGreater,
+	">=":  lessGreater,
+	"+":   sum,
+	"-":   sum,
+	"*":   product,
+	"/":   product,
+	"%":   product,
+	"**":  product,
+}
+
+// expression prints exp, wrapping it in parentheses when it binds less
+// tightly than its context requires.
+func (p *printer) expression(exp ast.Expression, context int) {
+	switch e := exp.(type) {
+	case nil:
+
+	case *ast.Identifier:
+		p.write(e.Value)
+
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		p.write(e.TokenLiteral())
+
+	case *ast.StringLiteral:
+		p.write(quote(e.Value))
+
+	case *ast.PrefixExpression:
+		p.parenthesize(prefix < context, func() {
+			p.write(e.Operator)
+			// `- -x` would otherwise print as `--x`, a different token.
+			if inner, ok := e.Right.(*ast.PrefixExpression); ok && inner.Operator == e.Operator {
+				p.write("(")
+				p.expression(e.Right, lowest)
+				p.write(")")
+				return
+			}
+			p.expression(e.Right, prefix)
+		})
+
+	case *ast.InfixExpression:
+		precedence := precedences[e.Operator]
+		p.parenthesize(precedence < context, func() {
+			p.expression(e.Left, precedence)
+			p.write(" " + e.Operator + " ")
+			p.expression(e.Right, precedence+1)
+		})
+
+	case *ast.CallExpression:
+		p.expression(e.Function, call)
+		p.write("(")
+		p.list(e.Token, e.Rparen, e.Arguments)
+		p.write(")")
+
+	case *ast.IndexExpression:
+		p.expression(e.Left, call)
+		p.write("[")
+		p.expression(e.Index, lowest)
+		p.write("]")
+
+	case *ast.AttributeAccess:
+		p.expression(e.Object, call)
+		p.write("." + e.Property.Value)
+
+	case *ast.ArrayLiteral:
+		p.write("[")
+		p.list(e.Token, e.Rbracket, e.Elements)
+		p.write("]")
+
+	case *ast.SetLiteral:
+		p.write("{")
+		p.list(e.Token, e.Rbrace, e.Elements)
+		p.write("}")
+
+	case *ast.DictLiteral:
+		keys := dictKeys(e)
+		starts, inner := []token.Token{}, []ast.Node{}
+		for _, key := range keys {
+			starts = append(starts, ast.StartToken(key))
+			inner = append(inner, key, e.Pairs[key])
+		}
+		p.write("{")
+		p.items(e.Token, e.Rbrace, starts, inner, func(i int) int {
+			p.expression(keys[i], lowest)
+			p.write(": ")
+			p.expression(e.Pairs[keys[i]], lowest)
+			return endLine(e.Pairs[keys[i]])
+		})
+		p.write("}")
+
+	case *ast.FunctionLiteral:
+		p.write("fct(")
+		starts := []token.Token{}
+		for _, param := range e.Parameters {
+			starts = append(starts, param.Token)
+		}
+		p.items(e.Token, e.Body.Token, starts, nil, func(i int) int {
+			param := e.Parameters[i]
+			p.write(param.Value)
+			if param.Type != nil {
+				p.write(": " + param.Type.String())
+			}
+			return param.Token.Line
+		})
+		p.write(")")
+		if e.ReturnType != nil {
+			p.write(": " + e.ReturnType.String())
+		}
+		p.write(" ")
+		p.block(e.Body)
+
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(e.Condition, lowest)
+		p.write(") ")
+		p.block(e.Consequence)
+		if e.Alternative != nil {
+			p.write(" else ")
+			p.block(e.Alternative)
+		}
+
+	case *ast.MatchExpression:
+		p.match(e)
+
+	default:
+		p.write(exp.String())
+	}
+}
+
+func (p *printer) parenthesize(needed bool, print func()) {
+	if needed {
+		p.write("(")
+	}
+	print()
+	if needed {
+		p.write(")")
+	}
+}
+
+func (p *printer) list(open, end token.Token, exps []ast.Expression) {
+	starts, inner := []token.Token{}, []ast.Node{}
+	for _, exp := range exps {
+		starts = append(starts, ast.StartToken(exp))
+		inner = append(inner, exp)
+	}
+	p.items(open, end, starts, inner, func(i int) int {
+		p.expression(exps[i], lowest)
+		return endLine(exps[i])
+	})
+}
+
+func (p *printer) match(e *ast.MatchExpression) {
+	p.write("match (")
+	p.expression(e.Subject, lowest)
+	p.write(") {")
+	p.indent++
+	p.lastLine = 0
+
+	for _, arm := range e.Arms {
+		start := arm.Body.Token
+		if arm.Pattern != nil {
+			start = ast.StartToken(arm.Pattern)
+		}
+		p.flushComments(start)
+		p.separate(start.Line)
+
+		if arm.Pattern == nil {
+			p.write("else")
+		} else {
+			p.expression(arm.Pattern, lowest)
+		}
+		p.write(" => ")
+
+		if arm.Body.Token.Type == token.LBRACE {
+			p.block(arm.Body)
+		} else {
+			p.expression(arm.Body.Statements[0].(*ast.ExpressionStatement).Expression, lowest)
+			p.write(",")
+		}
+
+		last := endLine(arm.Body)
+		p.lastLine = last
+		p.trailingComments(last, token.Token{})
+	}
+
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func dictKeys(d *ast.DictLiteral) []ast.Expression {
+	if len(d.Keys) == len(d.Pairs) {
+		return d.Keys
+	}
+
+	keys := []ast.Expression{}
+	for key := range d.Pairs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+func quote(s string) string {
+	return `"` + s + `"`
+}
+
+// endLine is the last source line a node is known to occupy.
+func endLine(node ast.Node) int {
+	last := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if line := ast.StartToken(n).Line; line > last {
+			last = line
+		}
+		if _, end := brackets(n); end.Line > last {
+			last = end.Line
+		}
+		return true
+	})
+	return last
+}
+
+// brackets returns the tokens that open and close a node, for the nodes
+// the parser records the closing token of.
+func brackets(node ast.Node) (open, end token.Token) {
+	switch n := node.(type) {
+	case *ast.BlockStatement:
+		return n.Token, n.Rbrace
+	case *ast.ArrayLiteral:
+		return n.Token, n.Rbracket
+	case *ast.SetLiteral:
+		return n.Token, n.Rbrace
+	case *ast.DictLiteral:
+		return n.Token, n.Rbrace
+	case *ast.CallExpression:
+		return n.Token, n.Rparen
+	}
+	return token.Token{}, token.Token{}
+}
+
+// nested reports whether c sits inside brackets within one of nodes, where
+// printing that node takes care of it.
+func nested(c token.Token, nodes []ast.Node) bool {
+	found := false
+	for _, node := range nodes {
+		ast.Inspect(node, func(n ast.Node) bool {
+			open, end := brackets(n)
+			if end.Line > 0 && before(open, c) && before(c, end) {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
@@ -0,0 +1,169 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x<<1", "var x << 1;\n"},
+		{"var x << 1;\n\n\n\nshow(x)", "var x << 1;\n\nshow(x);\n"},
+		{"x<<x+1", "x << x + 1;\n"},
+		{"var f<<fct(a,b){a+b}", "var f << fct(a, b) {\n    a + b;\n};\n"},
+		{"var f << fct() {}", "var f << fct() {};\n"},
+		{"var f << fct(a: int): [int] { return [a]; }", "var f << fct(a: int): [int] {\n    return [a];\n};\n"},
+		{"if(a){1}else{2}", "if (a) {\n    1;\n} else {\n    2;\n}\n"},
+		{"while(i<3){i<<i+1}", "while (i < 3) {\n    i << i + 1;\n}\n"},
+		{"for(x in xs){show(x)}", "for (x in xs) {\n    show(x);\n}\n"},
+		{"(a + b) * c", "(a + b) * c;\n"},
+		{"a - (b - c)", "a - (b - c);\n"},
+		{"(a - b) - c", "a - b - c;\n"},
+		{"-(a + b)", "-(a + b);\n"},
+		{"- -a", "-(-a);\n"},
+		{"a or b and c", "a or b and c;\n"},
+		{"(a or b) and c", "(a or b) and c;\n"},
+		{`{"b":1,"a":[1,2]}`, "{\"b\": 1, \"a\": [1, 2]};\n"},
+		{"{1,2}", "{1, 2};\n"},
+		{"enum S {A,B,}", "enum S { A, B }\n"},
+		{"import \"lib.zum\"\nshow(1)", "import \"lib.zum\"\nshow(1);\n"},
+		{"match(s){S.A=>1,else=>{2}}", "match (s) {\n    S.A => 1,\n    else => {\n        2;\n    }\n}\n"},
+		{"var gen << fct() { yield 1 }", "var gen << fct() {\n    yield 1;\n};\n"},
+		{"d[\"a\"].hour", "d[\"a\"].hour;\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source([]byte(tt.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.input, err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf("%q: wrong output.\nwant=%q\ngot =%q", tt.input, tt.expected, out)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+
+
+/// Adds numbers.
+var add << fct(a, b) { // opens
+  a + b // sum
+  // end of body
+}
+show(add(1, 2)) //3
+/* done */
+`
+	expected := `// header
+
+/// Adds numbers.
+var add << fct(a, b) { // opens
+    a + b; // sum
+    // end of body
+};
+show(add(1, 2)); //3
+/* done */
+`
+
+	out, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if string(out) != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", expected, out)
+	}
+}
+
+func TestCommentsInLiterals(t *testing.T) {
+	// Already formatted, so formatting must leave it as it is.
+	input := `var d << {
+    "a": 1, // one
+    /* inline */
+    "b": 2
+};
+var roles << {
+    "admin",
+    // "guest",
+    "user"
+};
+show([
+    1, // one
+    2
+]);
+var f << fct(
+    a, // first
+    b
+) {
+    a + b;
+};
+if (d["a"] > 1) { // why
+    show(1);
+}
+if (d["b"] > 1) {
+    show(2);
+} // done
+`
+
+	once, err := Source([]byte(input))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if string(once) != input {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", input, once)
+	}
+
+	oneLine := "if (a) { show(1); } // done\nvar xs << [1, /* two */ 2];\n"
+	expected := "if (a) {\n    show(1);\n} // done\nvar xs << [\n    1,\n    /* two */\n    2\n];\n"
+	out, err := Source([]byte(oneLine))
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if string(out) != expected {
+		t.Errorf("wrong output.\nwant=%q\ngot =%q", expected, out)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := Source([]byte("var << 1")); err == nil {
+		t.Errorf("expected an error for invalid source")
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	files, err := filepath.Glob("../code_examples/*/*.zum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, _ := filepath.Glob("../code_examples/*.zum")
+	files = append(files, top...)
+
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		once, err := Source(src)
+		if err != nil {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+
+		twice, err := Source(once)
+		if err != nil {
+			t.Errorf("%s: formatted output does not parse: %s", file, err)
+			continue
+		}
+
+		if string(once) != string(twice) {
+			t.Errorf("%s: formatting is not idempotent:\n%s", file, strings.TrimSpace(string(twice)))
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Package imports finds the file an import statement refers to, so the
+// compiler, the evaluator and the transpiler resolve imports the same way.
+package imports
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// modulesDir holds the packages installed with `zumbra pkg add`.
+const modulesDir = "zumbra_modules"
+
+// Resolve returns the file `import path` refers to in a file in dir: path
+// relative to dir, to one of importPaths or to the closest zumbra_modules
+// directory. When the file exists in none of them, the path relative to dir
+// is returned.
+func Resolve(dir, path string, importPaths []string) string {
+	local := filepath.Clean(filepath.Join(dir, path))
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+
+	for _, importDir := range importPaths {
+		candidate := filepath.Clean(filepath.Join(importDir, path))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	// Packages are found in the zumbra_modules directory of the importing
+	// file's directory or of the closest parent that has one.
+	for {
+		candidate := filepath.Clean(filepath.Join(dir, modulesDir, path))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return local
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,45 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"app/src/local.zum",
+		"lib/shared.zum",
+		"app/zumbra_modules/pkg/pkg.zum",
+	}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(root, "app", "src")
+	importPaths := []string{filepath.Join(root, "lib")}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"local.zum", "app/src/local.zum"},
+		{"shared.zum", "lib/shared.zum"},
+		{"pkg/pkg.zum", "app/zumbra_modules/pkg/pkg.zum"},
+		{"missing.zum", "app/src/missing.zum"},
+	}
+
+	for _, tt := range tests {
+		got := Resolve(dir, tt.path, importPaths)
+		if got != filepath.Join(root, tt.expected) {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.path, got, filepath.Join(root, tt.expected))
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"zumbra/project"
+)
+
+// initCommand implements `zumbra init [-name name] [dir]`, which creates a
+// project in dir, by default the current directory.
+func initCommand(args []string) bool {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	name := flags.String("name", "", "project name, by default the directory name")
+	flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		fmt.Println("usage: zumbra init [-name name] [dir]")
+		return false
+	}
+
+	dir := flags.Arg(0)
+	if dir == "" {
+		dir = "."
+	}
+
+	m, err := project.Init(dir, *name)
+	if err != nil {
+		fmt.Printf("Error when trying to create the project: %s\n", err)
+		return false
+	}
+
+	fmt.Printf("Created project %s in %s\n", m.Name, m.Dir)
+	fmt.Println("Run it with `zumbra run` and test it with `zumbra test`.")
+	return true
+}
@@ -10,15 +10,32 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+	line         int
+	column       int
+	keepComments bool
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// NewWithComments returns a lexer that also emits COMMENT tokens for `//`
+// and `/* */` comments, so tools can keep them around.
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.keepComments = true
+	return l
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -29,9 +46,32 @@ func (l *Lexer) readChar() {
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
 
-	l.skipWhitespace()
+		if l.ch != '/' || (l.peekChar() != '/' && l.peekChar() != '*') {
+			break
+		}
+
+		line, column := l.line, l.column
+		tok := l.readComment()
+		if tok.Type == token.DOC_COMMENT || tok.Type == token.ILLEGAL || l.keepComments {
+			tok.Line = line
+			tok.Column = column
+			return tok
+		}
+	}
+
+	line, column := l.line, l.column
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+
+	return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '.':
@@ -47,7 +87,6 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.LTE, Literal: string(ch) + string(l.ch)}
-			l.readChar()
 		} else {
 			tok = newToken(token.LT, l.ch)
 		}
@@ -82,15 +121,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.MINUS, l.ch)
 		}
 	case '/':
-		if l.peekChar() == '/' {
-			l.readChar()
-			for l.ch != '\n' && l.ch != 0 {
-				l.readChar()
-			}
-			return l.NextToken()
-		} else {
-			tok = newToken(token.SLASH, l.ch)
-		}
+		tok = newToken(token.SLASH, l.ch)
 	case '>':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -104,6 +135,10 @@ func (l *Lexer) NextToken() token.Token {
 			ch := l.ch
 			l.readChar()
 			tok = token.Token{Type: token.EQUAL, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: string(ch) + string(l.ch)}
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
@@ -126,12 +161,17 @@ func (l *Lexer) NextToken() token.Token {
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
+		if l.ch == 0 {
+			tok = token.Token{Type: token.ILLEGAL, Literal: "unterminated string"}
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '|':
+		tok = newToken(token.PIPE, l.ch)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -197,6 +237,35 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+func (l *Lexer) readComment() token.Token {
+	position := l.position
+
+	if l.peekChar() == '*' {
+		l.readChar()
+		l.readChar()
+		for !(l.ch == '*' && l.peekChar() == '/') {
+			if l.ch == 0 {
+				return token.Token{Type: token.ILLEGAL, Literal: "unterminated block comment"}
+			}
+			l.readChar()
+		}
+		l.readChar()
+		l.readChar()
+		return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+	}
+
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	literal := strings.TrimRight(l.input[position:l.position], "\r")
+
+	if strings.HasPrefix(literal, "///") && !strings.HasPrefix(literal, "////") {
+		return token.Token{Type: token.DOC_COMMENT, Literal: literal}
+	}
+
+	return token.Token{Type: token.COMMENT, Literal: literal}
+}
+
 func (l *Lexer) readString() string {
 	position := l.position + 1
 	for {
@@ -125,3 +125,133 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestComments(t *testing.T) {
+	input := `// line comment
+var a << 1; /* block
+comment */ a
+/// doc comment
+var url << "http://zumbra.dev"; // trailing
+`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.VAR, "var"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "<<"},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.DOC_COMMENT, "/// doc comment"},
+		{token.VAR, "var"},
+		{token.IDENT, "url"},
+		{token.ASSIGN, "<<"},
+		{token.STRING, "http://zumbra.dev"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestCommentTokens(t *testing.T) {
+	input := `// line comment
+var a << 1; /* block */
+/// doc comment
+/* unterminated`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.COMMENT, "// line comment", 1, 1},
+		{token.VAR, "var", 2, 1},
+		{token.IDENT, "a", 2, 5},
+		{token.ASSIGN, "<<", 2, 7},
+		{token.INT, "1", 2, 10},
+		{token.SEMICOLON, ";", 2, 11},
+		{token.COMMENT, "/* block */", 2, 13},
+		{token.DOC_COMMENT, "/// doc comment", 3, 1},
+		{token.ILLEGAL, "unterminated block comment", 4, 1},
+		{token.EOF, "", 4, 16},
+	}
+
+	l := NewWithComments(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComparisonOperators(t *testing.T) {
+	input := `a<=3 b>=4`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LTE, "<="},
+		{token.INT, "3"},
+		{token.IDENT, "b"},
+		{token.GTE, ">="},
+		{token.INT, "4"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedString(t *testing.T) {
+	l := New(`show("open`)
+
+	for _, want := range []token.TokenType{token.IDENT, token.LPAREN, token.ILLEGAL, token.EOF} {
+		tok := l.NextToken()
+		if tok.Type != want {
+			t.Fatalf("tokentype wrong. expected=%q, got=%q", want, tok.Type)
+		}
+		if tok.Type == token.ILLEGAL && (tok.Literal != "unterminated string" || tok.Column != 6) {
+			t.Errorf("wrong illegal token: %+v", tok)
+		}
+	}
+}
@@ -0,0 +1,520 @@
+// Package lint finds likely mistakes in Zumbra programs without running
+// them. A rule can be silenced for one line with a comment:
+//
+//	show(x) // zumbra-lint-disable-line unused-variable
+//	// zumbra-lint-disable-next-line
+//	var first << 1;
+//
+// Without rule names every rule is disabled for that line.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"zumbra/ast"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/token"
+)
+
+const (
+	UnusedVariable   = "unused-variable"
+	UnusedParameter  = "unused-parameter"
+	Shadow           = "shadow"
+	Unreachable      = "unreachable"
+	UndefinedAssign  = "undefined-assign"
+	BuiltinArity     = "builtin-arity"
+	SQLConcatenation = "sql-concatenation"
+	HardcodedSecret  = "hardcoded-secret"
+)
+
+// Rules lists the names of every rule.
+var Rules = []string{
+	UnusedVariable,
+	UnusedParameter,
+	Shadow,
+	Unreachable,
+	UndefinedAssign,
+	BuiltinArity,
+	SQLConcatenation,
+	HardcodedSecret,
+}
+
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.File, d.Line, d.Column, d.Message, d.Rule)
+}
+
+// File lints a source file. Imports are resolved relative to its directory.
+func File(filename string) ([]Diagnostic, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics, err := Source(src, filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range diagnostics {
+		diagnostics[i].File = filename
+	}
+	return diagnostics, nil
+}
+
+func Source(src []byte, dir string) ([]Diagnostic, error) {
+	p := parser.New(lexer.NewWithComments(string(src)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Errors(), "\n"))
+	}
+
+	return Program(program, dir), nil
+}
+
+// Program lints a parsed program, dropping diagnostics disabled by its
+// comments.
+func Program(program *ast.Program, dir string) []Diagnostic {
+	table := compiler.NewSymbolTable()
+	for i, b := range builtins.Builtins {
+		table.DefineBuiltin(i, b.Name)
+	}
+
+	l := &linter{
+		dir:      dir,
+		scope:    &scope{table: table, declarations: map[string]*declaration{}},
+		imported: map[string]bool{},
+	}
+	l.statements(program.Statements)
+
+	disabled := disabledRules(program.Comments)
+	diagnostics := []Diagnostic{}
+	for _, d := range l.diagnostics {
+		if !disabled.has(d.Line, d.Rule) {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+
+	return diagnostics
+}
+
+type declaration struct {
+	name  *ast.Identifier
+	rule  string
+	value ast.Expression
+	used  bool
+}
+
+// scope mirrors the compiler: only function literals open a new one.
+type scope struct {
+	outer        *scope
+	table        *compiler.SymbolTable
+	declarations map[string]*declaration
+}
+
+type linter struct {
+	dir         string
+	scope       *scope
+	diagnostics []Diagnostic
+	imported    map[string]bool
+	// openImports is set when an import could not be read, so assignments
+	// to unknown globals may be fine.
+	openImports bool
+}
+
+func (l *linter) report(tok token.Token, rule, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:    tok.Line,
+		Column:  tok.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) enterScope() {
+	l.scope = &scope{
+		outer:        l.scope,
+		table:        compiler.NewEnclosedSymbolTable(l.scope.table),
+		declarations: map[string]*declaration{},
+	}
+}
+
+func (l *linter) leaveScope() {
+	for _, d := range l.scope.declarations {
+		l.reportUnused(d)
+	}
+	l.scope = l.scope.outer
+}
+
+func (l *linter) reportUnused(d *declaration) {
+	name := d.name.Value
+	if d.used || strings.HasPrefix(name, "_") {
+		return
+	}
+
+	if d.rule == UnusedParameter {
+		l.report(d.name.Token, UnusedParameter, "parameter %s is never used", name)
+	} else {
+		l.report(d.name.Token, UnusedVariable, "variable %s is declared but never used", name)
+	}
+}
+
+func (l *linter) define(name *ast.Identifier, rule string, value ast.Expression) {
+	s := l.scope
+
+	if previous, ok := s.declarations[name.Value]; ok {
+		// Top-level variables may be used by files that import this one.
+		if s.outer != nil {
+			l.reportUnused(previous)
+		}
+	} else if symbol, ok := s.table.Resolve(name.Value); ok {
+		switch symbol.Scope {
+		case compiler.BuiltinScope:
+			l.report(name.Token, Shadow, "%s shadows the builtin %s", name.Value, name.Value)
+		case compiler.FunctionScope:
+		default:
+			if outer := l.lookup(name.Value); outer != nil {
+				l.report(name.Token, Shadow, "%s shadows the variable declared at line %d", name.Value, outer.name.Token.Line)
+			}
+		}
+	}
+
+	s.table.Define(name.Value)
+	s.declarations[name.Value] = &declaration{name: name, rule: rule, value: value}
+}
+
+func (l *linter) lookup(name string) *declaration {
+	for s := l.scope; s != nil; s = s.outer {
+		if d, ok := s.declarations[name]; ok {
+			return d
+		}
+	}
+	return nil
+}
+
+func (l *linter) statements(stmts []ast.Statement) {
+	returned := false
+
+	for _, stmt := range stmts {
+		if returned {
+			l.report(ast.StartToken(stmt), Unreachable, "unreachable code after return")
+			returned = false
+		}
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			returned = true
+		}
+
+		l.statement(stmt)
+	}
+}
+
+func (l *linter) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VarStatement:
+		l.define(s.Name, UnusedVariable, s.Value)
+		l.expression(s.Value)
+
+	case *ast.AssignStatement:
+		l.expression(s.Value)
+		if _, ok := l.scope.table.Resolve(s.Name.Value); !ok && !l.openImports {
+			l.report(s.Name.Token, UndefinedAssign, "assignment to undefined variable %s", s.Name.Value)
+		}
+
+	case *ast.ReturnStatement:
+		l.expression(s.ReturnValue)
+
+	case *ast.YieldStatement:
+		l.expression(s.Value)
+
+	case *ast.ExpressionStatement:
+		l.expression(s.Expression)
+
+	case *ast.WhileStatement:
+		l.expression(s.Condition)
+		l.block(s.Body)
+
+	case *ast.ForStatement:
+		l.expression(s.Iterable)
+		l.define(s.Variable, UnusedVariable, nil)
+		l.block(s.Body)
+
+	case *ast.EnumStatement:
+		l.define(s.Name, UnusedVariable, nil)
+
+	case *ast.ImportStatement:
+		l.importFile(s.Path.Value)
+	}
+}
+
+func (l *linter) block(b *ast.BlockStatement) {
+	if b != nil {
+		l.statements(b.Statements)
+	}
+}
+
+func (l *linter) expression(exp ast.Expression) {
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		if d := l.lookup(e.Value); d != nil {
+			d.used = true
+		}
+		// Resolving records free variables in the enclosing tables, as
+		// the compiler does.
+		l.scope.table.Resolve(e.Value)
+
+	case *ast.PrefixExpression:
+		l.expression(e.Right)
+
+	case *ast.InfixExpression:
+		l.expression(e.Left)
+		l.expression(e.Right)
+
+	case *ast.CallExpression:
+		l.call(e)
+		l.expression(e.Function)
+		for _, arg := range e.Arguments {
+			l.expression(arg)
+		}
+
+	case *ast.IndexExpression:
+		l.expression(e.Left)
+		l.expression(e.Index)
+
+	case *ast.AttributeAccess:
+		l.expression(e.Object)
+
+	case *ast.ArrayLiteral:
+		for _, el := range e.Elements {
+			l.expression(el)
+		}
+
+	case *ast.SetLiteral:
+		for _, el := range e.Elements {
+			l.expression(el)
+		}
+
+	case *ast.DictLiteral:
+		for _, key := range ast.SortedKeys(e) {
+			l.expression(key)
+			l.expression(e.Pairs[key])
+		}
+
+	case *ast.IfExpression:
+		l.expression(e.Condition)
+		l.block(e.Consequence)
+		l.block(e.Alternative)
+
+	case *ast.MatchExpression:
+		l.expression(e.Subject)
+		for _, arm := range e.Arms {
+			l.expression(arm.Pattern)
+			l.block(arm.Body)
+		}
+
+	case *ast.FunctionLiteral:
+		l.enterScope()
+		if e.Name != "" {
+			l.scope.table.DefineFunctionName(e.Name)
+		}
+		for _, param := range e.Parameters {
+			l.define(param, UnusedParameter, nil)
+		}
+		l.block(e.Body)
+		l.leaveScope()
+	}
+}
+
+// call checks calls to builtins that have not been shadowed.
+func (l *linter) call(e *ast.CallExpression) {
+	fn, ok := e.Function.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	if symbol, ok := l.scope.table.Resolve(fn.Value); !ok || symbol.Scope != compiler.BuiltinScope {
+		return
+	}
+
+	if min, max, ok := builtins.Arity(fn.Value); ok {
+		got := len(e.Arguments)
+		switch {
+		case max < 0 && got < min:
+			l.report(fn.Token, BuiltinArity, "%s expects at least %d arguments, got %d", fn.Value, min, got)
+		case max >= 0 && (got < min || got > max):
+			want := fmt.Sprint(min)
+			if min != max {
+				want = fmt.Sprintf("%d to %d", min, max)
+			}
+			l.report(fn.Token, BuiltinArity, "%s expects %s arguments, got %d", fn.Value, want, got)
+		}
+	}
+
+	if strings.HasPrefix(fn.Value, "mysql") {
+		for _, arg := range e.Arguments {
+			if l.concatenated(arg) {
+				l.report(ast.StartToken(arg), SQLConcatenation, "SQL built by string concatenation is passed to %s; it may allow SQL injection", fn.Value)
+			}
+		}
+	}
+
+	if fn.Value == "jwtCreateToken" && len(e.Arguments) > 1 {
+		if _, ok := l.value(e.Arguments[1]).(*ast.StringLiteral); ok {
+			l.report(ast.StartToken(e.Arguments[1]), HardcodedSecret, "hard-coded secret passed to jwtCreateToken; load it with dotenvGet instead")
+		}
+	}
+}
+
+// value follows an identifier to the expression it was declared with.
+func (l *linter) value(exp ast.Expression) ast.Expression {
+	if ident, ok := exp.(*ast.Identifier); ok {
+		if d := l.lookup(ident.Value); d != nil && d.value != nil {
+			return d.value
+		}
+	}
+	return exp
+}
+
+// concatenated reports whether exp joins a string literal with something
+// that is not a literal.
+func (l *linter) concatenated(exp ast.Expression) bool {
+	infix, ok := l.value(exp).(*ast.InfixExpression)
+	if !ok || infix.Operator != "+" {
+		return false
+	}
+
+	literal, dynamic := false, false
+	var visit func(ast.Expression)
+	visit = func(exp ast.Expression) {
+		switch e := exp.(type) {
+		case *ast.InfixExpression:
+			if e.Operator == "+" {
+				visit(e.Left)
+				visit(e.Right)
+				return
+			}
+			dynamic = true
+		case *ast.StringLiteral:
+			literal = true
+		case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		default:
+			dynamic = true
+		}
+	}
+	visit(infix)
+
+	return literal && dynamic
+}
+
+// importFile declares the top-level names of an imported file, as the
+// compiler compiles it into the same global scope.
+func (l *linter) importFile(path string) {
+	full := filepath.Clean(filepath.Join(l.dir, path))
+	if l.imported[full] {
+		return
+	}
+	l.imported[full] = true
+
+	src, err := os.ReadFile(full)
+	if err != nil {
+		l.openImports = true
+		return
+	}
+
+	program := parser.New(lexer.New(string(src))).ParseProgram()
+
+	global := l.scope
+	for global.outer != nil {
+		global = global.outer
+	}
+
+	dir := l.dir
+	l.dir = filepath.Dir(full)
+	for _, stmt := range program.Statements {
+		var name *ast.Identifier
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			name = s.Name
+		case *ast.EnumStatement:
+			name = s.Name
+		case *ast.ImportStatement:
+			l.importFile(s.Path.Value)
+		}
+
+		if name != nil {
+			global.table.Define(name.Value)
+			global.declarations[name.Value] = &declaration{name: name, rule: UnusedVariable, used: true}
+		}
+	}
+	l.dir = dir
+}
+
+// disabled maps a line to the rules disabled on it; an empty list means
+// every rule.
+type disabled map[int][]string
+
+func (d disabled) has(line int, rule string) bool {
+	rules, ok := d[line]
+	if !ok {
+		return false
+	}
+	if len(rules) == 0 {
+		return true
+	}
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func disabledRules(comments []*ast.Comment) disabled {
+	d := disabled{}
+
+	for _, c := range comments {
+		fields := strings.Fields(strings.ReplaceAll(c.Text(), ",", " "))
+		if len(fields) == 0 {
+			continue
+		}
+
+		line := c.Token.Line
+		switch fields[0] {
+		case "zumbra-lint-disable-line":
+		case "zumbra-lint-disable-next-line":
+			line += strings.Count(c.Token.Literal, "\n") + 1
+		default:
+			continue
+		}
+
+		if _, ok := d[line]; ok && len(d[line]) == 0 {
+			continue
+		}
+		if len(fields) == 1 {
+			d[line] = []string{}
+		} else {
+			d[line] = append(d[line], fields[1:]...)
+		}
+	}
+
+	return d
+}
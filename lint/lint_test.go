@@ -0,0 +1,129 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Diagnostic
+	}{
+		{
+			`var f << fct(a, b) { var unused << 1; a; }; f(1, 2);`,
+			[]Diagnostic{
+				{Line: 1, Column: 17, Rule: UnusedParameter, Message: "parameter b is never used"},
+				{Line: 1, Column: 26, Rule: UnusedVariable, Message: "variable unused is declared but never used"},
+			},
+		},
+		{`var top << 1; var f << fct(_ignored) { 1 }; f(1);`, []Diagnostic{}},
+		{
+			"var x << 1;\nvar f << fct(x) { x }; f(x);\nvar first << 2;",
+			[]Diagnostic{
+				{Line: 2, Column: 14, Rule: Shadow, Message: "x shadows the variable declared at line 1"},
+				{Line: 3, Column: 5, Rule: Shadow, Message: "first shadows the builtin first"},
+			},
+		},
+		{
+			`var f << fct() { return 1; show(2); show(3); }; f();`,
+			[]Diagnostic{
+				{Line: 1, Column: 28, Rule: Unreachable, Message: "unreachable code after return"},
+			},
+		},
+		{
+			`var x << 1; x << 2; y << 3;`,
+			[]Diagnostic{
+				{Line: 1, Column: 21, Rule: UndefinedAssign, Message: "assignment to undefined variable y"},
+			},
+		},
+		{
+			`sizeOf(); show(); round(1, 2, "up", 4); var sizeOf << fct() {}; sizeOf();`,
+			[]Diagnostic{
+				{Line: 1, Column: 1, Rule: BuiltinArity, Message: "sizeOf expects 1 arguments, got 0"},
+				{Line: 1, Column: 19, Rule: BuiltinArity, Message: "round expects 1 to 3 arguments, got 4"},
+				{Line: 1, Column: 45, Rule: Shadow, Message: "sizeOf shadows the builtin sizeOf"},
+			},
+		},
+		{
+			"var id << input();\nmysqlGetFromTable(\"users\", \"*\", \"id = \" + id);\nvar where << \"name = '\" + id + \"'\";\nmysqlDeleteFromTable(\"users\", where);\nmysqlDropTable(\"a\" + \"b\");",
+			[]Diagnostic{
+				{Line: 2, Column: 33, Rule: SQLConcatenation, Message: "SQL built by string concatenation is passed to mysqlGetFromTable; it may allow SQL injection"},
+				{Line: 4, Column: 31, Rule: SQLConcatenation, Message: "SQL built by string concatenation is passed to mysqlDeleteFromTable; it may allow SQL injection"},
+			},
+		},
+		{
+			"var secret << \"s3cr3t\";\njwtCreateToken(\"user\", secret, 1);\njwtCreateToken(\"user\", dotenvGet(\"SECRET\"), 1);",
+			[]Diagnostic{
+				{Line: 2, Column: 24, Rule: HardcodedSecret, Message: "hard-coded secret passed to jwtCreateToken; load it with dotenvGet instead"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		diagnostics, err := Source([]byte(tt.input), ".")
+		if err != nil {
+			t.Fatalf("%q: unexpected error %s", tt.input, err)
+		}
+
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("%q: wrong number of diagnostics. want=%d, got=%v", tt.input, len(tt.expected), diagnostics)
+			continue
+		}
+
+		for i, d := range diagnostics {
+			if d != tt.expected[i] {
+				t.Errorf("%q: diagnostic %d wrong.\nwant=%+v\ngot =%+v", tt.input, i, tt.expected[i], d)
+			}
+		}
+	}
+}
+
+func TestDisableComments(t *testing.T) {
+	input := `var f << fct(a) {
+    var x << 1; // zumbra-lint-disable-line unused-variable
+    // zumbra-lint-disable-next-line
+    var y << 1;
+    var z << 1; // zumbra-lint-disable-line shadow
+};
+f(1);`
+
+	diagnostics, err := Source([]byte(input), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"unused-parameter 1",
+		"unused-variable 5",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("wrong diagnostics: %v", diagnostics)
+	}
+	for i, d := range diagnostics {
+		if got := fmt.Sprintf("%s %d", d.Rule, d.Line); got != expected[i] {
+			t.Errorf("diagnostic %d wrong. want=%q, got=%q", i, expected[i], got)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.zum"), []byte(`var counter << 0;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(dir, "main.zum")
+	if err := os.WriteFile(main, []byte("import \"lib.zum\"\ncounter << counter + 1;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics, err := File(main)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diagnostics)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"zumbra/lint"
+)
+
+// lintCommand implements `zumbra lint [-format json|text] paths...`. It
+// returns false when a file has problems or could not be linted.
+func lintCommand(args []string) bool {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	output := flags.String("format", "json", "output format: json or text")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || (*output != "json" && *output != "text") {
+		fmt.Println("usage: zumbra lint [-format json|text] files...")
+		return false
+	}
+
+	files, err := zumbraFiles(flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	ok := true
+	diagnostics := []lint.Diagnostic{}
+	for _, file := range files {
+		found, err := lint.File(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, err)
+			ok = false
+			continue
+		}
+		diagnostics = append(diagnostics, found...)
+	}
+
+	if *output == "text" {
+		for _, d := range diagnostics {
+			fmt.Println(d)
+		}
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(diagnostics)
+	}
+
+	return ok && len(diagnostics) == 0
+}
@@ -0,0 +1,420 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"zumbra/ast"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/token"
+)
+
+// declaration is a name introduced by var, a parameter, a for loop or an
+// enum, possibly in an imported file.
+type declaration struct {
+	name   *ast.Identifier
+	uri    string
+	kind   int
+	detail string
+	doc    string
+	scope  *scope
+}
+
+type reference struct {
+	name *ast.Identifier
+	decl *declaration
+}
+
+type importPath struct {
+	path *ast.StringLiteral
+	file string
+}
+
+// scope follows the compiler: the program and every function literal have
+// one. end is the zero token when the function was never closed.
+type scope struct {
+	outer        *scope
+	table        *compiler.SymbolTable
+	start, end   token.Token
+	declarations []*declaration
+	names        map[string]*declaration
+}
+
+func (s *scope) contains(pos token.Token) bool {
+	if s.outer == nil {
+		return true
+	}
+	return !before(pos, s.start) && (s.end.Line == 0 || before(pos, s.end))
+}
+
+type document struct {
+	uri     string
+	dir     string
+	text    string
+	lines   lines
+	program *ast.Program
+	errors  []parser.ParseError
+
+	declarations []*declaration
+	references   []reference
+	imports      []importPath
+	scopes       []*scope
+
+	imported map[string]bool
+	files    map[string]lines
+	current  *scope
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:      uri,
+		dir:      filepath.Dir(uriToPath(uri)),
+		text:     text,
+		lines:    splitLines(text),
+		imported: map[string]bool{},
+	}
+	d.files = map[string]lines{uri: d.lines}
+
+	p := parser.New(lexer.New(text))
+	d.program = p.ParseProgram()
+	d.errors = p.ParseErrors()
+
+	table := compiler.NewSymbolTable()
+	for i, b := range builtins.Builtins {
+		table.DefineBuiltin(i, b.Name)
+	}
+	d.current = &scope{table: table, names: map[string]*declaration{}}
+	d.scopes = append(d.scopes, d.current)
+
+	d.statements(d.program.Statements)
+	return d
+}
+
+func (d *document) enterScope(start, end token.Token) {
+	d.current = &scope{
+		outer: d.current,
+		table: compiler.NewEnclosedSymbolTable(d.current.table),
+		start: start,
+		end:   end,
+		names: map[string]*declaration{},
+	}
+	d.scopes = append(d.scopes, d.current)
+}
+
+func (d *document) leaveScope() {
+	d.current = d.current.outer
+}
+
+func (d *document) define(decl *declaration) {
+	decl.scope = d.current
+	d.current.table.Define(decl.name.Value)
+	d.current.names[decl.name.Value] = decl
+	d.current.declarations = append(d.current.declarations, decl)
+	if decl.uri == d.uri {
+		d.declarations = append(d.declarations, decl)
+	}
+}
+
+func (d *document) lookup(name string) *declaration {
+	if symbol, ok := d.current.table.Resolve(name); !ok || symbol.Scope == compiler.BuiltinScope {
+		return nil
+	}
+	for s := d.current; s != nil; s = s.outer {
+		if decl, ok := s.names[name]; ok {
+			return decl
+		}
+	}
+	return nil
+}
+
+func (d *document) statements(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		d.statement(stmt)
+	}
+}
+
+func (d *document) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.VarStatement:
+		if s.Name == nil {
+			return
+		}
+		d.define(varDeclaration(s, d.uri))
+		d.expression(s.Value)
+
+	case *ast.AssignStatement:
+		d.identifier(s.Name)
+		d.expression(s.Value)
+
+	case *ast.ReturnStatement:
+		d.expression(s.ReturnValue)
+
+	case *ast.YieldStatement:
+		d.expression(s.Value)
+
+	case *ast.ExpressionStatement:
+		d.expression(s.Expression)
+
+	case *ast.WhileStatement:
+		d.expression(s.Condition)
+		d.block(s.Body)
+
+	case *ast.ForStatement:
+		d.expression(s.Iterable)
+		if s.Variable != nil {
+			d.define(&declaration{name: s.Variable, uri: d.uri, kind: symbolVariable, detail: "var " + s.Variable.Value})
+		}
+		d.block(s.Body)
+
+	case *ast.EnumStatement:
+		if s.Name != nil {
+			d.define(enumDeclaration(s, d.uri))
+		}
+
+	case *ast.ImportStatement:
+		if s.Path == nil {
+			return
+		}
+		file := filepath.Clean(filepath.Join(d.dir, s.Path.Value))
+		d.imports = append(d.imports, importPath{path: s.Path, file: file})
+		d.importFile(file)
+	}
+}
+
+func (d *document) block(b *ast.BlockStatement) {
+	if b != nil {
+		d.statements(b.Statements)
+	}
+}
+
+func (d *document) identifier(ident *ast.Identifier) {
+	if decl := d.lookup(ident.Value); decl != nil {
+		d.references = append(d.references, reference{name: ident, decl: decl})
+	}
+}
+
+func (d *document) expression(exp ast.Expression) {
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		d.identifier(e)
+
+	case *ast.PrefixExpression:
+		d.expression(e.Right)
+
+	case *ast.InfixExpression:
+		d.expression(e.Left)
+		d.expression(e.Right)
+
+	case *ast.CallExpression:
+		d.expression(e.Function)
+		for _, arg := range e.Arguments {
+			d.expression(arg)
+		}
+
+	case *ast.IndexExpression:
+		d.expression(e.Left)
+		d.expression(e.Index)
+
+	case *ast.AttributeAccess:
+		d.expression(e.Object)
+
+	case *ast.ArrayLiteral:
+		for _, el := range e.Elements {
+			d.expression(el)
+		}
+
+	case *ast.SetLiteral:
+		for _, el := range e.Elements {
+			d.expression(el)
+		}
+
+	case *ast.DictLiteral:
+		for _, key := range ast.SortedKeys(e) {
+			d.expression(key)
+			d.expression(e.Pairs[key])
+		}
+
+	case *ast.IfExpression:
+		d.expression(e.Condition)
+		d.block(e.Consequence)
+		d.block(e.Alternative)
+
+	case *ast.MatchExpression:
+		d.expression(e.Subject)
+		for _, arm := range e.Arms {
+			d.expression(arm.Pattern)
+			d.block(arm.Body)
+		}
+
+	case *ast.FunctionLiteral:
+		var end token.Token
+		if e.Body != nil {
+			end = e.Body.Rbrace
+		}
+		d.enterScope(e.Token, end)
+		if e.Name != "" {
+			d.current.table.DefineFunctionName(e.Name)
+		}
+		for _, param := range e.Parameters {
+			d.define(&declaration{name: param, uri: d.uri, kind: symbolVariable, detail: "parameter " + param.Value})
+		}
+		d.block(e.Body)
+		d.leaveScope()
+	}
+}
+
+// importFile declares the top-level names of an imported file, which the
+// compiler adds to the importing program's globals.
+func (d *document) importFile(file string) {
+	if d.imported[file] {
+		return
+	}
+	d.imported[file] = true
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+
+	uri := pathToURI(file)
+	d.files[uri] = splitLines(string(src))
+	program := parser.New(lexer.New(string(src))).ParseProgram()
+
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			if s.Name != nil {
+				d.define(varDeclaration(s, uri))
+			}
+		case *ast.EnumStatement:
+			if s.Name != nil {
+				d.define(enumDeclaration(s, uri))
+			}
+		case *ast.ImportStatement:
+			if s.Path != nil {
+				d.importFile(filepath.Clean(filepath.Join(filepath.Dir(file), s.Path.Value)))
+			}
+		}
+	}
+}
+
+func varDeclaration(s *ast.VarStatement, uri string) *declaration {
+	decl := &declaration{name: s.Name, uri: uri, kind: symbolVariable, detail: "var " + s.Name.Value}
+
+	if s.Type != nil {
+		decl.detail += ": " + s.Type.String()
+	}
+	if fl, ok := s.Value.(*ast.FunctionLiteral); ok {
+		decl.kind = symbolFunction
+		decl.detail = "var " + s.Name.Value + " << " + functionSignature(fl)
+	}
+	decl.doc = s.Doc.Text()
+
+	return decl
+}
+
+func enumDeclaration(s *ast.EnumStatement, uri string) *declaration {
+	variants := []string{}
+	for _, v := range s.Variants {
+		variants = append(variants, v.Value)
+	}
+
+	return &declaration{
+		name:   s.Name,
+		uri:    uri,
+		kind:   symbolEnum,
+		detail: fmt.Sprintf("enum %s { %s }", s.Name.Value, strings.Join(variants, ", ")),
+		doc:    s.Doc.Text(),
+	}
+}
+
+func functionSignature(fl *ast.FunctionLiteral) string {
+	params := []string{}
+	for _, p := range fl.Parameters {
+		if p.Type != nil {
+			params = append(params, p.Value+": "+p.Type.String())
+		} else {
+			params = append(params, p.Value)
+		}
+	}
+
+	signature := "fct(" + strings.Join(params, ", ") + ")"
+	if fl.ReturnType != nil {
+		signature += ": " + fl.ReturnType.String()
+	}
+	return signature
+}
+
+// identifierAt returns the identifier under pos and what it refers to.
+// Builtins have no declaration.
+func (d *document) identifierAt(pos token.Token) (*ast.Identifier, *declaration) {
+	for _, decl := range d.declarations {
+		if covers(decl.name.Token, len(decl.name.Value), pos) {
+			return decl.name, decl
+		}
+	}
+	for _, ref := range d.references {
+		if covers(ref.name.Token, len(ref.name.Value), pos) {
+			return ref.name, ref.decl
+		}
+	}
+
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Identifier); ok && covers(ident.Token, len(ident.Value), pos) {
+			found = ident
+		}
+		return found == nil
+	})
+	return found, nil
+}
+
+func (d *document) importAt(pos token.Token) (importPath, bool) {
+	for _, imp := range d.imports {
+		// The token starts at the opening quote.
+		if covers(imp.path.Token, len(imp.path.Value)+1, pos) {
+			return imp, true
+		}
+	}
+	return importPath{}, false
+}
+
+// visible returns the declarations that code at pos can refer to, innermost
+// first.
+func (d *document) visible(pos token.Token) []*declaration {
+	innermost := d.scopes[0]
+	for _, s := range d.scopes {
+		if s.contains(pos) && (innermost.outer == nil || !before(s.start, innermost.start)) {
+			innermost = s
+		}
+	}
+
+	result := []*declaration{}
+	seen := map[string]bool{}
+	for s := innermost; s != nil; s = s.outer {
+		for i := len(s.declarations) - 1; i >= 0; i-- {
+			decl := s.declarations[i]
+			if seen[decl.name.Value] {
+				continue
+			}
+			if decl.uri == d.uri && !before(decl.name.Token, pos) {
+				continue
+			}
+			seen[decl.name.Value] = true
+			result = append(result, decl)
+		}
+	}
+	return result
+}
+
+func before(a, b token.Token) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+func covers(tok token.Token, length int, pos token.Token) bool {
+	return tok.Line == pos.Line && pos.Column >= tok.Column && pos.Column <= tok.Column+length
+}
@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf16"
+)
+
+// The subset of the Language Server Protocol the server speaks. Lines and
+// characters are zero-based, while Zumbra tokens count both from one.
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	invalidRequest       = -32600
+	methodNotFound       = -32601
+	invalidParams        = -32602
+	internalError        = -32603
+	serverNotInitialized = -32002
+)
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// lines is the text of a file split at newlines. The lexer counts columns
+// in bytes while LSP counts characters in UTF-16 code units, so positions
+// are converted with the line they are on.
+type lines []string
+
+func splitLines(text string) lines {
+	return strings.Split(text, "\n")
+}
+
+// position converts a 1-based line and byte column to an LSP position.
+func (ls lines) position(line, column int) Position {
+	line, column = max(line-1, 0), max(column-1, 0)
+	if line >= len(ls) {
+		return Position{Line: line, Character: column}
+	}
+
+	text := ls[line]
+	character := max(column-len(text), 0)
+	for _, r := range text[:min(column, len(text))] {
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// column converts an LSP position to the 1-based byte column on its line.
+func (ls lines) column(pos Position) int {
+	if pos.Line < 0 || pos.Line >= len(ls) {
+		return pos.Character + 1
+	}
+
+	text := ls[pos.Line]
+	character := 0
+	for i, r := range text {
+		if character >= pos.Character {
+			return i + 1
+		}
+		character += utf16.RuneLen(r)
+	}
+	return len(text) + max(pos.Character-character, 0) + 1
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didSaveParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Text         *string                `json:"text"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type documentSymbolParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents markupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+const (
+	completionFunction = 3
+	completionVariable = 6
+	completionEnum     = 13
+	completionKeyword  = 14
+)
+
+type CompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+const (
+	symbolEnum       = 10
+	symbolFunction   = 12
+	symbolVariable   = 13
+	symbolEnumMember = 22
+)
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
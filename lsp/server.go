@@ -0,0 +1,486 @@
+// Package lsp implements a Language Server Protocol server for Zumbra over
+// stdio. It publishes diagnostics when a file is opened or saved and
+// answers go-to-definition, hover, completion and document symbol
+// requests.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"zumbra/ast"
+	"zumbra/checker"
+	"zumbra/lint"
+	"zumbra/object/builtins"
+	"zumbra/token"
+)
+
+type Server struct {
+	in          *bufio.Reader
+	out         io.Writer
+	documents   map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) read() ([]byte, error) {
+	length := -1
+
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %s", value)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.in, body)
+	return body, err
+}
+
+func (s *Server) write(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.write(response{JSONRPC: "2.0", ID: id, Result: body})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, message string) error {
+	return s.write(response{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: message}})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) handle(req request) (err error) {
+	// Half-typed code can produce partial trees; a bug in handling one
+	// should fail the request, not the editor session.
+	defer func() {
+		if r := recover(); r != nil && req.ID != nil {
+			err = s.replyError(req.ID, internalError, fmt.Sprintf("internal error: %v", r))
+		}
+	}()
+
+	if !s.initialized && req.Method != "initialize" {
+		if req.ID == nil {
+			return nil
+		}
+		return s.replyError(req.ID, serverNotInitialized, "server not initialized")
+	}
+
+	if s.shutdown && req.ID != nil {
+		return s.replyError(req.ID, invalidRequest, "server is shutting down")
+	}
+
+	switch req.Method {
+	case "initialize":
+		s.initialized = true
+		return s.reply(req.ID, map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"positionEncoding": "utf-16",
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1,
+					"save":      map[string]bool{"includeText": true},
+				},
+				"definitionProvider":     true,
+				"hoverProvider":          true,
+				"documentSymbolProvider": true,
+				"completionProvider":     map[string]interface{}{"triggerCharacters": []string{}},
+			},
+			"serverInfo": map[string]string{"name": "zumbra"},
+		})
+
+	case "initialized":
+		return nil
+
+	case "shutdown":
+		s.shutdown = true
+		return s.reply(req.ID, nil)
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		s.open(params.TextDocument.URI, params.TextDocument.Text)
+		return s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params.ContentChanges) == 0 {
+			return nil
+		}
+		changes := params.ContentChanges
+		s.open(params.TextDocument.URI, changes[len(changes)-1].Text)
+		return nil
+
+	case "textDocument/didSave":
+		var params didSaveParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		if params.Text != nil {
+			s.open(params.TextDocument.URI, *params.Text)
+		}
+		return s.publishDiagnostics(params.TextDocument.URI)
+
+	case "textDocument/didClose":
+		var params didSaveParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+
+	case "textDocument/definition":
+		return s.positionRequest(req, s.definition)
+
+	case "textDocument/hover":
+		return s.positionRequest(req, s.hover)
+
+	case "textDocument/completion":
+		return s.positionRequest(req, s.completion)
+
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return s.replyError(req.ID, invalidParams, err.Error())
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return s.reply(req.ID, []DocumentSymbol{})
+		}
+		return s.reply(req.ID, documentSymbols(doc.lines, doc.program.Statements))
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+	return s.replyError(req.ID, methodNotFound, "method not supported: "+req.Method)
+}
+
+func (s *Server) open(uri, text string) {
+	s.documents[uri] = newDocument(uri, text)
+}
+
+func (s *Server) positionRequest(req request, answer func(*document, token.Token) interface{}) error {
+	var params textDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.replyError(req.ID, invalidParams, err.Error())
+	}
+
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return s.reply(req.ID, nil)
+	}
+
+	pos := token.Token{Line: params.Position.Line + 1, Column: doc.lines.column(params.Position)}
+	return s.reply(req.ID, answer(doc, pos))
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil
+	}
+
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diagnostics(doc),
+	})
+}
+
+// diagnostics reports parse errors or, when the file parses, type errors
+// and lint warnings.
+func diagnostics(doc *document) []Diagnostic {
+	result := []Diagnostic{}
+
+	if len(doc.errors) != 0 {
+		for _, e := range doc.errors {
+			result = append(result, Diagnostic{
+				Range:    doc.lines.pointRange(e.Line, e.Column),
+				Severity: severityError,
+				Source:   "zumbra",
+				Message:  e.Message,
+			})
+		}
+		return result
+	}
+
+	for _, e := range checker.Check(doc.program) {
+		result = append(result, Diagnostic{
+			Range:    doc.lines.pointRange(e.Line, e.Column),
+			Severity: severityError,
+			Source:   "zumbra check",
+			Message:  e.Message,
+		})
+	}
+
+	warnings, err := lint.Source([]byte(doc.text), doc.dir)
+	if err == nil {
+		for _, w := range warnings {
+			result = append(result, Diagnostic{
+				Range:    doc.lines.pointRange(w.Line, w.Column),
+				Severity: severityWarning,
+				Code:     w.Rule,
+				Source:   "zumbra lint",
+				Message:  w.Message,
+			})
+		}
+	}
+
+	return result
+}
+
+func (s *Server) definition(doc *document, pos token.Token) interface{} {
+	if imp, ok := doc.importAt(pos); ok {
+		return Location{URI: pathToURI(imp.file)}
+	}
+
+	_, decl := doc.identifierAt(pos)
+	if decl == nil {
+		return nil
+	}
+	return Location{URI: decl.uri, Range: doc.files[decl.uri].nameRange(decl.name)}
+}
+
+func (s *Server) hover(doc *document, pos token.Token) interface{} {
+	ident, decl := doc.identifierAt(pos)
+	if ident == nil {
+		return nil
+	}
+
+	var text string
+	switch {
+	case decl != nil:
+		text = "```zumbra\n" + decl.detail + "\n```"
+		if decl.doc != "" {
+			text += "\n\n" + decl.doc
+		}
+	default:
+		signature, ok := builtins.Signatures[ident.Value]
+		if !ok || builtins.GetBuiltinByName(ident.Value) == nil {
+			return nil
+		}
+		text = "```zumbra\n" + signature + "\n```\n\nbuiltin"
+	}
+
+	return Hover{
+		Contents: markupContent{Kind: "markdown", Value: text},
+		Range:    doc.lines.nameRange(ident),
+	}
+}
+
+var keywords = []string{
+	"else", "enum", "false", "fct", "for", "if", "import", "in", "match", "return", "true", "var", "while", "yield",
+}
+
+func (s *Server) completion(doc *document, pos token.Token) interface{} {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+
+	for _, decl := range doc.visible(pos) {
+		kind := completionVariable
+		switch decl.kind {
+		case symbolFunction:
+			kind = completionFunction
+		case symbolEnum:
+			kind = completionEnum
+		}
+		seen[decl.name.Value] = true
+		items = append(items, CompletionItem{Label: decl.name.Value, Kind: kind, Detail: decl.detail, Documentation: decl.doc})
+	}
+
+	names := []string{}
+	for _, b := range builtins.Builtins {
+		names = append(names, b.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		items = append(items, CompletionItem{Label: name, Kind: completionFunction, Detail: builtins.Signatures[name]})
+	}
+
+	for _, keyword := range keywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+
+	return items
+}
+
+func documentSymbols(ls lines, stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.VarStatement:
+			if s.Name == nil {
+				continue
+			}
+			decl := varDeclaration(s, "")
+			symbol := DocumentSymbol{
+				Name:           s.Name.Value,
+				Detail:         decl.detail,
+				Kind:           decl.kind,
+				Range:          ls.nodeRange(s),
+				SelectionRange: ls.nameRange(s.Name),
+			}
+			if fl, ok := s.Value.(*ast.FunctionLiteral); ok && fl.Body != nil {
+				symbol.Children = documentSymbols(ls, fl.Body.Statements)
+			}
+			symbols = append(symbols, symbol)
+
+		case *ast.EnumStatement:
+			if s.Name == nil {
+				continue
+			}
+			symbol := DocumentSymbol{
+				Name:           s.Name.Value,
+				Detail:         enumDeclaration(s, "").detail,
+				Kind:           symbolEnum,
+				Range:          ls.nodeRange(s),
+				SelectionRange: ls.nameRange(s.Name),
+			}
+			for _, v := range s.Variants {
+				symbol.Children = append(symbol.Children, DocumentSymbol{
+					Name:           v.Value,
+					Kind:           symbolEnumMember,
+					Range:          ls.nameRange(v),
+					SelectionRange: ls.nameRange(v),
+				})
+			}
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	return symbols
+}
+
+func (ls lines) pointRange(line, column int) Range {
+	pos := ls.position(line, column)
+	return Range{Start: pos, End: pos}
+}
+
+func (ls lines) nameRange(ident *ast.Identifier) Range {
+	tok := ident.Token
+	return Range{
+		Start: ls.position(tok.Line, tok.Column),
+		End:   ls.position(tok.Line, tok.Column+len(ident.Value)),
+	}
+}
+
+// nodeRange spans from the first token of node to the end of the last
+// token known to be in it.
+func (ls lines) nodeRange(node ast.Node) Range {
+	start := ast.StartToken(node)
+	end := start
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if tok := ast.StartToken(n); before(end, tok) {
+			end = tok
+		}
+		if b, ok := n.(*ast.BlockStatement); ok && before(end, b.Rbrace) {
+			end = b.Rbrace
+		}
+		return true
+	})
+
+	length := len(end.Literal)
+	if end.Type == token.STRING {
+		length += 2
+	}
+	return Range{
+		Start: ls.position(start.Line, start.Column),
+		End:   ls.position(end.Line, end.Column+length),
+	}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,411 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type session struct {
+	t      *testing.T
+	input  bytes.Buffer
+	nextID int
+}
+
+func (s *session) send(method string, params interface{}) {
+	s.sendMessage(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *session) request(method string, params interface{}) int {
+	s.nextID++
+	s.sendMessage(map[string]interface{}{"jsonrpc": "2.0", "id": s.nextID, "method": method, "params": params})
+	return s.nextID
+}
+
+func (s *session) sendMessage(message interface{}) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	fmt.Fprintf(&s.input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+type received struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// run sends everything queued to a new server and returns what it wrote.
+func (s *session) run() []received {
+	s.t.Helper()
+
+	var output bytes.Buffer
+	if err := NewServer(&s.input, &output).Serve(); err != nil {
+		s.t.Fatalf("server error: %s", err)
+	}
+
+	messages := []received{}
+	reader := bufio.NewReader(&output)
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			s.t.Fatalf("bad header %q", header)
+		}
+		reader.ReadString('\n')
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			s.t.Fatal(err)
+		}
+
+		var msg received
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func result(t *testing.T, messages []received, id int, value interface{}) {
+	t.Helper()
+	for _, msg := range messages {
+		if msg.ID != nil && *msg.ID == id {
+			if msg.Error != nil {
+				t.Fatalf("request %d failed: %s", id, msg.Error.Message)
+			}
+			if err := json.Unmarshal(msg.Result, value); err != nil {
+				t.Fatalf("request %d: %s", id, err)
+			}
+			return
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+}
+
+func diagnosticsFor(t *testing.T, messages []received, uri string) [][]Diagnostic {
+	t.Helper()
+	published := [][]Diagnostic{}
+	for _, msg := range messages {
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params publishDiagnosticsParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.URI == uri {
+			published = append(published, params.Diagnostics)
+		}
+	}
+	return published
+}
+
+func newSession(t *testing.T) *session {
+	s := &session{t: t}
+	s.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	s.send("initialized", map[string]interface{}{})
+	return s
+}
+
+func (s *session) finish() []received {
+	s.request("shutdown", nil)
+	s.send("exit", nil)
+	return s.run()
+}
+
+func at(uri string, line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line, "character": character},
+	}
+}
+
+func open(s *session, uri, text string) {
+	s.send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "zumbra", "version": 1, "text": text},
+	})
+}
+
+func TestInitialize(t *testing.T) {
+	s := &session{t: t}
+	before := s.request("hover", at("file:///a.zum", 0, 0))
+	id := s.request("initialize", map[string]interface{}{})
+	messages := s.finish()
+
+	for _, msg := range messages {
+		if msg.ID != nil && *msg.ID == before && (msg.Error == nil || msg.Error.Code != serverNotInitialized) {
+			t.Errorf("request before initialize was not rejected: %+v", msg)
+		}
+	}
+
+	var initialized struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	result(t, messages, id, &initialized)
+
+	for _, capability := range []string{"positionEncoding", "definitionProvider", "hoverProvider", "documentSymbolProvider", "completionProvider", "textDocumentSync"} {
+		if _, ok := initialized.Capabilities[capability]; !ok {
+			t.Errorf("capability %s missing", capability)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	uri := "file:///tmp/main.zum"
+
+	s := newSession(t)
+	open(s, uri, "var x << ;")
+	s.send("textDocument/didSave", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"text":         "var f << fct(a) { 1 }; f(1);\nvar n: int << \"a\";",
+	})
+	published := diagnosticsFor(t, s.finish(), uri)
+
+	if len(published) != 2 {
+		t.Fatalf("expected diagnostics on open and on save, got %d", len(published))
+	}
+
+	if len(published[0]) != 1 || published[0][0].Severity != severityError || published[0][0].Range.Start != (Position{0, 9}) {
+		t.Errorf("wrong parse error diagnostics: %+v", published[0])
+	}
+
+	got := []string{}
+	for _, d := range published[1] {
+		got = append(got, fmt.Sprintf("%d:%d %d %s", d.Range.Start.Line, d.Range.Start.Character, d.Severity, d.Message))
+	}
+	expected := []string{
+		"1:14 1 cannot assign string to n of type int",
+		"0:13 2 parameter a is never used",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics.\nwant=%q\ngot =%q", expected, got)
+	}
+}
+
+func TestDefinitionAndHover(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.zum")
+	if err := os.WriteFile(lib, []byte("/// Doubles x.\nvar double << fct(x) { x * 2 };\n/// Primary colors.\nenum Color { Red, Blue }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(filepath.Join(dir, "main.zum"))
+
+	s := newSession(t)
+	open(s, uri, `import "lib.zum"
+var total << 1;
+var add << fct(total) { total + double(2) };
+show(add(total));
+show(Color.Red);`)
+	local := s.request("textDocument/definition", at(uri, 2, 25))
+	global := s.request("textDocument/definition", at(uri, 3, 10))
+	imported := s.request("textDocument/definition", at(uri, 2, 33))
+	file := s.request("textDocument/definition", at(uri, 0, 10))
+	builtinHover := s.request("textDocument/hover", at(uri, 3, 1))
+	importedHover := s.request("textDocument/hover", at(uri, 2, 33))
+	enumHover := s.request("textDocument/hover", at(uri, 4, 6))
+	nothing := s.request("textDocument/hover", at(uri, 1, 13))
+	messages := s.finish()
+
+	var location Location
+	result(t, messages, local, &location)
+	if location.URI != uri || location.Range.Start != (Position{2, 15}) {
+		t.Errorf("wrong definition of parameter: %+v", location)
+	}
+
+	result(t, messages, global, &location)
+	if location.URI != uri || location.Range.Start != (Position{1, 4}) {
+		t.Errorf("wrong definition of global: %+v", location)
+	}
+
+	result(t, messages, imported, &location)
+	if location.URI != pathToURI(lib) || location.Range.Start != (Position{1, 4}) {
+		t.Errorf("wrong definition of imported variable: %+v", location)
+	}
+
+	result(t, messages, file, &location)
+	if location.URI != pathToURI(lib) {
+		t.Errorf("wrong definition of import path: %+v", location)
+	}
+
+	var hover Hover
+	result(t, messages, builtinHover, &hover)
+	if !strings.Contains(hover.Contents.Value, "show(...values: any): null") {
+		t.Errorf("wrong builtin hover: %q", hover.Contents.Value)
+	}
+
+	result(t, messages, importedHover, &hover)
+	if hover.Contents.Value != "```zumbra\nvar double << fct(x)\n```\n\nDoubles x." {
+		t.Errorf("wrong variable hover: %q", hover.Contents.Value)
+	}
+
+	result(t, messages, enumHover, &hover)
+	if hover.Contents.Value != "```zumbra\nenum Color { Red, Blue }\n```\n\nPrimary colors." {
+		t.Errorf("wrong enum hover: %q", hover.Contents.Value)
+	}
+
+	var empty *Hover
+	result(t, messages, nothing, &empty)
+	if empty != nil {
+		t.Errorf("expected no hover over a literal, got %+v", empty)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	uri := "file:///tmp/main.zum"
+
+	s := newSession(t)
+	open(s, uri, `var greeting << "hi";
+var greet << fct(name) {
+    var message << greeting + name;
+
+};
+var later << 1;`)
+	inside := s.request("textDocument/completion", at(uri, 3, 4))
+	outside := s.request("textDocument/completion", at(uri, 4, 2))
+	messages := s.finish()
+
+	labels := func(id int) map[string]CompletionItem {
+		var items []CompletionItem
+		result(t, messages, id, &items)
+		found := map[string]CompletionItem{}
+		for _, item := range items {
+			found[item.Label] = item
+		}
+		return found
+	}
+
+	items := labels(inside)
+	for _, name := range []string{"message", "name", "greeting", "greet", "show", "var"} {
+		if _, ok := items[name]; !ok {
+			t.Errorf("completion inside the function is missing %s", name)
+		}
+	}
+	if _, ok := items["later"]; ok {
+		t.Errorf("completion offers a variable declared later")
+	}
+	if items["sizeOf"].Detail != "sizeOf(value: string | [any] | set | iterator): int" {
+		t.Errorf("wrong builtin detail: %q", items["sizeOf"].Detail)
+	}
+
+	items = labels(outside)
+	if _, ok := items["message"]; ok {
+		t.Errorf("completion outside the function offers its locals")
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	uri := "file:///tmp/main.zum"
+
+	s := newSession(t)
+	open(s, uri, `enum Color { Red, Green }
+var paint << fct(c) {
+    var label << "x";
+    label
+};
+var count << 1;`)
+	id := s.request("textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]string{"uri": uri}})
+	messages := s.finish()
+
+	var symbols []DocumentSymbol
+	result(t, messages, id, &symbols)
+
+	got := []string{}
+	var visit func([]DocumentSymbol, string)
+	visit = func(list []DocumentSymbol, indent string) {
+		for _, sym := range list {
+			got = append(got, fmt.Sprintf("%s%s %d %d:%d-%d:%d", indent, sym.Name, sym.Kind,
+				sym.Range.Start.Line, sym.Range.Start.Character, sym.Range.End.Line, sym.Range.End.Character))
+			visit(sym.Children, indent+"  ")
+		}
+	}
+	visit(symbols, "")
+
+	expected := []string{
+		"Color 10 0:0-0:23",
+		"  Red 22 0:13-0:16",
+		"  Green 22 0:18-0:23",
+		"paint 12 1:0-4:1",
+		"  label 13 2:4-2:20",
+		"count 13 5:0-5:14",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong symbols.\nwant=%q\ngot =%q", expected, got)
+	}
+}
+
+func TestIncompleteSource(t *testing.T) {
+	uri := "file:///tmp/main.zum"
+	sources := []string{
+		"var f << fct(a, { if (",
+		"var << fct() { return",
+		"enum { A",
+		"import",
+		"match (x) { A =>",
+		"for (x in ",
+	}
+
+	for _, src := range sources {
+		s := newSession(t)
+		open(s, uri, src)
+		ids := []int{
+			s.request("textDocument/hover", at(uri, 0, 5)),
+			s.request("textDocument/definition", at(uri, 0, 5)),
+			s.request("textDocument/completion", at(uri, 0, 20)),
+			s.request("textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]string{"uri": uri}}),
+		}
+		messages := s.finish()
+
+		for _, id := range ids {
+			for _, msg := range messages {
+				if msg.ID != nil && *msg.ID == id && msg.Error != nil {
+					t.Errorf("%q: request %d failed: %s", src, id, msg.Error.Message)
+				}
+			}
+		}
+	}
+}
+
+func TestPositionsCountUTF16(t *testing.T) {
+	uri := "file:///tmp/main.zum"
+
+	s := newSession(t)
+	// é takes two bytes and one UTF-16 unit, 😀 four bytes and two units.
+	open(s, uri, "var s << \"héllo😀\"; var n << 1;\nshow(n, \"😀\"); var m: int << \"a\";")
+	definition := s.request("textDocument/definition", at(uri, 1, 5))
+	hover := s.request("textDocument/hover", at(uri, 0, 24))
+	messages := s.finish()
+
+	var location Location
+	result(t, messages, definition, &location)
+	if location.Range != (Range{Position{0, 24}, Position{0, 25}}) {
+		t.Errorf("wrong definition range: %+v", location.Range)
+	}
+
+	var h Hover
+	result(t, messages, hover, &h)
+	if h.Contents.Value != "```zumbra\nvar n\n```" {
+		t.Errorf("wrong hover after multi-byte characters: %q", h.Contents.Value)
+	}
+
+	published := diagnosticsFor(t, messages, uri)
+	if len(published) != 1 || len(published[0]) != 1 || published[0][0].Range.Start != (Position{1, 29}) {
+		t.Errorf("wrong diagnostics: %+v", published)
+	}
+}
@@ -4,30 +4,112 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
+	"strings"
 
+	"zumbra/bundle"
+	"zumbra/checker"
 	"zumbra/compiler"
 	"zumbra/lexer"
+	"zumbra/lsp"
 	"zumbra/object"
 	"zumbra/object/builtins"
 	"zumbra/parser"
+	"zumbra/project"
 	"zumbra/repl"
-	"zumbra/transpiler"
 	"zumbra/vm"
 )
 
 func main() {
+	runBundle()
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
-	if len(os.Args) > 2 && os.Args[1] == "build" {
-		err := buildZumbra(os.Args[2])
-		if err != nil {
-			fmt.Printf("Error when trying to build the file: %s\n", err)
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		if !buildCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 2 && os.Args[1] == "check" {
+		if !checkFile(os.Args[2]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		if !formatCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		if !lintCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "test" {
+		if !testCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		if !initCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "pkg" {
+		if !pkgCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "compile" {
+		if !compileCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		if !runCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "disasm" {
+		if !disasmCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		if !debugCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 		return
 	}
@@ -46,6 +128,117 @@ func main() {
 }
 
 func runFile(filename string) {
+	code := loadBytecode(filename)
+	if code == nil || !loadProjectEnv(filename) {
+		return
+	}
+
+	globals := make([]object.Object, vm.GlobalSize)
+	machine := vm.NewWithGlobalsStore(code, globals)
+	err := machine.Run()
+	if err != nil {
+		fmt.Printf("Error on VM execution: %s\n", err)
+		return
+	}
+
+	machine.LastPoppedStackElem()
+}
+
+// runBundle runs the program bundled into the running executable, if it
+// carries one, and exits: executables made by `zumbra build --bundle` only
+// run their program. It runs on every invocation, so only a corrupt bundle
+// stops zumbra; an executable without one, or that cannot be read, runs the
+// command line.
+func runBundle() {
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	data, err := bundle.Read(self)
+	if err != nil {
+		fmt.Printf("Error when trying to load the bundled program: %s\n", err)
+		os.Exit(1)
+	}
+	if data == nil {
+		return
+	}
+
+	code, err := compiler.UnmarshalBytecode(data)
+	if err != nil {
+		fmt.Printf("Error when trying to load the bundled program: %s\n", err)
+		os.Exit(1)
+	}
+
+	machine := vm.NewWithGlobalsStore(code, make([]object.Object, vm.GlobalSize))
+	if err := machine.Run(); err != nil {
+		fmt.Printf("Error on VM execution: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// loadBytecode returns the bytecode to run for filename, printing what went
+// wrong and returning nil when there is none. A .zbc file is read as it is;
+// sources are compiled unless the compile cache holds them.
+func loadBytecode(filename string) *compiler.Bytecode {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error when trying to read the file: %s\n", err)
+		os.Exit(1)
+	}
+
+	if filepath.Ext(filename) == ".zbc" {
+		code, err := compiler.UnmarshalBytecode(data)
+		if err != nil {
+			fmt.Printf("Error when trying to load %s: %s\n", filename, err)
+			return nil
+		}
+		return code
+	}
+
+	absPath, err := filepath.Abs(filename)
+	if err != nil {
+		fmt.Printf("Path error: %s\n", err)
+		return nil
+	}
+
+	// The import paths decide which files get imported, and the
+	// optimization level the code that is emitted, so they are part of the
+	// key.
+	key := absPath + fmt.Sprintf("\x00O%d", optimization)
+	if m := projectFor(filename); m != nil {
+		key += "\x00" + strings.Join(m.ImportPaths(), "\x00")
+	}
+
+	cache := compiler.DefaultCache()
+	if cache != nil {
+		if code, ok := cache.Load(key, data); ok {
+			return code
+		}
+	}
+
+	comp, _ := compileFile(filename)
+	if comp == nil {
+		return nil
+	}
+
+	code := comp.Bytecode()
+	if cache != nil {
+		// A program that cannot be cached still runs.
+		cache.Store(key, data, code, comp.Imports())
+	}
+	return code
+}
+
+// optimization is the level compileFile optimizes programs at. The commands
+// that compile set it with their -O flag.
+var optimization = compiler.DefaultOptimization
+
+// compileFile parses, checks and compiles a program, printing what went
+// wrong and returning nil when it cannot run. The symbol table holds the
+// program's globals.
+func compileFile(filename string) (*compiler.Compiler, *compiler.SymbolTable) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		fmt.Printf("Error when trying to read the file: %s\n", err)
@@ -54,7 +247,6 @@ func runFile(filename string) {
 
 	source := string(data)
 	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalSize)
 	symbolTable := compiler.NewSymbolTable()
 
 	for i, v := range builtins.Builtins {
@@ -70,92 +262,87 @@ func runFile(filename string) {
 		for _, msg := range p.Errors() {
 			fmt.Println("\t" + msg)
 		}
-		return
+		return nil, nil
+	}
+
+	if typeErrors := checker.Check(program); checker.Annotated(program) && len(typeErrors) != 0 {
+		fmt.Println("Type errors:")
+		for _, e := range typeErrors {
+			fmt.Println("\t" + e.Error())
+		}
+		return nil, nil
 	}
 
 	absPath, err := filepath.Abs(filename)
 	if err != nil {
 		fmt.Printf("Path error: %s\n", err)
-		return
+		return nil, nil
 	}
 	dir := filepath.Dir(absPath)
 
 	comp := compiler.NewWithStateAndDir(symbolTable, constants, dir) // AQUI
-	err = comp.Compile(program)
-	if err != nil {
+	if m := projectFor(filename); m != nil {
+		comp.SetImportPaths(m.ImportPaths())
+	}
+	if err := comp.SetOptimization(optimization); err != nil {
 		fmt.Printf("Compilation error: %s\n", err)
-		return
+		return nil, nil
 	}
-
-	code := comp.Bytecode()
-	constants = code.Constants
-
-	machine := vm.NewWithGlobalsStore(code, globals)
-	err = machine.Run()
+	err = comp.Compile(program)
 	if err != nil {
-		fmt.Printf("Error on VM execution: %s\n", err)
-		return
+		fmt.Printf("Compilation error: %s\n", err)
+		return nil, nil
 	}
 
-	machine.LastPoppedStackElem()
+	return comp, symbolTable
 }
 
-func buildZumbra(filename string) error {
-	data, err := os.ReadFile(filename)
+// projectFor returns the manifest of the project filename belongs to, or
+// nil outside a project. A manifest that cannot be read ends the program.
+func projectFor(filename string) *project.Manifest {
+	m, err := project.Find(filepath.Dir(filename))
 	if err != nil {
-		return fmt.Errorf("Error when trying to read the file: %s\n", err)
-	}
-
-	source := string(data)
-	goCode, err := transpiler.ZumbraTranspiler(source)
-	if err != nil {
-		return fmt.Errorf("erro ao transpilar: %w", err)
+		fmt.Printf("Error when trying to read the project manifest: %s\n", err)
+		os.Exit(1)
 	}
+	return m
+}
 
-	if _, err := os.Stat("build"); err == nil {
-		err := os.RemoveAll("build")
-		if err != nil {
-			return fmt.Errorf("Error when trying to remove build: %w", err)
-		}
+// loadProjectEnv loads the env files of the project filename belongs to.
+func loadProjectEnv(filename string) bool {
+	m := projectFor(filename)
+	if m == nil {
+		return true
 	}
-	err = os.MkdirAll("build", 0755)
-	if err != nil {
-		return fmt.Errorf("Error when trying to create build dir: %w", err)
+	if err := m.LoadEnv(); err != nil {
+		fmt.Printf("Error when trying to load the env files: %s\n", err)
+		return false
 	}
+	return true
+}
 
-	err = os.WriteFile("build/main.go", []byte(goCode), 0644)
+func checkFile(filename string) bool {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return fmt.Errorf("Error when trying to write main.go: %w", err)
+		fmt.Printf("Error when trying to read the file: %s\n", err)
+		return false
 	}
 
-	goModContent := `
-module zumbra-generated
-
-go 1.21
-
-require (
-	github.com/go-sql-driver/mysql v1.9.2
-	github.com/golang-jwt/jwt/v5 v5.2.2
-)
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
 
-`
-	err = os.WriteFile("build/go.mod", []byte(goModContent), 0644)
-	if err != nil {
-		return fmt.Errorf("Error when trying to write go.mod: %w", err)
+	if len(p.Errors()) != 0 {
+		fmt.Println("Parsing errors:")
+		for _, msg := range p.Errors() {
+			fmt.Println("\t" + msg)
+		}
+		return false
 	}
 
-	cmd := exec.Command("go", "mod", "tidy")
-	cmd.Dir = "build"
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
-	if err != nil {
-		return fmt.Errorf("error running go mod tidy: %w", err)
+	typeErrors := checker.Check(program)
+	for _, e := range typeErrors {
+		fmt.Printf("%s:%s\n", filename, e.Error())
 	}
 
-	cmd = exec.Command("go", "build", "-o", "zumbra-app", "main.go")
-	cmd.Dir = "build"
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return len(typeErrors) == 0
 }
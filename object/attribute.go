@@ -0,0 +1,36 @@
+package object
+
+import "fmt"
+
+// Attribute returns obj.name: a field of a date or a variant of an enum.
+func Attribute(obj Object, name string) (Object, error) {
+	switch obj := obj.(type) {
+	case *Date:
+		switch name {
+		case "hour":
+			return &Integer{Value: int64(obj.Hour)}, nil
+		case "minute":
+			return &Integer{Value: int64(obj.Minute)}, nil
+		case "day":
+			return &Integer{Value: int64(obj.Day)}, nil
+		case "second":
+			return &Integer{Value: int64(obj.Second)}, nil
+		case "month":
+			return &Integer{Value: int64(obj.Month)}, nil
+		case "year":
+			return &Integer{Value: int64(obj.Year)}, nil
+		case "fullDate":
+			return &String{Value: obj.FullDate.String()}, nil
+		}
+		return nil, fmt.Errorf("unknown attribute %s for Date", name)
+
+	case *Enum:
+		variant, ok := obj.Variant(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown variant %s for enum %s", name, obj.Name)
+		}
+		return variant, nil
+	}
+
+	return nil, fmt.Errorf("object type %s has no attributes", obj.Type())
+}
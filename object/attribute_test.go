@@ -0,0 +1,57 @@
+package object
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttribute(t *testing.T) {
+	full := time.Date(2024, time.March, 9, 14, 30, 5, 0, time.UTC)
+	date := &Date{FullDate: full, Hour: 14, Minute: 30, Day: 9, Second: 5, Month: time.March, Year: 2024}
+	enum := NewEnum("Status", []string{"Active", "Banned"})
+
+	tests := []struct {
+		obj      Object
+		name     string
+		expected string
+	}{
+		{date, "hour", "14"},
+		{date, "minute", "30"},
+		{date, "day", "9"},
+		{date, "second", "5"},
+		{date, "month", "3"},
+		{date, "year", "2024"},
+		{date, "fullDate", full.String()},
+		{enum, "Banned", "Status.Banned"},
+	}
+
+	for _, tt := range tests {
+		attr, err := Attribute(tt.obj, tt.name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+			continue
+		}
+		if attr.Inspect() != tt.expected {
+			t.Errorf("%s: wrong value. want=%q, got=%q", tt.name, tt.expected, attr.Inspect())
+		}
+	}
+}
+
+func TestAttributeErrors(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		name     string
+		expected string
+	}{
+		{&Date{}, "week", "unknown attribute week for Date"},
+		{NewEnum("Status", []string{"Active"}), "Gone", "unknown variant Gone for enum Status"},
+		{&Integer{Value: 1}, "hour", "object type INTEGER has no attributes"},
+	}
+
+	for _, tt := range tests {
+		_, err := Attribute(tt.obj, tt.name)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. want=%q, got=%v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package builtins
 
 import (
-	"math"
 	"sort"
 	"zumbra/object"
 )
@@ -74,21 +73,14 @@ func MaxBuiltin() *object.Builtin {
 			if len(args) != 1 {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return NewError("argument to `max` must be ARRAY, got %s", args[0].Type())
+			arr, errObj := arrayArgument("max", args[0])
+			if errObj != nil {
+				return errObj
 			}
-
-			arr := args[0].(*object.Array)
 			if len(arr.Elements) == 0 {
 				return nil
 			}
-			max := arr.Elements[0]
-			for _, el := range arr.Elements[1:] {
-				if math.Max(float64(max.(*object.Integer).Value), float64(el.(*object.Integer).Value)) == float64(el.(*object.Integer).Value) {
-					max = el
-				}
-			}
-			return max
+			return extreme("max", arr.Elements, 1)
 		},
 	}
 }
@@ -99,26 +91,35 @@ func MinBuiltin() *object.Builtin {
 			if len(args) != 1 {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return NewError("argument to `min` must be ARRAY, got %s", args[0].Type())
+			arr, errObj := arrayArgument("min", args[0])
+			if errObj != nil {
+				return errObj
 			}
-
-			arr := args[0].(*object.Array)
 			if len(arr.Elements) == 0 {
 				return nil
 			}
 
-			min := arr.Elements[0]
-			for _, el := range arr.Elements[1:] {
-				if math.Min(float64(min.(*object.Integer).Value), float64(el.(*object.Integer).Value)) == float64(el.(*object.Integer).Value) {
-					min = el
-				}
-			}
-			return min
+			return extreme("min", arr.Elements, -1)
 		},
 	}
 }
 
+// extreme returns the largest number in elements when want is 1, or the
+// smallest when want is -1. Ties go to the last one.
+func extreme(name string, elements []object.Object, want int) object.Object {
+	result := elements[0]
+	for _, el := range elements {
+		c, ok := object.Compare(el, result)
+		if !ok {
+			return NewError("argument to `%s` must contain only numbers, got %s", name, el.Type())
+		}
+		if c == want || c == 0 {
+			result = el
+		}
+	}
+	return result
+}
+
 func ArrayFirstBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
@@ -126,6 +127,14 @@ func ArrayFirstBuiltin() *object.Builtin {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
+			if it, ok := args[0].(object.Iterator); ok {
+				value, _, err := it.Next()
+				if err != nil {
+					return NewError("%s", err)
+				}
+				return value
+			}
+
 			if args[0].Type() != object.ARRAY_OBJ {
 				return NewError("argument to `first` must be ARRAY, got %s", args[0].Type())
 			}
@@ -147,11 +156,10 @@ func ArrayLastBuiltin() *object.Builtin {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return NewError("argument to `last` must be ARRAY, got %s", args[0].Type())
+			arr, errObj := arrayArgument("last", args[0])
+			if errObj != nil {
+				return errObj
 			}
-
-			arr := args[0].(*object.Array)
 			length := len(arr.Elements)
 			if length > 0 {
 				return arr.Elements[length-1]
@@ -169,11 +177,10 @@ func AllButFirstBuiltin() *object.Builtin {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return NewError("argument to `allButFirst` must be ARRAY, got %s", args[0].Type())
+			arr, errObj := arrayArgument("allButFirst", args[0])
+			if errObj != nil {
+				return errObj
 			}
-
-			arr := args[0].(*object.Array)
 			length := len(arr.Elements)
 			if length > 0 {
 				newElements := make([]object.Object, length-1, length-1)
@@ -192,7 +199,7 @@ func IndexOfBuiltin() *object.Builtin {
 			if len(args) != 2 {
 				return NewError("wrong number of arguments. got=%d, want=2", len(args))
 			}
-			if args[0].Type() != object.ARRAY_OBJ {
+			if args[0].Type() != object.ARRAY_OBJ && !isIterator(args[0]) {
 				return NewError("argument to `indexOf` must be ARRAY, got %s", args[0].Type())
 			}
 			if args[1].Type() != object.INTEGER_OBJ && args[1].Type() != object.STRING_OBJ {
@@ -202,7 +209,6 @@ func IndexOfBuiltin() *object.Builtin {
 			var index any
 			var typeOf string
 
-			arr := args[0].(*object.Array)
 			if args[1].Type() == object.INTEGER_OBJ {
 				index = args[1].(*object.Integer).Value
 				typeOf = object.INTEGER_OBJ
@@ -213,7 +219,16 @@ func IndexOfBuiltin() *object.Builtin {
 				typeOf = object.STRING_OBJ
 			}
 
-			for i, el := range arr.Elements {
+			it, _ := object.NewIterator(args[0])
+			for i := 0; ; i++ {
+				el, ok, err := it.Next()
+				if err != nil {
+					return NewError("%s", err)
+				}
+				if !ok {
+					break
+				}
+
 				if typeOf == object.INTEGER_OBJ {
 					if el.(*object.Integer).Value == index.(int64) {
 						return NewInteger(int64(i))
@@ -238,7 +253,7 @@ func OrganizeBuiltins() *object.Builtin {
 
 			by := "asc"
 
-			if args[0].Type() != object.ARRAY_OBJ {
+			if args[0].Type() != object.ARRAY_OBJ && !isIterator(args[0]) {
 				return NewError("first argument to `organize` must be ARRAY, got %s", args[0].Type())
 			}
 
@@ -248,7 +263,11 @@ func OrganizeBuiltins() *object.Builtin {
 				}
 			}
 
-			arr := args[0].(*object.Array)
+			arr, errObj := arrayArgument("organize", args[0])
+			if errObj != nil {
+				return errObj
+			}
+
 			switch by {
 			case "asc":
 				sort.Slice(arr.Elements, func(i, j int) bool {
@@ -273,14 +292,15 @@ func SumBuiltin() *object.Builtin {
 				return NewError("wrong number of arguments. got=%d, want=1", len(args))
 			}
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return NewError("argument to `sum` must be ARRAY, got %s", args[0].Type())
+			arr, errObj := arrayArgument("sum", args[0])
+			if errObj != nil {
+				return errObj
 			}
 
 			var sum float64
 			var hasFloat bool = false
 
-			for _, el := range args[0].(*object.Array).Elements {
+			for _, el := range arr.Elements {
 				if el.Type() != object.INTEGER_OBJ && el.Type() != object.FLOAT_OBJ {
 					return NewError("argument to `sum` must be INTEGER or FLOAT, got %s", el.Type())
 				}
@@ -302,3 +322,8 @@ func SumBuiltin() *object.Builtin {
 		},
 	}
 }
+
+func isIterator(obj object.Object) bool {
+	_, ok := obj.(object.Iterator)
+	return ok
+}
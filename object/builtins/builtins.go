@@ -5,6 +5,8 @@ import (
 	"zumbra/object"
 )
 
+// Builtins are referred to by their index in compiled code, so new ones go
+// at the end to keep the indexes of the others.
 var Builtins = []struct {
 	Name    string
 	Builtin *object.Builtin
@@ -40,10 +42,10 @@ var Builtins = []struct {
 		"dictValues", DictValuesBuiltin(),
 	},
 	{
-		"dotenvLoad", loadEnvBuiltin(),
+		"dotenvLoad", process.loadEnvBuiltin(),
 	},
 	{
-		"dotenvGet", getEnvBuiltin(),
+		"dotenvGet", process.getEnvBuiltin(),
 	},
 	{
 		"first", ArrayFirstBuiltin(),
@@ -70,10 +72,10 @@ var Builtins = []struct {
 		"jsonParse", JsonParse(),
 	},
 	{
-		"jwtCreateToken", createTokenBuiltin(),
+		"jwtCreateToken", process.createTokenBuiltin(),
 	},
 	{
-		"jwtVerifyToken", verifyTokenBuiltin(),
+		"jwtVerifyToken", process.verifyTokenBuiltin(),
 	},
 	{
 		"last", ArrayLastBuiltin(),
@@ -85,31 +87,31 @@ var Builtins = []struct {
 		"min", MinBuiltin(),
 	},
 	{
-		"mysqlConnection", MySqlConnectionBuiltin(),
+		"mysqlConnection", process.mysqlConnectionBuiltin(),
 	},
 	{
-		"mysqlCreateTable", mysqlCreateTableBuiltin(),
+		"mysqlCreateTable", process.mysqlCreateTableBuiltin(),
 	},
 	{
-		"mysqlDeleteFromTable", mysqlDeleteFromTableBuiltin(),
+		"mysqlDeleteFromTable", process.mysqlDeleteFromTableBuiltin(),
 	},
 	{
-		"mysqlDropTable", mysqlDeleteTableBuiltin(),
+		"mysqlDropTable", process.mysqlDeleteTableBuiltin(),
 	},
 	{
-		"mysqlGetFromTable", mysqlGetFromTableBuiltin(),
+		"mysqlGetFromTable", process.mysqlGetFromTableBuiltin(),
 	},
 	{
-		"mysqlInsertIntoTable", mysqlInsertIntoTableBuiltin(),
+		"mysqlInsertIntoTable", process.mysqlInsertIntoTableBuiltin(),
 	},
 	{
-		"mysqlShowTables", mysqlShowTablesBuiltin(),
+		"mysqlShowTables", process.mysqlShowTablesBuiltin(),
 	},
 	{
-		"mysqlShowTableColumns", mysqlShowTableColumnsBuiltin(),
+		"mysqlShowTableColumns", process.mysqlShowTableColumnsBuiltin(),
 	},
 	{
-		"mysqlUpdateIntoTable", mysqlUpdateIntoTableBuiltin(),
+		"mysqlUpdateIntoTable", process.mysqlUpdateIntoTableBuiltin(),
 	},
 	{
 		"organize", OrganizeBuiltins(),
@@ -121,12 +123,11 @@ var Builtins = []struct {
 		"randomInteger", GenerateRandomIntegerBuiltin(),
 	},
 	{
-		"registerRoute", RegisterRoutesBuiltin(),
+		"registerRoute", process.registerRouteBuiltin(),
 	},
 	{
 		"removeFromArray", RemoveFromArrayBuiltin(),
 	},
-
 	{
 		"removeWhiteSpaces", RemoveWhiteSpacesBuiltin(),
 	},
@@ -140,13 +141,13 @@ var Builtins = []struct {
 		"sendWhatsapp", SendWhatsappBuiltin(),
 	},
 	{
-		"server", CreateServerBuiltin(),
+		"server", process.serverBuiltin(),
 	},
 	{
 		"serveFile", ServeFileBuiltin(),
 	},
 	{
-		"serveStatic", ServerStaticBuiltin(),
+		"serveStatic", process.serveStaticBuiltin(),
 	},
 	{
 		"show", ShowBuiltin(),
@@ -175,6 +176,66 @@ var Builtins = []struct {
 	{
 		"toUppercase", UppercaseBuiltin(),
 	},
+	{
+		"set", SetBuiltin(),
+	},
+	{
+		"setAdd", SetAddBuiltin(),
+	},
+	{
+		"setDifference", SetDifferenceBuiltin(),
+	},
+	{
+		"setHas", SetHasBuiltin(),
+	},
+	{
+		"setIntersect", SetIntersectBuiltin(),
+	},
+	{
+		"setRemove", SetRemoveBuiltin(),
+	},
+	{
+		"setUnion", SetUnionBuiltin(),
+	},
+	{
+		"bigint", BigIntBuiltin(),
+	},
+	{
+		"decimal", DecimalBuiltin(),
+	},
+	{
+		"decimalRounding", DecimalRoundingBuiltin(),
+	},
+	{
+		"jsonStringify", JsonStringify(),
+	},
+	{
+		"round", RoundBuiltin(),
+	},
+	{
+		"iter", IterBuiltin(),
+	},
+	{
+		"next", NextBuiltin(),
+	},
+	{
+		"take", TakeBuiltin(),
+	},
+	{
+		"toArray", ToArrayBuiltin(),
+	},
+	{
+		"assertEqual", AssertEqualBuiltin(),
+	},
+	{
+		"assertThrows", AssertThrowsBuiltin(),
+	},
+	{
+		"assertTrue", AssertTrueBuiltin(),
+	},
+	{
+		"test", TestBuiltin(),
+	},
 }
 
 func NewBoolean(value bool) *object.Boolean {
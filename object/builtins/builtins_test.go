@@ -0,0 +1,35 @@
+package builtins
+
+import "testing"
+
+// TestBuiltinIndexes checks that no builtin moved: compiled code refers to
+// builtins by index, so new ones must be appended to this list and the
+// table.
+func TestBuiltinIndexes(t *testing.T) {
+	names := []string{
+		"addToArrayStart", "addToArrayEnd", "addToDict", "allButFirst", "bhaskara",
+		"capitalize", "date", "deleteFromDict", "dictKeys", "dictValues",
+		"dotenvLoad", "dotenvGet", "first", "get", "getFromDict", "hashCode", "html",
+		"indexOf", "input", "jsonParse", "jwtCreateToken", "jwtVerifyToken", "last",
+		"max", "min", "mysqlConnection", "mysqlCreateTable", "mysqlDeleteFromTable",
+		"mysqlDropTable", "mysqlGetFromTable", "mysqlInsertIntoTable",
+		"mysqlShowTables", "mysqlShowTableColumns", "mysqlUpdateIntoTable",
+		"organize", "randomFloat", "randomInteger", "registerRoute",
+		"removeFromArray", "removeWhiteSpaces", "replace", "sendEmail",
+		"sendWhatsapp", "server", "serveFile", "serveStatic", "show", "sizeOf",
+		"sum", "toBool", "toFloat", "toInt", "toLowercase", "toString",
+		"toUppercase", "set", "setAdd", "setDifference", "setHas", "setIntersect",
+		"setRemove", "setUnion", "bigint", "decimal", "decimalRounding",
+		"jsonStringify", "round", "iter", "next", "take", "toArray", "assertEqual",
+		"assertThrows", "assertTrue", "test",
+	}
+
+	if len(Builtins) != len(names) {
+		t.Fatalf("wrong number of builtins. want=%d, got=%d", len(names), len(Builtins))
+	}
+	for i, name := range names {
+		if Builtins[i].Name != name {
+			t.Errorf("Builtins[%d] is %s, want %s", i, Builtins[i].Name, name)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package builtins
+
+// Capability groups the builtins that reach outside the program, or change
+// the process running it, so a sandbox can leave them out.
+type Capability string
+
+const (
+	Network    Capability = "network"
+	Filesystem Capability = "filesystem"
+	Database   Capability = "database"
+	Messaging  Capability = "messaging"
+	Process    Capability = "process"
+)
+
+var AllCapabilities = []Capability{Network, Filesystem, Database, Messaging, Process}
+
+// capabilities classifies every builtin by the capabilities it needs.
+// Builtins that only compute with their arguments, or only keep state in
+// the interpreter running them, need none. Filesystem also covers env files
+// and reading input, as both expose the host to the program. Process covers
+// the settings shared by the whole process.
+var capabilities = map[string][]Capability{
+	"get":           {Network},
+	"server":        {Network},
+	"registerRoute": {Network},
+	"serveFile":     {Network, Filesystem},
+	"serveStatic":   {Network, Filesystem},
+
+	"dotenvLoad": {Filesystem},
+	"dotenvGet":  {Filesystem},
+	"input":      {Filesystem},
+
+	"mysqlConnection":       {Database},
+	"mysqlCreateTable":      {Database},
+	"mysqlDeleteFromTable":  {Database},
+	"mysqlDropTable":        {Database},
+	"mysqlGetFromTable":     {Database},
+	"mysqlInsertIntoTable":  {Database},
+	"mysqlShowTables":       {Database},
+	"mysqlShowTableColumns": {Database},
+	"mysqlUpdateIntoTable":  {Database},
+
+	"sendEmail":    {Messaging},
+	"sendWhatsapp": {Messaging},
+
+	"decimalRounding": {Process},
+
+	"addToArrayStart":   {},
+	"addToArrayEnd":     {},
+	"addToDict":         {},
+	"allButFirst":       {},
+	"assertEqual":       {},
+	"assertThrows":      {},
+	"assertTrue":        {},
+	"bigint":            {},
+	"bhaskara":          {},
+	"capitalize":        {},
+	"decimal":           {},
+	"date":              {},
+	"deleteFromDict":    {},
+	"dictKeys":          {},
+	"dictValues":        {},
+	"first":             {},
+	"getFromDict":       {},
+	"hashCode":          {},
+	"html":              {},
+	"indexOf":           {},
+	"iter":              {},
+	"jsonParse":         {},
+	"jsonStringify":     {},
+	"jwtCreateToken":    {},
+	"jwtVerifyToken":    {},
+	"last":              {},
+	"max":               {},
+	"min":               {},
+	"next":              {},
+	"organize":          {},
+	"randomFloat":       {},
+	"randomInteger":     {},
+	"removeFromArray":   {},
+	"removeWhiteSpaces": {},
+	"replace":           {},
+	"round":             {},
+	"set":               {},
+	"setAdd":            {},
+	"setDifference":     {},
+	"setHas":            {},
+	"setIntersect":      {},
+	"setRemove":         {},
+	"setUnion":          {},
+	"show":              {},
+	"sizeOf":            {},
+	"sum":               {},
+	"take":              {},
+	"test":              {},
+	"toArray":           {},
+	"toBool":            {},
+	"toFloat":           {},
+	"toInt":             {},
+	"toLowercase":       {},
+	"toString":          {},
+	"toUppercase":       {},
+}
+
+// Requires returns the capabilities the builtin name needs.
+func Requires(name string) []Capability {
+	return capabilities[name]
+}
+
+// Allowed reports whether the builtin name only needs capabilities in
+// allow. Builtins missing from the classification are never allowed, so a
+// new builtin cannot reach a sandbox before it is classified.
+func Allowed(name string, allow []Capability) bool {
+	needs, classified := capabilities[name]
+	if !classified {
+		return false
+	}
+	for _, needed := range needs {
+		found := false
+		for _, c := range allow {
+			if c == needed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
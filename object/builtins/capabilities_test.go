@@ -0,0 +1,32 @@
+package builtins
+
+import "testing"
+
+func TestEveryBuiltinIsClassified(t *testing.T) {
+	for _, b := range Builtins {
+		if _, ok := capabilities[b.Name]; !ok {
+			t.Errorf("%s has no capabilities entry", b.Name)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []Capability
+		want  bool
+	}{
+		{"sizeOf", nil, true},
+		{"get", nil, false},
+		{"get", []Capability{Network}, true},
+		{"serveFile", []Capability{Network}, false},
+		{"serveFile", []Capability{Network, Filesystem}, true},
+		{"unclassified", AllCapabilities, false},
+	}
+
+	for _, tt := range tests {
+		if got := Allowed(tt.name, tt.allow); got != tt.want {
+			t.Errorf("Allowed(%q, %v) = %t, want %t", tt.name, tt.allow, got, tt.want)
+		}
+	}
+}
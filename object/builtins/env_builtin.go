@@ -3,14 +3,13 @@ package builtins
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"zumbra/object"
 )
 
-var EnvVars = map[string]string{}
-
-func loadEnvBuiltin() *object.Builtin {
+func (s *State) loadEnvBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -30,22 +29,7 @@ func loadEnvBuiltin() *object.Builtin {
 			}
 			defer file.Close()
 
-			scanner := bufio.NewScanner(file)
-
-			for scanner.Scan() {
-				line := scanner.Text()
-				if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
-					continue
-				}
-				parts := strings.SplitN(line, "=", 2)
-				if len(parts) == 2 {
-					key := strings.TrimSpace(parts[0])
-					value := strings.TrimSpace(parts[1])
-					EnvVars[key] = value
-				}
-			}
-
-			if err := scanner.Err(); err != nil {
+			if err := s.readEnv(file); err != nil {
 				return NewError("failed to read file: %s", err)
 			}
 
@@ -54,7 +38,43 @@ func loadEnvBuiltin() *object.Builtin {
 	}
 }
 
-func getEnvBuiltin() *object.Builtin {
+// LoadEnvFile reads KEY=value lines from path into the env vars of the
+// process, as dotenvLoad does.
+func LoadEnvFile(path string) error {
+	return process.LoadEnvFile(path)
+}
+
+// LoadEnvFile reads KEY=value lines from path into the env vars of s.
+func (s *State) LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return s.readEnv(file)
+}
+
+func (s *State) readEnv(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) == 2 {
+			key := strings.TrimSpace(parts[0])
+			value := strings.TrimSpace(parts[1])
+			s.env[key] = value
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *State) getEnvBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -67,7 +87,7 @@ func getEnvBuiltin() *object.Builtin {
 				return NewError("argument to `getEnv` must be STRING, got %s", args[0].Type())
 			}
 
-			value, ok := EnvVars[key.Value]
+			value, ok := s.env[key.Value]
 
 			if !ok {
 				return nil
@@ -1,6 +1,7 @@
 package builtins
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -21,11 +22,30 @@ type StaticRoute struct {
 	StaticDir   string
 }
 
-var staticRoutes []StaticRoute
+// ShutdownServers stops the servers the process started with server(),
+// letting requests in flight finish until ctx is done.
+func ShutdownServers(ctx context.Context) error {
+	return process.ShutdownServers(ctx)
+}
 
-var registerRoutes []Route
+// ShutdownServers stops the servers started with the server() of s,
+// letting requests in flight finish until ctx is done.
+func (s *State) ShutdownServers(ctx context.Context) error {
+	s.serversMu.Lock()
+	running := s.servers
+	s.servers = nil
+	s.serversMu.Unlock()
+
+	var firstErr error
+	for _, srvr := range running {
+		if err := srvr.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
 
-func CreateServerBuiltin() *object.Builtin {
+func (s *State) serverBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 
@@ -38,12 +58,13 @@ func CreateServerBuiltin() *object.Builtin {
 				return NewError("argument to `server` must be INTEGER, got %s", args[0].Type())
 			}
 
-			for _, sr := range staticRoutes {
-				http.Handle(sr.RoutePrefix+"/", http.StripPrefix(sr.RoutePrefix, http.FileServer(http.Dir(sr.StaticDir))))
+			mux := http.NewServeMux()
+			for _, sr := range s.staticRoutes {
+				mux.Handle(sr.RoutePrefix+"/", http.StripPrefix(sr.RoutePrefix, http.FileServer(http.Dir(sr.StaticDir))))
 			}
 
-			http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-				route := matchRoute(r)
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				route := s.matchRoute(r)
 
 				if route == nil {
 					http.NotFound(w, r)
@@ -66,12 +87,14 @@ func CreateServerBuiltin() *object.Builtin {
 					} else {
 						w.Write([]byte("function did not return string"))
 					}
+				case object.Iterator:
+					streamIterator(w, handler)
 				default:
 					w.Write([]byte("unsupported handler type"))
 				}
 			})
 			portStr := fmt.Sprintf("%d", portObj.Value)
-			srvr := &http.Server{Addr: ":" + portStr, Handler: nil}
+			srvr := &http.Server{Addr: ":" + portStr, Handler: mux}
 
 			ln, err := net.Listen("tcp", srvr.Addr)
 
@@ -82,7 +105,11 @@ func CreateServerBuiltin() *object.Builtin {
 
 			fmt.Printf("Zumbra server started on port %s\n", portStr)
 
-			if err := srvr.Serve(ln); err != nil {
+			s.serversMu.Lock()
+			s.servers = append(s.servers, srvr)
+			s.serversMu.Unlock()
+
+			if err := srvr.Serve(ln); err != nil && err != http.ErrServerClosed {
 				fmt.Printf("Server stopped unexpectedly. got %s\n", err)
 				return NewError("Server stopped unexpectedly. got %s", err)
 			}
@@ -92,6 +119,32 @@ func CreateServerBuiltin() *object.Builtin {
 	}
 }
 
+// streamIterator writes every value of an iterator as soon as it is
+// produced. Generators start over on each request.
+func streamIterator(w http.ResponseWriter, it object.Iterator) {
+	if r, ok := it.(object.Restartable); ok {
+		it = r.Restart()
+	}
+
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		value, ok, err := it.Next()
+		if err != nil {
+			fmt.Printf("Stream stopped unexpectedly. got %s\n", err)
+			return
+		}
+		if !ok {
+			return
+		}
+
+		w.Write([]byte(value.Inspect()))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
 func GetBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
@@ -126,7 +179,7 @@ func GetBuiltin() *object.Builtin {
 	}
 }
 
-func RegisterRoutesBuiltin() *object.Builtin {
+func (s *State) registerRouteBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 
@@ -143,7 +196,7 @@ func RegisterRoutesBuiltin() *object.Builtin {
 				return NewError("method and path must be STRING")
 			}
 
-			registerRoutes = append(registerRoutes, Route{
+			s.routes = append(s.routes, Route{
 				Method:      strings.ToUpper(method.Value),
 				Path:        path.Value,
 				HandlerBody: handler,
@@ -155,7 +208,7 @@ func RegisterRoutesBuiltin() *object.Builtin {
 	}
 }
 
-func UseMiddlewaresBuiltin() *object.Builtin {
+func (s *State) UseMiddlewaresBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -169,10 +222,10 @@ func UseMiddlewaresBuiltin() *object.Builtin {
 				return NewError("method and path must be STRING")
 			}
 
-			for i, route := range registerRoutes {
+			for i, route := range s.routes {
 				if route.Path == path.Value {
 					if middlewareName.Value == "logger" {
-						registerRoutes[i].Middlewares = append(registerRoutes[i].Middlewares, func(w http.ResponseWriter, r *http.Request) bool {
+						s.routes[i].Middlewares = append(s.routes[i].Middlewares, func(w http.ResponseWriter, r *http.Request) bool {
 							fmt.Println("Request: ", r.Method, r.URL.Path)
 							return true
 						})
@@ -206,7 +259,7 @@ func HtmlHandlerBuiltin() *object.Builtin {
 	}
 }
 
-func ServerStaticBuiltin() *object.Builtin {
+func (s *State) serveStaticBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -220,7 +273,9 @@ func ServerStaticBuiltin() *object.Builtin {
 				return NewError("method and path must be STRING")
 			}
 
-			staticRoutes = append(staticRoutes, StaticRoute{
+			useFile(dir.Value)
+
+			s.staticRoutes = append(s.staticRoutes, StaticRoute{
 				RoutePrefix: prefix.Value,
 				StaticDir:   dir.Value,
 			})
@@ -230,8 +285,8 @@ func ServerStaticBuiltin() *object.Builtin {
 	}
 }
 
-func matchRoute(r *http.Request) *Route {
-	for _, route := range registerRoutes {
+func (s *State) matchRoute(r *http.Request) *Route {
+	for _, route := range s.routes {
 		if route.Method != r.Method {
 			continue
 		}
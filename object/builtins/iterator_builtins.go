@@ -0,0 +1,131 @@
+package builtins
+
+import (
+	"zumbra/object"
+)
+
+func IterBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			it, err := object.NewIterator(args[0])
+			if err != nil {
+				return NewError("argument to `iter` not supported, got %s", args[0].Type())
+			}
+
+			return it
+		},
+	}
+}
+
+// NextBuiltin advances an iterator and returns {"value": v, "done": false},
+// or {"value": null, "done": true} once it is exhausted.
+func NextBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			it, ok := args[0].(object.Iterator)
+			if !ok {
+				return NewError("argument to `next` must be ITERATOR or GENERATOR, got %s", args[0].Type())
+			}
+
+			value, ok, err := it.Next()
+			if err != nil {
+				return NewError("%s", err)
+			}
+			if !ok {
+				value = &object.Null{}
+			}
+
+			result := &object.Dict{Pairs: map[object.DictKey]object.DictPair{}}
+			for _, pair := range []object.DictPair{
+				{Key: NewString("value"), Value: value},
+				{Key: NewString("done"), Value: NewBoolean(!ok)},
+			} {
+				result.Pairs[pair.Key.(*object.String).DictKey()] = pair
+			}
+
+			return result
+		},
+	}
+}
+
+// TakeBuiltin pulls at most n values, leaving the rest of the iterator
+// untouched.
+func TakeBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			n, ok := args[1].(*object.Integer)
+			if !ok {
+				return NewError("second argument to `take` must be INTEGER, got %s", args[1].Type())
+			}
+
+			it, err := object.NewIterator(args[0])
+			if err != nil {
+				return NewError("argument to `take` not supported, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for int64(len(elements)) < n.Value {
+				value, ok, err := it.Next()
+				if err != nil {
+					return NewError("%s", err)
+				}
+				if !ok {
+					break
+				}
+				elements = append(elements, value)
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	}
+}
+
+func ToArrayBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if arr, ok := args[0].(*object.Array); ok {
+				return &object.Array{Elements: append([]object.Object{}, arr.Elements...)}
+			}
+
+			elements, err := object.Collect(args[0])
+			if err != nil {
+				return NewError("%s", err)
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	}
+}
+
+// arrayArgument lets builtins that read a whole array accept any iterator
+// as well, draining it into a new array.
+func arrayArgument(name string, obj object.Object) (*object.Array, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return obj, nil
+	case object.Iterator:
+		elements, err := object.Collect(obj)
+		if err != nil {
+			return nil, NewError("%s", err)
+		}
+		return &object.Array{Elements: elements}, nil
+	}
+
+	return nil, NewError("argument to `%s` must be ARRAY, got %s", name, obj.Type())
+}
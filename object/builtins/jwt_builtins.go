@@ -7,9 +7,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secretKey string
-
-func createTokenBuiltin() *object.Builtin {
+func (s *State) createTokenBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 3 {
@@ -29,7 +27,7 @@ func createTokenBuiltin() *object.Builtin {
 			}
 
 			username := args[0].(*object.String).Value
-			secretKey = args[1].(*object.String).Value
+			s.secretKey = args[1].(*object.String).Value
 			expiration := args[2].(*object.Integer).Value
 
 			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -37,9 +35,9 @@ func createTokenBuiltin() *object.Builtin {
 				"exp":      time.Now().Add(time.Hour * time.Duration(expiration)).Unix(),
 			})
 
-			tokenStr, err := token.SignedString([]byte(secretKey))
+			tokenStr, err := token.SignedString([]byte(s.secretKey))
 			if err != nil {
-				return NewError("Failed to create token, createToken('%s', '%s', '%d'). got %s", username, secretKey, expiration, err)
+				return NewError("Failed to create token, createToken('%s', '%s', '%d'). got %s", username, s.secretKey, expiration, err)
 			}
 
 			return &object.String{Value: tokenStr}
@@ -47,7 +45,7 @@ func createTokenBuiltin() *object.Builtin {
 	}
 }
 
-func verifyTokenBuiltin() *object.Builtin {
+func (s *State) verifyTokenBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -65,7 +63,7 @@ func verifyTokenBuiltin() *object.Builtin {
 					NewError("unexpected signing method: %v", token.Header["alg"])
 					return nil, nil
 				}
-				return []byte(secretKey), nil
+				return []byte(s.secretKey), nil
 			})
 			if err != nil {
 				return NewError("Failed to verify token, verifyToken('%s'). got %s", tokenStr, err)
@@ -3,15 +3,14 @@ package builtins
 import (
 	"database/sql"
 	"fmt"
+	"math/big"
 	"strings"
 	"zumbra/object"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var db_connection *sql.DB
-
-func MySqlConnectionBuiltin() *object.Builtin {
+func (s *State) mysqlConnectionBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 5 {
@@ -29,12 +28,12 @@ func MySqlConnectionBuiltin() *object.Builtin {
 			database := args[4].(*object.String).Value
 
 			var err error
-			db_connection, err = sql.Open("mysql", user+":"+password+"@tcp("+host+":"+port+")/"+database)
+			s.db, err = sql.Open("mysql", user+":"+password+"@tcp("+host+":"+port+")/"+database)
 			if err != nil {
 				return NewError("Failed to open database, mysqlConnection('%s', '%s', '%s', '%s', '%s'). got %s", host, port, user, password, database, err)
 			}
 
-			err = db_connection.Ping()
+			err = s.db.Ping()
 			if err != nil {
 				return NewError("Failed to ping database, mysqlConnection('%s', '%s', '%s', '%s', '%s'). got %s", host, port, user, password, database, err)
 			}
@@ -46,7 +45,7 @@ func MySqlConnectionBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlCreateTableBuiltin() *object.Builtin {
+func (s *State) mysqlCreateTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -57,14 +56,14 @@ func mysqlCreateTableBuiltin() *object.Builtin {
 				return NewError("All arguments to `mysqlCreateTable` must be STRING, got %s", args[0].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
 			tableName := args[0].(*object.String).Value
 			fields := args[1].(*object.String).Value
 
-			_, err := db_connection.Exec("CREATE TABLE " + tableName + " (" + fields + ");")
+			_, err := s.db.Exec("CREATE TABLE " + tableName + " (" + fields + ");")
 			if err != nil {
 				return NewError("Failed to create table, mysqlCreateTable('%s', '%s'). got %s", tableName, fields, err)
 			}
@@ -76,18 +75,18 @@ func mysqlCreateTableBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlShowTablesBuiltin() *object.Builtin {
+func (s *State) mysqlShowTablesBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 0 {
 				return NewError("wrong number of arguments, mysqlShowTables(). got=%d, want=0", len(args))
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
-			rows, err := db_connection.Query("SHOW TABLES")
+			rows, err := s.db.Query("SHOW TABLES")
 			if err != nil {
 				return NewError("Failed to show tables, mysqlShowTables(). got %s", err)
 			}
@@ -112,7 +111,7 @@ func mysqlShowTablesBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlShowTableColumnsBuiltin() *object.Builtin {
+func (s *State) mysqlShowTableColumnsBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
@@ -123,13 +122,13 @@ func mysqlShowTableColumnsBuiltin() *object.Builtin {
 				return NewError("All arguments to `mysqlShowTableColumns` must be STRING, got %s", args[0].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
 			tableName := args[0].(*object.String).Value
 
-			rows, err := db_connection.Query("SHOW COLUMNS FROM " + tableName)
+			rows, err := s.db.Query("SHOW COLUMNS FROM " + tableName)
 			if err != nil {
 				return NewError("Failed to show table columns, mysqlShowTableColumns('%s'). got %s", tableName, err)
 			}
@@ -158,14 +157,14 @@ func mysqlShowTableColumnsBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlDeleteTableBuiltin() *object.Builtin {
+func (s *State) mysqlDeleteTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return NewError("wrong number of arguments, mysqlDeleteTable(tableName). got=%d, want=1", len(args))
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
@@ -173,7 +172,7 @@ func mysqlDeleteTableBuiltin() *object.Builtin {
 
 			query := fmt.Sprintf("DROP TABLE %s", tableName)
 
-			_, err := db_connection.Exec(query)
+			_, err := s.db.Exec(query)
 			if err != nil {
 				return NewError("Failed to drop table, mysqlDeleteTable('%s'). got %s", tableName, err)
 			}
@@ -185,7 +184,7 @@ func mysqlDeleteTableBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlGetFromTableBuiltin() *object.Builtin {
+func (s *State) mysqlGetFromTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 3 {
@@ -196,7 +195,7 @@ func mysqlGetFromTableBuiltin() *object.Builtin {
 				return NewError("All arguments to `mysqlGetFromTable` must be STRING, got %s", args[0].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
@@ -208,7 +207,7 @@ func mysqlGetFromTableBuiltin() *object.Builtin {
 				condition = ";"
 			}
 
-			rows, err := db_connection.Query("SELECT " + fields + " FROM " + tableName + condition)
+			rows, err := s.db.Query("SELECT " + fields + " FROM " + tableName + condition)
 			if err != nil {
 				return NewError("Failed to get from table, mysqlGetFromTable('%s', '%s', '%s'). got %s", tableName, fields, condition, err)
 			}
@@ -218,6 +217,11 @@ func mysqlGetFromTableBuiltin() *object.Builtin {
 				return NewError("Failed to get columns from result set: %s", err)
 			}
 
+			columnTypes, err := rows.ColumnTypes()
+			if err != nil {
+				return NewError("Failed to get column types from result set: %s", err)
+			}
+
 			var records []map[string]interface{}
 
 			for rows.Next() {
@@ -238,7 +242,7 @@ func mysqlGetFromTableBuiltin() *object.Builtin {
 
 					b, ok := val.([]byte)
 					if ok {
-						v = string(b)
+						v = valueFromColumn(columnTypes[i].DatabaseTypeName(), string(b))
 					} else {
 						v = val
 					}
@@ -267,7 +271,7 @@ func mysqlGetFromTableBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlInsertIntoTableBuiltin() *object.Builtin {
+func (s *State) mysqlInsertIntoTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -282,7 +286,7 @@ func mysqlInsertIntoTableBuiltin() *object.Builtin {
 				return NewError("Second argument to `mysqlInsertIntoTable` must be a DICT, got %s", args[1].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
@@ -301,7 +305,7 @@ func mysqlInsertIntoTableBuiltin() *object.Builtin {
 
 			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", tableName, strings.Join(keys, ","), strings.Join(placeholders, ","))
 
-			_, err := db_connection.Exec(query, argsValues...)
+			_, err := s.db.Exec(query, argsValues...)
 			if err != nil {
 				return NewError("Failed to insert into table, mysqlInsertIntoTable('%s', '%v'). got %s", tableName, dict.Inspect(), err)
 			}
@@ -312,7 +316,7 @@ func mysqlInsertIntoTableBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlUpdateIntoTableBuiltin() *object.Builtin {
+func (s *State) mysqlUpdateIntoTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 3 {
@@ -331,7 +335,7 @@ func mysqlUpdateIntoTableBuiltin() *object.Builtin {
 				return NewError("Last argument to `mysqlUpdateIntoTable` must be STRING, got %s", args[2].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
@@ -355,7 +359,7 @@ func mysqlUpdateIntoTableBuiltin() *object.Builtin {
 
 			query := fmt.Sprintf("UPDATE %s SET %s %s", tableName, strings.Join(assignments, ", "), condition)
 
-			_, err := db_connection.Exec(query, argsValues...)
+			_, err := s.db.Exec(query, argsValues...)
 			if err != nil {
 				return NewError("Failed to update into table, mysqlUpdateIntoTable('%s', '%v', '%s'). got %s", tableName, dict.Inspect(), condition, err)
 			}
@@ -366,7 +370,7 @@ func mysqlUpdateIntoTableBuiltin() *object.Builtin {
 	}
 }
 
-func mysqlDeleteFromTableBuiltin() *object.Builtin {
+func (s *State) mysqlDeleteFromTableBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
@@ -381,7 +385,7 @@ func mysqlDeleteFromTableBuiltin() *object.Builtin {
 				return NewError("Last argument to `mysqlDeleteFromTable` must be STRING, got %s", args[1].Type())
 			}
 
-			if db_connection == nil {
+			if s.db == nil {
 				return NewError("Database is not connected. Use mysqlConnection(...) before creating tables.")
 			}
 
@@ -394,7 +398,7 @@ func mysqlDeleteFromTableBuiltin() *object.Builtin {
 
 			query := fmt.Sprintf("DELETE FROM %s %s", tableName, condition)
 
-			_, err := db_connection.Exec(query)
+			_, err := s.db.Exec(query)
 			if err != nil {
 				return NewError("Failed to delete from table, mysqlDeleteFromTable('%s', '%s'). got %s", tableName, condition, err)
 			}
@@ -414,16 +418,38 @@ func goValueFromObject(obj object.Object) interface{} {
 	case *object.Boolean:
 		return v.Value
 	default:
+		// Decimals and bigints are sent as their exact text, which MySQL
+		// converts to the column type.
 		return v.Inspect()
 	}
 }
 
+// valueFromColumn keeps DECIMAL columns exact and reads BIGINT columns as
+// numbers, promoting those that do not fit in an int64 (BIGINT UNSIGNED).
+func valueFromColumn(typeName, raw string) interface{} {
+	switch strings.ToUpper(typeName) {
+	case "DECIMAL", "NUMERIC":
+		if d, err := object.ParseDecimal(raw); err == nil {
+			return d
+		}
+	case "BIGINT", "UNSIGNED BIGINT":
+		if n, ok := new(big.Int).SetString(raw, 10); ok {
+			return object.NewBigInt(n)
+		}
+	}
+	return raw
+}
+
 func objectFromGoValue(v interface{}) object.Object {
 	switch val := v.(type) {
 	case string:
 		return &object.String{Value: val}
+	case object.Object:
+		return val
 	case int64:
 		return &object.Integer{Value: val}
+	case uint64:
+		return object.NewBigInt(new(big.Int).SetUint64(val))
 	case int:
 		return &object.Integer{Value: int64(val)}
 	case float64:
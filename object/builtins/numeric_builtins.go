@@ -0,0 +1,134 @@
+package builtins
+
+import (
+	"math/big"
+	"strings"
+	"zumbra/object"
+)
+
+func BigIntBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Integer:
+				return &object.BigInt{Value: big.NewInt(obj.Value)}
+			case *object.BigInt:
+				return obj
+			case *object.Decimal:
+				return &object.BigInt{Value: obj.Rescale(0, object.RoundDown).Unscaled}
+			case *object.String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(obj.Value), 10)
+				if !ok {
+					return NewError("could not parse %q as bigint", obj.Value)
+				}
+				return &object.BigInt{Value: value}
+			default:
+				return NewError("argument to `bigint` not supported, got=%s", args[0].Type())
+			}
+		},
+	}
+}
+
+func DecimalBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				value, err := object.ParseDecimal(str.Value)
+				if err != nil {
+					return NewError("%s", err)
+				}
+				return value
+			}
+
+			value, ok := object.ToDecimal(args[0])
+			if !ok {
+				return NewError("argument to `decimal` not supported, got=%s", args[0].Type())
+			}
+			return value
+		},
+	}
+}
+
+func RoundBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return NewError("wrong number of arguments. got=%d, want=1..3", len(args))
+			}
+
+			places := int64(0)
+			if len(args) > 1 {
+				p, ok := args[1].(*object.Integer)
+				if !ok || p.Value < 0 || p.Value > object.MaxDecimalScale {
+					return NewError("second argument to `round` must be an INTEGER from 0 to %d, got %s", object.MaxDecimalScale, args[1].Inspect())
+				}
+				places = p.Value
+			}
+
+			mode := object.DefaultDecimalContext.Rounding
+			if len(args) > 2 {
+				m, ok := args[2].(*object.String)
+				if !ok {
+					return NewError("third argument to `round` must be STRING, got %s", args[2].Type())
+				}
+				parsed, err := object.ParseRoundingMode(m.Value)
+				if err != nil {
+					return NewError("%s", err)
+				}
+				mode = parsed
+			}
+
+			switch obj := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return obj
+			case *object.Decimal:
+				return obj.Rescale(int32(places), mode)
+			case *object.Float:
+				d, ok := object.ToDecimal(obj)
+				if !ok {
+					return obj
+				}
+				return NewFloat(d.Rescale(int32(places), mode).Float())
+			default:
+				return NewError("argument to `round` not supported, got=%s", args[0].Type())
+			}
+		},
+	}
+}
+
+// DecimalRoundingBuiltin changes how many digits decimal divisions keep and
+// the rounding mode they and `round` use by default.
+func DecimalRoundingBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			scale, ok := args[0].(*object.Integer)
+			if !ok || scale.Value < 0 || scale.Value > object.MaxDecimalScale {
+				return NewError("first argument to `decimalRounding` must be an INTEGER from 0 to %d, got %s", object.MaxDecimalScale, args[0].Inspect())
+			}
+
+			m, ok := args[1].(*object.String)
+			if !ok {
+				return NewError("second argument to `decimalRounding` must be STRING, got %s", args[1].Type())
+			}
+			mode, err := object.ParseRoundingMode(m.Value)
+			if err != nil {
+				return NewError("%s", err)
+			}
+
+			object.DefaultDecimalContext = object.DecimalContext{Scale: int32(scale.Value), Rounding: mode}
+			return nil
+		},
+	}
+}
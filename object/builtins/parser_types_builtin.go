@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"zumbra/object"
 )
@@ -24,6 +25,8 @@ func ToStringParserBuiltin() *object.Builtin {
 				value = obj.Value
 			case *object.Boolean:
 				value = obj.Value
+			case *object.BigInt, *object.Decimal:
+				value = obj.Inspect()
 			default:
 				return NewError("argument to `toString` not supported, got=%s", args[0].Type())
 			}
@@ -57,8 +60,10 @@ func ToIntParserBuiltin() *object.Builtin {
 				} else {
 					return NewInteger(0)
 				}
-			case *object.Integer:
+			case *object.Integer, *object.BigInt:
 				return obj
+			case *object.Decimal:
+				return obj.Integer()
 			default:
 				return NewError("argument to `toInt` not supported, got=%s", args[0].Type())
 			}
@@ -92,6 +97,11 @@ func ToFloatParserBuiltin() *object.Builtin {
 				}
 			case *object.Integer:
 				return NewFloat(float64(obj.Value))
+			case *object.BigInt:
+				value, _ := new(big.Float).SetInt(obj.Value).Float64()
+				return NewFloat(value)
+			case *object.Decimal:
+				return NewFloat(obj.Float())
 			default:
 				return NewError("argument to `toFloat` not supported, got=%s", args[0].Type())
 			}
@@ -145,6 +155,77 @@ func JsonParse() *object.Builtin {
 	}
 }
 
+// JsonStringify serialises a value to JSON. BigInts and decimals are written
+// as numbers with all of their digits.
+func JsonStringify() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return NewError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			value, err := convertToJSON(args[0])
+			if err != nil {
+				return NewError("%s", err)
+			}
+
+			out, err := json.Marshal(value)
+			if err != nil {
+				return NewError("could not serialise to JSON: %s", err)
+			}
+
+			return NewString(string(out))
+		},
+	}
+}
+
+func convertToJSON(obj object.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.BigInt, *object.Decimal:
+		return json.Number(obj.Inspect()), nil
+	case *object.EnumValue:
+		return obj.Inspect(), nil
+	case *object.Array:
+		return convertElementsToJSON(obj.Elements)
+	case *object.Set:
+		return convertElementsToJSON(obj.Values())
+	case *object.Dict:
+		result := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			value, err := convertToJSON(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			result[pair.Key.Inspect()] = value
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("cannot serialise %s to JSON", obj.Type())
+}
+
+func convertElementsToJSON(elements []object.Object) (interface{}, error) {
+	result := make([]interface{}, 0, len(elements))
+	for _, el := range elements {
+		value, err := convertToJSON(el)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
 func convertToObject(data interface{}) object.Object {
 	switch val := data.(type) {
 	case map[string]interface{}:
@@ -7,6 +7,16 @@ import (
 	"zumbra/object"
 )
 
+// FileUsed, when set, is told about the files and directories programs
+// serve, so `zumbra run -watch` can restart them when these change.
+var FileUsed func(path string)
+
+func useFile(path string) {
+	if FileUsed != nil {
+		FileUsed(path)
+	}
+}
+
 func ServeFileBuiltin() *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
@@ -20,6 +30,7 @@ func ServeFileBuiltin() *object.Builtin {
 			}
 
 			path := filepath.Clean(pathObj.Value)
+			useFile(path)
 
 			content, err := os.ReadFile(path)
 			if err != nil {
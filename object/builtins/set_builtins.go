@@ -0,0 +1,149 @@
+package builtins
+
+import (
+	"zumbra/object"
+)
+
+func SetBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return NewError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+
+			set := object.NewSet()
+			if len(args) == 0 {
+				return set
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return NewError("argument to `set` must be ARRAY, got %s", args[0].Type())
+			}
+
+			for _, el := range arr.Elements {
+				if err := set.Add(el); err != nil {
+					return NewError("%s", err)
+				}
+			}
+
+			return set
+		},
+	}
+}
+
+func SetAddBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			set, ok := args[0].(*object.Set)
+			if !ok {
+				return NewError("argument to `setAdd` must be SET, got %s", args[0].Type())
+			}
+
+			if err := set.Add(args[1]); err != nil {
+				return NewError("%s", err)
+			}
+
+			return set
+		},
+	}
+}
+
+func SetRemoveBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			set, ok := args[0].(*object.Set)
+			if !ok {
+				return NewError("argument to `setRemove` must be SET, got %s", args[0].Type())
+			}
+
+			set.Remove(args[1])
+
+			return set
+		},
+	}
+}
+
+func SetHasBuiltin() *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			set, ok := args[0].(*object.Set)
+			if !ok {
+				return NewError("argument to `setHas` must be SET, got %s", args[0].Type())
+			}
+
+			return NewBoolean(set.Has(args[1]))
+		},
+	}
+}
+
+func SetUnionBuiltin() *object.Builtin {
+	return setOperation("setUnion", func(a, b *object.Set) *object.Set {
+		result := object.NewSet()
+		for _, el := range a.Values() {
+			result.Add(el)
+		}
+		for _, el := range b.Values() {
+			result.Add(el)
+		}
+		return result
+	})
+}
+
+func SetIntersectBuiltin() *object.Builtin {
+	return setOperation("setIntersect", func(a, b *object.Set) *object.Set {
+		result := object.NewSet()
+		for _, el := range a.Values() {
+			if b.Has(el) {
+				result.Add(el)
+			}
+		}
+		return result
+	})
+}
+
+func SetDifferenceBuiltin() *object.Builtin {
+	return setOperation("setDifference", func(a, b *object.Set) *object.Set {
+		result := object.NewSet()
+		for _, el := range a.Values() {
+			if !b.Has(el) {
+				result.Add(el)
+			}
+		}
+		return result
+	})
+}
+
+func setOperation(name string, op func(a, b *object.Set) *object.Set) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			a, ok := args[0].(*object.Set)
+			if !ok {
+				return NewError("argument to `%s` must be SET, got %s", name, args[0].Type())
+			}
+
+			b, ok := args[1].(*object.Set)
+			if !ok {
+				return NewError("argument to `%s` must be SET, got %s", name, args[1].Type())
+			}
+
+			return op(a, b)
+		},
+	}
+}
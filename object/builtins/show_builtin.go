@@ -2,21 +2,32 @@ package builtins
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"zumbra/object"
 )
 
 func ShowBuiltin() *object.Builtin {
+	return ShowTo(nil)
+}
+
+// ShowTo returns a show that prints to w, or to os.Stdout when w is nil.
+func ShowTo(w io.Writer) *object.Builtin {
 	return &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
+			out := w
+			if out == nil {
+				out = os.Stdout
+			}
 
 			if len(args) == 0 {
-				fmt.Println()
+				fmt.Fprintln(out)
 				return nil
 			}
 
 			if len(args) == 1 {
-				fmt.Println(args[0].Inspect())
+				fmt.Fprintln(out, args[0].Inspect())
 				return nil
 			}
 
@@ -34,7 +45,7 @@ func ShowBuiltin() *object.Builtin {
 
 			formatConverted := strings.ReplaceAll(format, "{}", "%v")
 
-			fmt.Printf(formatConverted+"\n", values...)
+			fmt.Fprintf(out, formatConverted+"\n", values...)
 			return nil
 
 		},
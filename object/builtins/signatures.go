@@ -0,0 +1,115 @@
+package builtins
+
+import "strings"
+
+// Signatures describes the parameters and result of every builtin using the
+// Zumbra type annotation syntax. A parameter prefixed with `...` accepts any
+// number of arguments and one suffixed with `?` may be omitted.
+var Signatures = map[string]string{
+	"addToArrayEnd":         "addToArrayEnd(array: [any], value: any): [any]",
+	"addToArrayStart":       "addToArrayStart(array: [any], value: any): [any]",
+	"addToDict":             "addToDict(dict: dict, key: string | int | bool, value: any): null",
+	"allButFirst":           "allButFirst(array: [any] | iterator): [any] | null",
+	"assertEqual":           "assertEqual(actual: any, expected: any, message?: string): null",
+	"assertThrows":          "assertThrows(function: fct, message?: string): string",
+	"assertTrue":            "assertTrue(value: any, message?: string): null",
+	"bhaskara":              "bhaskara(a: int, b: int, c: int): [float] | float | null",
+	"bigint":                "bigint(value: int | bigint | decimal | string): bigint",
+	"capitalize":            "capitalize(text: string): string",
+	"date":                  "date(): date",
+	"decimal":               "decimal(value: string | int | bigint | float | decimal): decimal",
+	"decimalRounding":       "decimalRounding(scale: int, mode: string): null",
+	"deleteFromDict":        "deleteFromDict(dict: dict, key: string | int | bool): null",
+	"dictKeys":              "dictKeys(dict: dict): [any]",
+	"dictValues":            "dictValues(dict: dict): [any]",
+	"dotenvGet":             "dotenvGet(key: string): string | null",
+	"dotenvLoad":            "dotenvLoad(path: string): null",
+	"first":                 "first(array: [any] | iterator): any",
+	"get":                   "get(url: string): {string: string}",
+	"getFromDict":           "getFromDict(dict: dict, key: string | int | bool): any",
+	"hashCode":              "hashCode(text: string): string",
+	"html":                  "html(content: string): fct(): string",
+	"indexOf":               "indexOf(array: [any] | iterator, value: int | string): int",
+	"input":                 "input(prompt?: any): string",
+	"iter":                  "iter(value: [any] | set | dict | string | iterator): iterator",
+	"jsonParse":             "jsonParse(json: string): dict",
+	"jsonStringify":         "jsonStringify(value: any): string",
+	"jwtCreateToken":        "jwtCreateToken(username: string, secret: string, hours: int): string",
+	"jwtVerifyToken":        "jwtVerifyToken(token: string): string",
+	"last":                  "last(array: [any] | iterator): any",
+	"max":                   "max(array: [int | bigint | float | decimal] | iterator): any",
+	"min":                   "min(array: [int | bigint | float | decimal] | iterator): any",
+	"mysqlConnection":       "mysqlConnection(host: string, port: string, user: string, password: string, database: string): null",
+	"mysqlCreateTable":      "mysqlCreateTable(table: string, fields: string): null",
+	"mysqlDeleteFromTable":  "mysqlDeleteFromTable(table: string, condition: string): null",
+	"mysqlDropTable":        "mysqlDropTable(table: string): null",
+	"mysqlGetFromTable":     "mysqlGetFromTable(table: string, fields: string, condition: string): [dict]",
+	"mysqlInsertIntoTable":  "mysqlInsertIntoTable(table: string, values: dict): null",
+	"mysqlShowTableColumns": "mysqlShowTableColumns(table: string): [string]",
+	"mysqlShowTables":       "mysqlShowTables(): [string]",
+	"mysqlUpdateIntoTable":  "mysqlUpdateIntoTable(table: string, values: dict, condition: string): null",
+	"next":                  "next(iterator: iterator): {string: any}",
+	"organize":              "organize(array: [int] | iterator, order?: string): [int]",
+	"randomFloat":           "randomFloat(from?: int | float, to?: int | float): float",
+	"randomInteger":         "randomInteger(from?: int, to?: int): int",
+	"registerRoute":         "registerRoute(method: string, path: string, handler: any): null",
+	"removeFromArray":       "removeFromArray(array: [any], index: int): [any]",
+	"removeWhiteSpaces":     "removeWhiteSpaces(text: string): string",
+	"replace":               "replace(text: string, old: string, new: string): string",
+	"round":                 "round(value: int | bigint | float | decimal, places?: int, mode?: string): int | bigint | float | decimal",
+	"sendEmail":             "sendEmail(message: dict): null",
+	"sendWhatsapp":          "sendWhatsapp(message: dict): null",
+	"serveFile":             "serveFile(path: string, values?: dict): string",
+	"serveStatic":           "serveStatic(prefix: string, dir: string): null",
+	"server":                "server(port: int): null",
+	"set":                   "set(values?: [any]): set",
+	"setAdd":                "setAdd(set: set, value: any): set",
+	"setDifference":         "setDifference(a: set, b: set): set",
+	"setHas":                "setHas(set: set, value: any): bool",
+	"setIntersect":          "setIntersect(a: set, b: set): set",
+	"setRemove":             "setRemove(set: set, value: any): set",
+	"setUnion":              "setUnion(a: set, b: set): set",
+	"show":                  "show(...values: any): null",
+	"sizeOf":                "sizeOf(value: string | [any] | set | iterator): int",
+	"sum":                   "sum(array: [int | float] | iterator): int | float",
+	"take":                  "take(iterator: [any] | set | dict | string | iterator, n: int): [any]",
+	"test":                  "test(name: string, body: fct): null",
+	"toArray":               "toArray(iterator: [any] | set | dict | string | iterator): [any]",
+	"toBool":                "toBool(value: string | int | float | bool): bool",
+	"toFloat":               "toFloat(value: string | int | float | bool | bigint | decimal): float",
+	"toInt":                 "toInt(value: string | int | float | bool | bigint | decimal): int | bigint",
+	"toLowercase":           "toLowercase(text: string): string",
+	"toString":              "toString(value: int | float | bool | bigint | decimal): string",
+	"toUppercase":           "toUppercase(text: string): string",
+}
+
+// Arity returns how many arguments the named builtin accepts according to
+// its signature. max is -1 when the builtin is variadic.
+func Arity(name string) (min, max int, ok bool) {
+	signature, ok := Signatures[name]
+	if !ok {
+		return 0, 0, false
+	}
+
+	open := strings.Index(signature, "(")
+	close := strings.Index(signature, ")")
+	params := strings.TrimSpace(signature[open+1 : close])
+	if params == "" {
+		return 0, 0, true
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		name, _, _ := strings.Cut(param, ":")
+		name = strings.TrimSpace(name)
+
+		switch {
+		case strings.HasPrefix(name, "..."):
+			return min, -1, true
+		case !strings.HasSuffix(name, "?"):
+			min++
+		}
+		max++
+	}
+
+	return min, max, true
+}
@@ -16,6 +16,14 @@ func SizeOfBuiltin() *object.Builtin {
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			case *object.String:
 				return &object.Integer{Value: int64(len(arg.Value))}
+			case *object.Set:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			case object.Iterator:
+				elements, err := object.Collect(arg)
+				if err != nil {
+					return NewError("%s", err)
+				}
+				return &object.Integer{Value: int64(len(elements))}
 			default:
 				return NewError("argument to `sizeOf` not supported, got %s", args[0].Type())
 			}
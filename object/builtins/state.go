@@ -0,0 +1,52 @@
+package builtins
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"zumbra/object"
+)
+
+// State is what the builtins remember between calls: the database
+// connection, routes and servers, env vars and JWT key. Every
+// zumbra.Interpreter has its own, so embedded programs cannot see or change
+// each other's; the Builtins table uses the one of the process.
+type State struct {
+	db  *sql.DB
+	env map[string]string
+
+	routes       []Route
+	staticRoutes []StaticRoute
+	serversMu    sync.Mutex
+	servers      []*http.Server
+
+	secretKey string
+}
+
+func NewState() *State {
+	return &State{env: map[string]string{}}
+}
+
+var process = NewState()
+
+// Builtins returns the builtins that keep their state in s, by name.
+func (s *State) Builtins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"dotenvLoad":            s.loadEnvBuiltin(),
+		"dotenvGet":             s.getEnvBuiltin(),
+		"jwtCreateToken":        s.createTokenBuiltin(),
+		"jwtVerifyToken":        s.verifyTokenBuiltin(),
+		"mysqlConnection":       s.mysqlConnectionBuiltin(),
+		"mysqlCreateTable":      s.mysqlCreateTableBuiltin(),
+		"mysqlDeleteFromTable":  s.mysqlDeleteFromTableBuiltin(),
+		"mysqlDropTable":        s.mysqlDeleteTableBuiltin(),
+		"mysqlGetFromTable":     s.mysqlGetFromTableBuiltin(),
+		"mysqlInsertIntoTable":  s.mysqlInsertIntoTableBuiltin(),
+		"mysqlShowTables":       s.mysqlShowTablesBuiltin(),
+		"mysqlShowTableColumns": s.mysqlShowTableColumnsBuiltin(),
+		"mysqlUpdateIntoTable":  s.mysqlUpdateIntoTableBuiltin(),
+		"registerRoute":         s.registerRouteBuiltin(),
+		"server":                s.serverBuiltin(),
+		"serveStatic":           s.serveStaticBuiltin(),
+	}
+}
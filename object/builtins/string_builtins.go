@@ -1,7 +1,6 @@
 package builtins
 
 import (
-	"fmt"
 	"strings"
 	"zumbra/object"
 )
@@ -71,11 +70,8 @@ func RemoveWhiteSpacesBuiltin() *object.Builtin {
 				return NewError("argument to `removeWhiteSpaces` must be STRING, got %s", args[0].Type())
 			}
 
-			fmt.Println(args[0].(*object.String).Value)
 			val := strings.ReplaceAll(args[0].(*object.String).Value, " ", "")
 
-			fmt.Println(val)
-
 			return NewString(val)
 		},
 	}
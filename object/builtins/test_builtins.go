@@ -0,0 +1,271 @@
+package builtins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"zumbra/object"
+)
+
+// Test is a function registered with test(), run later by `zumbra test`.
+type Test struct {
+	Name string
+	Fn   object.Object
+}
+
+// Registry keeps the tests a file registers and the assertion failures
+// its tests record. `zumbra test` uses a registry per file; the builtins of
+// the Builtins table have none, so there test() does nothing and the
+// assertions only return their errors.
+type Registry struct {
+	tests    []Test
+	failures []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Builtins returns test and the assertions, recording into r.
+func (r *Registry) Builtins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"test":         testBuiltin(r),
+		"assertEqual":  assertEqualBuiltin(r),
+		"assertThrows": assertThrowsBuiltin(r),
+		"assertTrue":   assertTrueBuiltin(r),
+	}
+}
+
+// TakeTests returns the tests registered since the last call.
+func (r *Registry) TakeTests() []Test {
+	tests := r.tests
+	r.tests = nil
+	return tests
+}
+
+// TakeFailures returns the assertion failures recorded since the last call.
+func (r *Registry) TakeFailures() []string {
+	failures := r.failures
+	r.failures = nil
+	return failures
+}
+
+func (r *Registry) fail(message object.Object, format string, a ...interface{}) *object.Error {
+	text := fmt.Sprintf(format, a...)
+	if message != nil {
+		text = message.Inspect() + ": " + text
+	}
+
+	if r != nil {
+		r.failures = append(r.failures, text)
+	}
+	return NewError("assertion failed: %s", text)
+}
+
+func optionalMessage(name string, args []object.Object, index int) (object.Object, *object.Error) {
+	if len(args) <= index {
+		return nil, nil
+	}
+	if _, ok := args[index].(*object.String); !ok {
+		return nil, NewError("message to `%s` must be STRING, got %s", name, args[index].Type())
+	}
+	return args[index], nil
+}
+
+func TestBuiltin() *object.Builtin {
+	return testBuiltin(nil)
+}
+
+func AssertEqualBuiltin() *object.Builtin {
+	return assertEqualBuiltin(nil)
+}
+
+func AssertTrueBuiltin() *object.Builtin {
+	return assertTrueBuiltin(nil)
+}
+
+func AssertThrowsBuiltin() *object.Builtin {
+	return assertThrowsBuiltin(nil)
+}
+
+func testBuiltin(r *Registry) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return NewError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			name, ok := args[0].(*object.String)
+			if !ok {
+				return NewError("first argument to `test` must be STRING, got %s", args[0].Type())
+			}
+
+			switch args[1].(type) {
+			case *object.Closure, *object.Function:
+			default:
+				return NewError("second argument to `test` must be a function, got %s", args[1].Type())
+			}
+
+			if r != nil {
+				r.tests = append(r.tests, Test{Name: name.Value, Fn: args[1]})
+			}
+			return nil
+		},
+	}
+}
+
+func assertEqualBuiltin(r *Registry) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 || len(args) > 3 {
+				return NewError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+
+			message, err := optionalMessage("assertEqual", args, 2)
+			if err != nil {
+				return err
+			}
+
+			actual, expected := args[0], args[1]
+			if object.Equal(actual, expected) {
+				return nil
+			}
+
+			return r.fail(message, "values are not equal\n%s", strings.Join(diff("", actual, expected), "\n"))
+		},
+	}
+}
+
+func assertTrueBuiltin(r *Registry) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return NewError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			message, err := optionalMessage("assertTrue", args, 1)
+			if err != nil {
+				return err
+			}
+
+			if b, ok := args[0].(*object.Boolean); ok && b.Value {
+				return nil
+			}
+
+			return r.fail(message, "expected true, got %s", describe(args[0]))
+		},
+	}
+}
+
+// assertThrowsBuiltin calls a function and passes when it fails with a
+// runtime error or returns an error. The error message is returned.
+func assertThrowsBuiltin(r *Registry) *object.Builtin {
+	return &object.Builtin{
+		Callback: func(call object.CallFunction, args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 2 {
+				return NewError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			message, err := optionalMessage("assertThrows", args, 1)
+			if err != nil {
+				return err
+			}
+
+			result, callErr := call(args[0])
+			if callErr != nil {
+				return NewString(callErr.Error())
+			}
+			if e, ok := result.(*object.Error); ok {
+				return NewString(e.Message)
+			}
+
+			return r.fail(message, "expected an error, got %s", describe(result))
+		},
+	}
+}
+
+func describe(obj object.Object) string {
+	if s, ok := obj.(*object.String); ok {
+		return fmt.Sprintf("%q", s.Value)
+	}
+	return obj.Inspect()
+}
+
+// diff lists the differences between actual and expected, one line per
+// differing element, with the path that leads to it.
+func diff(path string, actual, expected object.Object) []string {
+	if object.Equal(actual, expected) {
+		return nil
+	}
+
+	where := path
+	if where == "" {
+		where = "value"
+	}
+	mismatch := []string{fmt.Sprintf("  %s: got %s, want %s", where, describe(actual), describe(expected))}
+
+	switch a := actual.(type) {
+	case *object.Array:
+		e, ok := expected.(*object.Array)
+		if !ok {
+			return mismatch
+		}
+
+		lines := []string{}
+		for i := 0; i < len(a.Elements) || i < len(e.Elements); i++ {
+			at := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(e.Elements):
+				lines = append(lines, fmt.Sprintf("  %s: unexpected %s", at, describe(a.Elements[i])))
+			case i >= len(a.Elements):
+				lines = append(lines, fmt.Sprintf("  %s: missing %s", at, describe(e.Elements[i])))
+			default:
+				lines = append(lines, diff(at, a.Elements[i], e.Elements[i])...)
+			}
+		}
+		return lines
+
+	case *object.Dict:
+		e, ok := expected.(*object.Dict)
+		if !ok {
+			return mismatch
+		}
+
+		keys := map[object.DictKey]object.Object{}
+		for k, pair := range a.Pairs {
+			keys[k] = pair.Key
+		}
+		for k, pair := range e.Pairs {
+			keys[k] = pair.Key
+		}
+
+		lines := []string{}
+		for _, k := range sortedKeys(keys) {
+			at := fmt.Sprintf("%s[%s]", path, describe(keys[k]))
+			got, inActual := a.Pairs[k]
+			want, inExpected := e.Pairs[k]
+			switch {
+			case !inExpected:
+				lines = append(lines, fmt.Sprintf("  %s: unexpected %s", at, describe(got.Value)))
+			case !inActual:
+				lines = append(lines, fmt.Sprintf("  %s: missing %s", at, describe(want.Value)))
+			default:
+				lines = append(lines, diff(at, got.Value, want.Value)...)
+			}
+		}
+		return lines
+	}
+
+	return mismatch
+}
+
+func sortedKeys(keys map[object.DictKey]object.Object) []object.DictKey {
+	sorted := []object.DictKey{}
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return keys[sorted[i]].Inspect() < keys[sorted[j]].Inspect()
+	})
+	return sorted
+}
@@ -9,6 +9,7 @@ type Environment struct {
 	store         map[string]Object
 	outer         *Environment
 	importedFiles map[string]bool
+	dir           string
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -24,6 +25,19 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Assign sets name in the closest environment that defines it. It reports
+// whether one does.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -37,3 +51,16 @@ func (e *Environment) IsImported(path string) bool {
 func (e *Environment) MarkImported(path string) {
 	e.importedFiles[path] = true
 }
+
+// Dir is where imports are resolved from. Enclosed environments use the
+// directory of the one they extend.
+func (e *Environment) Dir() string {
+	if e.dir == "" && e.outer != nil {
+		return e.outer.Dir()
+	}
+	return e.dir
+}
+
+func (e *Environment) SetDir(dir string) {
+	e.dir = dir
+}
@@ -0,0 +1,141 @@
+package object
+
+// Equal compares values structurally: arrays element by element, dicts and
+// sets by their contents and numbers by value, so 1 equals 1.0.
+func Equal(a, b Object) bool {
+	if cmp, ok := Compare(a, b); ok {
+		return cmp == 0
+	}
+
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !Equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *Dict:
+		b, ok := b.(*Dict)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !Equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+
+	case *Set:
+		b, ok := b.(*Set)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for key := range a.Elements {
+			if _, ok := b.Elements[key]; !ok {
+				return false
+			}
+		}
+		return true
+
+	case *EnumValue:
+		b, ok := b.(*EnumValue)
+		return ok && a.Inspect() == b.Inspect()
+
+	case *Error:
+		b, ok := b.(*Error)
+		return ok && a.Message == b.Message
+	}
+
+	return a == b
+}
+
+// Copy returns a deep copy of the arrays, dicts, sets and closures in obj.
+// Other values are immutable and are returned as they are. Values shared
+// inside obj stay shared in the copy.
+func Copy(obj Object) Object {
+	return copyObject(obj, map[Object]Object{})
+}
+
+// CopyAll copies several values at once. Values shared between them stay
+// shared between the copies.
+func CopyAll(objs []Object) []Object {
+	copies := map[Object]Object{}
+	result := make([]Object, len(objs))
+	for i, obj := range objs {
+		result[i] = copyObject(obj, copies)
+	}
+	return result
+}
+
+func copyObject(obj Object, copies map[Object]Object) Object {
+	switch obj := obj.(type) {
+	case *Array:
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+		c := &Array{Elements: make([]Object, len(obj.Elements))}
+		copies[obj] = c
+		for i, el := range obj.Elements {
+			c.Elements[i] = copyObject(el, copies)
+		}
+		return c
+
+	case *Dict:
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+		c := &Dict{Pairs: make(map[DictKey]DictPair, len(obj.Pairs))}
+		copies[obj] = c
+		for key, pair := range obj.Pairs {
+			c.Pairs[key] = DictPair{Key: pair.Key, Value: copyObject(pair.Value, copies)}
+		}
+		return c
+
+	case *Set:
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+		c := NewSet()
+		copies[obj] = c
+		for _, el := range obj.Values() {
+			c.Add(el)
+		}
+		return c
+
+	case *Closure:
+		if len(obj.Free) == 0 {
+			return obj
+		}
+		if c, ok := copies[obj]; ok {
+			return c
+		}
+		c := &Closure{Fn: obj.Fn, Free: make([]Object, len(obj.Free))}
+		copies[obj] = c
+		for i, free := range obj.Free {
+			c.Free[i] = copyObject(free, copies)
+		}
+		return c
+	}
+
+	return obj
+}
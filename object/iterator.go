@@ -0,0 +1,75 @@
+package object
+
+import "fmt"
+
+// Iterator produces values one at a time. Next returns ok=false once the
+// iterator is exhausted.
+type Iterator interface {
+	Object
+	Next() (value Object, ok bool, err error)
+}
+
+// Restartable iterators can produce a fresh copy of themselves that starts
+// from the beginning, which lets a generator serve every HTTP request.
+type Restartable interface {
+	Restart() Iterator
+}
+
+// ElementsIterator walks over a snapshot of a collection, so changing the
+// collection inside a `for` loop does not affect the loop.
+type ElementsIterator struct {
+	elements []Object
+	pos      int
+}
+
+func (it *ElementsIterator) Type() ObjectType { return ITERATOR_OBJ }
+func (it *ElementsIterator) Inspect() string  { return "iterator" }
+
+func (it *ElementsIterator) Next() (Object, bool, error) {
+	if it.pos >= len(it.elements) {
+		return nil, false, nil
+	}
+
+	el := it.elements[it.pos]
+	it.pos++
+	return el, true, nil
+}
+
+// NewIterator returns obj itself when it is already an iterator, and an
+// iterator over its Elements otherwise.
+func NewIterator(obj Object) (Iterator, error) {
+	if it, ok := obj.(Iterator); ok {
+		return it, nil
+	}
+
+	elements, ok := Elements(obj)
+	if !ok {
+		return nil, fmt.Errorf("object is not iterable: %s", obj.Type())
+	}
+
+	return &ElementsIterator{elements: elements}, nil
+}
+
+// Collect drains obj into a slice. Arrays are returned as they are.
+func Collect(obj Object) ([]Object, error) {
+	if arr, ok := obj.(*Array); ok {
+		return arr.Elements, nil
+	}
+
+	it, err := NewIterator(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := []Object{}
+	for {
+		el, ok, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return elements, nil
+		}
+		elements = append(elements, el)
+	}
+}
@@ -0,0 +1,438 @@
+package object
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (b *BigInt) Inspect() string  { return b.Value.String() }
+
+func (b *BigInt) DictKey() DictKey {
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+
+	return DictKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+// NewBigInt returns an Integer when value fits in 64 bits, so a BigInt only
+// ever holds numbers that really need it.
+func NewBigInt(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+// Decimal is an exact base-10 number: Unscaled * 10^-Scale.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int32
+}
+
+func (d *Decimal) Type() ObjectType { return DECIMAL_OBJ }
+func (d *Decimal) Inspect() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.Scale <= 0 {
+		return sign + digits + strings.Repeat("0", int(-d.Scale))
+	}
+
+	scale := int(d.Scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// DictKey ignores trailing zeros so that 1.50 and 1.5 are the same key.
+func (d *Decimal) DictKey() DictKey {
+	h := fnv.New64a()
+	h.Write([]byte(d.normalize().Inspect()))
+
+	return DictKey{Type: d.Type(), Value: h.Sum64()}
+}
+
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"
+	RoundHalfDown RoundingMode = "half_down"
+	RoundHalfEven RoundingMode = "half_even"
+	RoundUp       RoundingMode = "up"
+	RoundDown     RoundingMode = "down"
+	RoundCeiling  RoundingMode = "ceiling"
+	RoundFloor    RoundingMode = "floor"
+)
+
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	switch mode := RoundingMode(s); mode {
+	case RoundHalfUp, RoundHalfDown, RoundHalfEven, RoundUp, RoundDown, RoundCeiling, RoundFloor:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode: %s", s)
+}
+
+// DecimalContext controls how many digits a decimal division keeps and how
+// the last one is rounded.
+type DecimalContext struct {
+	Scale    int32
+	Rounding RoundingMode
+}
+
+var DefaultDecimalContext = DecimalContext{Scale: 16, Rounding: RoundHalfUp}
+
+// MaxDecimalScale is the most digits after the point that decimalRounding
+// and round accept, so a program cannot make every division build huge
+// numbers.
+const MaxDecimalScale = 1000
+
+func ParseDecimal(s string) (*Decimal, error) {
+	input := strings.TrimSpace(s)
+	digits := strings.TrimLeft(input, "+-")
+	if len(input)-len(digits) > 1 {
+		return nil, fmt.Errorf("could not parse %q as decimal", s)
+	}
+
+	var scale int32
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		scale = int32(len(digits) - i - 1)
+		digits = digits[:i] + digits[i+1:]
+	}
+
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return nil, fmt.Errorf("could not parse %q as decimal", s)
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if strings.HasPrefix(input, "-") {
+		unscaled.Neg(unscaled)
+	}
+
+	return &Decimal{Unscaled: unscaled, Scale: scale}, nil
+}
+
+// ToDecimal converts any number to a Decimal. Floats use their shortest
+// representation, so 0.1 becomes exactly 0.1.
+func ToDecimal(obj Object) (*Decimal, bool) {
+	switch obj := obj.(type) {
+	case *Decimal:
+		return obj, true
+	case *Integer:
+		return &Decimal{Unscaled: big.NewInt(obj.Value)}, true
+	case *BigInt:
+		return &Decimal{Unscaled: new(big.Int).Set(obj.Value)}, true
+	case *Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return nil, false
+		}
+		d, err := ParseDecimal(strconv.FormatFloat(obj.Value, 'f', -1, 64))
+		return d, err == nil
+	}
+	return nil, false
+}
+
+func toBigInt(obj Object) (*big.Int, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return big.NewInt(obj.Value), true
+	case *BigInt:
+		return obj.Value, true
+	}
+	return nil, false
+}
+
+func (d *Decimal) Float() float64 {
+	f, _ := strconv.ParseFloat(d.Inspect(), 64)
+	return f
+}
+
+// Rescale returns d with exactly scale digits after the point, rounding with
+// mode when digits are dropped.
+func (d *Decimal) Rescale(scale int32, mode RoundingMode) *Decimal {
+	if scale >= d.Scale {
+		unscaled := new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale))
+		return &Decimal{Unscaled: unscaled, Scale: scale}
+	}
+
+	return &Decimal{Unscaled: roundQuo(d.Unscaled, pow10(d.Scale-scale), mode), Scale: scale}
+}
+
+// Integer truncates d towards zero.
+func (d *Decimal) Integer() Object {
+	return NewBigInt(d.Rescale(0, RoundDown).Unscaled)
+}
+
+func (d *Decimal) Cmp(other *Decimal) int {
+	scale := max(d.Scale, other.Scale)
+	return d.Rescale(scale, RoundDown).Unscaled.Cmp(other.Rescale(scale, RoundDown).Unscaled)
+}
+
+func (d *Decimal) normalize() *Decimal {
+	result := &Decimal{Unscaled: new(big.Int).Set(d.Unscaled), Scale: d.Scale}
+	ten := big.NewInt(10)
+	rem := new(big.Int)
+	for result.Scale > 0 {
+		q, r := new(big.Int).QuoRem(result.Unscaled, ten, rem)
+		if r.Sign() != 0 {
+			break
+		}
+		result.Unscaled = q
+		result.Scale--
+	}
+	return result
+}
+
+func (d *Decimal) add(other *Decimal, negate bool) *Decimal {
+	scale := max(d.Scale, other.Scale)
+	left := d.Rescale(scale, RoundDown).Unscaled
+	right := other.Rescale(scale, RoundDown).Unscaled
+
+	result := new(big.Int)
+	if negate {
+		result.Sub(left, right)
+	} else {
+		result.Add(left, right)
+	}
+	return &Decimal{Unscaled: result, Scale: scale}
+}
+
+func (d *Decimal) mul(other *Decimal) *Decimal {
+	return &Decimal{Unscaled: new(big.Int).Mul(d.Unscaled, other.Unscaled), Scale: d.Scale + other.Scale}
+}
+
+// quo divides using DefaultDecimalContext. Trailing zeros are dropped, but
+// the result never has fewer digits than its operands, so 10.00 / 4 is 2.50.
+func (d *Decimal) quo(other *Decimal) (*Decimal, error) {
+	if other.Unscaled.Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	ctx := DefaultDecimalContext
+	scale := max(ctx.Scale, d.Scale, other.Scale)
+
+	numerator := new(big.Int).Mul(d.Unscaled, pow10(scale+other.Scale-d.Scale))
+	result := &Decimal{Unscaled: roundQuo(numerator, other.Unscaled, ctx.Rounding), Scale: scale}
+
+	keep := max(d.Scale, other.Scale)
+	normalized := result.normalize()
+	if normalized.Scale < keep {
+		return normalized.Rescale(keep, RoundDown), nil
+	}
+	return normalized, nil
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func roundQuo(n, d *big.Int, mode RoundingMode) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+
+	positive := n.Sign() == d.Sign()
+
+	var increment bool
+	switch mode {
+	case RoundUp:
+		increment = true
+	case RoundDown:
+		increment = false
+	case RoundCeiling:
+		increment = positive
+	case RoundFloor:
+		increment = !positive
+	default:
+		half := new(big.Int).Lsh(new(big.Int).Abs(r), 1).Cmp(new(big.Int).Abs(d))
+		switch mode {
+		case RoundHalfDown:
+			increment = half > 0
+		case RoundHalfEven:
+			increment = half > 0 || (half == 0 && q.Bit(0) == 1)
+		default:
+			increment = half >= 0
+		}
+	}
+
+	if increment {
+		if positive {
+			q.Add(q, big.NewInt(1))
+		} else {
+			q.Sub(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// IntegerOverflows reports whether result, computed with int64 arithmetic,
+// wrapped around.
+func IntegerOverflows(operator string, left, right, result int64) bool {
+	switch operator {
+	case "+":
+		return (left > 0 && right > 0 && result < 0) || (left < 0 && right < 0 && result >= 0)
+	case "-":
+		return (left >= 0 && right < 0 && result < 0) || (left < 0 && right > 0 && result >= 0)
+	case "*":
+		return left != 0 && (result/left != right || (left == -1 && right == math.MinInt64))
+	case "/":
+		return left == math.MinInt64 && right == -1
+	}
+	return false
+}
+
+// IsExactNumber reports whether obj needs Arithmetic and Compare rather
+// than the int64 and float64 fast paths.
+func IsExactNumber(obj Object) bool {
+	switch obj.(type) {
+	case *BigInt, *Decimal:
+		return true
+	}
+	return false
+}
+
+// Arithmetic applies operator to two numbers, at least one of which is a
+// BigInt or Decimal, or two Integers whose result overflows int64. Decimals
+// win over everything else; a BigInt mixed with a Float gives a Float.
+func Arithmetic(operator string, left, right Object) (Object, error) {
+	if left.Type() == DECIMAL_OBJ || right.Type() == DECIMAL_OBJ {
+		l, lok := ToDecimal(left)
+		r, rok := ToDecimal(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+		}
+
+		switch operator {
+		case "+":
+			return l.add(r, false), nil
+		case "-":
+			return l.add(r, true), nil
+		case "*":
+			return l.mul(r), nil
+		case "/":
+			return l.quo(r)
+		}
+		return nil, fmt.Errorf("unknown decimal operator: %s", operator)
+	}
+
+	if left.Type() == FLOAT_OBJ || right.Type() == FLOAT_OBJ {
+		l, lok := toFloat(left)
+		r, rok := toFloat(right)
+		if !lok || !rok {
+			return nil, fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+		}
+
+		switch operator {
+		case "+":
+			return &Float{Value: l + r}, nil
+		case "-":
+			return &Float{Value: l - r}, nil
+		case "*":
+			return &Float{Value: l * r}, nil
+		case "/":
+			return &Float{Value: l / r}, nil
+		}
+		return nil, fmt.Errorf("unknown float operator: %s", operator)
+	}
+
+	l, lok := toBigInt(left)
+	r, rok := toBigInt(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+	}
+
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(l, r)
+	case "-":
+		result.Sub(l, r)
+	case "*":
+		result.Mul(l, r)
+	case "/", "%":
+		if r.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if operator == "/" {
+			result.Quo(l, r)
+		} else {
+			result.Rem(l, r)
+		}
+	default:
+		return nil, fmt.Errorf("unknown integer operator: %s", operator)
+	}
+
+	return NewBigInt(result), nil
+}
+
+// Compare orders two numbers of any kind. ok is false when either side is
+// not a number.
+func Compare(left, right Object) (result int, ok bool) {
+	if left.Type() == FLOAT_OBJ || right.Type() == FLOAT_OBJ {
+		if left.Type() != DECIMAL_OBJ && right.Type() != DECIMAL_OBJ {
+			l, lok := toFloat(left)
+			r, rok := toFloat(right)
+			if !lok || !rok {
+				return 0, false
+			}
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	l, lok := ToDecimal(left)
+	r, rok := ToDecimal(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	return l.Cmp(r), true
+}
+
+func Negate(obj Object) (Object, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		if obj.Value == math.MinInt64 {
+			return NewBigInt(new(big.Int).Neg(big.NewInt(obj.Value))), true
+		}
+		return &Integer{Value: -obj.Value}, true
+	case *BigInt:
+		return NewBigInt(new(big.Int).Neg(obj.Value)), true
+	case *Decimal:
+		return &Decimal{Unscaled: new(big.Int).Neg(obj.Unscaled), Scale: obj.Scale}, true
+	}
+	return nil, false
+}
+
+func toFloat(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Float:
+		return obj.Value, true
+	case *Integer:
+		return float64(obj.Value), true
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f, true
+	case *Decimal:
+		return obj.Float(), true
+	}
+	return 0, false
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +31,13 @@ const (
 	DATE_OBJ              = "DATE"
 	RECORD_OBJ            = "RECORD"
 	ENV_OBJ               = "ENV"
+	ENUM_OBJ              = "ENUM"
+	ENUM_VALUE_OBJ        = "ENUM_VALUE"
+	SET_OBJ               = "SET"
+	BIGINT_OBJ            = "BIGINT"
+	DECIMAL_OBJ           = "DECIMAL"
+	ITERATOR_OBJ          = "ITERATOR"
+	GENERATOR_OBJ         = "GENERATOR"
 )
 
 type Object interface {
@@ -101,8 +109,15 @@ func (s *String) Inspect() string  { return s.Value }
 
 type BuiltinFunction func(args ...Object) Object
 
+// CallFunction calls a Zumbra function from Go. Runtime errors are
+// returned as error.
+type CallFunction func(fn Object, args ...Object) (Object, error)
+
 type Builtin struct {
 	Fn BuiltinFunction
+	// Callback is used instead of Fn by builtins that call functions they
+	// are given.
+	Callback func(call CallFunction, args ...Object) Object
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
@@ -189,6 +204,15 @@ type CompiledFunction struct {
 	Instructions  code.Instructions
 	NumLocals     int
 	NumParameters int
+	// Generator functions return a generator instead of running their body.
+	Generator bool
+	// Debugging information: the name given with `var name << fct`, the
+	// source line of every instruction and the names of locals and free
+	// variables by index.
+	Name       string
+	Lines      code.LineTable
+	LocalNames []string
+	FreeNames  []string
 }
 
 func (cf *CompiledFunction) Type() ObjectType { return COMPILED_FUNCTION_OBJ }
@@ -238,3 +262,163 @@ func (r *Record) Type() ObjectType { return RECORD_OBJ }
 func (r *Record) Inspect() string {
 	return fmt.Sprintf("%v", r.Fields)
 }
+
+type Enum struct {
+	Name     string
+	Variants []*EnumValue
+}
+
+func NewEnum(name string, variants []string) *Enum {
+	enum := &Enum{Name: name}
+	for i, v := range variants {
+		enum.Variants = append(enum.Variants, &EnumValue{Enum: name, Name: v, Ordinal: i})
+	}
+	return enum
+}
+
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	variants := []string{}
+	for _, v := range e.Variants {
+		variants = append(variants, v.Name)
+	}
+	return fmt.Sprintf("enum %s { %s }", e.Name, strings.Join(variants, ", "))
+}
+
+func (e *Enum) Variant(name string) (*EnumValue, bool) {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type EnumValue struct {
+	Enum    string
+	Name    string
+	Ordinal int
+}
+
+func (ev *EnumValue) Type() ObjectType { return ENUM_VALUE_OBJ }
+func (ev *EnumValue) Inspect() string  { return ev.Enum + "." + ev.Name }
+
+func (ev *EnumValue) DictKey() DictKey {
+	h := fnv.New64a()
+	h.Write([]byte(ev.Inspect()))
+
+	return DictKey{Type: ev.Type(), Value: h.Sum64()}
+}
+
+// Set keeps its elements in insertion order so that printing and iterating
+// over it is deterministic.
+type Set struct {
+	Elements map[DictKey]Object
+	keys     []DictKey
+}
+
+func NewSet() *Set {
+	return &Set{Elements: map[DictKey]Object{}}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	if len(s.keys) == 0 {
+		return "set()"
+	}
+
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range s.Values() {
+		elements = append(elements, el.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (s *Set) Add(obj Object) error {
+	key, ok := obj.(Dictable)
+	if !ok {
+		return fmt.Errorf("unusable as set element: %s", obj.Type())
+	}
+
+	k := key.DictKey()
+	if _, ok := s.Elements[k]; !ok {
+		s.keys = append(s.keys, k)
+	}
+	s.Elements[k] = obj
+
+	return nil
+}
+
+func (s *Set) Remove(obj Object) {
+	key, ok := obj.(Dictable)
+	if !ok {
+		return
+	}
+
+	k := key.DictKey()
+	if _, ok := s.Elements[k]; !ok {
+		return
+	}
+
+	delete(s.Elements, k)
+	for i, existing := range s.keys {
+		if existing == k {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Set) Has(obj Object) bool {
+	key, ok := obj.(Dictable)
+	if !ok {
+		return false
+	}
+
+	_, ok = s.Elements[key.DictKey()]
+	return ok
+}
+
+func (s *Set) Values() []Object {
+	values := make([]Object, 0, len(s.keys))
+	for _, k := range s.keys {
+		values = append(values, s.Elements[k])
+	}
+	return values
+}
+
+// Elements returns the values a `for` loop visits when iterating over obj:
+// the items of arrays and sets, the keys of dicts and the characters of
+// strings.
+func Elements(obj Object) ([]Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return append([]Object{}, obj.Elements...), true
+	case *Set:
+		return obj.Values(), true
+	case *Dict:
+		keys := []Object{}
+		for _, pair := range obj.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Inspect() < keys[j].Inspect()
+		})
+		return keys, true
+	case *String:
+		chars := []Object{}
+		for _, ch := range obj.Value {
+			chars = append(chars, &String{Value: string(ch)})
+		}
+		return chars, true
+	}
+
+	return nil, false
+}
@@ -1,6 +1,7 @@
 package object
 
 import (
+	"math"
 	"testing"
 )
 
@@ -18,3 +19,173 @@ func TestStringDictKey(t *testing.T) {
 		t.Errorf("diff1.DictKey() != diff2.DictKey()")
 	}
 }
+
+func TestSet(t *testing.T) {
+	set := NewSet()
+	set.Add(&String{Value: "b"})
+	set.Add(&Integer{Value: 1})
+	set.Add(&String{Value: "b"})
+
+	if len(set.Elements) != 2 {
+		t.Fatalf("set has wrong size. want=2, got=%d", len(set.Elements))
+	}
+
+	if set.Inspect() != "{b, 1}" {
+		t.Errorf("set.Inspect() wrong. got=%q", set.Inspect())
+	}
+
+	if err := set.Add(&Array{}); err == nil {
+		t.Errorf("expected error when adding an array to a set")
+	}
+
+	set.Remove(&String{Value: "b"})
+	if set.Has(&String{Value: "b"}) || !set.Has(&Integer{Value: 1}) {
+		t.Errorf("set has wrong elements after remove: %s", set.Inspect())
+	}
+}
+
+func TestEnumValueDictKey(t *testing.T) {
+	enum := NewEnum("Status", []string{"Active", "Banned"})
+	active, _ := enum.Variant("Active")
+	banned, _ := enum.Variant("Banned")
+
+	if active.DictKey() == banned.DictKey() {
+		t.Errorf("variants have the same dict key")
+	}
+
+	if active.Inspect() != "Status.Active" {
+		t.Errorf("active.Inspect() wrong. got=%q", active.Inspect())
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"10.35", "10.35"},
+		{"-0.05", "-0.05"},
+		{"+7", "7"},
+		{".5", "0.5"},
+		{"1.500", "1.500"},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.input)
+		if err != nil {
+			t.Fatalf("ParseDecimal(%q) returned error: %s", tt.input, err)
+		}
+		if d.Inspect() != tt.expected {
+			t.Errorf("ParseDecimal(%q) = %s, want %s", tt.input, d.Inspect(), tt.expected)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1.2.3", "--1", "1e5"} {
+		if _, err := ParseDecimal(input); err == nil {
+			t.Errorf("ParseDecimal(%q) should fail", input)
+		}
+	}
+
+	a, _ := ParseDecimal("1.50")
+	b, _ := ParseDecimal("1.5")
+	if a.DictKey() != b.DictKey() {
+		t.Errorf("equal decimals have different dict keys")
+	}
+}
+
+func TestDecimalRounding(t *testing.T) {
+	tests := []struct {
+		input    string
+		mode     RoundingMode
+		expected string
+	}{
+		{"2.345", RoundHalfUp, "2.35"},
+		{"2.345", RoundHalfDown, "2.34"},
+		{"2.345", RoundHalfEven, "2.34"},
+		{"2.355", RoundHalfEven, "2.36"},
+		{"2.341", RoundUp, "2.35"},
+		{"-2.341", RoundUp, "-2.35"},
+		{"2.349", RoundDown, "2.34"},
+		{"-2.341", RoundCeiling, "-2.34"},
+		{"-2.341", RoundFloor, "-2.35"},
+		{"2.3", RoundHalfUp, "2.30"},
+	}
+
+	for _, tt := range tests {
+		d, _ := ParseDecimal(tt.input)
+		if got := d.Rescale(2, tt.mode).Inspect(); got != tt.expected {
+			t.Errorf("%s rounded %s = %s, want %s", tt.input, tt.mode, got, tt.expected)
+		}
+	}
+}
+
+func TestIntegerOverflowPromotion(t *testing.T) {
+	result, err := Arithmetic("*", &Integer{Value: math.MaxInt64}, &Integer{Value: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Type() != BIGINT_OBJ || result.Inspect() != "18446744073709551614" {
+		t.Errorf("wrong result: %s %s", result.Type(), result.Inspect())
+	}
+
+	result, _ = Arithmetic("-", result, result)
+	if _, ok := result.(*Integer); !ok {
+		t.Errorf("small result was not demoted to Integer, got %T", result)
+	}
+
+	var max int64 = math.MaxInt64
+	if !IntegerOverflows("+", max, 1, max+1) {
+		t.Errorf("overflow not detected")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	set := NewSet()
+	set.Add(&String{Value: "a"})
+	otherSet := NewSet()
+	otherSet.Add(&String{Value: "a"})
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Float{Value: 1}, true},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "1"}, &Integer{Value: 1}, false},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{Elements: []Object{&Integer{Value: 1}}}, true},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{Elements: []Object{}}, false},
+		{set, otherSet, true},
+		{&Null{}, &Null{}, true},
+	}
+
+	for i, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("tests[%d]: Equal(%s, %s) = %t, want %t", i, tt.a.Inspect(), tt.b.Inspect(), got, tt.expected)
+		}
+	}
+}
+
+func TestCopyAll(t *testing.T) {
+	shared := &Array{Elements: []Object{&Integer{Value: 1}}}
+	dict := &Dict{Pairs: map[DictKey]DictPair{}}
+	key := &String{Value: "list"}
+	dict.Pairs[key.DictKey()] = DictPair{Key: key, Value: shared}
+
+	copies := CopyAll([]Object{shared, dict, nil})
+
+	copied := copies[0].(*Array)
+	if copied == shared {
+		t.Fatalf("array was not copied")
+	}
+	if copies[1].(*Dict).Pairs[key.DictKey()].Value != copied {
+		t.Errorf("shared array is no longer shared in the copy")
+	}
+	if copies[2] != nil {
+		t.Errorf("nil was not kept, got %v", copies[2])
+	}
+
+	copied.Elements[0] = &Integer{Value: 2}
+	if shared.Elements[0].(*Integer).Value != 1 {
+		t.Errorf("changing the copy changed the original")
+	}
+}
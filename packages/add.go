@@ -0,0 +1,304 @@
+package packages
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"zumbra/project"
+)
+
+// Add vendors the library at source into modules and records it in the
+// lockfile in root. source is a directory, a git checkout or a .tar,
+// .tar.gz or .tgz file. The package is named name, or else after the
+// library's zumbra.json or its directory or file name.
+func Add(root, modules, source, name string) (Package, error) {
+	source, err := filepath.Abs(source)
+	if err != nil {
+		return Package{}, err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return Package{}, err
+	}
+
+	if err := os.MkdirAll(modules, 0755); err != nil {
+		return Package{}, err
+	}
+	staging, err := os.MkdirTemp(modules, ".add-")
+	if err != nil {
+		return Package{}, err
+	}
+	defer os.RemoveAll(staging)
+
+	fallback, version := defaultName(source), ""
+	switch {
+	case !info.IsDir():
+		if !isTarball(source) {
+			return Package{}, fmt.Errorf("%s is not a directory or a .tar, .tar.gz or .tgz file", source)
+		}
+		// Tarballs are usually named like lib-1.0.tar.gz.
+		fallback, version = splitVersion(fallback)
+		err = extractTarball(source, staging)
+	case isGit(source):
+		version, err = exportGit(source, staging)
+	default:
+		err = copyDir(source, staging)
+	}
+	if err != nil {
+		return Package{}, err
+	}
+
+	manifestVersion, manifestName, err := readManifest(staging)
+	if err != nil {
+		return Package{}, err
+	}
+	if manifestVersion != "" {
+		version = manifestVersion
+	}
+	if version == "" {
+		version = "local"
+	}
+	if name == "" {
+		name = manifestName
+	}
+	if name == "" {
+		name = fallback
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return Package{}, fmt.Errorf("invalid package name %q", name)
+	}
+
+	hash, err := Hash(staging)
+	if err != nil {
+		return Package{}, err
+	}
+
+	dest := filepath.Join(modules, name)
+	if err := os.RemoveAll(dest); err != nil {
+		return Package{}, err
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return Package{}, err
+	}
+
+	lock, err := ReadLock(root)
+	if err != nil {
+		return Package{}, err
+	}
+	p := Package{Name: name, Version: version, Source: source, Hash: hash}
+	lock.Set(p)
+	return p, lock.Write(root)
+}
+
+func readManifest(dir string) (version, name string, err error) {
+	file := filepath.Join(dir, project.ManifestFile)
+	if _, err := os.Stat(file); err != nil {
+		return "", "", nil
+	}
+	m, err := project.Load(file)
+	if err != nil {
+		return "", "", err
+	}
+	return m.Version, m.Name, nil
+}
+
+func defaultName(source string) string {
+	name := filepath.Base(source)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".git"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// splitVersion splits a name like lib-1.0 or lib-v1.0 into lib and its
+// version.
+func splitVersion(name string) (string, string) {
+	i := strings.LastIndex(name, "-")
+	if i < 0 {
+		return name, ""
+	}
+	version := name[i+1:]
+	if digits := strings.TrimPrefix(version, "v"); digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return name, ""
+	}
+	return name[:i], version
+}
+
+func isTarball(file string) bool {
+	return strings.HasSuffix(file, ".tar") || strings.HasSuffix(file, ".tar.gz") || strings.HasSuffix(file, ".tgz")
+}
+
+// skip reports whether a library file is left out of the vendored copy:
+// hidden files, like .git, and the library's own dependencies.
+func skip(name string) bool {
+	return strings.HasPrefix(name, ".") || name == project.DefaultDependencyDir
+}
+
+func copyDir(source, dest string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == source {
+			return nil
+		}
+		if skip(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// isGit reports whether dir is a git checkout or a bare repository.
+func isGit(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	_, errHead := os.Stat(filepath.Join(dir, "HEAD"))
+	_, errObjects := os.Stat(filepath.Join(dir, "objects"))
+	return errHead == nil && errObjects == nil
+}
+
+// exportGit vendors the files committed at HEAD and returns the version git
+// describes them as.
+func exportGit(dir, dest string) (string, error) {
+	archive, err := exec.Command("git", "-C", dir, "archive", "--format=tar", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git archive in %s: %s", dir, commandError(err))
+	}
+	if err := extractTar(bytes.NewReader(archive), dest, false); err != nil {
+		return "", err
+	}
+
+	version, err := exec.Command("git", "-C", dir, "describe", "--tags", "--always").Output()
+	if err != nil {
+		return "", fmt.Errorf("git describe in %s: %s", dir, commandError(err))
+	}
+	return strings.TrimSpace(string(version)), nil
+}
+
+func commandError(err error) string {
+	if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+		return strings.TrimSpace(string(exit.Stderr))
+	}
+	return err.Error()
+}
+
+func extractTarball(file, dest string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(file, ".tar") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return extractTar(r, dest, true)
+}
+
+// extractTar writes the regular files in r under dest. With strip, a single
+// directory holding everything, as in lib-1.0/..., is left out of the paths.
+func extractTar(r io.Reader, dest string, strip bool) error {
+	type file struct {
+		name string
+		data []byte
+	}
+	files := []file{}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("archive entry %s is outside the archive", header.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{name, data})
+	}
+
+	prefix := ""
+	if strip && len(files) > 0 {
+		if top, _, ok := strings.Cut(files[0].name, "/"); ok {
+			prefix = top + "/"
+			for _, f := range files {
+				if !strings.HasPrefix(f.name, prefix) {
+					prefix = ""
+					break
+				}
+			}
+		}
+	}
+
+	for _, f := range files {
+		name := strings.TrimPrefix(f.name, prefix)
+		if skipped(name) {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, f.data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipped reports whether any element of a slash-separated path is
+// skipped.
+func skipped(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if skip(part) {
+			return true
+		}
+	}
+	return false
+}
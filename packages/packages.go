@@ -0,0 +1,168 @@
+// Package packages vendors Zumbra libraries into a project's zumbra_modules
+// directory and records what was installed in a lockfile, so copies can be
+// checked later. Libraries come from local directories, git checkouts or
+// tarballs; nothing is downloaded.
+package packages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const LockFile = "zumbra.lock"
+
+// Package is a lockfile entry.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	// Hash is the sha256 of the vendored files, as computed by Hash.
+	Hash string `json:"hash"`
+}
+
+type Lock struct {
+	Packages []Package `json:"packages"`
+}
+
+// ReadLock reads the lockfile in dir. A missing lockfile is an empty one.
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Lock{Packages: []Package{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("%s: %s", LockFile, err)
+	}
+	if lock.Packages == nil {
+		lock.Packages = []Package{}
+	}
+	return lock, nil
+}
+
+// Write saves the lockfile in dir, with the packages sorted by name.
+func (l *Lock) Write(dir string) error {
+	sort.Slice(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Name < l.Packages[j].Name
+	})
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, LockFile), append(data, '\n'), 0644)
+}
+
+// Set adds p, replacing the package with the same name.
+func (l *Lock) Set(p Package) {
+	for i, existing := range l.Packages {
+		if existing.Name == p.Name {
+			l.Packages[i] = p
+			return
+		}
+	}
+	l.Packages = append(l.Packages, p)
+}
+
+// Hash returns "sha256:" and the hex digest of the files under dir: their
+// slash-separated paths and contents, in path order.
+func Hash(dir string) (string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	entries := []string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		entries = append(entries, filepath.ToSlash(rel)+"\x00"+hex.EncodeToString(sum[:])+"\n")
+	}
+	sort.Strings(entries)
+
+	h := sha256.New()
+	for _, entry := range entries {
+		h.Write([]byte(entry))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Problem is a package whose vendored copy does not match the lockfile.
+type Problem struct {
+	Name    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Name + ": " + p.Message
+}
+
+// Verify checks every package in the lockfile in root against its copy in
+// modules, and reports directories in modules the lockfile does not know.
+func Verify(root, modules string) ([]Problem, error) {
+	lock, err := ReadLock(root)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []Problem{}
+	locked := map[string]bool{}
+
+	for _, p := range lock.Packages {
+		locked[p.Name] = true
+
+		dir := filepath.Join(modules, p.Name)
+		if _, err := os.Stat(dir); err != nil {
+			problems = append(problems, Problem{p.Name, "not installed"})
+			continue
+		}
+
+		hash, err := Hash(dir)
+		if err != nil {
+			return nil, err
+		}
+		if hash != p.Hash {
+			problems = append(problems, Problem{p.Name, fmt.Sprintf("hash mismatch: lockfile has %s, files have %s", p.Hash, hash)})
+		}
+	}
+
+	entries, err := os.ReadDir(modules)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !locked[entry.Name()] && !strings.HasPrefix(entry.Name(), ".") {
+			problems = append(problems, Problem{entry.Name(), "not in " + LockFile})
+		}
+	}
+
+	return problems, nil
+}
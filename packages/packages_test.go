@@ -0,0 +1,227 @@
+package packages
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files := []string{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+func TestAddDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "strutil"), map[string]string{
+		"shout.zum":                  `var shout << fct(s) { s + "!" };`,
+		"zumbra.json":                `{"name": "strings", "version": "1.2.0"}`,
+		".env":                       "SECRET=1",
+		"zumbra_modules/dep/dep.zum": ``,
+	})
+	root := filepath.Join(dir, "app")
+	modules := filepath.Join(root, "zumbra_modules")
+
+	p, err := Add(root, modules, filepath.Join(dir, "strutil"), "")
+	if err != nil {
+		t.Fatalf("Add: %s", err)
+	}
+	if p.Name != "strings" || p.Version != "1.2.0" || !strings.HasPrefix(p.Hash, "sha256:") {
+		t.Errorf("wrong package: %+v", p)
+	}
+
+	want := []string{"strings/shout.zum", "strings/zumbra.json"}
+	if got := listFiles(t, modules); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong files vendored.\nwant=%v\ngot=%v", want, got)
+	}
+
+	lock, err := ReadLock(root)
+	if err != nil {
+		t.Fatalf("ReadLock: %s", err)
+	}
+	if !reflect.DeepEqual(lock.Packages, []Package{p}) {
+		t.Errorf("wrong lockfile: %+v", lock.Packages)
+	}
+
+	// Adding again replaces the package.
+	writeFiles(t, filepath.Join(dir, "strutil"), map[string]string{"zumbra.json": `{"name": "strings", "version": "1.3.0"}`})
+	if _, err := Add(root, modules, filepath.Join(dir, "strutil"), ""); err != nil {
+		t.Fatalf("Add: %s", err)
+	}
+	lock, _ = ReadLock(root)
+	if len(lock.Packages) != 1 || lock.Packages[0].Version != "1.3.0" {
+		t.Errorf("wrong lockfile after adding again: %+v", lock.Packages)
+	}
+}
+
+func TestAddTarball(t *testing.T) {
+	dir := t.TempDir()
+	tarball := filepath.Join(dir, "fmtlib-2.0.tar.gz")
+
+	f, err := os.Create(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"fmtlib-2.0/wrap.zum":     `var wrap << fct(s) { "[" + s + "]" };`,
+		"fmtlib-2.0/util/pad.zum": `var pad << fct(s) { " " + s };`,
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	modules := filepath.Join(dir, "zumbra_modules")
+	p, err := Add(dir, modules, tarball, "")
+	if err != nil {
+		t.Fatalf("Add: %s", err)
+	}
+	if p.Name != "fmtlib" || p.Version != "2.0" {
+		t.Errorf("wrong package: %+v", p)
+	}
+
+	want := []string{"fmtlib/util/pad.zum", "fmtlib/wrap.zum"}
+	if got := listFiles(t, modules); !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong files vendored.\nwant=%v\ngot=%v", want, got)
+	}
+}
+
+func TestAddGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "mathlib")
+	writeFiles(t, repo, map[string]string{"double.zum": `var double << fct(x) { x * 2 };`})
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", "double.zum")
+	git("commit", "-q", "-m", "add double")
+	git("tag", "v0.3.0")
+	writeFiles(t, repo, map[string]string{"draft.zum": `uncommitted`})
+
+	modules := filepath.Join(dir, "app", "zumbra_modules")
+	p, err := Add(filepath.Join(dir, "app"), modules, repo, "")
+	if err != nil {
+		t.Fatalf("Add: %s", err)
+	}
+	if p.Name != "mathlib" || p.Version != "v0.3.0" {
+		t.Errorf("wrong package: %+v", p)
+	}
+	if got := listFiles(t, modules); !reflect.DeepEqual(got, []string{"mathlib/double.zum"}) {
+		t.Errorf("only committed files should be vendored, got %v", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "a"), map[string]string{"a.zum": `var a << 1;`})
+	writeFiles(t, filepath.Join(dir, "b"), map[string]string{"b.zum": `var b << 2;`})
+	writeFiles(t, filepath.Join(dir, "c"), map[string]string{"c.zum": `var c << 3;`})
+
+	root := filepath.Join(dir, "app")
+	modules := filepath.Join(root, "zumbra_modules")
+	for _, lib := range []string{"a", "b", "c"} {
+		if _, err := Add(root, modules, filepath.Join(dir, lib), ""); err != nil {
+			t.Fatalf("Add: %s", err)
+		}
+	}
+
+	problems, err := Verify(root, modules)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Verify = %v, %v", problems, err)
+	}
+
+	writeFiles(t, modules, map[string]string{"b/b.zum": `var b << 20;`, "stray/x.zum": ``})
+	os.RemoveAll(filepath.Join(modules, "c"))
+
+	problems, err = Verify(root, modules)
+	if err != nil {
+		t.Fatalf("Verify: %s", err)
+	}
+	got := []string{}
+	for _, p := range problems {
+		got = append(got, p.Name+": "+strings.SplitN(p.Message, ":", 2)[0])
+	}
+	want := []string{"b: hash mismatch", "c: not installed", "stray: not in zumbra.lock"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong problems.\nwant=%v\ngot=%v", want, got)
+	}
+}
+
+func TestHashIgnoresLocation(t *testing.T) {
+	files := map[string]string{"a.zum": "1", "sub/b.zum": "2"}
+	one, two := t.TempDir(), t.TempDir()
+	writeFiles(t, one, files)
+	writeFiles(t, two, files)
+
+	h1, err1 := Hash(one)
+	h2, err2 := Hash(two)
+	if err1 != nil || err2 != nil || h1 != h2 {
+		t.Errorf("same files hash differently: %s %v, %s %v", h1, err1, h2, err2)
+	}
+
+	writeFiles(t, two, map[string]string{"sub/c.zum": ""})
+	if h3, _ := Hash(two); h3 == h1 {
+		t.Errorf("adding a file did not change the hash")
+	}
+}
+
+func TestUnsafeTarball(t *testing.T) {
+	dir := t.TempDir()
+	tarball := filepath.Join(dir, "evil.tar")
+	f, _ := os.Create(tarball)
+	tw := tar.NewWriter(f)
+	tw.WriteHeader(&tar.Header{Name: "../escape.zum", Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+	tw.Write([]byte("x"))
+	tw.Close()
+	f.Close()
+
+	_, err := Add(dir, filepath.Join(dir, "zumbra_modules"), tarball, "")
+	if err == nil || !strings.Contains(err.Error(), "outside the archive") {
+		t.Errorf("expected an error for an entry outside the archive, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.zum")); err == nil {
+		t.Errorf("file written outside the archive")
+	}
+}
@@ -1,9 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 
 	"strconv"
+	"strings"
 	"zumbra/ast"
 	"zumbra/lexer"
 	"zumbra/token"
@@ -16,6 +19,8 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQUAL: EQUALS,
 	token.LT:        LESSGREATER,
 	token.GT:        LESSGREATER,
+	token.LTE:       LESSGREATER,
+	token.GTE:       LESSGREATER,
 	token.PLUS:      SUM,
 	token.MINUS:     SUM,
 	token.SLASH:     PRODUCT,
@@ -24,6 +29,7 @@ var precedences = map[token.TokenType]int{
 	token.POWER:     PRODUCT,
 	token.LPAREN:    CALL,
 	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
 }
 
 const (
@@ -49,10 +55,16 @@ type Parser struct {
 	l *lexer.Lexer
 
 	errors []string
+	// errorTokens holds the token each error was found at.
+	errorTokens []token.Token
 
 	curToken  token.Token
 	peekToken token.Token
 
+	curDoc   *ast.CommentGroup
+	peekDoc  *ast.CommentGroup
+	comments []*ast.Comment
+
 	prefixParseFcts map[token.TokenType]prefixParseFct
 	infixParseFcts  map[token.TokenType]infixParseFct
 }
@@ -77,6 +89,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseDictLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFcts = make(map[token.TokenType]infixParseFct)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -110,7 +123,28 @@ func (p *Parser) Errors() []string {
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curDoc = p.peekDoc
+	p.peekDoc = nil
+
+	for {
+		tok := p.l.NextToken()
+
+		switch tok.Type {
+		case token.COMMENT:
+			p.comments = append(p.comments, &ast.Comment{Token: tok})
+		case token.DOC_COMMENT:
+			comment := &ast.Comment{Token: tok}
+			p.comments = append(p.comments, comment)
+
+			if p.peekDoc == nil {
+				p.peekDoc = &ast.CommentGroup{}
+			}
+			p.peekDoc.List = append(p.peekDoc.List, comment)
+		default:
+			p.peekToken = tok
+			return
+		}
+	}
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -124,6 +158,8 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+
+	program.Comments = p.comments
 	return program
 }
 
@@ -133,10 +169,16 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseVarStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	case token.WHILE:
 		return p.parseWhileStatement()
 	case token.IMPORT:
 		return p.parseImportStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.FOR:
+		return p.parseForStatement()
 	case token.IDENT:
 		if p.peekTokenIs(token.ASSIGN) {
 			return p.parseAssignStatement()
@@ -202,8 +244,82 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	return stmt
 }
 
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.curToken, Doc: p.curDoc}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Variants = append(stmt.Variants, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	if len(stmt.Variants) == 0 {
+		msg := fmt.Sprintf("enum %s must have at least one variant", stmt.Name.Value)
+		p.addError(p.curToken, msg)
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseVarStatement() *ast.VarStatement {
-	stmt := &ast.VarStatement{Token: p.curToken}
+	stmt := &ast.VarStatement{Token: p.curToken, Doc: p.curDoc}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -211,6 +327,12 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Type = p.parseType()
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -222,7 +344,7 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 		fl.Name = stmt.Name.Value
 	}
 
-	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -247,9 +369,56 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+func (p *Parser) addError(tok token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorTokens = append(p.errorTokens, tok)
+}
+
+// Incomplete reports whether the errors come from the input ending early,
+// as when a block, a string or a comment is still open, so more input could
+// fix them.
+func (p *Parser) Incomplete() bool {
+	for _, tok := range p.errorTokens {
+		if tok.Type == token.EOF || (tok.Type == token.ILLEGAL && strings.HasPrefix(tok.Literal, "unterminated")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError is a parser error together with where it was found.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p *Parser) ParseErrors() []ParseError {
+	errors := []ParseError{}
+	for i, msg := range p.errors {
+		tok := p.errorTokens[i]
+		errors = append(errors, ParseError{Line: tok.Line, Column: tok.Column, Message: msg})
+	}
+	return errors
+}
+
 func (p *Parser) peekError(t token.TokenType) {
+	if p.peekToken.Type == token.ILLEGAL {
+		p.illegalError(p.peekToken)
+		return
+	}
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, msg)
+}
+
+// illegalError reports what the lexer could not read, such as a string or a
+// block comment left open, with the line where it starts.
+func (p *Parser) illegalError(tok token.Token) {
+	msg := fmt.Sprintf("illegal character %q at line %d", tok.Literal, tok.Line)
+	if strings.HasPrefix(tok.Literal, "unterminated") {
+		msg = fmt.Sprintf("%s at line %d", tok.Literal, tok.Line)
+	}
+	p.addError(tok, msg)
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
@@ -266,6 +435,20 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	stmt := &ast.YieldStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fct prefixParseFct) {
 	p.prefixParseFcts[tokenType] = fct
 }
@@ -310,9 +493,15 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if n, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = n
+			return lit
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -321,8 +510,12 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 }
 
 func (p *Parser) noPrefixParseFctError(t token.TokenType) {
+	if t == token.ILLEGAL {
+		p.illegalError(p.curToken)
+		return
+	}
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken, msg)
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -409,6 +602,59 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := &ast.MatchArm{}
+		if !p.curTokenIs(token.ELSE) {
+			arm.Pattern = p.parseExpression(LOWEST)
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		if p.curTokenIs(token.LBRACE) {
+			arm.Body = p.parseBlockStatement()
+		} else {
+			stmt := &ast.ExpressionStatement{Token: p.curToken}
+			stmt.Expression = p.parseExpression(LOWEST)
+			arm.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
@@ -423,6 +669,12 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	if p.curTokenIs(token.EOF) {
+		p.addError(p.curToken, "expected next token to be }, got EOF instead")
+	}
+
+	block.Rbrace = p.curToken
+
 	return block
 }
 
@@ -435,6 +687,12 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	lit.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseType()
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -454,14 +712,12 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 
 	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	identifiers = append(identifiers, p.parseParameter())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		identifiers = append(identifiers, p.parseParameter())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -474,6 +730,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Rparen = p.curToken
 	return exp
 }
 
@@ -509,6 +766,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
 
 	return array
 }
@@ -559,6 +817,10 @@ func (p *Parser) parseDictLiteral() ast.Expression {
 
 		key := p.parseExpression(LOWEST)
 
+		if len(dict.Pairs) == 0 && !p.peekTokenIs(token.COLON) {
+			return p.parseSetLiteral(dict.Token, key)
+		}
+
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -568,6 +830,7 @@ func (p *Parser) parseDictLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		dict.Pairs[key] = value
+		dict.Keys = append(dict.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -577,10 +840,31 @@ func (p *Parser) parseDictLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	dict.Rbrace = p.curToken
 
 	return dict
 }
 
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+	set := &ast.SetLiteral{Token: tok, Elements: []ast.Expression{first}}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if p.peekTokenIs(token.RBRACE) {
+			break
+		}
+		p.nextToken()
+		set.Elements = append(set.Elements, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	set.Rbrace = p.curToken
+
+	return set
+}
+
 func (p *Parser) parseFloatLiteral() ast.Expression {
 	float := &ast.FloatLiteral{Token: p.curToken}
 
@@ -588,7 +872,7 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 
 	if err != nil {
 		msg := fmt.Sprintf("Could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -611,3 +895,121 @@ func (p *Parser) parseAttributeAccess(left ast.Expression) ast.Expression {
 		Property: property,
 	}
 }
+
+func (p *Parser) parseParameter() *ast.Identifier {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		ident.Type = p.parseType()
+	}
+
+	return ident
+}
+
+// ParseType parses a standalone type annotation such as `[int] | null`.
+func (p *Parser) ParseType() ast.TypeNode {
+	t := p.parseType()
+	if t != nil && !p.peekTokenIs(token.EOF) {
+		p.peekError(token.EOF)
+	}
+	return t
+}
+
+func (p *Parser) parseType() ast.TypeNode {
+	first := p.parseSingleType()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+
+	union := &ast.UnionType{Token: p.curToken, Types: []ast.TypeNode{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+
+		t := p.parseSingleType()
+		if t == nil {
+			return nil
+		}
+		union.Types = append(union.Types, t)
+	}
+
+	return union
+}
+
+func (p *Parser) parseSingleType() ast.TypeNode {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+
+	case token.LBRACKET:
+		array := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+
+		array.Element = p.parseType()
+		if array.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+
+		return array
+
+	case token.LBRACE:
+		dict := &ast.DictType{Token: p.curToken}
+		p.nextToken()
+
+		dict.Key = p.parseType()
+		if dict.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+
+		dict.Value = p.parseType()
+		if dict.Value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+
+		return dict
+
+	case token.FUNCTION:
+		fct := &ast.FunctionType{Token: p.curToken}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		if p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+		} else {
+			for {
+				p.nextToken()
+
+				param := p.parseType()
+				if param == nil {
+					return nil
+				}
+				fct.Parameters = append(fct.Parameters, param)
+
+				if !p.peekTokenIs(token.COMMA) {
+					break
+				}
+				p.nextToken()
+			}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			fct.ReturnType = p.parseType()
+		}
+
+		return fct
+	}
+
+	msg := fmt.Sprintf("expected a type, got %s instead", p.curToken.Type)
+	p.addError(p.curToken, msg)
+	return nil
+}
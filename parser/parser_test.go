@@ -79,6 +79,60 @@ func TestVarStatements2(t *testing.T) {
 	}
 }
 
+func TestVarStatementWithoutSemicolon(t *testing.T) {
+	input := `var x << 1
+show(x <= 2)`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	if program.String() != "var x = 1;show((x <= 2))" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	p := New(lexer.New("var x << 1;\nvar << 2;"))
+	p.ParseProgram()
+
+	errors := p.ParseErrors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parse errors")
+	}
+
+	first := errors[0]
+	if first.Line != 2 || first.Column != 5 || first.Message != "expected next token to be IDENT, got << instead" {
+		t.Errorf("wrong first error: %+v", first)
+	}
+}
+
+func TestIllegalTokenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x << 1;\n/* open\nvar y << 2;", "unterminated block comment at line 2"},
+		{"var x << 1;\nvar y << \"open;", "unterminated string at line 2"},
+		{"var x << 1;\nshow(1, \"open);", "unterminated string at line 2"},
+		{"var x << 1 $ 2;", "illegal character \"$\" at line 1"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -1082,3 +1136,284 @@ func TestLogicalExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestDocComments(t *testing.T) {
+	input := `
+/// Adds two numbers.
+/// Returns their sum.
+var add << fct(a, b) { a + b };
+
+// not a doc comment
+var x << 1;
+
+/// The states of an account.
+enum Status { Active, Banned }
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	add := program.Statements[0].(*ast.VarStatement)
+	if add.Doc.Text() != "Adds two numbers.\nReturns their sum." {
+		t.Errorf("add.Doc wrong. got=%q", add.Doc.Text())
+	}
+
+	x := program.Statements[1].(*ast.VarStatement)
+	if x.Doc != nil {
+		t.Errorf("x.Doc is not nil. got=%q", x.Doc.Text())
+	}
+
+	status := program.Statements[2].(*ast.EnumStatement)
+	if status.Doc.Text() != "The states of an account." {
+		t.Errorf("status.Doc wrong. got=%q", status.Doc.Text())
+	}
+
+	if len(program.Comments) != 3 {
+		t.Errorf("program.Comments does not contain 3 comments. got=%d", len(program.Comments))
+	}
+}
+
+func TestPreservedComments(t *testing.T) {
+	input := `
+// header
+var x << 1; /* trailing */
+x
+`
+
+	l := lexer.NewWithComments(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	expected := []string{"header", "trailing"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("program.Comments has wrong length. want=%d, got=%d", len(expected), len(program.Comments))
+	}
+
+	for i, text := range expected {
+		if program.Comments[i].Text() != text {
+			t.Errorf("program.Comments[%d] wrong. want=%q, got=%q", i, text, program.Comments[i].Text())
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x: int << 1;", "var x: int = 1;"},
+		{"var xs: [int] << [];", "var xs: [int] = [];"},
+		{"var d: {string: [float]} << {};", "var d: {string: [float]} = {};"},
+		{"var v: int | string << 1;", "var v: int | string = 1;"},
+		{"var f: fct(int, string): bool << g;", "var f: fct(int, string): bool = g;"},
+		{"fct(a: int, b: string): bool { true }", "fct(a: int, b: string): bool true"},
+		{"fct(a, b: [int]) { a }", "fct(a, b: [int]) a"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	p := New(lexer.New("var x: << 1;"))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Fatalf("expected parser errors for missing type")
+	}
+}
+
+func TestEnumStatement(t *testing.T) {
+	input := `enum Status { Active, Banned, }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Name.Value != "Status" {
+		t.Errorf("stmt.Name.Value not %q. got=%q", "Status", stmt.Name.Value)
+	}
+
+	if stmt.String() != "enum Status { Active, Banned }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (s) { Status.Active => 1, Status.Banned => { x } else => 3 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Arms) != 3 {
+		t.Fatalf("match does not have 3 arms. got=%d", len(exp.Arms))
+	}
+
+	if exp.Arms[0].Pattern.String() != "Status.Active" {
+		t.Errorf("wrong first pattern. got=%q", exp.Arms[0].Pattern.String())
+	}
+
+	if exp.Arms[2].Pattern != nil {
+		t.Errorf("else arm has a pattern: %s", exp.Arms[2].Pattern)
+	}
+
+	if exp.Arms[1].Body.String() != "x" {
+		t.Errorf("wrong second body. got=%q", exp.Arms[1].Body.String())
+	}
+}
+
+func TestSetLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{1, 2 + 3}", "{1, (2 + 3)}"},
+		{"{1,}", "{1}"},
+		{`{"a"}`, `{a}`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		set, ok := stmt.Expression.(*ast.SetLiteral)
+		if !ok {
+			t.Fatalf("exp is not *ast.SetLiteral. got=%T", stmt.Expression)
+		}
+
+		if set.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, set.String())
+		}
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (x in xs) { show(x); }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("stmt is not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Variable.Value != "x" {
+		t.Errorf("stmt.Variable.Value not %q. got=%q", "x", stmt.Variable.Value)
+	}
+
+	if stmt.String() != "for (x in xs) show(x)" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestAttributeAccessPrecedence(t *testing.T) {
+	input := `s == Status.Active`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "(s == Status.Active)" {
+		t.Errorf("wrong precedence. got=%q", program.String())
+	}
+}
+
+func TestYieldStatement(t *testing.T) {
+	l := lexer.New("fct() { yield x + 1; yield 2 }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("not a function literal: %T", stmt.Expression)
+	}
+
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("wrong number of statements: %d", len(fn.Body.Statements))
+	}
+
+	for i, expected := range []string{"yield (x + 1);", "yield 2;"} {
+		ys, ok := fn.Body.Statements[i].(*ast.YieldStatement)
+		if !ok {
+			t.Fatalf("statement %d is not YieldStatement: %T", i, fn.Body.Statements[i])
+		}
+		if ys.String() != expected {
+			t.Errorf("wrong string. want=%q, got=%q", expected, ys.String())
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"var f << fct(x) {", true},
+		{"if (true) { 1 } else {", true},
+		{"[1, 2,", true},
+		{"show(1,", true},
+		{`"abc`, true},
+		{"/* comment", true},
+		{"var x << 1 +", true},
+		{`{"a": 1`, true},
+		{"enum Color {", true},
+		{"1 + 1", false},
+		{`show("{")`, false},
+		{"var x << 1; x = 2", false},
+		{"1 + )", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if p.Incomplete() != tt.incomplete {
+			t.Errorf("Incomplete() for %q = %v, want %v (errors: %v)", tt.input, p.Incomplete(), tt.incomplete, p.Errors())
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"zumbra/packages"
+	"zumbra/project"
+)
+
+// pkgCommand implements `zumbra pkg add [-name name] source` and
+// `zumbra pkg verify`.
+func pkgCommand(args []string) bool {
+	if len(args) == 0 {
+		fmt.Println("usage: zumbra pkg add [-name name] dir|git-dir|file.tar.gz")
+		fmt.Println("       zumbra pkg verify")
+		return false
+	}
+
+	root, modules, ok := packageDirs()
+	if !ok {
+		return false
+	}
+
+	switch args[0] {
+	case "add":
+		flags := flag.NewFlagSet("pkg add", flag.ExitOnError)
+		name := flags.String("name", "", "package name, by default the library's own")
+		sources := parseInterspersed(flags, args[1:])
+		if len(sources) != 1 {
+			fmt.Println("usage: zumbra pkg add [-name name] dir|git-dir|file.tar.gz")
+			return false
+		}
+
+		p, err := packages.Add(root, modules, sources[0], *name)
+		if err != nil {
+			fmt.Printf("Error when trying to add %s: %s\n", sources[0], err)
+			return false
+		}
+		fmt.Printf("added %s %s\n", p.Name, p.Version)
+		return true
+
+	case "verify":
+		problems, err := packages.Verify(root, modules)
+		if err != nil {
+			fmt.Printf("Error when trying to verify the packages: %s\n", err)
+			return false
+		}
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) == 0 {
+			fmt.Println("all packages match " + packages.LockFile)
+		}
+		return len(problems) == 0
+
+	default:
+		fmt.Printf("unknown pkg command %s\n", args[0])
+		return false
+	}
+}
+
+// packageDirs returns where the lockfile and the packages go: the project
+// directory and its first dependency directory, or the current directory
+// and its zumbra_modules outside a project.
+func packageDirs() (string, string, bool) {
+	m, err := project.Find(".")
+	if err != nil {
+		fmt.Printf("Error when trying to read the project manifest: %s\n", err)
+		return "", "", false
+	}
+	if m != nil {
+		return m.Dir, m.Path(m.DependencyDirs[0]), true
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Println(err)
+		return "", "", false
+	}
+	return cwd, filepath.Join(cwd, project.DefaultDependencyDir), true
+}
@@ -0,0 +1,189 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"zumbra/object"
+)
+
+// The pprof format is a gzipped protocol buffer, described in
+// github.com/google/pprof/proto/profile.proto. Only the fields below are
+// written, which is all `go tool pprof` needs.
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID        = 1
+	functionName      = 2
+	functionFilename  = 4
+	functionStartLine = 5
+)
+
+type protobuf struct {
+	data []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.varint(uint64(field) << 3)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protobuf) message(field int, build func(*protobuf)) {
+	inner := &protobuf{}
+	build(inner)
+	b.bytes(field, inner.data)
+}
+
+func (b *protobuf) packed(field int, values []uint64) {
+	inner := &protobuf{}
+	for _, v := range values {
+		inner.varint(v)
+	}
+	b.bytes(field, inner.data)
+}
+
+// WritePprof writes the samples as a CPU profile for `go tool pprof`.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	table := []string{""}
+	index := map[string]int64{"": 0}
+	str := func(s string) int64 {
+		if i, ok := index[s]; ok {
+			return i
+		}
+		index[s] = int64(len(table))
+		table = append(table, s)
+		return index[s]
+	}
+
+	b := &protobuf{}
+	valueType := func(field int, typ, unit string) {
+		b.message(field, func(m *protobuf) {
+			m.int64(valueTypeType, str(typ))
+			m.int64(valueTypeUnit, str(unit))
+		})
+	}
+	valueType(profileSampleType, "samples", "count")
+	valueType(profileSampleType, "cpu", "nanoseconds")
+
+	functions := map[*object.CompiledFunction]uint64{}
+	type location struct {
+		fn   uint64
+		line int
+	}
+	locations := map[location]uint64{}
+	var locationOrder []location
+
+	keys := make([]string, 0, len(p.samples))
+	for key := range p.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := p.samples[key]
+		ids := []uint64{}
+		for _, f := range s.stack {
+			fnID, ok := functions[f.fn]
+			if !ok {
+				fnID = uint64(len(functions) + 1)
+				functions[f.fn] = fnID
+			}
+
+			loc := location{fn: fnID, line: f.line}
+			id, ok := locations[loc]
+			if !ok {
+				id = uint64(len(locations) + 1)
+				locations[loc] = id
+				locationOrder = append(locationOrder, loc)
+			}
+			ids = append(ids, id)
+		}
+
+		b.message(profileSample, func(m *protobuf) {
+			m.packed(sampleLocationID, ids)
+			m.packed(sampleValue, []uint64{uint64(s.count), uint64(s.count * p.Interval.Nanoseconds())})
+		})
+	}
+
+	for _, loc := range locationOrder {
+		b.message(profileLocation, func(m *protobuf) {
+			m.uint64(locationID, locations[loc])
+			m.message(locationLine, func(line *protobuf) {
+				line.uint64(lineFunctionID, loc.fn)
+				line.int64(lineLine, int64(loc.line))
+			})
+		})
+	}
+
+	fns := make([]*object.CompiledFunction, len(functions))
+	for fn, id := range functions {
+		fns[id-1] = fn
+	}
+	for i, fn := range fns {
+		name, file, line := p.name(fn)
+		b.message(profileFunction, func(m *protobuf) {
+			m.uint64(functionID, uint64(i+1))
+			m.int64(functionName, str(name))
+			m.int64(functionFilename, str(file))
+			m.int64(functionStartLine, int64(line))
+		})
+	}
+
+	cpu := &protobuf{}
+	cpu.int64(valueTypeType, str("cpu"))
+	cpu.int64(valueTypeUnit, str("nanoseconds"))
+
+	b.int64(profileTimeNanos, p.start.UnixNano())
+	b.int64(profileDurationNanos, p.wall.Nanoseconds())
+	b.bytes(profilePeriodType, cpu.data)
+	b.int64(profilePeriod, p.Interval.Nanoseconds())
+
+	// The string table goes last so it holds every string used above.
+	for _, s := range table {
+		b.bytes(profileStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.data); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,267 @@
+// Package profiler measures where a Zumbra program spends its time. A VM
+// hook counts every executed opcode and every function call, and records
+// the call stack once per interval. The hook reads the clock itself rather
+// than waiting for a timer goroutine, which a busy VM can starve.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+	"zumbra/code"
+	"zumbra/object"
+	"zumbra/vm"
+)
+
+const DefaultInterval = time.Millisecond
+
+// clockEvery is how many instructions run between reads of the clock.
+const clockEvery = 64
+
+// frame is one entry of a sampled stack.
+type frame struct {
+	fn   *object.CompiledFunction
+	line int
+}
+
+type sample struct {
+	stack []frame
+	count int64
+}
+
+type Profiler struct {
+	// File is the program being profiled. Line tables refer to it with an
+	// empty file name.
+	File     string
+	Interval time.Duration
+
+	opcodes  [256]int64
+	calls    map[*object.CompiledFunction]int64
+	samples  map[string]*sample
+	lastCall *vm.Frame
+
+	main       *object.CompiledFunction
+	executed   int64
+	start      time.Time
+	nextSample time.Time
+	wall       time.Duration
+	taken      int64
+}
+
+func New(file string) *Profiler {
+	return &Profiler{
+		File:     file,
+		Interval: DefaultInterval,
+		calls:    map[*object.CompiledFunction]int64{},
+		samples:  map[string]*sample{},
+	}
+}
+
+// Start installs the profiler on machine and starts sampling.
+func (p *Profiler) Start(machine *vm.VM) {
+	machine.SetHook(p.hook)
+	p.main = machine.Frames()[0].Closure().Fn
+	p.start = time.Now()
+	p.nextSample = p.start.Add(p.Interval)
+}
+
+// Stop records how long the program ran once it finished.
+func (p *Profiler) Stop() {
+	p.wall = time.Since(p.start)
+}
+
+func (p *Profiler) hook(machine *vm.VM) error {
+	frames := machine.Frames()
+	current := frames[len(frames)-1]
+
+	ins := current.Instructions()
+	p.opcodes[ins[current.IP()]]++
+
+	// Every call gets a new frame, which runs its first instruction at 0.
+	if current != p.lastCall && current.IP() == 0 {
+		p.calls[current.Closure().Fn]++
+	}
+	p.lastCall = current
+
+	p.executed++
+	if p.executed%clockEvery != 0 {
+		return nil
+	}
+
+	// A slow instruction such as a builtin call may cover several
+	// intervals; the stack that follows it is charged for all of them.
+	now := time.Now()
+	if now.Before(p.nextSample) {
+		return nil
+	}
+	intervals := int64(now.Sub(p.nextSample)/p.Interval) + 1
+	p.record(machine, intervals)
+	p.nextSample = p.nextSample.Add(time.Duration(intervals) * p.Interval)
+	return nil
+}
+
+func (p *Profiler) record(machine *vm.VM, intervals int64) {
+	stack := []frame{}
+	key := ""
+
+	for m := machine; m != nil; m = m.Caller() {
+		frames := m.Frames()
+		for i := len(frames) - 1; i >= 0; i-- {
+			fn := frames[i].Closure().Fn
+			if len(fn.Instructions) == 0 {
+				continue
+			}
+
+			line := 0
+			if entry, ok := fn.Lines.Lookup(frames[i].IP()); ok {
+				line = entry.Line
+			}
+			stack = append(stack, frame{fn: fn, line: line})
+			key += fmt.Sprintf("%p:%d;", fn, line)
+		}
+	}
+
+	if s, ok := p.samples[key]; ok {
+		s.count += intervals
+	} else {
+		p.samples[key] = &sample{stack: stack, count: intervals}
+	}
+	p.taken += intervals
+}
+
+// Function is what the report shows about one function. Self counts the
+// samples taken while it was running, Cumulative those taken while it was
+// on the stack.
+type Function struct {
+	Name       string
+	File       string
+	Line       int
+	Calls      int64
+	Self       time.Duration
+	Cumulative time.Duration
+}
+
+func (p *Profiler) name(fn *object.CompiledFunction) (name, file string, line int) {
+	file = p.File
+	if len(fn.Lines) > 0 {
+		line = fn.Lines[0].Line
+		if fn.Lines[0].File != "" {
+			file = fn.Lines[0].File
+		}
+	}
+
+	switch {
+	case fn.Name != "":
+		name = fn.Name
+	case fn == p.main:
+		name = "<main>"
+		line = 1
+	default:
+		name = "<anonymous>"
+	}
+	return name, file, line
+}
+
+// Functions returns the profile of every function that was called or
+// sampled, slowest first.
+func (p *Profiler) Functions() []Function {
+	found := map[*object.CompiledFunction]*Function{}
+	get := func(fn *object.CompiledFunction) *Function {
+		if f, ok := found[fn]; ok {
+			return f
+		}
+		name, file, line := p.name(fn)
+		f := &Function{Name: name, File: file, Line: line, Calls: p.calls[fn]}
+		found[fn] = f
+		return f
+	}
+
+	for fn := range p.calls {
+		get(fn)
+	}
+	for _, s := range p.samples {
+		elapsed := time.Duration(s.count) * p.Interval
+		get(s.stack[0].fn).Self += elapsed
+
+		seen := map[*object.CompiledFunction]bool{}
+		for _, f := range s.stack {
+			if !seen[f.fn] {
+				seen[f.fn] = true
+				get(f.fn).Cumulative += elapsed
+			}
+		}
+	}
+
+	functions := []Function{}
+	for _, f := range found {
+		functions = append(functions, *f)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		a, b := functions[i], functions[j]
+		if a.Cumulative != b.Cumulative {
+			return a.Cumulative > b.Cumulative
+		}
+		if a.Calls != b.Calls {
+			return a.Calls > b.Calls
+		}
+		return a.Name < b.Name
+	})
+	return functions
+}
+
+type Opcode struct {
+	Name  string
+	Count int64
+}
+
+// Opcodes returns how often every executed opcode ran, most frequent first.
+func (p *Profiler) Opcodes() []Opcode {
+	opcodes := []Opcode{}
+	for op, count := range p.opcodes {
+		if count == 0 {
+			continue
+		}
+		name := fmt.Sprintf("op %d", op)
+		if def, err := code.Lookup(byte(op)); err == nil {
+			name = def.Name
+		}
+		opcodes = append(opcodes, Opcode{Name: name, Count: count})
+	}
+
+	sort.Slice(opcodes, func(i, j int) bool {
+		if opcodes[i].Count != opcodes[j].Count {
+			return opcodes[i].Count > opcodes[j].Count
+		}
+		return opcodes[i].Name < opcodes[j].Name
+	})
+	return opcodes
+}
+
+// WriteReport writes the functions and opcodes as text tables.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	fmt.Fprintf(w, "Total time %s, %d samples every %s\n\n", p.wall.Round(time.Microsecond), p.taken, p.Interval)
+
+	percent := func(d time.Duration) float64 {
+		total := time.Duration(p.taken) * p.Interval
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(d) / float64(total)
+	}
+
+	fmt.Fprintf(w, "%10s %10s %6s %10s %6s  %s\n", "calls", "self", "self%", "cum", "cum%", "function")
+	for _, f := range p.Functions() {
+		fmt.Fprintf(w, "%10d %10s %5.1f%% %10s %5.1f%%  %s (%s:%d)\n",
+			f.Calls, f.Self, percent(f.Self), f.Cumulative, percent(f.Cumulative), f.Name, filepath.Base(f.File), f.Line)
+	}
+
+	fmt.Fprintf(w, "\n%12s  %s\n", "executed", "opcode")
+	for _, op := range p.Opcodes() {
+		fmt.Fprintf(w, "%12d  %s\n", op.Count, op.Name)
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
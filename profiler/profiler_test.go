@@ -0,0 +1,119 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/parser"
+	"zumbra/vm"
+)
+
+func profile(t *testing.T, input string) *Profiler {
+	t.Helper()
+	comp := compiler.New()
+	if err := comp.Compile(parser.New(lexer.New(input)).ParseProgram()); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	machine := vm.New(comp.Bytecode())
+	p := New("main.zum")
+	p.Interval = time.Microsecond
+	p.Start(machine)
+	if err := machine.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	p.Stop()
+	return p
+}
+
+const fib = `var fib << fct(n) {
+    if (n < 2) {
+        return n;
+    }
+    fib(n - 1) + fib(n - 2)
+};
+var twice << fct(f) { f(); f() };
+twice(fct() { fib(15) });`
+
+func TestFunctions(t *testing.T) {
+	p := profile(t, fib)
+
+	calls := map[string]int64{}
+	for _, f := range p.Functions() {
+		calls[f.Name] += f.Calls
+		if f.Self > f.Cumulative {
+			t.Errorf("%s: self time %s above cumulative time %s", f.Name, f.Self, f.Cumulative)
+		}
+	}
+
+	expected := map[string]int64{"<main>": 1, "twice": 1, "<anonymous>": 2, "fib": 2 * 1973}
+	for name, count := range expected {
+		if calls[name] != count {
+			t.Errorf("wrong call count for %s. want=%d, got=%d", name, count, calls[name])
+		}
+	}
+
+	if p.taken == 0 {
+		t.Fatalf("no samples taken")
+	}
+	for _, f := range p.Functions() {
+		if f.Name == "<main>" && f.Cumulative != time.Duration(p.taken)*p.Interval {
+			t.Errorf("main is not on every sampled stack: %+v", f)
+		}
+	}
+}
+
+func TestOpcodes(t *testing.T) {
+	p := profile(t, "var x << 1; x + 2; x + 3;")
+
+	counts := map[string]int64{}
+	for _, op := range p.Opcodes() {
+		counts[op.Name] = op.Count
+	}
+	expected := map[string]int64{"OpConstant": 3, "OpSetGlobal": 1, "OpGetGlobal": 2, "OpAdd": 2, "OpPop": 2}
+	if len(counts) != len(expected) {
+		t.Errorf("wrong opcodes: %v", counts)
+	}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("wrong count for %s. want=%d, got=%d", name, count, counts[name])
+		}
+	}
+}
+
+func TestReports(t *testing.T) {
+	p := profile(t, fib)
+
+	var report bytes.Buffer
+	if err := p.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"function", "fib (main.zum:2)", "OpCall"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report is missing %q:\n%s", want, report.String())
+		}
+	}
+
+	var pprof bytes.Buffer
+	if err := p.WritePprof(&pprof); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&pprof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"fib", "main.zum", "nanoseconds"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("profile is missing %q", want)
+		}
+	}
+}
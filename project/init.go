@@ -0,0 +1,113 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Init creates a project named name in dir: a manifest, a small web server
+// with its tests and an env file. It fails instead of overwriting any file.
+func Init(dir, name string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	m := &Manifest{
+		Name:           name,
+		Entry:          "src/main.zum",
+		SourceRoots:    []string{"src"},
+		DependencyDirs: []string{DefaultDependencyDir},
+		EnvFiles:       []string{".env"},
+		Dir:            dir,
+	}
+
+	files := map[string]string{
+		"src/main.zum":         mainTemplate,
+		"src/pages.zum":        pagesTemplate,
+		"tests/pages_test.zum": testTemplate,
+		".env":                 envTemplate,
+		".gitignore":           gitignoreTemplate,
+	}
+	for _, file := range append(sortedKeys(files), ManifestFile) {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			return nil, fmt.Errorf("%s already exists", filepath.Join(dir, file))
+		}
+	}
+
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		content = strings.ReplaceAll(content, "{{name}}", name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.Write(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func sortedKeys(files map[string]string) []string {
+	keys := []string{}
+	for file := range files {
+		keys = append(keys, file)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const mainTemplate = `import "pages.zum"
+
+var port << dotenvGet("PORT");
+if (!port) {
+    port << "3333";
+}
+
+registerRoute("GET", "/", home());
+registerRoute("GET", "/about", about());
+
+server(toInt(port));
+`
+
+const pagesTemplate = `var title << "{{name}}";
+
+var page << fct(heading, body) {
+    "<h1>" + heading + "</h1><p>" + body + "</p>";
+};
+
+var home << fct() {
+    page(title, "Welcome to " + title + "!");
+};
+
+var about << fct() {
+    page("About", title + " is written in Zumbra.");
+};
+`
+
+const testTemplate = `import "../src/pages.zum"
+
+test("page wraps the heading and body", fct() {
+    assertEqual(page("Hi", "there"), "<h1>Hi</h1><p>there</p>");
+});
+
+test("home welcomes the visitor", fct() {
+    assertEqual(home(), "<h1>{{name}}</h1><p>Welcome to {{name}}!</p>");
+});
+`
+
+const envTemplate = `PORT=3333
+`
+
+const gitignoreTemplate = DefaultDependencyDir + `/
+`
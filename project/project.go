@@ -0,0 +1,132 @@
+// Package project reads zumbra.json, the manifest that makes a directory a
+// Zumbra project: its name, the file `zumbra run` starts from, where imports
+// are looked up and which env files are loaded before the program runs.
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"zumbra/object/builtins"
+)
+
+const ManifestFile = "zumbra.json"
+
+const DefaultDependencyDir = "zumbra_modules"
+
+type Manifest struct {
+	Name string `json:"name"`
+	// Version is recorded when the project is installed as a package.
+	Version string `json:"version,omitempty"`
+	// Entry is the file `zumbra run` starts from. Libraries need none.
+	Entry string `json:"entry,omitempty"`
+	// SourceRoots and DependencyDirs are searched, in order, for imports
+	// that are not found next to the importing file.
+	SourceRoots    []string `json:"sourceRoots,omitempty"`
+	DependencyDirs []string `json:"dependencyDirs,omitempty"`
+	// EnvFiles are loaded like dotenvLoad does before the program runs.
+	EnvFiles []string `json:"envFiles,omitempty"`
+
+	// Dir is the directory holding the manifest. Paths in the manifest are
+	// relative to it.
+	Dir string `json:"-"`
+}
+
+// Load reads the manifest in file.
+func Load(file string) (*Manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	if m.Name == "" {
+		return nil, fmt.Errorf("%s: missing \"name\"", file)
+	}
+	if len(m.DependencyDirs) == 0 {
+		m.DependencyDirs = []string{DefaultDependencyDir}
+	}
+
+	m.Dir, err = filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Find returns the manifest of the project dir belongs to, looking in dir
+// and then in its parents. It returns nil when there is none.
+func Find(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		file := filepath.Join(dir, ManifestFile)
+		if _, err := os.Stat(file); err == nil {
+			return Load(file)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Path returns the absolute path of a path given in the manifest.
+func (m *Manifest) Path(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(m.Dir, path)
+}
+
+func (m *Manifest) EntryFile() string {
+	return m.Path(m.Entry)
+}
+
+// ImportPaths returns the source roots followed by the dependency
+// directories.
+func (m *Manifest) ImportPaths() []string {
+	paths := []string{}
+	for _, dir := range m.SourceRoots {
+		paths = append(paths, m.Path(dir))
+	}
+	for _, dir := range m.DependencyDirs {
+		paths = append(paths, m.Path(dir))
+	}
+	return paths
+}
+
+// LoadEnv loads the manifest's env files. Files that do not exist are
+// skipped, so a project can list a .env that only some checkouts have.
+func (m *Manifest) LoadEnv() error {
+	for _, file := range m.EnvFiles {
+		err := builtins.LoadEnvFile(m.Path(file))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write saves the manifest to zumbra.json in m.Dir.
+func (m *Manifest) Write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.Dir, ManifestFile), append(data, '\n'), 0644)
+}
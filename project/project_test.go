@@ -0,0 +1,132 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/tester"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, ManifestFile, `{"name": "app", "entry": "src/main.zum", "sourceRoots": ["src", "lib"]}`)
+
+	m, err := Load(file)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if m.EntryFile() != filepath.Join(dir, "src", "main.zum") {
+		t.Errorf("wrong entry file: %s", m.EntryFile())
+	}
+
+	want := []string{filepath.Join(dir, "src"), filepath.Join(dir, "lib"), filepath.Join(dir, DefaultDependencyDir)}
+	if !reflect.DeepEqual(m.ImportPaths(), want) {
+		t.Errorf("wrong import paths.\nwant=%v\ngot=%v", want, m.ImportPaths())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		manifest string
+		err      string
+	}{
+		{`{"entry": "main.zum"}`, `missing "name"`},
+		{`{"name": "app", "entry": "main.zum", "entrypoint": "x"}`, `unknown field "entrypoint"`},
+		{`{"name": "app",`, `unexpected EOF`},
+	}
+
+	for _, tt := range tests {
+		file := writeFile(t, t.TempDir(), ManifestFile, tt.manifest)
+		_, err := Load(file)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Load(%s) error = %v, want %q", tt.manifest, err, tt.err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ManifestFile, `{"name": "app", "entry": "main.zum"}`)
+	nested := filepath.Join(dir, "src", "deep")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Find(nested)
+	if err != nil || m == nil {
+		t.Fatalf("Find = %v, %v", m, err)
+	}
+	if m.Dir != dir || m.Name != "app" {
+		t.Errorf("found the wrong manifest: %+v", m)
+	}
+
+	if m, err := Find(t.TempDir()); m != nil || err != nil {
+		t.Errorf("expected no manifest, got %v, %v", m, err)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, ".env", "# comment\nPROJECT_TEST_KEY = value\n")
+	m := &Manifest{Dir: dir, EnvFiles: []string{".env", ".env.local"}}
+
+	if err := m.LoadEnv(); err != nil {
+		t.Fatalf("LoadEnv: %s", err)
+	}
+	value := builtins.GetBuiltinByName("dotenvGet").Fn(&object.String{Value: "PROJECT_TEST_KEY"})
+	if value == nil || value.Inspect() != "value" {
+		t.Errorf("env file not loaded: got %v", value)
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+
+	m, err := Init(dir, "")
+	if err != nil {
+		t.Fatalf("Init: %s", err)
+	}
+	if m.Name != "shop" {
+		t.Errorf("wrong name: %s", m.Name)
+	}
+
+	loaded, err := Load(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Errorf("manifest written wrong.\nwant=%+v\ngot=%+v", m, loaded)
+	}
+
+	for _, file := range []string{"src/main.zum", "src/pages.zum", "tests/pages_test.zum", ".env", ".gitignore"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("%s not created: %s", file, err)
+		}
+	}
+
+	for _, r := range tester.Run([]string{filepath.Join(dir, "tests", "pages_test.zum")}) {
+		if !r.Passed() {
+			t.Errorf("scaffolded test %q failed: %v", r.Name, r.Failures)
+		}
+	}
+
+	if _, err := Init(dir, "again"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected Init to refuse an existing project, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const historySize = 1000
+
+var errInterrupted = errors.New("interrupted")
+
+// editor reads input lines. On a terminal it edits them in place: arrow
+// keys, Home/End and the usual Ctrl shortcuts move around the line, Up and
+// Down go through the history and Tab completes names. Anywhere else it
+// reads plain lines.
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// fd is the terminal being edited on, or -1.
+	fd int
+
+	history     []string
+	historyFile string
+
+	// complete returns the completions for the word ending at pos.
+	complete func(line []rune, pos int) (start int, candidates []string)
+}
+
+func newEditor(in io.Reader, out io.Writer) *editor {
+	e := &editor{in: bufio.NewReader(in), out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		e.fd = int(f.Fd())
+	}
+	return e
+}
+
+// loadHistory reads the history kept in file and saves the lines entered
+// from now on to it.
+func (e *editor) loadHistory(file string) {
+	e.historyFile = file
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+}
+
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+
+	e.history = append(e.history, line)
+	if len(e.history) > historySize {
+		e.history = e.history[len(e.history)-historySize:]
+	}
+
+	if e.historyFile != "" {
+		// Losing the history is not worth interrupting the session for.
+		os.WriteFile(e.historyFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// readLine reads one line, without its newline. It returns io.EOF when the
+// input ends and errInterrupted when the line is cancelled with Ctrl-C.
+func (e *editor) readLine(prompt string) (string, error) {
+	if e.fd < 0 {
+		io.WriteString(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := enableRaw(e.fd)
+	if err != nil {
+		e.fd = -1
+		return e.readLine(prompt)
+	}
+	defer restore()
+
+	l := &lineState{e: e, prompt: prompt, historyIndex: len(e.history)}
+	l.refresh()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			line := string(l.buf)
+			e.addHistory(line)
+			return line, nil
+		case 3: // Ctrl-C
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(l.buf) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			l.delete()
+		case 127, 8: // Backspace
+			if l.pos > 0 {
+				l.pos--
+				l.delete()
+			}
+		case 1: // Ctrl-A
+			l.move(-l.pos)
+		case 5: // Ctrl-E
+			l.move(len(l.buf) - l.pos)
+		case 2: // Ctrl-B
+			l.move(-1)
+		case 6: // Ctrl-F
+			l.move(1)
+		case 11: // Ctrl-K
+			l.buf = l.buf[:l.pos]
+			l.refresh()
+		case 21: // Ctrl-U
+			l.buf = append([]rune{}, l.buf[l.pos:]...)
+			l.pos = 0
+			l.refresh()
+		case 23: // Ctrl-W
+			start := l.pos
+			for start > 0 && l.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && l.buf[start-1] != ' ' {
+				start--
+			}
+			l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+			l.pos = start
+			l.refresh()
+		case 12: // Ctrl-L
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+			l.refresh()
+		case 16: // Ctrl-P
+			l.browse(-1)
+		case 14: // Ctrl-N
+			l.browse(1)
+		case '\t':
+			l.completeWord()
+		case 27:
+			l.escape()
+		default:
+			if unicode.IsPrint(r) {
+				l.insert(r)
+			}
+		}
+	}
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	e      *editor
+	prompt string
+	buf    []rune
+	pos    int
+
+	historyIndex int
+	// pending keeps the line being typed while browsing the history.
+	pending []rune
+}
+
+func (l *lineState) refresh() {
+	fmt.Fprintf(l.e.out, "\r%s%s\x1b[K", l.prompt, string(l.buf))
+	if back := len(l.buf) - l.pos; back > 0 {
+		fmt.Fprintf(l.e.out, "\x1b[%dD", back)
+	}
+}
+
+func (l *lineState) insert(runes ...rune) {
+	tail := append(runes, l.buf[l.pos:]...)
+	l.buf = append(l.buf[:l.pos], tail...)
+	l.pos += len(runes)
+	l.refresh()
+}
+
+func (l *lineState) delete() {
+	if l.pos < len(l.buf) {
+		l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+		l.refresh()
+	}
+}
+
+func (l *lineState) move(n int) {
+	l.pos = max(0, min(len(l.buf), l.pos+n))
+	l.refresh()
+}
+
+func (l *lineState) browse(step int) {
+	index := l.historyIndex + step
+	if index < 0 || index > len(l.e.history) {
+		return
+	}
+	if l.historyIndex == len(l.e.history) {
+		l.pending = append([]rune{}, l.buf...)
+	}
+
+	l.historyIndex = index
+	if index == len(l.e.history) {
+		l.buf = l.pending
+	} else {
+		l.buf = []rune(l.e.history[index])
+	}
+	l.pos = len(l.buf)
+	l.refresh()
+}
+
+// escape handles the escape sequences sent by arrow, Home, End and Delete
+// keys.
+func (l *lineState) escape() {
+	r, _, err := l.e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return
+	}
+
+	param := ""
+	for {
+		r, _, err = l.e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		if (r < '0' || r > '9') && r != ';' {
+			break
+		}
+		param += string(r)
+	}
+
+	switch r {
+	case 'A':
+		l.browse(-1)
+	case 'B':
+		l.browse(1)
+	case 'C':
+		l.move(1)
+	case 'D':
+		l.move(-1)
+	case 'H':
+		l.move(-l.pos)
+	case 'F':
+		l.move(len(l.buf) - l.pos)
+	case '~':
+		switch param {
+		case "1", "7":
+			l.move(-l.pos)
+		case "4", "8":
+			l.move(len(l.buf) - l.pos)
+		case "3":
+			l.delete()
+		}
+	}
+}
+
+func (l *lineState) completeWord() {
+	if l.e.complete == nil {
+		return
+	}
+
+	start, candidates := l.e.complete(l.buf, l.pos)
+	word := string(l.buf[start:l.pos])
+
+	switch len(candidates) {
+	case 0:
+		io.WriteString(l.e.out, "\a")
+	case 1:
+		l.insert([]rune(strings.TrimPrefix(candidates[0], word))...)
+	default:
+		prefix := commonPrefix(candidates)
+		if len(prefix) > len(word) {
+			l.insert([]rune(strings.TrimPrefix(prefix, word))...)
+			return
+		}
+		fmt.Fprintf(l.e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+		l.refresh()
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -1,11 +1,18 @@
 package repl
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
 
 	"zumbra/compiler"
+	"zumbra/disasm"
 	"zumbra/lexer"
 	"zumbra/object"
 	"zumbra/object/builtins"
@@ -15,78 +22,302 @@ import (
 
 const PROMPT = ">> "
 
-func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+const CONTINUATION_PROMPT = ".. "
+
+const HISTORY_FILE = ".zumbra_history"
+
+var keywords = []string{
+	"and", "else", "enum", "false", "fct", "for", "if", "import", "in", "match", "or", "return", "true", "var", "while", "yield",
+}
 
-	constants := []object.Object{}
-	globals := make([]object.Object, vm.GlobalSize)
-	symbolTable := compiler.NewSymbolTable()
+var commands = []struct {
+	name string
+	args string
+	help string
+}{
+	{":help", "", "show this help"},
+	{":load", "file", "run a file in this session"},
+	{":globals", "", "list the global variables and their values"},
+	{":disasm", "expr", "show the bytecode of an expression"},
+	{":time", "expr", "evaluate an expression and show how long it took"},
+	{":reset", "", "forget every variable and start over"},
+}
+
+type session struct {
+	out         io.Writer
+	constants   []object.Object
+	globals     []object.Object
+	symbolTable *compiler.SymbolTable
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out}
+	s.reset()
+	return s
+}
+
+func (s *session) reset() {
+	s.constants = []object.Object{}
+	s.globals = make([]object.Object, vm.GlobalSize)
+	s.symbolTable = compiler.NewSymbolTable()
 
 	for i, v := range builtins.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
+		s.symbolTable.DefineBuiltin(i, v.Name)
+	}
+}
+
+func Start(in io.Reader, out io.Writer) {
+	s := newSession(out)
+
+	e := newEditor(in, out)
+	e.complete = s.complete
+	if e.fd >= 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			e.loadHistory(filepath.Join(home, HISTORY_FILE))
+		}
 	}
 
 	for {
-		var lines string
-		var openBraces int
+		input, err := readInput(e)
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err != nil {
+			return
+		}
 
-		fmt.Printf(PROMPT)
-		for scanner.Scan() {
-			line := scanner.Text()
-			lines += line + "\n"
+		if strings.HasPrefix(strings.TrimSpace(input), ":") {
+			s.command(strings.TrimSpace(input))
+			continue
+		}
 
-			openBraces += countChar(line, '{')
-			openBraces -= countChar(line, '}')
+		s.evalAndPrint(input)
+	}
+}
+
+// readInput reads lines until they form a complete program, or a meta
+// command. An empty line ends the input even when it is not complete, so
+// the errors get shown.
+func readInput(e *editor) (string, error) {
+	line, err := e.readLine(PROMPT)
+	if err != nil {
+		return "", err
+	}
+	input := line
 
-			if openBraces <= 0 {
-				break
+	for {
+		source := input
+		if name, arg := splitCommand(input); name != "" {
+			if name != ":disasm" && name != ":time" {
+				return input, nil
 			}
-			fmt.Printf(".. ")
+			source = arg
 		}
 
-		l := lexer.New(lines)
-		p := parser.New(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
-			continue
+		p := parser.New(lexer.New(source))
+		p.ParseProgram()
+		if !p.Incomplete() {
+			return input, nil
 		}
 
-		comp := compiler.NewWithState(symbolTable, constants)
-		err := comp.Compile(program)
+		line, err := e.readLine(CONTINUATION_PROMPT)
 		if err != nil {
-			fmt.Fprintf(out, "compiler error: %s\n", err)
-			continue
+			return "", err
+		}
+		if strings.TrimSpace(line) == "" {
+			return input, nil
 		}
+		input += "\n" + line
+	}
+}
 
-		code := comp.Bytecode()
-		constants = code.Constants
+// splitCommand splits a meta command into its name and argument. The name
+// is empty when input is not a command.
+func splitCommand(input string) (string, string) {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, ":") {
+		return "", ""
+	}
+	name, arg, _ := strings.Cut(input, " ")
+	return name, strings.TrimSpace(arg)
+}
 
-		machine := vm.NewWithGlobalsStore(code, globals)
-		err = machine.Run()
-		if err != nil {
-			fmt.Fprintf(out, "vm error: %s\n", err)
+func (s *session) command(input string) {
+	name, arg := splitCommand(input)
+
+	switch name {
+	case ":help":
+		for _, c := range commands {
+			fmt.Fprintf(s.out, "  %-16s %s\n", strings.TrimSpace(c.name+" "+c.args), c.help)
+		}
+
+	case ":load":
+		if arg == "" {
+			fmt.Fprintln(s.out, "usage: :load file")
+			return
+		}
+		s.load(arg)
+
+	case ":globals":
+		s.printGlobals()
+
+	case ":disasm":
+		if arg == "" {
+			fmt.Fprintln(s.out, "usage: :disasm expr")
+			return
+		}
+		s.disassemble(arg)
+
+	case ":time":
+		if arg == "" {
+			fmt.Fprintln(s.out, "usage: :time expr")
+			return
+		}
+		start := time.Now()
+		s.evalAndPrint(arg)
+		fmt.Fprintf(s.out, "time: %s\n", time.Since(start))
+
+	case ":reset":
+		s.reset()
+		fmt.Fprintln(s.out, "session reset")
+
+	default:
+		fmt.Fprintf(s.out, "unknown command %s, type :help to list the commands\n", name)
+	}
+}
+
+// eval compiles and runs source in the session. dir is where imports are
+// looked up. It returns nil when something went wrong, after printing it.
+func (s *session) eval(source string, dir string) object.Object {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return nil
+	}
+
+	comp := compiler.NewWithStateAndDir(s.symbolTable, s.constants, dir)
+	err := comp.Compile(program)
+	if err != nil {
+		fmt.Fprintf(s.out, "compiler error: %s\n", err)
+		return nil
+	}
+
+	code := comp.Bytecode()
+	s.constants = code.Constants
+
+	machine := vm.NewWithGlobalsStore(code, s.globals)
+	s.globals = machine.Globals()
+	err = machine.Run()
+	if err != nil {
+		fmt.Fprintf(s.out, "vm error: %s\n", err)
+		return nil
+	}
+
+	return machine.LastPoppedStackElem()
+}
+
+func (s *session) evalAndPrint(source string) {
+	dir, _ := os.Getwd()
+	result := s.eval(source, dir)
+	if result != nil && result.Type() != object.NULL_OBJ {
+		io.WriteString(s.out, result.Inspect())
+		io.WriteString(s.out, "\n")
+	}
+}
+
+func (s *session) load(file string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error when trying to read the file: %s\n", err)
+		return
+	}
+
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		fmt.Fprintf(s.out, "Path error: %s\n", err)
+		return
+	}
+	s.eval(string(data), filepath.Dir(absPath))
+}
+
+func (s *session) printGlobals() {
+	names := s.symbolTable.Names()
+	for i, name := range names {
+		// A name defined again lives at its latest index.
+		if symbol, ok := s.symbolTable.Resolve(name); !ok || symbol.Index != i {
 			continue
 		}
 
-		lastPopped := machine.LastPoppedStackElem()
-		if lastPopped.Type() != object.NULL_OBJ {
-			io.WriteString(out, lastPopped.Inspect())
-			io.WriteString(out, "\n")
+		value := "<unset>"
+		if s.globals[i] != nil {
+			value = s.globals[i].Inspect()
 		}
+		fmt.Fprintf(s.out, "%s = %s\n", name, value)
+	}
+}
+
+// disassemble compiles source against a copy of the session, so defining
+// variables in it changes nothing, and prints the bytecode.
+func (s *session) disassemble(source string) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.out, p.Errors())
+		return
+	}
+
+	symbolTable := s.symbolTable.Copy()
+	comp := compiler.NewWithState(symbolTable, []object.Object{})
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(s.out, "compiler error: %s\n", err)
+		return
+	}
 
+	err := disasm.WriteSource(s.out, comp.Bytecode(), "<repl>", source, symbolTable.Names())
+	if err != nil {
+		fmt.Fprintf(s.out, "disasm error: %s\n", err)
 	}
 }
 
-func countChar(s string, ch rune) int {
-	count := 0
-	for _, c := range s {
-		if c == ch {
-			count++
+// complete returns the meta commands, keywords, builtins and globals that
+// start with the word before pos.
+func (s *session) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+
+	word := string(line[start:pos])
+	names := []string{}
+
+	if start == 1 && line[0] == ':' {
+		start, word = 0, ":"+word
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+	} else {
+		names = append(names, keywords...)
+		for _, b := range builtins.Builtins {
+			names = append(names, b.Name)
+		}
+		names = append(names, s.symbolTable.Names()...)
+	}
+
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, word) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
 		}
 	}
-	return count
+	sort.Strings(candidates)
+	return start, candidates
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
 }
 
 func printParserErrors(out io.Writer, errors []string) {
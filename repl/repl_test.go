@@ -0,0 +1,100 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runSession(t *testing.T, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+	return strings.ReplaceAll(out.String(), PROMPT, "")
+}
+
+func TestMultilineInput(t *testing.T) {
+	out := runSession(t, `if (true) {
+1 + 2
+}
+"}" + "{"
+[1,
+2]
+`)
+
+	want := ".. .. 3\n}{\n.. [1, 2]\n"
+	if out != want {
+		t.Errorf("wrong output.\nwant=%q\ngot=%q", want, out)
+	}
+}
+
+func TestEmptyLineEndsIncompleteInput(t *testing.T) {
+	out := runSession(t, "if (true) {\n\n1 + 1\n")
+	if !strings.Contains(out, "expected next token to be }, got EOF instead") || !strings.HasSuffix(out, "2\n") {
+		t.Errorf("wrong output: %q", out)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lib.zum")
+	if err := os.WriteFile(file, []byte(`var triple << fct(x) { x * 3 };`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{":help\n", []string{":load file", ":reset"}, nil},
+		{":load " + file + "\ntriple(3)\n", []string{"9\n"}, nil},
+		{"var a << 1;\nvar b << \"two\";\n:globals\n", []string{"a = 1\nb = two\n"}, nil},
+		{":disasm 1 + 2\n", []string{"; <repl>:1  1 + 2", "OpAdd", "constants:"}, nil},
+		{":disasm var c << 1;\nc\n", []string{"OpSetGlobal 0            ; c", "undefined variable c"}, nil},
+		{":time 2 * 21\n", []string{"42\ntime: "}, nil},
+		{"var a << 1;\n:reset\n:globals\na\n", []string{"session reset\n", "undefined variable a"}, []string{"a = 1"}},
+		{":nope\n", []string{"unknown command :nope"}, nil},
+	}
+
+	for _, tt := range tests {
+		out := runSession(t, tt.input)
+		for _, s := range tt.contains {
+			if !strings.Contains(out, s) {
+				t.Errorf("output of %q does not contain %q:\n%s", tt.input, s, out)
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(out, s) {
+				t.Errorf("output of %q contains %q:\n%s", tt.input, s, out)
+			}
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	s := newSession(&bytes.Buffer{})
+	s.eval(`var counter << 0; var count_all << 1;`, "")
+
+	tests := []struct {
+		line  string
+		start int
+		want  []string
+	}{
+		{"coun", 0, []string{"count_all", "counter"}},
+		{"show(counte", 5, []string{"counter"}},
+		{"whi", 0, []string{"while"}},
+		{":lo", 0, []string{":load"}},
+		{"zzz", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, got := s.complete(line, len(line))
+		if start != tt.start || strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("complete(%q) = %d %v, want %d %v", tt.line, start, got, tt.start, tt.want)
+		}
+	}
+}
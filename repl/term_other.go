@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package repl
+
+import "errors"
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func enableRaw(fd int) (func(), error) {
+	return nil, errors.New("line editing is not supported on this system")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"zumbra/compiler"
+	"zumbra/object"
+	"zumbra/profiler"
+	"zumbra/project"
+	"zumbra/vm"
+)
+
+// runCommand implements `zumbra run [-O level] [-max-frames n]
+// [-profile|-watch] [file.zum|file.zbc]`. Without a file it runs the entry
+// point of the project in the current directory. With -profile it prints a
+// report of where the time went to stderr and writes a pprof profile; with
+// -watch it runs the program again whenever its files change. -O sets how
+// much the program is optimized and -max-frames how deeply its calls may
+// nest.
+func runCommand(args []string) bool {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	profile := flags.Bool("profile", false, "report function and opcode statistics")
+	profileOutput := flags.String("profile-output", "zumbra.pprof", "where -profile writes the pprof profile")
+	watch := flags.Bool("watch", false, "restart the program when its files change")
+	flags.IntVar(&optimization, "O", compiler.DefaultOptimization, "optimization level, from 0 to 2")
+	maxFrames := flags.Int("max-frames", vm.MaxFrames, "how deeply calls may nest before the program stops with a stack overflow")
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || (*watch && *profile) || *maxFrames < 1 {
+		fmt.Println("usage: zumbra run [-O level] [-max-frames n] [-profile] [-profile-output file] [file.zum|file.zbc]")
+		fmt.Println("       zumbra run -watch [-O level] [-max-frames n] [file.zum]")
+		return false
+	}
+
+	filename := flags.Arg(0)
+	if filename == "" {
+		m, err := project.Find(".")
+		if err != nil {
+			fmt.Printf("Error when trying to read the project manifest: %s\n", err)
+			return false
+		}
+		if m == nil {
+			fmt.Printf("no %s found; give a file to run or create a project with zumbra init\n", project.ManifestFile)
+			return false
+		}
+		if m.Entry == "" {
+			fmt.Printf("%s has no \"entry\" to run\n", filepath.Join(m.Dir, project.ManifestFile))
+			return false
+		}
+		filename = m.EntryFile()
+	}
+
+	if *watch {
+		// The program gets the flags it was given, except -watch.
+		forwarded := []string{}
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name != "watch" {
+				forwarded = append(forwarded, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		return watchRun(filename, forwarded)
+	}
+	setupWatchedProgram()
+
+	code := loadBytecode(filename)
+	if code == nil || !loadProjectEnv(filename) {
+		return false
+	}
+
+	machine := vm.NewWithGlobalsStore(code, make([]object.Object, vm.GlobalSize))
+	machine.SetMaxFrames(*maxFrames)
+
+	var prof *profiler.Profiler
+	if *profile {
+		absPath, _ := filepath.Abs(filename)
+		prof = profiler.New(absPath)
+		prof.Start(machine)
+	}
+
+	err := machine.Run()
+
+	if prof != nil {
+		prof.Stop()
+		prof.WriteReport(os.Stderr)
+		if err := writeProfile(prof, *profileOutput); err != nil {
+			fmt.Fprintf(os.Stderr, "Error when writing the profile: %s\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "pprof profile written to %s\n", *profileOutput)
+		}
+	}
+
+	if err != nil {
+		fmt.Printf("Error on VM execution: %s\n", err)
+		return false
+	}
+	return true
+}
+
+func writeProfile(prof *profiler.Profiler, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := prof.WritePprof(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
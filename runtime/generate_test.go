@@ -0,0 +1,246 @@
+package runtime
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate the runtime from the object package")
+
+// leftOut are the files of object/builtins zrt does without: the builtins
+// table, which zrt keeps as a map of what built programs can use, the
+// sandbox and evaluator metadata, the testing builtins and the builtins
+// that need a mail or WhatsApp account.
+var leftOut = map[string]bool{
+	"builtins.go":              true,
+	"capabilities.go":          true,
+	"signatures.go":            true,
+	"test_builtins.go":         true,
+	"send_email_builtin.go":    true,
+	"send_whatsapp_builtin.go": true,
+}
+
+// TestGenerated checks that the generated part of zrt, and the module files
+// built programs get, are up to date with the object package and go.mod.
+// Run it with -update to regenerate them.
+func TestGenerated(t *testing.T) {
+	files, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if *update {
+			if err := os.WriteFile(name, files[name], 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Errorf("%s, run go test ./runtime -update", err)
+			continue
+		}
+		if !bytes.Equal(got, files[name]) {
+			t.Errorf("%s is out of date, run go test ./runtime -update", name)
+		}
+	}
+
+	entries, err := os.ReadDir("zrt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		name := filepath.Join("zrt", entry.Name())
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := files[name]; !ok && bytes.HasPrefix(data, []byte("// Code generated")) {
+			t.Errorf("%s was generated from a file that is gone, remove it", name)
+		}
+	}
+}
+
+// generate returns the generated files by their path relative to the
+// runtime package.
+func generate() (map[string][]byte, error) {
+	sources := map[string]string{
+		"numeric.go":  "../object/numeric.go",
+		"iterator.go": "../object/iterator.go",
+	}
+	entries, err := os.ReadDir("../object/builtins")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") && !leftOut[name] {
+			sources[name] = "../object/builtins/" + name
+		}
+	}
+
+	files := map[string][]byte{}
+	for name, source := range sources {
+		data, err := translate(source)
+		if err != nil {
+			return nil, err
+		}
+		files[filepath.Join("zrt", name)] = data
+	}
+
+	requires, sums, err := dependencies()
+	if err != nil {
+		return nil, err
+	}
+	files["zrt.mod"] = requires
+	files["zrt.sum"] = sums
+	return files, nil
+}
+
+// translate rewrites a file of the object package, or of object/builtins,
+// into the zrt package: object.Object becomes Value, object.ObjectType
+// becomes ValueType and the other names of the object package lose their
+// qualifier.
+func translate(source string) ([]byte, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	edits := []edit{{offset(file.Name.Pos()), offset(file.Name.End()), "zrt"}}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if spec.(*ast.ImportSpec).Path.Value != `"zumbra/object"` {
+				continue
+			}
+			if len(gen.Specs) == 1 {
+				edits = append(edits, edit{offset(gen.Pos()), offset(gen.End()), ""})
+				break
+			}
+			// Drop the whole line, so no blank line is left behind.
+			start := bytes.LastIndexByte(data[:offset(spec.Pos())], '\n') + 1
+			end := offset(spec.End())
+			if end < len(data) && data[end] == '\n' {
+				end++
+			}
+			edits = append(edits, edit{start, end, ""})
+		}
+	}
+
+	renames := map[string]string{"Object": "Value", "ObjectType": "ValueType"}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ImportSpec:
+			return false
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && x.Name == "object" {
+				edits = append(edits, edit{offset(x.Pos()), offset(n.Sel.Pos()), ""})
+			}
+		case *ast.Ident:
+			if name, ok := renames[n.Name]; ok {
+				edits = append(edits, edit{offset(n.Pos()), offset(n.End()), name})
+			}
+		}
+		return true
+	})
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte{}, data...)
+	for _, e := range edits {
+		out = append(out[:e.start:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+
+	header := fmt.Sprintf("// Code generated by go test ./runtime -update from %s. DO NOT EDIT.\n\n", strings.TrimPrefix(source, "../"))
+	formatted, err := format.Source(append([]byte(header), out...))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", source, err)
+	}
+	return formatted, nil
+}
+
+// dependencies returns the require block and the go.sum lines of the
+// modules zrt imports, at the versions the zumbra module uses.
+func dependencies() (requires, sums []byte, err error) {
+	out, err := exec.Command("go", "list", "-deps", "-f", "{{with .Module}}{{if not .Main}}{{.Path}} {{.Version}}{{end}}{{end}}", "./zrt").Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("go list: %s", err)
+	}
+	imports, err := exec.Command("go", "list", "-f", `{{join .Imports "\n"}}`, "./zrt").Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("go list: %s", err)
+	}
+
+	modules := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			modules[line] = true
+		}
+	}
+	lines := make([]string, 0, len(modules))
+	for module := range modules {
+		lines = append(lines, module)
+	}
+	sort.Strings(lines)
+
+	var req bytes.Buffer
+	req.WriteString("require (\n")
+	for _, module := range lines {
+		path := strings.Fields(module)[0]
+		indirect := " // indirect"
+		for _, imp := range strings.Split(string(imports), "\n") {
+			if imp == path || strings.HasPrefix(imp, path+"/") {
+				indirect = ""
+			}
+		}
+		fmt.Fprintf(&req, "\t%s%s\n", module, indirect)
+	}
+	req.WriteString(")\n")
+
+	goSum, err := os.ReadFile("../go.sum")
+	if err != nil {
+		return nil, nil, err
+	}
+	var sum bytes.Buffer
+	for _, line := range strings.Split(string(goSum), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && modules[fields[0]+" "+strings.TrimSuffix(fields[1], "/go.mod")] {
+			sum.WriteString(line + "\n")
+		}
+	}
+	return req.Bytes(), sum.Bytes(), nil
+}
@@ -0,0 +1,95 @@
+package zrt
+
+import "fmt"
+
+// Builtins holds the builtins a built program can use, by name. The ones
+// that need a mail or WhatsApp account and the testing builtins are left
+// out.
+var Builtins = map[string]*Builtin{
+	"addToArrayStart":       AddToArrayStartBuiltin(),
+	"addToArrayEnd":         AddToArrayEndBuiltin(),
+	"addToDict":             AddToDictBuiltin(),
+	"allButFirst":           AllButFirstBuiltin(),
+	"bigint":                BigIntBuiltin(),
+	"bhaskara":              BhaskaraBuiltin(),
+	"capitalize":            CapitalizeBuiltin(),
+	"decimal":               DecimalBuiltin(),
+	"decimalRounding":       DecimalRoundingBuiltin(),
+	"date":                  DateBuiltin(),
+	"deleteFromDict":        DeleteFromDictBuiltin(),
+	"dictKeys":              DictKeysBuiltin(),
+	"dictValues":            DictValuesBuiltin(),
+	"dotenvLoad":            process.loadEnvBuiltin(),
+	"dotenvGet":             process.getEnvBuiltin(),
+	"first":                 ArrayFirstBuiltin(),
+	"get":                   GetBuiltin(),
+	"getFromDict":           GetFromDictBuiltin(),
+	"hashCode":              HashCodeBuiltin(),
+	"html":                  HtmlHandlerBuiltin(),
+	"indexOf":               IndexOfBuiltin(),
+	"input":                 InputBuiltin(),
+	"iter":                  IterBuiltin(),
+	"jsonParse":             JsonParse(),
+	"jsonStringify":         JsonStringify(),
+	"jwtCreateToken":        process.createTokenBuiltin(),
+	"jwtVerifyToken":        process.verifyTokenBuiltin(),
+	"last":                  ArrayLastBuiltin(),
+	"max":                   MaxBuiltin(),
+	"min":                   MinBuiltin(),
+	"mysqlConnection":       process.mysqlConnectionBuiltin(),
+	"mysqlCreateTable":      process.mysqlCreateTableBuiltin(),
+	"mysqlDeleteFromTable":  process.mysqlDeleteFromTableBuiltin(),
+	"mysqlDropTable":        process.mysqlDeleteTableBuiltin(),
+	"mysqlGetFromTable":     process.mysqlGetFromTableBuiltin(),
+	"mysqlInsertIntoTable":  process.mysqlInsertIntoTableBuiltin(),
+	"mysqlShowTables":       process.mysqlShowTablesBuiltin(),
+	"mysqlShowTableColumns": process.mysqlShowTableColumnsBuiltin(),
+	"mysqlUpdateIntoTable":  process.mysqlUpdateIntoTableBuiltin(),
+	"next":                  NextBuiltin(),
+	"organize":              OrganizeBuiltins(),
+	"randomFloat":           GenerateRandomFloatBuiltin(),
+	"randomInteger":         GenerateRandomIntegerBuiltin(),
+	"registerRoute":         process.registerRouteBuiltin(),
+	"removeFromArray":       RemoveFromArrayBuiltin(),
+	"removeWhiteSpaces":     RemoveWhiteSpacesBuiltin(),
+	"replace":               ReplaceBuiltin(),
+	"round":                 RoundBuiltin(),
+	"server":                process.serverBuiltin(),
+	"serveFile":             ServeFileBuiltin(),
+	"serveStatic":           process.serveStaticBuiltin(),
+	"set":                   SetBuiltin(),
+	"setAdd":                SetAddBuiltin(),
+	"setDifference":         SetDifferenceBuiltin(),
+	"setHas":                SetHasBuiltin(),
+	"setIntersect":          SetIntersectBuiltin(),
+	"setRemove":             SetRemoveBuiltin(),
+	"setUnion":              SetUnionBuiltin(),
+	"show":                  ShowBuiltin(),
+	"sizeOf":                SizeOfBuiltin(),
+	"sum":                   SumBuiltin(),
+	"take":                  TakeBuiltin(),
+	"toArray":               ToArrayBuiltin(),
+	"toBool":                ToBoolParserBuiltin(),
+	"toFloat":               ToFloatParserBuiltin(),
+	"toInt":                 ToIntParserBuiltin(),
+	"toLowercase":           LowercaseBuiltin(),
+	"toString":              ToStringParserBuiltin(),
+	"toUppercase":           UppercaseBuiltin(),
+}
+
+func NewBoolean(value bool) *Boolean {
+	return &Boolean{Value: value}
+}
+func NewFloat(value float64) *Float {
+	return &Float{Value: value}
+}
+func NewString(value string) *String {
+	return &String{Value: value}
+}
+
+func NewInteger(value int64) *Integer {
+	return &Integer{Value: value}
+}
+func NewError(format string, a ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, a...)}
+}
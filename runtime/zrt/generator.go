@@ -0,0 +1,37 @@
+package zrt
+
+import "iter"
+
+// Generator is returned by calling a function that contains `yield`. The
+// body only starts running on the first call to Next and is suspended at
+// every yield.
+type Generator struct {
+	body func(yield func(Value))
+	next func() (Value, bool)
+}
+
+// NewGenerator returns a generator running body, which hands out values by
+// calling yield.
+func NewGenerator(body func(yield func(Value))) *Generator {
+	seq := func(yield func(Value) bool) {
+		// Generators are never stopped early, so yield never returns
+		// false.
+		body(func(v Value) { yield(v) })
+	}
+
+	next, _ := iter.Pull(seq)
+	return &Generator{body: body, next: next}
+}
+
+func (g *Generator) Type() ValueType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string { return "generator" }
+
+func (g *Generator) Next() (Value, bool, error) {
+	v, ok := g.next()
+	return v, ok, nil
+}
+
+// Restart returns a generator running the same body from the start.
+func (g *Generator) Restart() Iterator {
+	return NewGenerator(g.body)
+}
@@ -0,0 +1,385 @@
+package zrt
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// RuntimeError stops the program, as an error returned by the VM does.
+type RuntimeError struct {
+	Message string
+}
+
+func (e *RuntimeError) Error() string { return e.Message }
+
+func fail(format string, a ...interface{}) {
+	panic(&RuntimeError{Message: fmt.Sprintf(format, a...)})
+}
+
+// Main runs program and reports a runtime error the way `zumbra run` does.
+func Main(program func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			fmt.Printf("Error on VM execution: %s\n", e.Message)
+			os.Exit(1)
+		}
+	}()
+
+	program()
+}
+
+// BigIntLiteral returns the value of an integer literal too large for an
+// int64.
+func BigIntLiteral(digits string) Value {
+	value, _ := new(big.Int).SetString(digits, 10)
+	return &BigInt{Value: value}
+}
+
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
+func Truthy(v Value) bool {
+	switch v := v.(type) {
+	case *Boolean:
+		return v.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
+
+// opcodes numbers the operators like the VM's opcodes, which the VM's
+// error messages print.
+var opcodes = map[string]int{
+	"+": 1, "-": 3, "*": 4, "/": 5, "%": 6,
+	"==": 9, "!=": 10, ">": 11, "<": 12, "<=": 13, ">=": 14,
+}
+
+func Add(left, right Value) Value { return arithmetic("+", left, right) }
+func Sub(left, right Value) Value { return arithmetic("-", left, right) }
+func Mul(left, right Value) Value { return arithmetic("*", left, right) }
+func Div(left, right Value) Value { return arithmetic("/", left, right) }
+func Mod(left, right Value) Value { return arithmetic("%", left, right) }
+
+func arithmetic(op string, left, right Value) Value {
+	switch {
+	case IsExactNumber(left) || IsExactNumber(right):
+		return exact(op, left, right)
+
+	case left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ:
+		l, r := left.(*Integer).Value, right.(*Integer).Value
+
+		var result int64
+		switch op {
+		case "+":
+			result = l + r
+		case "-":
+			result = l - r
+		case "*":
+			result = l * r
+		case "/", "%":
+			if r == 0 {
+				fail("division by zero")
+			}
+			if op == "/" {
+				result = l / r
+			} else {
+				result = l % r
+			}
+		}
+
+		if IntegerOverflows(op, l, r, result) {
+			return exact(op, left, right)
+		}
+		return &Integer{Value: result}
+
+	case isFloatPair(left, right):
+		l, _ := toFloat(left)
+		r, _ := toFloat(right)
+
+		switch op {
+		case "+":
+			return &Float{Value: l + r}
+		case "-":
+			return &Float{Value: l - r}
+		case "*":
+			return &Float{Value: l * r}
+		case "/":
+			return &Float{Value: l / r}
+		}
+		fail("unknown float operator: %d", opcodes[op])
+
+	case left.Type() == STRING_OBJ && right.Type() == STRING_OBJ:
+		if op != "+" {
+			fail("unknown string operator: %d", opcodes[op])
+		}
+		return &String{Value: left.(*String).Value + right.(*String).Value}
+	}
+
+	fail("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+	return nil
+}
+
+// isFloatPair reports whether the VM handles left and right with float64
+// arithmetic: both are integers or floats and at least one is a float.
+func isFloatPair(left, right Value) bool {
+	number := func(v Value) bool { return v.Type() == INTEGER_OBJ || v.Type() == FLOAT_OBJ }
+	return number(left) && number(right) && (left.Type() == FLOAT_OBJ || right.Type() == FLOAT_OBJ)
+}
+
+func exact(op string, left, right Value) Value {
+	result, err := Arithmetic(op, left, right)
+	if err != nil {
+		fail("%s", err)
+	}
+	return result
+}
+
+func Equal(left, right Value) Value {
+	return NativeBool(compare("==", left, right))
+}
+
+func NotEqual(left, right Value) Value {
+	return NativeBool(compare("!=", left, right))
+}
+
+func Greater(left, right Value) Value {
+	return NativeBool(compare(">", left, right))
+}
+
+func Less(left, right Value) Value {
+	return NativeBool(compare("<", left, right))
+}
+
+func GreaterEqual(left, right Value) Value {
+	return NativeBool(compare(">=", left, right))
+}
+
+func LessEqual(left, right Value) Value {
+	return NativeBool(compare("<=", left, right))
+}
+
+func compare(op string, left, right Value) bool {
+	if left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ {
+		l, r := left.(*Integer).Value, right.(*Integer).Value
+		return ordered(op, cmp(l < r, l > r))
+	}
+
+	if IsExactNumber(left) || IsExactNumber(right) {
+		if result, ok := Compare(left, right); ok {
+			return ordered(op, result)
+		}
+	}
+
+	if isFloatPair(left, right) {
+		l, _ := toFloat(left)
+		r, _ := toFloat(right)
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		}
+		return ordered(op, cmp(l < r, l > r))
+	}
+
+	if left.Type() == STRING_OBJ && right.Type() == STRING_OBJ {
+		if op != "==" {
+			fail("unknown string operator: %d", opcodes[op])
+		}
+		return left.(*String).Value == right.(*String).Value
+	}
+
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+	fail("unknown operator: %d (%s %s)", opcodes[op], left.Type(), right.Type())
+	return false
+}
+
+func cmp(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func ordered(op string, result int) bool {
+	switch op {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case ">":
+		return result > 0
+	case "<":
+		return result < 0
+	case ">=":
+		return result >= 0
+	}
+	return result <= 0
+}
+
+// And and Or evaluate both of their operands, as the VM does.
+func And(left, right Value) Value {
+	return NativeBool(Truthy(left) && Truthy(right))
+}
+
+func Or(left, right Value) Value {
+	return NativeBool(Truthy(left) || Truthy(right))
+}
+
+// Not is true for the false and null singletons only, like the VM's `!`.
+func Not(v Value) Value {
+	switch v {
+	case TRUE:
+		return FALSE
+	case FALSE, NULL:
+		return TRUE
+	}
+	return FALSE
+}
+
+func Minus(v Value) Value {
+	negated, ok := Negate(v)
+	if !ok {
+		fail("unsupported type for negation: %s", v.Type())
+	}
+	return negated
+}
+
+func NewDict(pairs ...Value) Value {
+	dict := &Dict{Pairs: make(map[DictKey]DictPair, len(pairs)/2)}
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(Dictable)
+		if !ok {
+			fail("unusable as hash key: %s", pairs[i].Type())
+		}
+		dict.Pairs[key.DictKey()] = DictPair{Key: pairs[i], Value: pairs[i+1]}
+	}
+	return dict
+}
+
+func NewSetOf(elements ...Value) Value {
+	set := NewSet()
+	for _, el := range elements {
+		if err := set.Add(el); err != nil {
+			fail("%s", err)
+		}
+	}
+	return set
+}
+
+func Index(left, index Value) Value {
+	switch {
+	case left.Type() == ARRAY_OBJ && index.Type() == INTEGER_OBJ:
+		elements := left.(*Array).Elements
+		i := index.(*Integer).Value
+		if i < 0 || i > int64(len(elements)-1) {
+			return NULL
+		}
+		return elements[i]
+
+	case left.Type() == DICT_OBJ:
+		key, ok := index.(Dictable)
+		if !ok {
+			fail("unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.(*Dict).Pairs[key.DictKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	}
+
+	fail("index operator not supported: %s", left.Type())
+	return nil
+}
+
+// Attr reads the attributes of dates and the variants of enums.
+func Attr(v Value, name string) Value {
+	switch v := v.(type) {
+	case *Date:
+		switch name {
+		case "hour":
+			return &Integer{Value: int64(v.Hour)}
+		case "minute":
+			return &Integer{Value: int64(v.Minute)}
+		case "day":
+			return &Integer{Value: int64(v.Day)}
+		case "second":
+			return &Integer{Value: int64(v.Second)}
+		case "month":
+			return &Integer{Value: int64(v.Month)}
+		case "year":
+			return &Integer{Value: int64(v.Year)}
+		case "fullDate":
+			return &String{Value: v.FullDate.String()}
+		}
+		fail("unknown attribute %s for Date", name)
+
+	case *Enum:
+		variant, ok := v.Variant(name)
+		if !ok {
+			fail("unknown variant %s for enum %s", name, v.Name)
+		}
+		return variant
+	}
+
+	fail("object type %s has no attributes", v.Type())
+	return nil
+}
+
+func Call(fn Value, args ...Value) Value {
+	switch fn := fn.(type) {
+	case *Function:
+		if len(args) != fn.Params {
+			fail("wrong number of arguments: want=%d, got=%d", fn.Params, len(args))
+		}
+		return fn.Fn(args)
+
+	case *Builtin:
+		result := fn.Fn(args...)
+		if result == nil {
+			return NULL
+		}
+		return result
+	}
+
+	fail("calling non-function and non-built-in object: %s", fn.Type())
+	return nil
+}
+
+// Iterate starts a `for` loop over v.
+func Iterate(v Value) Iterator {
+	it, err := NewIterator(v)
+	if err != nil {
+		fail("%s", err)
+	}
+	return it
+}
+
+// Next advances a `for` loop.
+func Next(it Iterator) (Value, bool) {
+	v, ok, err := it.Next()
+	if err != nil {
+		fail("%s", err)
+	}
+	return v, ok
+}
@@ -0,0 +1,352 @@
+// Package zrt is the runtime of the Go programs `zumbra build` generates.
+// Its values mirror the object package and its operators mirror the VM, so
+// a built program prints what `zumbra run` prints.
+//
+// Its source is copied next to every generated program. The builtins, the
+// numbers and the iterators are generated from the object package, by
+// go test ./runtime -update, so they behave as they do under the VM.
+package zrt
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ValueType string
+
+const (
+	INTEGER_OBJ    = "INTEGER"
+	BOOLEAN_OBJ    = "BOOLEAN"
+	NULL_OBJ       = "NULL"
+	ERROR_OBJ      = "ERROR"
+	STRING_OBJ     = "STRING"
+	BUILTIN_OBJ    = "BUILTIN"
+	ARRAY_OBJ      = "ARRAY"
+	DICT_OBJ       = "DICT"
+	CLOSURE_OBJ    = "CLOSURE_OBJ"
+	FLOAT_OBJ      = "FLOAT"
+	DATE_OBJ       = "DATE"
+	ENUM_OBJ       = "ENUM"
+	ENUM_VALUE_OBJ = "ENUM_VALUE"
+	SET_OBJ        = "SET"
+	BIGINT_OBJ     = "BIGINT"
+	DECIMAL_OBJ    = "DECIMAL"
+	ITERATOR_OBJ   = "ITERATOR"
+	GENERATOR_OBJ  = "GENERATOR"
+)
+
+// Value is what object.Object is to the VM.
+type Value interface {
+	Type() ValueType
+	Inspect() string
+}
+
+var (
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+	NULL  = &Null{}
+)
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ValueType { return INTEGER_OBJ }
+func (i *Integer) Inspect() string { return fmt.Sprintf("%d", i.Value) }
+
+type Boolean struct {
+	Value bool
+}
+
+func (b *Boolean) Type() ValueType { return BOOLEAN_OBJ }
+func (b *Boolean) Inspect() string { return fmt.Sprintf("%t", b.Value) }
+
+type Null struct{}
+
+func (n *Null) Type() ValueType { return NULL_OBJ }
+func (n *Null) Inspect() string { return "null" }
+
+type Error struct {
+	Message string
+}
+
+func (e *Error) Type() ValueType { return ERROR_OBJ }
+func (e *Error) Inspect() string { return fmt.Sprintf("ERROR: %s", e.Message) }
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ValueType { return STRING_OBJ }
+func (s *String) Inspect() string { return s.Value }
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ValueType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	return strconv.FormatFloat(f.Value, 'f', -1, 64)
+}
+
+// Function is a compiled Zumbra function. Fn gets exactly Params
+// arguments.
+type Function struct {
+	Name   string
+	Params int
+	Fn     func(args []Value) Value
+}
+
+func (f *Function) Type() ValueType { return CLOSURE_OBJ }
+func (f *Function) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", f)
+}
+
+type BuiltinFunction func(args ...Value) Value
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ValueType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string { return "builtin function" }
+
+type Array struct {
+	Elements []Value
+}
+
+func (a *Array) Type() ValueType { return ARRAY_OBJ }
+func (a *Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range a.Elements {
+		elements = append(elements, el.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type DictKey struct {
+	Type  ValueType
+	Value uint64
+}
+
+type Dictable interface {
+	DictKey() DictKey
+}
+
+func (b *Boolean) DictKey() DictKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return DictKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) DictKey() DictKey {
+	return DictKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) DictKey() DictKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return DictKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type DictPair struct {
+	Key   Value
+	Value Value
+}
+
+type Dict struct {
+	Pairs map[DictKey]DictPair
+}
+
+func (d *Dict) Type() ValueType { return DICT_OBJ }
+func (d *Dict) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range d.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+":"+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+type Date struct {
+	FullDate time.Time
+	Hour     int
+	Minute   int
+	Day      int
+	Second   int
+	Month    time.Month
+	Year     int
+}
+
+func (d *Date) Type() ValueType { return DATE_OBJ }
+func (d *Date) Inspect() string { return d.FullDate.String() }
+
+type Enum struct {
+	Name     string
+	Variants []*EnumValue
+}
+
+func NewEnum(name string, variants ...string) *Enum {
+	enum := &Enum{Name: name}
+	for i, v := range variants {
+		enum.Variants = append(enum.Variants, &EnumValue{Enum: name, Name: v, Ordinal: i})
+	}
+	return enum
+}
+
+func (e *Enum) Type() ValueType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	variants := []string{}
+	for _, v := range e.Variants {
+		variants = append(variants, v.Name)
+	}
+	return fmt.Sprintf("enum %s { %s }", e.Name, strings.Join(variants, ", "))
+}
+
+func (e *Enum) Variant(name string) (*EnumValue, bool) {
+	for _, v := range e.Variants {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type EnumValue struct {
+	Enum    string
+	Name    string
+	Ordinal int
+}
+
+func (ev *EnumValue) Type() ValueType { return ENUM_VALUE_OBJ }
+func (ev *EnumValue) Inspect() string { return ev.Enum + "." + ev.Name }
+
+func (ev *EnumValue) DictKey() DictKey {
+	h := fnv.New64a()
+	h.Write([]byte(ev.Inspect()))
+
+	return DictKey{Type: ev.Type(), Value: h.Sum64()}
+}
+
+// Set keeps its elements in insertion order, like object.Set.
+type Set struct {
+	Elements map[DictKey]Value
+	keys     []DictKey
+}
+
+func NewSet() *Set {
+	return &Set{Elements: map[DictKey]Value{}}
+}
+
+func (s *Set) Type() ValueType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	if len(s.keys) == 0 {
+		return "set()"
+	}
+
+	elements := []string{}
+	for _, el := range s.Values() {
+		elements = append(elements, el.Inspect())
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+func (s *Set) Add(v Value) error {
+	key, ok := v.(Dictable)
+	if !ok {
+		return fmt.Errorf("unusable as set element: %s", v.Type())
+	}
+
+	k := key.DictKey()
+	if _, ok := s.Elements[k]; !ok {
+		s.keys = append(s.keys, k)
+	}
+	s.Elements[k] = v
+
+	return nil
+}
+
+func (s *Set) Remove(v Value) {
+	key, ok := v.(Dictable)
+	if !ok {
+		return
+	}
+
+	k := key.DictKey()
+	if _, ok := s.Elements[k]; !ok {
+		return
+	}
+
+	delete(s.Elements, k)
+	for i, existing := range s.keys {
+		if existing == k {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Set) Has(v Value) bool {
+	key, ok := v.(Dictable)
+	if !ok {
+		return false
+	}
+
+	_, ok = s.Elements[key.DictKey()]
+	return ok
+}
+
+func (s *Set) Values() []Value {
+	values := make([]Value, 0, len(s.keys))
+	for _, k := range s.keys {
+		values = append(values, s.Elements[k])
+	}
+	return values
+}
+
+// Elements returns the values a `for` loop visits, like object.Elements.
+func Elements(v Value) ([]Value, bool) {
+	switch v := v.(type) {
+	case *Array:
+		return append([]Value{}, v.Elements...), true
+	case *Set:
+		return v.Values(), true
+	case *Dict:
+		keys := []Value{}
+		for _, pair := range v.Pairs {
+			keys = append(keys, pair.Key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Inspect() < keys[j].Inspect()
+		})
+		return keys, true
+	case *String:
+		chars := []Value{}
+		for _, ch := range v.Value {
+			chars = append(chars, &String{Value: string(ch)})
+		}
+		return chars, true
+	}
+
+	return nil, false
+}
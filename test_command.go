@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"zumbra/tester"
+)
+
+// testCommand implements `zumbra test [-format tap|junit] [paths...]`. It
+// returns false when a test fails.
+func testCommand(args []string) bool {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	output := flags.String("format", "tap", "report format: tap or junit")
+	flags.Parse(args)
+
+	if *output != "tap" && *output != "junit" {
+		fmt.Println("usage: zumbra test [-format tap|junit] [paths...]")
+		return false
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := tester.Discover(paths)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	results := tester.Run(files)
+
+	if *output == "junit" {
+		err = tester.WriteJUnit(os.Stdout, results)
+	} else {
+		err = tester.WriteTAP(os.Stdout, results)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	for _, r := range results {
+		if !r.Passed() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+func (r Result) title() string {
+	if r.Name == "" {
+		return r.File
+	}
+	return r.File + ": " + r.Name
+}
+
+// WriteTAP reports results in the Test Anything Protocol, version 13.
+func WriteTAP(w io.Writer, results []Result) error {
+	var out strings.Builder
+
+	out.WriteString("TAP version 13\n")
+	fmt.Fprintf(&out, "1..%d\n", len(results))
+
+	for i, r := range results {
+		if r.Passed() {
+			fmt.Fprintf(&out, "ok %d - %s\n", i+1, r.title())
+			continue
+		}
+
+		fmt.Fprintf(&out, "not ok %d - %s\n", i+1, r.title())
+		out.WriteString("  ---\n  message: |\n")
+		for _, failure := range r.Failures {
+			for _, line := range strings.Split(failure, "\n") {
+				out.WriteString("    " + line + "\n")
+			}
+		}
+		out.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit reports results as JUnit XML with one test suite per file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitSuites{}
+	suites := map[string]int{}
+	durations := []time.Duration{}
+
+	for _, r := range results {
+		index, ok := suites[r.File]
+		if !ok {
+			index = len(report.Suites)
+			suites[r.File] = index
+			report.Suites = append(report.Suites, junitSuite{Name: r.File})
+			durations = append(durations, 0)
+		}
+		suite := &report.Suites[index]
+
+		name := r.Name
+		if name == "" {
+			name = r.File
+		}
+		c := junitCase{Name: name, Classname: r.File, Time: seconds(r.Duration)}
+		if !r.Passed() {
+			c.Failure = &junitFailure{
+				Message: strings.SplitN(r.Failures[0], "\n", 2)[0],
+				Text:    strings.Join(r.Failures, "\n"),
+			}
+			suite.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+		durations[index] += r.Duration
+		suite.Time = seconds(durations[index])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
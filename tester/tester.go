@@ -0,0 +1,177 @@
+// Package tester runs the tests that *_test.zum files register with
+// test(). A file's top-level code runs once; every test then runs against
+// its own copy of the resulting globals, so tests cannot see each other's
+// changes.
+package tester
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/vm"
+)
+
+type Result struct {
+	File     string
+	Name     string
+	Failures []string
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Discover returns the *_test.zum files in paths, searching directories
+// recursively. The tests of installed packages, in zumbra_modules, are left
+// out.
+func Discover(paths []string) ([]string, error) {
+	files := []string{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == "zumbra_modules" && file != path {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(file, "_test.zum") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// RunFile runs every test in a file. The error is set when the file itself
+// could not be parsed, compiled or run.
+func RunFile(filename string) ([]Result, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(data)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parsing errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return nil, err
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range builtins.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+
+	// The file gets its own registry, shadowing the stock test builtins.
+	registry := builtins.NewRegistry()
+	globals := make([]object.Object, vm.GlobalSize)
+	for name, builtin := range registry.Builtins() {
+		globals[symbolTable.Define(name).Index] = builtin
+	}
+
+	comp := compiler.NewWithStateAndDir(symbolTable, []object.Object{}, dir)
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("compilation error: %s", err)
+	}
+	bytecode := comp.Bytecode()
+
+	machine := vm.NewWithGlobalsStore(bytecode, globals)
+	if err := recovered(machine.Run); err != nil {
+		return nil, fmt.Errorf("error on VM execution: %s", err)
+	}
+	globals = machine.Globals()
+	tests := registry.TakeTests()
+	registry.TakeFailures()
+
+	results := []Result{}
+	for _, test := range tests {
+		results = append(results, run(filename, registry, test, bytecode, globals))
+	}
+
+	return results, nil
+}
+
+// run runs a single test. A test that panics fails, and the tests after it
+// still run.
+func run(filename string, registry *builtins.Registry, test builtins.Test, bytecode *compiler.Bytecode, globals []object.Object) Result {
+	isolated := object.CopyAll(globals)
+	machine := vm.NewWithGlobalsStore(bytecode, isolated)
+
+	start := time.Now()
+	err := recovered(func() error {
+		if _, err := machine.Call(test.Fn); err != nil {
+			return fmt.Errorf("runtime error: %s", err)
+		}
+		return nil
+	})
+	result := Result{
+		File:     filename,
+		Name:     test.Name,
+		Failures: registry.TakeFailures(),
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+	}
+
+	return result
+}
+
+// recovered runs fn, turning a panic into an error with the panic value and
+// the stack that led to it, so a bug in the VM shows up in the report
+// instead of ending the whole run.
+func recovered(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn()
+}
+
+// Run runs every file. A file that cannot be run is reported as a single
+// failing result with an empty name.
+func Run(files []string) []Result {
+	results := []Result{}
+
+	for _, file := range files {
+		fileResults, err := RunFile(file)
+		if err != nil {
+			results = append(results, Result{File: file, Failures: []string{err.Error()}})
+			continue
+		}
+		results = append(results, fileResults...)
+	}
+
+	return results
+}
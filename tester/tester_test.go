@@ -0,0 +1,166 @@
+package tester
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "lib.zum", `var double << fct(x) { x * 2 };`)
+	file := writeFile(t, dir, "lib_test.zum", `import "lib.zum"
+var total << 0;
+
+test("changes globals", fct() {
+    total << total + 1;
+    assertEqual(total, 1);
+});
+
+test("sees fresh globals", fct() {
+    assertEqual(total, 0);
+    assertEqual(double(2), 4);
+});
+
+test("fails", fct() {
+    assertEqual(double(2), 5, "double");
+});
+
+test("crashes", fct() {
+    1 + "a";
+});
+
+test("panics", fct() {
+    organize([2, "a"]);
+});
+
+test("catches errors", fct() {
+    assertThrows(fct() { 1 / 0 });
+    assertThrows(fct() { 1 + "a" });
+});
+
+test("does not hide panics", fct() {
+    assertThrows(fct() { organize([2, "a"]) });
+});
+`)
+
+	results, err := RunFile(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	panicked := "panic: interface conversion: object.Object is *object.String, not *object.Integer\n"
+	expected := []struct {
+		name     string
+		failures []string
+	}{
+		{"changes globals", nil},
+		{"sees fresh globals", nil},
+		{"fails", []string{"double: values are not equal\n  value: got 4, want 5"}},
+		{"crashes", []string{"runtime error: unsupported types for binary operation: INTEGER STRING"}},
+		{"panics", []string{panicked}},
+		{"catches errors", nil},
+		{"does not hide panics", []string{panicked}},
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("wrong number of results. want=%d, got=%d", len(expected), len(results))
+	}
+
+	for i, r := range results {
+		if r.Name != expected[i].name {
+			t.Errorf("results[%d] has wrong name. want=%q, got=%q", i, expected[i].name, r.Name)
+		}
+		if len(expected[i].failures) == 1 && expected[i].failures[0] == panicked {
+			if len(r.Failures) != 1 || !strings.HasPrefix(r.Failures[0], panicked) || !strings.Contains(r.Failures[0], "goroutine") {
+				t.Errorf("results[%d] should report the panic with its stack, got=%q", i, r.Failures)
+			}
+			continue
+		}
+		if strings.Join(r.Failures, "|") != strings.Join(expected[i].failures, "|") {
+			t.Errorf("results[%d] has wrong failures. want=%q, got=%q", i, expected[i].failures, r.Failures)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "zumbra_modules", "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "main.zum", ``)
+	writeFile(t, dir, "a_test.zum", ``)
+	writeFile(t, filepath.Join(dir, "sub"), "b_test.zum", ``)
+	writeFile(t, filepath.Join(dir, "zumbra_modules", "lib"), "lib_test.zum", ``)
+
+	files, err := Discover([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 || !strings.HasSuffix(files[0], "a_test.zum") || !strings.HasSuffix(files[1], "b_test.zum") {
+		t.Errorf("wrong files: %v", files)
+	}
+}
+
+func TestReports(t *testing.T) {
+	results := []Result{
+		{File: "a_test.zum", Name: "passes"},
+		{File: "a_test.zum", Name: "fails", Failures: []string{"values are not equal\n  value: got 1, want 2"}},
+		{File: "b_test.zum", Failures: []string{"compilation error: undefined variable x"}},
+	}
+
+	var tap bytes.Buffer
+	if err := WriteTAP(&tap, results); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTAP := `TAP version 13
+1..3
+ok 1 - a_test.zum: passes
+not ok 2 - a_test.zum: fails
+  ---
+  message: |
+    values are not equal
+      value: got 1, want 2
+  ...
+not ok 3 - b_test.zum
+  ---
+  message: |
+    compilation error: undefined variable x
+  ...
+`
+	if tap.String() != expectedTAP {
+		t.Errorf("wrong TAP output.\nwant=%q\ngot =%q", expectedTAP, tap.String())
+	}
+
+	var junit bytes.Buffer
+	if err := WriteJUnit(&junit, results); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fragment := range []string{
+		`<testsuite name="a_test.zum" tests="2" failures="1"`,
+		`<testcase name="passes" classname="a_test.zum" time="0.000"></testcase>`,
+		`<failure message="values are not equal">`,
+		`<testsuite name="b_test.zum" tests="1" failures="1"`,
+	} {
+		if !strings.Contains(junit.String(), fragment) {
+			t.Errorf("JUnit output does not contain %q:\n%s", fragment, junit.String())
+		}
+	}
+}
@@ -12,6 +12,10 @@ const (
 	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
+	// Comments
+	COMMENT     = "COMMENT"
+	DOC_COMMENT = "DOC_COMMENT"
+
 	// Operators
 	ASSIGN = "<<"
 
@@ -31,6 +35,8 @@ const (
 	PLUSPLUS   = "++"
 	MINUSMINUS = "--"
 	DOT        = "."
+	PIPE       = "|"
+	ARROW      = "=>"
 
 	// Logical
 	OR  = "or"
@@ -57,11 +63,18 @@ const (
 	RETURN   = "RETURN"
 	WHILE    = "WHILE"
 	IMPORT   = "IMPORT"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
+	FOR      = "FOR"
+	IN       = "IN"
+	YIELD    = "YIELD"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
 }
 
 var keywords = map[string]TokenType{
@@ -74,6 +87,11 @@ var keywords = map[string]TokenType{
 	"return": RETURN,
 	"while":  WHILE,
 	"import": IMPORT,
+	"enum":   ENUM,
+	"match":  MATCH,
+	"for":    FOR,
+	"in":     IN,
+	"yield":  YIELD,
 	"and":    AND,
 	"or":     OR,
 }
@@ -0,0 +1,38 @@
+package transpiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteModuleWithSeveralMains(t *testing.T) {
+	dir := t.TempDir()
+	mains := map[string][]byte{
+		".":        []byte("package main // root\n"),
+		"tools/a":  []byte("package main // a\n"),
+		"tools/b":  []byte("package main // b\n"),
+		"examples": []byte("package main // examples\n"),
+	}
+
+	if err := WriteModule(dir, mains); err != nil {
+		t.Fatal(err)
+	}
+
+	for pkg, main := range mains {
+		got, err := os.ReadFile(filepath.Join(dir, pkg, "main.go"))
+		if err != nil {
+			t.Errorf("%s: %s", pkg, err)
+			continue
+		}
+		if string(got) != string(main) {
+			t.Errorf("%s: wrong main.go. want=%q, got=%q", pkg, main, got)
+		}
+	}
+
+	for _, name := range []string{"go.mod", filepath.Join("zrt", "value.go")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %s", name, err)
+		}
+	}
+}
@@ -1,316 +1,800 @@
+// Package transpiler turns a Zumbra program into the source of a Go
+// program. The generated code works on the dynamic values of the zrt
+// runtime and follows the VM's semantics: closures capture their free
+// variables by value when they are created, variables belong to their
+// function rather than to their block, and the operators and builtins
+// behave, and fail, as they do in the VM.
 package transpiler
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+
+	"zumbra/ast"
+	"zumbra/compiler"
+	"zumbra/imports"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
 	"zumbra/runtime"
+	"zumbra/runtime/zrt"
 )
 
-func ZumbraTranspiler(zum string) (string, error) {
-	lines := strings.Split(zum, "\n")
-	var goBody []string
-	var blockStack []string
+// Module is the module path of the generated program.
+const Module = "zumbra-app"
 
-	var inFunction bool
-	var funcBuffer []string
-	var funcName string
-	var funcParams string
-	var tmpCounter int = 0
+// UnsupportedError reports a program using a builtin the zrt runtime
+// leaves out.
+type UnsupportedError struct {
+	Builtin string
+}
 
-	for _, line := range lines {
-		if idx := strings.Index(line, " //"); idx != -1 {
-			line = line[:idx]
-		}
-		line = strings.TrimSpace(line)
-		line = strings.TrimSuffix(line, ";")
-		line = strings.TrimSpace(line)
-
-		if strings.HasPrefix(line, "var ") && strings.Contains(line, "fct") {
-			inFunction = true
-			line = strings.TrimPrefix(line, "var ")
-			parts := strings.Split(line, "<<")
-			funcName = strings.TrimSpace(parts[0])
-
-			functionPart := strings.TrimSpace(parts[1])
-			paramStart := strings.Index(functionPart, "(")
-			paramEnd := strings.Index(functionPart, "){")
-			funcParams = functionPart[paramStart+1 : paramEnd]
-			continue
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("the builtin %s is not supported by zumbra build", e.Builtin)
+}
+
+type Options struct {
+	// Dir is where imports are resolved from.
+	Dir string
+	// ImportPaths are searched, in order, for imports not found in Dir.
+	ImportPaths []string
+	// Source names the program in the header of the generated file.
+	Source string
+}
+
+// Generate returns the main.go of a program that runs program. The program
+// must compile: compile errors are reported as the compiler reports them.
+func Generate(program *ast.Program, opts Options) ([]byte, error) {
+	if err := check(program, opts); err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		opts:      opts,
+		dir:       opts.Dir,
+		scope:     newGlobalScope(),
+		constants: map[string]string{},
+		builtins:  map[string]bool{},
+		imported:  map[string]bool{},
+	}
+
+	body := g.block(program.Statements)
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var out bytes.Buffer
+	source := opts.Source
+	if source == "" {
+		source = "a Zumbra program"
+	}
+	fmt.Fprintf(&out, "// Code generated by zumbra build from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "package main\n\nimport %q\n\n", Module+"/zrt")
+
+	names := make([]string, 0, len(g.builtins))
+	for name := range g.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&out, "var b_%s = zrt.Builtins[%q]\n", name, name)
+	}
+
+	for _, c := range g.constantDecls {
+		fmt.Fprintf(&out, "var %s\n", c)
+	}
+	for _, name := range g.scope.locals {
+		fmt.Fprintf(&out, "var %s zrt.Value\n", name)
+	}
+
+	fmt.Fprintf(&out, "\nfunc main() {\n\tzrt.Main(run)\n}\n\nfunc run() {\n%s}\n", body)
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go code: %s", err)
+	}
+	return formatted, nil
+}
+
+// check compiles program, so the generator only sees valid programs.
+func check(program *ast.Program, opts Options) error {
+	symbolTable := compiler.NewSymbolTable()
+	for i, b := range builtins.Builtins {
+		symbolTable.DefineBuiltin(i, b.Name)
+	}
+
+	comp := compiler.NewWithStateAndDir(symbolTable, []object.Object{}, opts.Dir)
+	comp.SetImportPaths(opts.ImportPaths)
+	return comp.Compile(program)
+}
+
+type symbolKind int
+
+const (
+	globalSymbol symbolKind = iota
+	localSymbol
+	builtinSymbol
+	freeSymbol
+	functionSymbol
+)
+
+type symbol struct {
+	kind   symbolKind
+	goName string
+}
+
+// scope resolves names like compiler.SymbolTable does. Every Zumbra
+// variable becomes a Go variable declared at the top of its function, and
+// free variables keep the Go name they have in the function defining them.
+type scope struct {
+	outer   *scope
+	depth   int
+	symbols map[string]symbol
+	// locals are the Go variables to declare, in order.
+	locals []string
+	// frees are the Go variables captured from enclosing functions.
+	frees []string
+	// generator is set for functions that yield.
+	generator bool
+}
+
+func newGlobalScope() *scope {
+	s := &scope{symbols: map[string]symbol{}}
+	for _, b := range builtins.Builtins {
+		s.symbols[b.Name] = symbol{kind: builtinSymbol, goName: "b_" + b.Name}
+	}
+	return s
+}
+
+func (s *scope) define(name string) symbol {
+	sym := symbol{kind: localSymbol, goName: fmt.Sprintf("l%d_%s", s.depth, name)}
+	if s.outer == nil {
+		sym = symbol{kind: globalSymbol, goName: "g_" + name}
+	}
+
+	if existing, ok := s.symbols[name]; !ok || existing.goName != sym.goName {
+		s.declare(sym.goName)
+	}
+	s.symbols[name] = sym
+	return sym
+}
+
+func (s *scope) declare(goName string) {
+	for _, name := range s.locals {
+		if name == goName {
+			return
 		}
+	}
+	s.locals = append(s.locals, goName)
+}
+
+func (s *scope) resolve(name string) (symbol, bool) {
+	sym, ok := s.symbols[name]
+	if ok || s.outer == nil {
+		return sym, ok
+	}
 
-		if inFunction {
-			fmt.Println(line)
-			if line == "}" {
-				bodyLines := funcBuffer
-				lastIndex := len(bodyLines) - 1
-				lastLine := strings.TrimSpace(bodyLines[lastIndex])
-				lastLine = strings.TrimSuffix(lastLine, ";")
-
-				fmt.Println(lastLine)
-				if !strings.HasPrefix(lastLine, "return") {
-					bodyLines[lastIndex] = "return " + lastLine
-				} else {
-					bodyLines[lastIndex] = lastLine
-				}
-
-				body := strings.Join(bodyLines, " ")
-
-				goBody = append(goBody,
-					fmt.Sprintf("var %s = func(%s int, %s int) int { %s }",
-						funcName, strings.Split(funcParams, ",")[0], strings.Split(funcParams, ",")[1], body))
-				inFunction = false
-				funcBuffer = nil
-				funcName = ""
-				funcParams = ""
-				continue
-			} else {
-				funcBuffer = append(funcBuffer, strings.TrimSpace(line))
+	sym, ok = s.outer.resolve(name)
+	if !ok || sym.kind == globalSymbol || sym.kind == builtinSymbol {
+		return sym, ok
+	}
+
+	free := symbol{kind: freeSymbol, goName: sym.goName}
+	s.frees = append(s.frees, sym.goName)
+	s.symbols[name] = free
+	return free, true
+}
+
+type generator struct {
+	opts Options
+	// dir is the directory of the file being generated, for its imports.
+	dir string
+	err error
+
+	scope *scope
+	out   *bytes.Buffer
+	temps int
+
+	// constants maps literals to the package-level variables holding them,
+	// which play the part of the VM's constant pool.
+	constants     map[string]string
+	constantDecls []string
+	builtins      map[string]bool
+	imported      map[string]bool
+}
+
+func (g *generator) fail(format string, a ...interface{}) {
+	if g.err == nil {
+		g.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (g *generator) emit(format string, a ...interface{}) {
+	fmt.Fprintf(g.out, format+"\n", a...)
+}
+
+func (g *generator) temp() string {
+	g.temps++
+	return fmt.Sprintf("t%d", g.temps)
+}
+
+// block generates statements into a buffer of their own and returns it.
+func (g *generator) block(statements []ast.Statement) string {
+	saved := g.out
+	g.out = &bytes.Buffer{}
+	for _, s := range statements {
+		g.statement(s)
+	}
+	code := g.out.String()
+	g.out = saved
+	return code
+}
+
+// valueBlock generates statements that leave their value in target: the
+// value of the last one when it is an expression, null otherwise. An empty
+// target discards the value.
+func (g *generator) valueBlock(statements []ast.Statement, target string) {
+	n := len(statements)
+	if n > 0 {
+		if last, ok := statements[n-1].(*ast.ExpressionStatement); ok && last.Expression != nil && target != "" {
+			for _, s := range statements[:n-1] {
+				g.statement(s)
 			}
-			continue
+			g.emit("%s = %s", target, g.expression(last.Expression))
+			return
 		}
+	}
 
-		if strings.HasPrefix(line, "if (") {
-			condition := strings.TrimPrefix(line, "if (")
-			condition = strings.TrimSuffix(condition, "){")
-			condition = strings.TrimSpace(condition)
+	for _, s := range statements {
+		g.statement(s)
+	}
+	if target != "" && !endsInReturn(statements) {
+		g.emit("%s = zrt.NULL", target)
+	}
+}
 
-			goBody = append(goBody, fmt.Sprintf("    if %s {", condition))
-			blockStack = append(blockStack, "if")
-			continue
+func endsInReturn(statements []ast.Statement) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	_, ok := statements[len(statements)-1].(*ast.ReturnStatement)
+	return ok
+}
+
+func (g *generator) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		switch e := s.Expression.(type) {
+		case nil:
+		case *ast.IfExpression:
+			g.ifExpression(e, "")
+		case *ast.MatchExpression:
+			g.matchExpression(e, "")
+		case *ast.CallExpression:
+			g.emit("%s", g.expression(e))
+		default:
+			g.emit("_ = %s", g.expression(e))
 		}
 
-		if strings.Contains(line, "else") {
-			if len(blockStack) > 0 && blockStack[len(blockStack)-1] == "if" {
-				goBody = append(goBody, "    } else {")
-				blockStack[len(blockStack)-1] = "if-else"
-			}
+	case *ast.VarStatement:
+		sym := g.scope.define(s.Name.Value)
+		g.emit("%s = %s", sym.goName, g.expression(s.Value))
+
+	case *ast.AssignStatement:
+		value := g.expression(s.Value)
+		sym, _ := g.scope.resolve(s.Name.Value)
+		g.emit("%s = %s", sym.goName, value)
+
+	case *ast.ReturnStatement:
+		value := g.expression(s.ReturnValue)
+		if g.scope.outer == nil || g.scope.generator {
+			g.emit("_ = %s", value)
+			g.emit("return")
+			return
+		}
+		g.emit("return %s", value)
 
-			continue
+	case *ast.YieldStatement:
+		g.emit("yield(%s)", g.expression(s.Value))
+
+	case *ast.BlockStatement:
+		for _, inner := range s.Statements {
+			g.statement(inner)
 		}
 
-		if strings.HasPrefix(line, "while (") {
-			condition := strings.TrimPrefix(line, "while (")
-			condition = strings.TrimSuffix(condition, ") {")
-			condition = strings.TrimSpace(condition)
+	case *ast.WhileStatement:
+		g.whileStatement(s)
 
-			goBody = append(goBody, fmt.Sprintf("for %s {", condition))
-			blockStack = append(blockStack, "while")
-			continue
+	case *ast.ForStatement:
+		g.forStatement(s)
+
+	case *ast.ImportStatement:
+		g.importStatement(s)
+
+	case *ast.EnumStatement:
+		names := []string{strconv.Quote(s.Name.Value)}
+		for _, v := range s.Variants {
+			names = append(names, strconv.Quote(v.Value))
 		}
+		enum := g.newConstant("zrt.NewEnum(" + strings.Join(names, ", ") + ")")
+		sym := g.scope.define(s.Name.Value)
+		g.emit("%s = %s", sym.goName, enum)
 
-		if line == "}" {
-			goBody = append(goBody, "    }")
-			if len(blockStack) > 0 {
-				blockStack = blockStack[:len(blockStack)-1]
-			}
+	default:
+		g.fail("zumbra build does not support %T", s)
+	}
+}
+
+func (g *generator) whileStatement(s *ast.WhileStatement) {
+	saved := g.out
+	g.out = &bytes.Buffer{}
+	condition := g.expression(s.Condition)
+	pre := g.out.String()
+	g.out = saved
+
+	if pre == "" {
+		g.emit("for zrt.Truthy(%s) {", condition)
+	} else {
+		g.emit("for {")
+		g.out.WriteString(pre)
+		g.emit("if !zrt.Truthy(%s) {\nbreak\n}", condition)
+	}
+	g.out.WriteString(g.block(s.Body.Statements))
+	g.emit("}")
+}
+
+func (g *generator) forStatement(s *ast.ForStatement) {
+	iterable := g.expression(s.Iterable)
+	sym := g.scope.define(s.Variable.Value)
+
+	it, el := g.temp(), g.temp()
+	g.emit("for %s := zrt.Iterate(%s); ; {", it, iterable)
+	g.emit("%s, ok := zrt.Next(%s)\nif !ok {\nbreak\n}", el, it)
+	g.emit("%s = %s", sym.goName, el)
+	g.out.WriteString(g.block(s.Body.Statements))
+	g.emit("}")
+}
+
+// importStatement generates the imported file in place, once per build, as
+// the compiler compiles it.
+func (g *generator) importStatement(s *ast.ImportStatement) {
+	path := imports.Resolve(g.dir, s.Path.Value, g.opts.ImportPaths)
+	if g.imported[path] {
+		return
+	}
+	g.imported[path] = true
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		g.fail("could not read imported file: %s", s.Path.Value)
+		return
+	}
+
+	p := parser.New(lexer.New(string(content)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		g.fail("could not parse imported file: %s", s.Path.Value)
+		return
+	}
+
+	saved := g.dir
+	g.dir = filepath.Dir(path)
+	for _, inner := range program.Statements {
+		g.statement(inner)
+	}
+	g.dir = saved
+}
+
+// ifExpression generates an if statement that leaves its value in target.
+func (g *generator) ifExpression(e *ast.IfExpression, target string) {
+	g.emit("if zrt.Truthy(%s) {", g.expression(e.Condition))
+	g.valueBlock(e.Consequence.Statements, target)
+	if e.Alternative != nil {
+		g.emit("} else {")
+		g.valueBlock(e.Alternative.Statements, target)
+	} else if target != "" {
+		g.emit("} else {\n%s = zrt.NULL", target)
+	}
+	g.emit("}")
+}
+
+// matchExpression compares the subject with each pattern in turn, as the
+// VM does, and runs the else arm, or leaves null, when none is equal.
+func (g *generator) matchExpression(e *ast.MatchExpression, target string) {
+	var alternative *ast.MatchArm
+	arms := []*ast.MatchArm{}
+	for _, arm := range e.Arms {
+		if arm.Pattern == nil {
+			alternative = arm
 			continue
 		}
+		arms = append(arms, arm)
+	}
 
-		if strings.HasPrefix(line, "show(") {
-			content := strings.TrimPrefix(line, "show(")
-			content = strings.TrimSuffix(content, ")")
-			args := splitArgs(content)
+	subject := g.expression(e.Subject)
+	if len(arms) == 0 {
+		g.emit("_ = %s", subject)
+	} else {
+		t := g.temp()
+		g.emit("%s := %s", t, subject)
+		subject = t
+	}
 
-			if len(args) == 0 {
-				goBody = append(goBody, `    fmt.Println()`)
-				continue
-			}
+	closing := 0
+	for i, arm := range arms {
+		pattern := g.expression(arm.Pattern)
+		g.emit("if zrt.Truthy(zrt.Equal(%s, %s)) {", subject, pattern)
+		g.valueBlock(arm.Body.Statements, target)
+		g.emit("} else {")
+		if i == len(arms)-1 {
+			break
+		}
+		closing++
+	}
 
-			if len(args) == 1 && strings.Contains(args[0], "[") {
-				goBody = append(goBody, fmt.Sprintf("    fmt.Println(%s)", args[0]))
-				continue
-			}
+	if alternative != nil {
+		g.valueBlock(alternative.Body.Statements, target)
+	} else if target != "" {
+		g.emit("%s = zrt.NULL", target)
+	}
 
-			if len(args) == 1 {
-				arg := strings.TrimSpace(args[0])
-
-				if strings.HasPrefix(arg, `"`) && strings.HasSuffix(arg, `"`) {
-					if strings.Contains(arg, "{}") {
-						goBody = append(goBody, fmt.Sprintf(`    fmt.Println(%s)`, arg))
-					} else {
-						goBody = append(goBody, fmt.Sprintf(`    fmt.Println(%s)`, arg))
-					}
-				} else {
-					goBody = append(goBody, fmt.Sprintf(`    fmt.Println(%s)`, arg))
-				}
-				continue
-			}
+	if len(arms) > 0 {
+		closing++
+	}
+	g.emit("%s", strings.Repeat("}\n", closing))
+}
 
-			format := args[0]
-			if strings.HasPrefix(format, `"`) && strings.HasSuffix(format, `"`) {
-				format = format[1 : len(format)-1]
-			}
+// expression returns Go code for e. Parts of e that need statements, like
+// if and match expressions, are generated before it into g.out.
+func (g *generator) expression(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.IntegerLiteral:
+		if e.Big != nil {
+			return g.constant(fmt.Sprintf("zrt.BigIntLiteral(%q)", e.Big.String()))
+		}
+		return g.constant(fmt.Sprintf("&zrt.Integer{Value: %d}", e.Value))
 
-			placeholders := strings.Count(format, "{}")
-			formatGo := strings.ReplaceAll(format, "{}", "%v")
+	case *ast.FloatLiteral:
+		return g.constant(fmt.Sprintf("&zrt.Float{Value: %s}", strconv.FormatFloat(e.Value, 'g', -1, 64)))
 
-			if placeholders > 0 && len(args)-1 < placeholders {
-				goBody = append(goBody, fmt.Sprintf(`    fmt.Println("%s")`, format))
-				continue
-			}
+	case *ast.StringLiteral:
+		return g.constant(fmt.Sprintf("&zrt.String{Value: %s}", strconv.Quote(e.Value)))
 
-			line := fmt.Sprintf(`    fmt.Printf("%s\n"`, formatGo)
-			if len(args) > 1 {
-				line += ", " + strings.Join(args[1:], ", ")
-			}
-			line += ")"
-			goBody = append(goBody, line)
-			continue
+	case *ast.Boolean:
+		if e.Value {
+			return "zrt.TRUE"
 		}
+		return "zrt.FALSE"
 
-		if strings.HasPrefix(line, "var ") {
-			line = strings.ReplaceAll(line, "<<", "=")
-			if strings.Contains(line, "json_parse(") {
-				parts := strings.SplitN(line, "=", 2)
-				varName := strings.TrimSpace(parts[0])
-				rightSide := strings.TrimSpace(parts[1])
-				goBody = append(goBody, fmt.Sprintf("     %s map[string]interface{} = %s", varName, rightSide))
-				continue
-			} else if strings.Contains(line, "{") && strings.Contains(line, "}") {
-				parts := strings.SplitN(line, "=", 2)
-				varName := strings.TrimSpace(parts[0])
-				rightSide := strings.TrimSpace(parts[1])
-				rightSide = strings.ReplaceAll(rightSide, "{", "map[string]interface{}{")
-				line = varName + " = " + rightSide
-				goBody = append(goBody, "    "+line)
-			} else if strings.Contains(line, "jwtCreateToken(") {
-				line = strings.ReplaceAll(line, "=", ", _ =")
-				goBody = append(goBody, "    "+line)
-			} else if strings.Contains(line, "[") && strings.Contains(line, "]") {
-				parts := strings.SplitN(line, "=", 2)
-				varName := strings.TrimSpace(parts[0])
-				arrayPart := strings.TrimSpace(parts[1])
-				arrayPart = strings.TrimPrefix(arrayPart, "[")
-				arrayPart = strings.TrimSuffix(arrayPart, "]")
-				arrayElements := strings.TrimSpace(arrayPart)
-
-				goBody = append(goBody, fmt.Sprintf("    %s = []interface{}{%s}", varName, arrayElements))
-			} else {
-				goBody = append(goBody, "    "+line)
-			}
-			continue
+	case *ast.Identifier:
+		return g.identifier(e.Value)
+
+	case *ast.PrefixExpression:
+		right := g.expression(e.Right)
+		if e.Operator == "!" {
+			return fmt.Sprintf("zrt.Not(%s)", right)
 		}
+		return fmt.Sprintf("zrt.Minus(%s)", right)
 
-		if strings.Contains(line, "<<") {
-			line = strings.ReplaceAll(line, "<<", "=")
-			goBody = append(goBody, line)
-			continue
+	case *ast.InfixExpression:
+		operands := g.operands(e.Left, e.Right)
+		return fmt.Sprintf("zrt.%s(%s, %s)", operators[e.Operator], operands[0], operands[1])
+
+	case *ast.ArrayLiteral:
+		return fmt.Sprintf("&zrt.Array{Elements: []zrt.Value{%s}}", strings.Join(g.operands(e.Elements...), ", "))
+
+	case *ast.DictLiteral:
+		// The compiler evaluates the pairs sorted by their keys' source.
+		keys := []ast.Expression{}
+		for k := range e.Pairs {
+			keys = append(keys, k)
 		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
 
-		if strings.HasPrefix(line, "//") {
-			goBody = append(goBody, "    "+line)
+		parts := []ast.Expression{}
+		for _, k := range keys {
+			parts = append(parts, k, e.Pairs[k])
 		}
+		return fmt.Sprintf("zrt.NewDict(%s)", strings.Join(g.operands(parts...), ", "))
 
-		if strings.Contains(line, "(") && strings.Contains(line, ")") {
-			if strings.HasPrefix(line, "addToArrayStart") || strings.HasPrefix(line, "addToArrayEnd") {
-				parts := strings.SplitN(line, "(", 2)
-				args := parts[1][:len(parts[1])-1]
-				funcName := parts[0]
-				targetVar := strings.Split(args, ",")[0]
-				goBody = append(goBody, fmt.Sprintf("    %s = %s(%s)", strings.TrimSpace(targetVar), funcName, args))
-
-			} else if strings.HasPrefix(line, "registerRoute(") {
-				openParen := strings.Index(line, "(")
-				closeParen := strings.LastIndex(line, ")")
-				argsStr := line[openParen+1 : closeParen]
-				args := splitArgs(argsStr)
-
-				if strings.HasPrefix(args[1], "serveFile(") {
-					innerOpen := strings.Index(args[1], "(")
-					innerClose := strings.LastIndex(args[1], ")")
-					innerArgsStr := args[1][innerOpen+1 : innerClose]
-					innerArgs := splitArgs(innerArgsStr)
-
-					if len(innerArgs) == 2 && strings.HasPrefix(innerArgs[1], "{") {
-						tmpCounter++
-						tmpVar := fmt.Sprintf("__tmp%d", tmpCounter)
-						mapContent := strings.ReplaceAll(innerArgs[1], "{", "map[string]interface{}{")
-						mapContent = strings.ReplaceAll(mapContent, "}", "}")
-						goBody = append(goBody, fmt.Sprintf("    var %s = %s", tmpVar, mapContent))
-						innerArgs[1] = tmpVar
-					}
-
-					serveFileCall := fmt.Sprintf("serveFile(%s, %s)", innerArgs[0], innerArgs[1])
-					line = fmt.Sprintf("registerRoute(%s, %s)", args[0], serveFileCall)
-				} else {
-					line = fmt.Sprintf("registerRoute(%s, %s)", args[0], args[1])
-				}
-				goBody = append(goBody, "    "+line)
-				continue
-
-			} else {
-				goBody = append(goBody, "    "+line)
-			}
-			continue
+	case *ast.SetLiteral:
+		return fmt.Sprintf("zrt.NewSetOf(%s)", strings.Join(g.operands(e.Elements...), ", "))
+
+	case *ast.IndexExpression:
+		operands := g.operands(e.Left, e.Index)
+		return fmt.Sprintf("zrt.Index(%s, %s)", operands[0], operands[1])
+
+	case *ast.AttributeAccess:
+		return fmt.Sprintf("zrt.Attr(%s, %q)", g.expression(e.Object), e.Property.Value)
+
+	case *ast.CallExpression:
+		operands := g.operands(append([]ast.Expression{e.Function}, e.Arguments...)...)
+		return fmt.Sprintf("zrt.Call(%s)", strings.Join(operands, ", "))
+
+	case *ast.FunctionLiteral:
+		return g.function(e)
+
+	case *ast.IfExpression:
+		t := g.temp()
+		g.emit("var %s zrt.Value", t)
+		g.ifExpression(e, t)
+		return t
+
+	case *ast.MatchExpression:
+		t := g.temp()
+		g.emit("var %s zrt.Value", t)
+		g.matchExpression(e, t)
+		return t
+	}
+
+	g.fail("zumbra build does not support %T", e)
+	return "zrt.NULL"
+}
+
+var operators = map[string]string{
+	"+":   "Add",
+	"-":   "Sub",
+	"*":   "Mul",
+	"/":   "Div",
+	"%":   "Mod",
+	"==":  "Equal",
+	"!=":  "NotEqual",
+	">":   "Greater",
+	"<":   "Less",
+	">=":  "GreaterEqual",
+	"<=":  "LessEqual",
+	"and": "And",
+	"or":  "Or",
+}
+
+// operands generates expressions evaluated left to right. When a later one
+// needs statements, the ones before it are stored in temporaries first so
+// they are still evaluated first.
+func (g *generator) operands(exprs ...ast.Expression) []string {
+	last := -1
+	for i, e := range exprs {
+		if needsStatements(e) {
+			last = i
 		}
+	}
 
+	result := make([]string, len(exprs))
+	for i, e := range exprs {
+		result[i] = g.expression(e)
+		if i < last && !isLiteral(e) {
+			t := g.temp()
+			g.emit("%s := %s", t, result[i])
+			result[i] = t
+		}
 	}
+	return result
+}
 
-	return fmt.Sprintf(
-		`package main
-
-		import (
-			"sort"
-			"fmt"
-			"time"
-			"bufio"
-			"os"
-			"strings"
-			"crypto/sha256"
-			"math"
-			"math/rand"
-			"encoding/json"
-			"net"
-			"net/http"
-			"strconv"
-			"errors"
-			"database/sql"
-			"path/filepath"
-			"bytes"
-
-			"github.com/golang-jwt/jwt/v5"
-			_ "github.com/go-sql-driver/mysql"
-		)
-
-		%s
-
-		func main() {
-			%s
+func needsStatements(e ast.Expression) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.IfExpression, *ast.MatchExpression:
+			found = true
 		}
-	`, runtime.Runtime(), strings.Join(goBody, "\n")), nil
+		return !found
+	})
+	return found
 }
 
-func splitArgs(input string) []string {
-	var args []string
-	var curr strings.Builder
-	inStr := false
-	parens := 0
+func isLiteral(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
 
-	for i := 0; i < len(input); i++ {
-		ch := input[i]
+func (g *generator) identifier(name string) string {
+	sym, ok := g.scope.resolve(name)
+	if !ok {
+		g.fail("undefined variable %s", name)
+		return "zrt.NULL"
+	}
 
-		if ch == '"' {
-			inStr = !inStr
+	if sym.kind == builtinSymbol {
+		if _, ok := zrt.Builtins[name]; !ok {
+			if g.err == nil {
+				g.err = &UnsupportedError{Builtin: name}
+			}
 		}
+		g.builtins[name] = true
+	}
+	return sym.goName
+}
+
+// constant returns the package-level variable holding the value of code.
+func (g *generator) constant(code string) string {
+	if name, ok := g.constants[code]; ok {
+		return name
+	}
+
+	name := g.newConstant(code)
+	g.constants[code] = name
+	return name
+}
+
+// newConstant returns a new package-level variable holding the value of
+// code.
+func (g *generator) newConstant(code string) string {
+	name := fmt.Sprintf("k%d", len(g.constantDecls))
+	g.constantDecls = append(g.constantDecls, fmt.Sprintf("%s zrt.Value = %s", name, code))
+	return name
+}
+
+// function generates a *zrt.Function. Its free variables are passed to a
+// function literal that is called right away, so the function keeps the
+// values they have when it is created, like a closure in the VM.
+func (g *generator) function(fl *ast.FunctionLiteral) string {
+	s := &scope{outer: g.scope, depth: g.scope.depth + 1, symbols: map[string]symbol{}}
+	s.generator = yields(fl.Body)
+
+	self := ""
+	if fl.Name != "" {
+		self = fmt.Sprintf("f%d_%s", s.depth, fl.Name)
+		s.symbols[fl.Name] = symbol{kind: functionSymbol, goName: self}
+	}
+
+	params := []string{}
+	args := []string{}
+	for i, p := range fl.Parameters {
+		params = append(params, s.define(p.Value).goName)
+		args = append(args, fmt.Sprintf("args[%d]", i))
+	}
+
+	saved := g.scope
+	g.scope = s
+	body := g.functionBody(fl.Body.Statements)
+	g.scope = saved
+
+	var fn bytes.Buffer
+	if len(s.locals) > 0 {
+		fmt.Fprintf(&fn, "var %s zrt.Value\n", strings.Join(s.locals, ", "))
+		fmt.Fprintf(&fn, "%s = %s\n", strings.Repeat("_, ", len(s.locals)-1)+"_", strings.Join(s.locals, ", "))
+	}
+	if len(params) > 0 {
+		fmt.Fprintf(&fn, "%s = %s\n", strings.Join(params, ", "), strings.Join(args, ", "))
+	}
+	fn.WriteString(body)
+
+	code := fn.String()
+	if s.generator {
+		code = fmt.Sprintf("return zrt.NewGenerator(func(yield func(zrt.Value)) {\n%s})\n", code)
+	}
+	literal := fmt.Sprintf("&zrt.Function{Name: %q, Params: %d, Fn: func(args []zrt.Value) zrt.Value {\n%s}}", fl.Name, len(fl.Parameters), code)
+
+	frees := unique(s.frees)
+	if len(frees) == 0 && self == "" {
+		return literal
+	}
+
+	var wrapper bytes.Buffer
+	params = []string{}
+	for _, free := range frees {
+		params = append(params, free+" zrt.Value")
+	}
+	fmt.Fprintf(&wrapper, "func(%s) zrt.Value {\n", strings.Join(params, ", "))
+	if self != "" {
+		fmt.Fprintf(&wrapper, "var %s zrt.Value\n%s = %s\nreturn %s\n", self, self, literal, self)
+	} else {
+		fmt.Fprintf(&wrapper, "return %s\n", literal)
+	}
+	fmt.Fprintf(&wrapper, "}(%s)", strings.Join(frees, ", "))
+	return wrapper.String()
+}
 
-		if !inStr {
-			if ch == '(' {
-				parens++
+// functionBody generates a function's statements. The value of the last
+// one is returned when it is an expression, null otherwise.
+func (g *generator) functionBody(statements []ast.Statement) string {
+	saved := g.out
+	g.out = &bytes.Buffer{}
+
+	if g.scope.generator {
+		for _, s := range statements {
+			g.statement(s)
+		}
+	} else {
+		n := len(statements)
+		if last, ok := lastExpression(statements); ok {
+			for _, s := range statements[:n-1] {
+				g.statement(s)
+			}
+			g.emit("return %s", g.expression(last))
+		} else {
+			for _, s := range statements {
+				g.statement(s)
 			}
-			if ch == ')' {
-				parens--
+			if !endsInReturn(statements) {
+				g.emit("return zrt.NULL")
 			}
 		}
+	}
 
-		if ch == ',' && !inStr && parens == 0 {
-			args = append(args, strings.TrimSpace(curr.String()))
-			curr.Reset()
-		} else {
-			curr.WriteByte(ch)
+	code := g.out.String()
+	g.out = saved
+	return code
+}
+
+func lastExpression(statements []ast.Statement) (ast.Expression, bool) {
+	if len(statements) == 0 {
+		return nil, false
+	}
+	last, ok := statements[len(statements)-1].(*ast.ExpressionStatement)
+	if !ok || last.Expression == nil {
+		return nil, false
+	}
+	return last.Expression, true
+}
+
+// yields reports whether body has a yield of its own, outside of nested
+// functions.
+func yields(body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.YieldStatement:
+			found = true
 		}
+		return !found
+	})
+	return found
+}
+
+func unique(names []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// WriteModule writes a Go module with a copy of the runtime into dir. Its
+// go.mod and go.sum pin the modules the runtime imports to the versions
+// zumbra uses. mains maps the directories of the module's main packages,
+// relative to dir, to their generated main.go.
+func WriteModule(dir string, mains map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
 	}
-	if curr.Len() > 0 {
-		args = append(args, strings.TrimSpace(curr.String()))
+
+	goMod := fmt.Sprintf("module %s\n\ngo 1.23\n\n%s", Module, runtime.Requires)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), []byte(runtime.Sums), 0644); err != nil {
+		return err
+	}
+
+	for pkg, main := range mains {
+		pkgDir := filepath.Join(dir, pkg)
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(pkgDir, "main.go"), main, 0644); err != nil {
+			return err
+		}
 	}
-	return args
+	return runtime.WriteTo(filepath.Join(dir, runtime.Package))
 }
@@ -0,0 +1,31 @@
+package transpiler
+
+import (
+	"errors"
+	"testing"
+
+	"zumbra/lexer"
+	"zumbra/parser"
+)
+
+func TestGenerateRejectsUnsupportedBuiltins(t *testing.T) {
+	program := parser.New(lexer.New(`sendEmail("a", "b", "c");`)).ParseProgram()
+
+	_, err := Generate(program, Options{Dir: t.TempDir()})
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || unsupported.Builtin != "sendEmail" {
+		t.Fatalf("wrong error. got=%v", err)
+	}
+	if err.Error() != "the builtin sendEmail is not supported by zumbra build" {
+		t.Errorf("wrong message. got=%q", err.Error())
+	}
+}
+
+func TestGenerateReportsCompileErrors(t *testing.T) {
+	program := parser.New(lexer.New(`show(missing);`)).ParseProgram()
+
+	_, err := Generate(program, Options{Dir: t.TempDir()})
+	if err == nil || err.Error() != "undefined variable missing" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
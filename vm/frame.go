@@ -24,3 +24,13 @@ func NewFrame(cl *object.Closure, basePointer int) *Frame {
 func (f *Frame) Instructions() code.Instructions {
 	return f.cl.Fn.Instructions
 }
+
+// Closure returns the closure the frame runs.
+func (f *Frame) Closure() *object.Closure {
+	return f.cl
+}
+
+// IP returns the offset of the instruction the frame is executing.
+func (f *Frame) IP() int {
+	return f.ip
+}
@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"zumbra/object"
+)
+
+// Generator is returned by calling a function that contains `yield`. It runs
+// the function's frame on a VM of its own, so the frame can be suspended at
+// every OpYield and resumed by the next call to Next.
+type Generator struct {
+	cl        *object.Closure
+	args      []object.Object
+	constants []object.Object
+	globals   []object.Object
+	hook      Hook
+	// maxFrames and depth are the frame limit and the depth of the VM
+	// that created the generator.
+	maxFrames int
+	depth     int
+	vm        *VM
+}
+
+func newGenerator(parent *VM, cl *object.Closure, args []object.Object) *Generator {
+	g := &Generator{
+		cl:        cl,
+		args:      append([]object.Object{}, args...),
+		constants: parent.constants,
+		globals:   parent.globals,
+		hook:      parent.hook,
+		maxFrames: parent.maxFrames,
+		depth:     parent.depth + parent.framesIndex,
+	}
+
+	vm := newCallVM(g.constants, g.globals, cl, g.args)
+	vm.startedBy(parent)
+	g.vm = vm
+	return g
+}
+
+// newCallVM prepares a VM that runs cl with args until it returns.
+func newCallVM(constants, globals []object.Object, cl *object.Closure, args []object.Object) *VM {
+	// frames[0] is an empty function: when cl's frame returns into it,
+	// Run finds no instructions left and stops.
+	empty := &object.Closure{Fn: &object.CompiledFunction{}}
+	frames := []*Frame{NewFrame(empty, 0), NewFrame(cl, 1)}
+
+	vm := &VM{
+		constants:   constants,
+		stack:       make([]object.Object, StackSize),
+		globals:     globals,
+		frames:      frames,
+		framesIndex: 2,
+	}
+	vm.growStack(1 + cl.Fn.NumLocals)
+	vm.stack[0] = cl
+	copy(vm.stack[1:], args)
+	vm.sp = 1 + cl.Fn.NumLocals
+
+	return vm
+}
+
+func (g *Generator) Type() object.ObjectType { return object.GENERATOR_OBJ }
+func (g *Generator) Inspect() string         { return "generator" }
+
+func (g *Generator) Next() (object.Object, bool, error) {
+	if g.vm == nil {
+		return nil, false, nil
+	}
+
+	err := g.vm.Run()
+	if err != nil {
+		g.vm = nil
+		return nil, false, err
+	}
+
+	if g.vm.yielded == nil {
+		g.vm = nil
+		return nil, false, nil
+	}
+
+	return g.vm.yielded, true, nil
+}
+
+func (g *Generator) Restart() object.Iterator {
+	parent := &VM{constants: g.constants, globals: g.globals, hook: g.hook, maxFrames: g.maxFrames, depth: g.depth}
+	return newGenerator(parent, g.cl, g.args)
+}
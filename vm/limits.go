@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"zumbra/object"
+)
+
+var (
+	ErrStepLimit       = errors.New("step limit exceeded")
+	ErrFrameLimit      = errors.New("frame limit exceeded")
+	ErrStackLimit      = errors.New("stack limit exceeded")
+	ErrAllocationLimit = errors.New("allocation limit exceeded")
+)
+
+// Limits bounds what a program may use. Zero fields are not limited.
+type Limits struct {
+	// Steps is how many instructions the program may run.
+	Steps int64
+	// Frames is how deeply calls may nest.
+	Frames int
+	// Stack is how many values may be on the stack at once.
+	Stack int
+	// Allocation is the largest string, in bytes, array, dict or set, in
+	// elements, or big integer or decimal, in machine words, the program may
+	// build.
+	Allocation int
+}
+
+// contextCheckInterval is how many instructions run between checks of the
+// context, which are slower than the other checks.
+const contextCheckInterval = 256
+
+// Hook returns a hook that stops the program with an error wrapping one of
+// the limit errors when it goes over l, or wrapping the context's error
+// once ctx is done. The budget is shared by the VMs the program starts, so
+// a hook is meant for one run.
+func (l Limits) Hook(ctx context.Context) Hook {
+	var steps int64
+	done := ctx.Done()
+
+	return func(vm *VM) error {
+		steps++
+		if l.Steps > 0 && steps > l.Steps {
+			return fmt.Errorf("%w: ran %d instructions", ErrStepLimit, l.Steps)
+		}
+		if done != nil && steps%contextCheckInterval == 0 {
+			select {
+			case <-done:
+				return fmt.Errorf("execution stopped: %w", ctx.Err())
+			default:
+			}
+		}
+
+		if l.Frames > 0 || l.Stack > 0 {
+			frames, stack := 0, 0
+			for v := vm; v != nil; v = v.caller {
+				frames += v.framesIndex
+				stack += v.sp
+			}
+			// The main frame does not count as a call.
+			if l.Frames > 0 && frames-1 > l.Frames {
+				return fmt.Errorf("%w: calls nested more than %d deep", ErrFrameLimit, l.Frames)
+			}
+			if l.Stack > 0 && stack > l.Stack {
+				return fmt.Errorf("%w: more than %d values on the stack", ErrStackLimit, l.Stack)
+			}
+		}
+
+		// Every value the program builds is on top of the stack after the
+		// instruction that built it.
+		if l.Allocation > 0 && vm.sp > 0 {
+			if size := sizeOf(vm.stack[vm.sp-1]); size > l.Allocation {
+				return fmt.Errorf("%w: value of size %d, limit is %d", ErrAllocationLimit, size, l.Allocation)
+			}
+		}
+		return nil
+	}
+}
+
+func sizeOf(obj object.Object) int {
+	switch obj := obj.(type) {
+	case *object.String:
+		return len(obj.Value)
+	case *object.Array:
+		return len(obj.Elements)
+	case *object.Dict:
+		return len(obj.Pairs)
+	case *object.Set:
+		return len(obj.Elements)
+	case *object.BigInt:
+		return len(obj.Value.Bits())
+	case *object.Decimal:
+		return len(obj.Unscaled.Bits())
+	}
+	return 0
+}
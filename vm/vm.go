@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"zumbra/code"
 	"zumbra/compiler"
@@ -8,14 +9,45 @@ import (
 	"zumbra/object/builtins"
 )
 
+// StackSize is how many values the stack of a VM holds at first. It grows
+// when the program needs more.
 const StackSize = 2048
 const GlobalSize = 65536
-const MaxFrames = 1024
+
+// MaxFrames is how deeply calls may nest, counting the calls made from
+// builtins and generators, unless SetMaxFrames says otherwise.
+const MaxFrames = 100000
+
+// ErrStackOverflow is returned when calls nest deeper than the VM allows.
+var ErrStackOverflow = errors.New("stack overflow")
 
 var True = &object.Boolean{Value: true}
 var False = &object.Boolean{Value: false}
 var Null = &object.Null{}
 
+// The VM shares one Integer for every result from minSmallInteger to
+// maxSmallInteger instead of allocating a new one each time. Integers are
+// never changed in place, so sharing them is safe.
+const (
+	minSmallInteger = -128
+	maxSmallInteger = 1024
+)
+
+var smallIntegers [maxSmallInteger - minSmallInteger + 1]*object.Integer
+
+func init() {
+	for i := range smallIntegers {
+		smallIntegers[i] = &object.Integer{Value: int64(i + minSmallInteger)}
+	}
+}
+
+func newInteger(value int64) *object.Integer {
+	if value >= minSmallInteger && value <= maxSmallInteger {
+		return smallIntegers[value-minSmallInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
 type VM struct {
 	constants   []object.Object
 	stack       []object.Object
@@ -23,22 +55,35 @@ type VM struct {
 	globals     []object.Object
 	frames      []*Frame
 	framesIndex int
+	// maxFrames is how many frames this VM and the VMs that started it
+	// may have in all; depth is how many the latter have.
+	maxFrames int
+	depth     int
+	// yielded is set when Run stops at an OpYield.
+	yielded object.Object
+	// hook runs before every instruction while set. caller is the VM
+	// whose builtin or generator started this one.
+	hook   Hook
+	caller *VM
 }
 
+// Hook is called before each instruction with the frame's ip at that
+// instruction. Returning an error stops Run with it.
+type Hook func(vm *VM) error
+
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFct := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFct := &object.CompiledFunction{Instructions: bytecode.Instructions, Lines: bytecode.Lines}
 	mainClosure := &object.Closure{Fn: mainFct}
 	mainFrame := NewFrame(mainClosure, 0)
 
-	frames := make([]*Frame, MaxFrames)
-	frames[0] = mainFrame
 	return &VM{
 		constants:   bytecode.Constants,
 		stack:       make([]object.Object, StackSize),
 		sp:          0,
-		globals:     make([]object.Object, GlobalSize),
-		frames:      frames,
+		globals:     growGlobals(make([]object.Object, GlobalSize), bytecode),
+		frames:      []*Frame{mainFrame},
 		framesIndex: 1,
+		maxFrames:   MaxFrames,
 	}
 }
 
@@ -55,16 +100,22 @@ func (vm *VM) Run() error {
 	var ins code.Instructions
 	var op code.Opcode
 
+	vm.yielded = nil
+
 	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
 		vm.currentFrame().ip++
+		if vm.hook != nil {
+			if err := vm.hook(vm); err != nil {
+				return err
+			}
+		}
 		ip = vm.currentFrame().ip
 		ins = vm.currentFrame().Instructions()
 		op = code.Opcode(ins[ip])
 
 		switch op {
-		case code.OpConstant:
-			constIndex := code.ReadUint16(ins[ip+1:])
-			vm.currentFrame().ip += 2
+		case code.OpConstant, code.OpConstantWide:
+			constIndex := vm.readOperand(ins, ip, op == code.OpConstantWide)
 
 			err := vm.push(vm.constants[constIndex])
 			if err != nil {
@@ -77,6 +128,15 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpAddImmediate:
+			immediate := int64(int16(code.ReadUint16(ins[ip+1:])))
+			vm.currentFrame().ip += 2
+
+			err := vm.executeAddImmediate(immediate)
+			if err != nil {
+				return err
+			}
+
 		case code.OpAnd:
 			right := vm.pop()
 			left := vm.pop()
@@ -122,19 +182,34 @@ func (vm *VM) Run() error {
 		case code.OpPop:
 			vm.pop()
 
-		case code.OpJump:
-			pos := int(code.ReadUint16(ins[ip+1:]))
+		case code.OpJump, code.OpJumpWide:
+			pos := vm.readOperand(ins, ip, op == code.OpJumpWide)
 			vm.currentFrame().ip = pos - 1
 
-		case code.OpJumpNotTruthy:
-			pos := int(code.ReadUint16(ins[ip+1:]))
-			vm.currentFrame().ip += 2
+		case code.OpJumpNotTruthy, code.OpJumpNotTruthyWide:
+			pos := vm.readOperand(ins, ip, op == code.OpJumpNotTruthyWide)
 
 			condition := vm.pop()
 			if !isTruthy(condition) {
 				vm.currentFrame().ip = pos - 1
 			}
 
+		case code.OpLocalCompareJump:
+			frame := vm.currentFrame()
+			local := vm.stack[frame.basePointer+int(code.ReadUint8(ins[ip+1:]))]
+			constant := vm.constants[code.ReadUint16(ins[ip+2:])]
+			comparison := code.Opcode(ins[ip+4])
+			pos := int(code.ReadUint16(ins[ip+5:]))
+			frame.ip += 6
+
+			holds, err := vm.compare(comparison, local, constant)
+			if err != nil {
+				return err
+			}
+			if !holds {
+				frame.ip = pos - 1
+			}
+
 		case code.OpNull:
 			err := vm.push(Null)
 
@@ -142,24 +217,21 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpSetGlobal:
-			globalIndex := code.ReadUint16(ins[ip+1:])
-			vm.currentFrame().ip += 2
+		case code.OpSetGlobal, code.OpSetGlobalWide:
+			globalIndex := vm.readOperand(ins, ip, op == code.OpSetGlobalWide)
 
 			vm.globals[globalIndex] = vm.pop()
 
-		case code.OpGetGlobal:
-			globalIndex := code.ReadUint16(ins[ip+1:])
-			vm.currentFrame().ip += 2
+		case code.OpGetGlobal, code.OpGetGlobalWide:
+			globalIndex := vm.readOperand(ins, ip, op == code.OpGetGlobalWide)
 
 			err := vm.push(vm.globals[globalIndex])
 			if err != nil {
 				return err
 			}
 
-		case code.OpArray:
-			numElements := int(code.ReadUint16(ins[ip+1:]))
-			vm.currentFrame().ip += 2
+		case code.OpArray, code.OpArrayWide:
+			numElements := vm.readOperand(ins, ip, op == code.OpArrayWide)
 
 			array := vm.buildArray(vm.sp-numElements, vm.sp)
 			vm.sp = vm.sp - numElements
@@ -169,9 +241,8 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpDict:
-			numElements := int(code.ReadUint16(ins[ip+1:]))
-			vm.currentFrame().ip += 2
+		case code.OpDict, code.OpDictWide:
+			numElements := vm.readOperand(ins, ip, op == code.OpDictWide)
 
 			dict, err := vm.buildDict(vm.sp-numElements, vm.sp)
 			if err != nil {
@@ -185,6 +256,63 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpSet, code.OpSetWide:
+			numElements := vm.readOperand(ins, ip, op == code.OpSetWide)
+
+			set, err := vm.buildSet(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+
+			vm.sp = vm.sp - numElements
+
+			err = vm.push(set)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDup:
+			err := vm.push(vm.StackTop())
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterInit:
+			iterable := vm.pop()
+
+			iter, err := object.NewIterator(iterable)
+			if err != nil {
+				return err
+			}
+
+			err = vm.push(iter)
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterNext, code.OpIterNextWide:
+			pos := vm.readOperand(ins, ip, op == code.OpIterNextWide)
+
+			iter := vm.StackTop().(object.Iterator)
+			value, ok, err := iter.Next()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				vm.pop()
+				vm.currentFrame().ip = pos - 1
+				continue
+			}
+
+			err = vm.push(value)
+			if err != nil {
+				return err
+			}
+
+		case code.OpYield:
+			vm.yielded = vm.pop()
+			return nil
+
 		case code.OpIndex:
 			index := vm.pop()
 			left := vm.pop()
@@ -203,6 +331,12 @@ func (vm *VM) Run() error {
 				return err
 			}
 
+		case code.OpCall0, code.OpCall1, code.OpCall2, code.OpCall3:
+			err := vm.executeCall(int(op - code.OpCall0))
+			if err != nil {
+				return err
+			}
+
 		case code.OpReturnValue:
 			returnValue := vm.pop()
 
@@ -223,19 +357,17 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpSetLocal:
-			localIndex := code.ReadUint8(ins[ip+1:])
-			vm.currentFrame().ip += 1
+		case code.OpSetLocal, code.OpSetLocalWide:
+			localIndex := vm.readByteOperand(ins, ip, op == code.OpSetLocalWide)
 
 			frame := vm.currentFrame()
-			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+			vm.stack[frame.basePointer+localIndex] = vm.pop()
 
-		case code.OpGetLocal:
-			localIndex := code.ReadUint8(ins[ip+1:])
-			vm.currentFrame().ip += 1
+		case code.OpGetLocal, code.OpGetLocalWide:
+			localIndex := vm.readByteOperand(ins, ip, op == code.OpGetLocalWide)
 
 			frame := vm.currentFrame()
-			err := vm.push(vm.stack[frame.basePointer+int(localIndex)])
+			err := vm.push(vm.stack[frame.basePointer+localIndex])
 			if err != nil {
 				return err
 			}
@@ -252,19 +384,17 @@ func (vm *VM) Run() error {
 				return err
 			}
 
-		case code.OpClosure:
-			constIndex := code.ReadUint16(ins[ip+1:])
-			numFree := code.ReadUint8(ins[ip+3:])
-			vm.currentFrame().ip += 3
+		case code.OpClosure, code.OpClosureWide:
+			constIndex := vm.readOperand(ins, ip, op == code.OpClosureWide)
+			numFree := vm.readByteOperand(ins, vm.currentFrame().ip, op == code.OpClosureWide)
 
-			err := vm.pushClosure(int(constIndex), int(numFree))
+			err := vm.pushClosure(constIndex, numFree)
 			if err != nil {
 				return err
 			}
 
-		case code.OpGetFree:
-			freeIndex := code.ReadUint8(ins[ip+1:])
-			vm.currentFrame().ip += 1
+		case code.OpGetFree, code.OpGetFreeWide:
+			freeIndex := vm.readByteOperand(ins, ip, op == code.OpGetFreeWide)
 
 			currentClosure := vm.currentFrame().cl
 			err := vm.push(currentClosure.Free[freeIndex])
@@ -297,31 +427,11 @@ func (vm *VM) Run() error {
 				return fmt.Errorf("attribute name must be a string, got %s", attrNameObj.Type())
 			}
 
-			obj := vm.pop()
-
-			switch d := obj.(type) {
-			case *object.Date:
-				switch attrName.Value {
-				case "hour":
-					vm.push(&object.Integer{Value: int64(d.Hour)})
-				case "minute":
-					vm.push(&object.Integer{Value: int64(d.Minute)})
-				case "day":
-					vm.push(&object.Integer{Value: int64(d.Day)})
-				case "second":
-					vm.push(&object.Integer{Value: int64(d.Second)})
-				case "month":
-					vm.push(&object.Integer{Value: int64(d.Month)})
-				case "year":
-					vm.push(&object.Integer{Value: int64(d.Year)})
-				case "fullDate":
-					vm.push(&object.String{Value: d.FullDate.String()})
-				default:
-					return fmt.Errorf("unknown attribute %s for Date", attrName.Value)
-				}
-			default:
-				return fmt.Errorf("object type %s has no attributes", obj.Type())
+			attr, err := object.Attribute(vm.pop(), attrName.Value)
+			if err != nil {
+				return err
 			}
+			vm.push(attr)
 
 		}
 
@@ -330,9 +440,32 @@ func (vm *VM) Run() error {
 	return nil
 }
 
+// readOperand returns the first operand of the instruction at ip, 4 bytes
+// wide for the wide opcodes and 2 bytes otherwise, and moves the frame's ip
+// to its last byte.
+func (vm *VM) readOperand(ins code.Instructions, ip int, wide bool) int {
+	if wide {
+		vm.currentFrame().ip += 4
+		return int(code.ReadUint32(ins[ip+1:]))
+	}
+	vm.currentFrame().ip += 2
+	return int(code.ReadUint16(ins[ip+1:]))
+}
+
+// readByteOperand is readOperand for the operands that are a single byte
+// in the narrow opcodes.
+func (vm *VM) readByteOperand(ins code.Instructions, ip int, wide bool) int {
+	if wide {
+		vm.currentFrame().ip += 4
+		return int(code.ReadUint32(ins[ip+1:]))
+	}
+	vm.currentFrame().ip += 1
+	return int(code.ReadUint8(ins[ip+1:]))
+}
+
 func (vm *VM) push(o object.Object) error {
-	if vm.sp >= StackSize {
-		return fmt.Errorf("stack overflow")
+	if vm.sp >= len(vm.stack) {
+		vm.growStack(vm.sp + 1)
 	}
 
 	vm.stack[vm.sp] = o
@@ -341,6 +474,17 @@ func (vm *VM) push(o object.Object) error {
 	return nil
 }
 
+// growStack makes room for size values on the stack, at least doubling it
+// so pushing stays cheap.
+func (vm *VM) growStack(size int) {
+	if size <= len(vm.stack) {
+		return
+	}
+	stack := make([]object.Object, max(size, 2*len(vm.stack)))
+	copy(stack, vm.stack)
+	vm.stack = stack
+}
+
 func (vm *VM) pop() object.Object {
 	o := vm.stack[vm.sp-1]
 	vm.sp--
@@ -359,6 +503,9 @@ func (vm *VM) executeBinaryOperation(op code.Opcode) error {
 	rightType := right.Type()
 
 	switch {
+	case object.IsExactNumber(left) || object.IsExactNumber(right):
+		return vm.executeExactOperation(op, left, right)
+
 	case leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ:
 		return vm.executeBinaryIntegerOperation(op, left, right)
 
@@ -393,15 +540,60 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 		result = leftValue - rightValue
 	case code.OpMul:
 		result = leftValue * rightValue
-	case code.OpDiv:
-		result = leftValue / rightValue
-	case code.OpMod:
-		result = leftValue % rightValue
+	case code.OpDiv, code.OpMod:
+		if rightValue == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		if op == code.OpDiv {
+			result = leftValue / rightValue
+		} else {
+			result = leftValue % rightValue
+		}
 	default:
 		return fmt.Errorf("unknown integer operator: %d", op)
 	}
 
-	return vm.push(&object.Integer{Value: result})
+	if object.IntegerOverflows(arithmeticOperators[op], leftValue, rightValue, result) {
+		return vm.executeExactOperation(op, left, right)
+	}
+
+	return vm.push(newInteger(result))
+}
+
+// arithmeticOperators is indexed by opcode rather than a map: looking it up
+// is on the path of every integer operation.
+var arithmeticOperators = [...]string{
+	code.OpAdd: "+",
+	code.OpSub: "-",
+	code.OpMul: "*",
+	code.OpDiv: "/",
+	code.OpMod: "%",
+}
+
+// executeAddImmediate adds immediate to the value on top of the stack. It
+// adds to integers directly and leaves anything else to
+// executeBinaryOperation.
+func (vm *VM) executeAddImmediate(immediate int64) error {
+	if left, ok := vm.stack[vm.sp-1].(*object.Integer); ok {
+		result := left.Value + immediate
+		if !object.IntegerOverflows("+", left.Value, immediate, result) {
+			vm.stack[vm.sp-1] = newInteger(result)
+			return nil
+		}
+	}
+
+	if err := vm.push(newInteger(immediate)); err != nil {
+		return err
+	}
+	return vm.executeBinaryOperation(code.OpAdd)
+}
+
+func (vm *VM) executeExactOperation(op code.Opcode, left, right object.Object) error {
+	result, err := object.Arithmetic(arithmeticOperators[op], left, right)
+	if err != nil {
+		return err
+	}
+	return vm.push(result)
 }
 
 func (vm *VM) executeFloatOperation(op code.Opcode, left, right object.Object) error {
@@ -489,6 +681,12 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 		return vm.executeIntegerComparison(op, left, right)
 	}
 
+	if object.IsExactNumber(left) || object.IsExactNumber(right) {
+		if result, ok := object.Compare(left, right); ok {
+			return vm.executeExactComparison(op, result)
+		}
+	}
+
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.FLOAT_OBJ {
 		return vm.executeIntLeftFloatRightComparison(op, left, right)
 	}
@@ -515,6 +713,41 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	}
 }
 
+// compare reports whether left and right compare as the comparison opcode
+// asks. It compares integers directly and leaves anything else to
+// executeComparison.
+func (vm *VM) compare(comparison code.Opcode, left, right object.Object) (bool, error) {
+	if l, ok := left.(*object.Integer); ok {
+		if r, ok := right.(*object.Integer); ok {
+			switch comparison {
+			case code.OpEqual:
+				return l.Value == r.Value, nil
+			case code.OpNotEqual:
+				return l.Value != r.Value, nil
+			case code.OpGreaterThan:
+				return l.Value > r.Value, nil
+			case code.OpLessThan:
+				return l.Value < r.Value, nil
+			case code.OpGreaterThanOrEqual:
+				return l.Value >= r.Value, nil
+			case code.OpLessThanOrEqual:
+				return l.Value <= r.Value, nil
+			}
+		}
+	}
+
+	if err := vm.push(left); err != nil {
+		return false, err
+	}
+	if err := vm.push(right); err != nil {
+		return false, err
+	}
+	if err := vm.executeComparison(comparison); err != nil {
+		return false, err
+	}
+	return isTruthy(vm.pop()), nil
+}
+
 func (vm *VM) executeIntLeftFloatRightComparison(op code.Opcode, left, right object.Object) error {
 	leftValue := float64(left.(*object.Integer).Value)
 	rightValue := right.(*object.Float).Value
@@ -614,6 +847,25 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 	}
 }
 
+func (vm *VM) executeExactComparison(op code.Opcode, result int) error {
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(result == 0))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(result != 0))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(result > 0))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(result < 0))
+	case code.OpGreaterThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(result >= 0))
+	case code.OpLessThanOrEqual:
+		return vm.push(nativeBoolToBooleanObject(result <= 0))
+	default:
+		return fmt.Errorf("unknown operator: %d", op)
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -639,12 +891,12 @@ func (vm *VM) executeBangOperator() error {
 func (vm *VM) executeMinusOperator() error {
 	val := vm.pop()
 
-	if val.Type() != object.INTEGER_OBJ {
+	negated, ok := object.Negate(val)
+	if !ok {
 		return fmt.Errorf("unsupported type for negation: %s", val.Type())
 	}
 
-	value := val.(*object.Integer).Value
-	return vm.push(&object.Integer{Value: -value})
+	return vm.push(negated)
 }
 
 func isTruthy(obj object.Object) bool {
@@ -658,12 +910,54 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
+// NewWithGlobalsStore returns a VM that keeps its globals in s. Programs
+// with more globals than s holds get a copy of s that is large enough,
+// which Globals returns.
 func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
 	vm := New(bytecode)
-	vm.globals = s
+	vm.globals = growGlobals(s, bytecode)
 	return vm
 }
 
+// growGlobals returns globals, or a larger copy of it when the program
+// uses globals past its end. Only the wide global opcodes reach past
+// GlobalSize. Globals grow before the program runs, so the VMs started by
+// calls and generators all share one store.
+func growGlobals(globals []object.Object, bytecode *compiler.Bytecode) []object.Object {
+	size := globalsUsed(bytecode.Instructions)
+	for _, constant := range bytecode.Constants {
+		if fn, ok := constant.(*object.CompiledFunction); ok {
+			size = max(size, globalsUsed(fn.Instructions))
+		}
+	}
+	if size <= len(globals) {
+		return globals
+	}
+
+	grown := make([]object.Object, size)
+	copy(grown, globals)
+	return grown
+}
+
+// globalsUsed returns one past the highest global index the wide global
+// opcodes in ins use.
+func globalsUsed(ins code.Instructions) int {
+	used := 0
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return used
+		}
+		operands, read := code.ReadOperands(def, ins[i+1:])
+		switch code.Opcode(ins[i]) {
+		case code.OpSetGlobalWide, code.OpGetGlobalWide:
+			used = max(used, operands[0]+1)
+		}
+		i += 1 + read
+	}
+	return used
+}
+
 func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
 	elements := make([]object.Object, endIndex-startIndex)
 
@@ -694,6 +988,18 @@ func (vm *VM) buildDict(startIndex, endIndex int) (object.Object, error) {
 	return &object.Dict{Pairs: dictedPairs}, nil
 }
 
+func (vm *VM) buildSet(startIndex, endIndex int) (object.Object, error) {
+	set := object.NewSet()
+
+	for i := startIndex; i < endIndex; i++ {
+		if err := set.Add(vm.stack[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
 func (vm *VM) executeIndexExpression(left, index object.Object) error {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
@@ -733,14 +1039,66 @@ func (vm *VM) executeDictIndex(dict, index object.Object) error {
 	return vm.push(pair.Value)
 }
 
+// SetHook installs a hook that runs before every instruction, also in the
+// VMs started by Call and by generators. A nil hook removes it.
+func (vm *VM) SetHook(hook Hook) {
+	vm.hook = hook
+}
+
+// Caller returns the VM that started this one through Call or a generator,
+// or nil.
+func (vm *VM) Caller() *VM {
+	return vm.caller
+}
+
+// Frames returns the active frames, outermost first.
+func (vm *VM) Frames() []*Frame {
+	return vm.frames[:vm.framesIndex]
+}
+
+// Locals returns the local variables of an active frame by index.
+func (vm *VM) Locals(f *Frame) []object.Object {
+	return vm.stack[f.basePointer : f.basePointer+f.cl.Fn.NumLocals]
+}
+
+func (vm *VM) Globals() []object.Object {
+	return vm.globals
+}
+
 func (vm *VM) currentFrame() *Frame {
 	return vm.frames[vm.framesIndex-1]
 }
 func (vm *VM) pushFrame(f *Frame) {
-	vm.frames[vm.framesIndex] = f
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.framesIndex] = f
+	}
 	vm.framesIndex++
 }
 
+// SetMaxFrames sets how deeply calls may nest before Run stops with
+// ErrStackOverflow. The VMs started by calls and generators share the limit.
+func (vm *VM) SetMaxFrames(n int) {
+	vm.maxFrames = n
+}
+
+// checkDepth returns ErrStackOverflow when one more call would nest deeper
+// than the VM allows.
+func (vm *VM) checkDepth() error {
+	if vm.depth+vm.framesIndex >= vm.maxFrames {
+		return fmt.Errorf("%w: calls nested more than %d deep", ErrStackOverflow, vm.maxFrames)
+	}
+	return nil
+}
+
+// startedBy makes vm share the hook and the frame limit of parent, which
+// started it.
+func (vm *VM) startedBy(parent *VM) {
+	vm.hook, vm.caller = parent.hook, parent
+	vm.maxFrames, vm.depth = parent.maxFrames, parent.depth+parent.framesIndex
+}
+
 func (vm *VM) popFrame() *Frame {
 	vm.framesIndex--
 	return vm.frames[vm.framesIndex]
@@ -750,9 +1108,19 @@ func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
 	if numArgs != cl.Fn.NumParameters {
 		return fmt.Errorf("wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
 	}
+	if err := vm.checkDepth(); err != nil {
+		return err
+	}
+
+	if cl.Fn.Generator {
+		generator := newGenerator(vm, cl, vm.stack[vm.sp-numArgs:vm.sp])
+		vm.sp = vm.sp - numArgs - 1
+		return vm.push(generator)
+	}
 
 	frame := NewFrame(cl, vm.sp-numArgs)
 	vm.pushFrame(frame)
+	vm.growStack(frame.basePointer + cl.Fn.NumLocals)
 	vm.sp = frame.basePointer + cl.Fn.NumLocals
 
 	return nil
@@ -774,7 +1142,12 @@ func (vm *VM) executeCall(numArgs int) error {
 func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	args := vm.stack[vm.sp-numArgs : vm.sp]
 
-	result := builtin.Fn(args...)
+	var result object.Object
+	if builtin.Callback != nil {
+		result = builtin.Callback(vm.Call, args...)
+	} else {
+		result = builtin.Fn(args...)
+	}
 	vm.sp = vm.sp - numArgs - 1
 
 	if result != nil {
@@ -786,6 +1159,45 @@ func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
 	return nil
 }
 
+// Call runs fn with args and returns its result. Closures run on a VM of
+// their own that shares this VM's constants and globals.
+func (vm *VM) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	switch fn := fn.(type) {
+	case *object.Closure:
+		if len(args) != fn.Fn.NumParameters {
+			return nil, fmt.Errorf("wrong number of arguments: want=%d, got=%d", fn.Fn.NumParameters, len(args))
+		}
+		if err := vm.checkDepth(); err != nil {
+			return nil, err
+		}
+		if fn.Fn.Generator {
+			return newGenerator(vm, fn, args), nil
+		}
+
+		machine := newCallVM(vm.constants, vm.globals, fn, args)
+		machine.startedBy(vm)
+		if err := machine.Run(); err != nil {
+			return nil, err
+		}
+		return machine.stack[0], nil
+
+	case *object.Builtin:
+		var result object.Object
+		if fn.Callback != nil {
+			result = fn.Callback(vm.Call, args...)
+		} else {
+			result = fn.Fn(args...)
+		}
+		if result == nil {
+			return Null, nil
+		}
+		return result, nil
+
+	default:
+		return nil, fmt.Errorf("calling non-function and non-built-in object: %s", fn.Type())
+	}
+}
+
 func (vm *VM) pushClosure(constIndex int, numFree int) error {
 	constant := vm.constants[constIndex]
 	function, ok := constant.(*object.CompiledFunction)
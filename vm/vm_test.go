@@ -1,8 +1,12 @@
 package vm
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 	"zumbra/ast"
 	"zumbra/compiler"
 	"zumbra/lexer"
@@ -38,36 +42,35 @@ type vmTestCase struct {
 func runVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
-	for _, tt := range tests {
-		program := parse(tt.input)
-		comp := compiler.New()
-		err := comp.Compile(program)
-		if err != nil {
-			t.Fatalf("compiler error: %s", err)
+	// Every program runs at every optimization level, which must not
+	// change its result.
+	for level := compiler.O0; level <= compiler.MaxOptimization; level++ {
+		for _, tt := range tests {
+			runVmTest(t, tt, level)
 		}
+	}
+}
 
-		/*
-			for i, constant := range comp.Bytecode().Constants {
-				fmt.Printf("CONSTANT %d %p (%T):\n", i, constant, constant)
-				switch constant := constant.(type) {
-				case *object.CompiledFunction:
-					fmt.Printf(" Instructions:\n%s", constant.Instructions)
-				case *object.Integer:
-					fmt.Printf(" Value: %d\n", constant.Value)
-				}
-				fmt.Printf("\n")
-			}*/
-
-		vm := New(comp.Bytecode())
-		err = vm.Run()
-		if err != nil {
-			t.Fatalf("vm error: %s", err)
-		}
+func runVmTest(t *testing.T, tt vmTestCase, level int) {
+	t.Helper()
 
-		stackElem := vm.LastPoppedStackElem()
+	program := parse(tt.input)
+	comp := compiler.New()
+	comp.SetOptimization(level)
+	err := comp.Compile(program)
+	if err != nil {
+		t.Fatalf("compiler error at -O%d: %s", level, err)
+	}
 
-		testExpectedObject(t, tt.expected, stackElem)
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err != nil {
+		t.Fatalf("vm error at -O%d: %s", level, err)
 	}
+
+	stackElem := vm.LastPoppedStackElem()
+
+	testExpectedObject(t, tt.expected, stackElem)
 }
 
 func testExpectedObject(t *testing.T, expected interface{}, actual object.Object) {
@@ -806,8 +809,576 @@ func TestAttributeAccess(t *testing.T) {
 				var a << date();
 				a.hour;
 			`,
-			expected: 11,
+			expected: time.Now().Hour(),
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestEnums(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				enum Status { Active, Banned }
+				Status.Active == Status.Active
+			`,
+			expected: true,
+		},
+		{
+			input: `
+				enum Status { Active, Banned }
+				Status.Active == Status.Banned
+			`,
+			expected: false,
+		},
+		{
+			input: `
+				enum Status { Active, Banned }
+				var s << Status.Banned;
+				s != Status.Active
+			`,
+			expected: true,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				enum Status { Active, Banned }
+				var describe << fct(s) {
+					match (s) {
+						Status.Active => "active",
+						Status.Banned => { var b << "ban"; b + "ned" }
+					}
+				};
+				describe(Status.Banned)
+			`,
+			expected: "banned",
+		},
+		{
+			input:    `match (2) { 1 => 10, 2 => 20, else => 30 }`,
+			expected: 20,
+		},
+		{
+			input:    `match (5) { 1 => 10, else => 30 }`,
+			expected: 30,
+		},
+		{
+			input:    `match (5) { 1 => 10 }`,
+			expected: Null,
+		},
+		{
+			input:    `match (1) { 1 => { var x << 1; } }`,
+			expected: Null,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestSets(t *testing.T) {
+	tests := []vmTestCase{
+		{`sizeOf({1, 2, 2, 3})`, 3},
+		{`setHas({"a", "b"}, "b")`, true},
+		{`setHas({"a", "b"}, "c")`, false},
+		{`var s << {1}; setAdd(s, 2); setAdd(s, 2); sizeOf(s)`, 2},
+		{`var s << {1, 2}; setRemove(s, 1); setHas(s, 1)`, false},
+		{`sizeOf(setUnion({1, 2}, {2, 3}))`, 3},
+		{`sizeOf(setIntersect({1, 2}, {2, 3}))`, 1},
+		{`setHas(setDifference({1, 2}, {2, 3}), 1)`, true},
+		{`sizeOf(set([1, 1, 2]))`, 2},
+		{`sizeOf(set())`, 0},
+	}
+	runVmTests(t, tests)
+
+	inspections := []struct {
+		input    string
+		expected string
+	}{
+		{`{3, 1, 3, 2}`, "{3, 1, 2}"},
+		{`set()`, "set()"},
+		{`enum Role { Admin } {Role.Admin}`, "{Role.Admin}"},
+	}
+
+	for _, tt := range inspections {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		if got := vm.LastPoppedStackElem().Inspect(); got != tt.expected {
+			t.Errorf("wrong set. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestForLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				var total << 0;
+				for (x in [1, 2, 3]) {
+					total << total + x;
+				}
+				total
+			`,
+			expected: 6,
+		},
+		{
+			input: `
+				var total << 0;
+				for (x in {5, 5, 1}) {
+					total << total + x;
+				}
+				total
+			`,
+			expected: 6,
+		},
+		{
+			input: `
+				var keys << "";
+				for (k in {"b": 1, "a": 2}) {
+					keys << keys + k;
+				}
+				keys
+			`,
+			expected: "ab",
+		},
+		{
+			input: `
+				var sum << fct(xs) {
+					var acc << 0;
+					for (x in xs) {
+						acc << acc + x;
+					}
+					acc
+				};
+				sum([4, 5]) + sum([])
+			`,
+			expected: 9,
+		},
+		{
+			input: `
+				var find << fct(xs) {
+					for (x in xs) {
+						if (x > 1) { return x; }
+					}
+					return 0;
+				};
+				find([1, 2, 3])
+			`,
+			expected: 2,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []vmTestCase{
+		{"toString(9223372036854775807 + 1)", "9223372036854775808"},
+		{"toString(-9223372036854775807 - 2)", "-9223372036854775809"},
+		{"toString(4294967296 * 4294967296)", "18446744073709551616"},
+		{"toString(99999999999999999999 - 99999999999999999998)", "1"},
+		{"(9223372036854775807 + 1) - 1", 9223372036854775807},
+		{"toString(bigint(\"123456789012345678901234567890\") % 1000)", "890"},
+		{"9223372036854775807 + 1 > 9223372036854775807", true},
+		{"99999999999999999999 == 99999999999999999999", true},
+		{"99999999999999999999 != 1", true},
+		{"toString(-99999999999999999999)", "-99999999999999999999"},
+		{"toString(99999999999999999999 + 0.5)", "1e+20"},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestDecimals(t *testing.T) {
+	tests := []vmTestCase{
+		{`toString(decimal("0.1") + decimal("0.2"))`, "0.3"},
+		{`decimal("0.1") + decimal("0.2") == decimal("0.3")`, true},
+		{`toString(decimal("10.35") * 3)`, "31.05"},
+		{`toString(decimal("10.00") - 0.5)`, "9.50"},
+		{`toString(decimal("10.00") / 4)`, "2.50"},
+		{`toString(decimal("1") / 3)`, "0.3333333333333333"},
+		{`toString(decimal("2") / 3)`, "0.6666666666666667"},
+		{`toString(-decimal("1.50"))`, "-1.50"},
+		{`decimal("1.50") == decimal("1.5")`, true},
+		{`decimal("1.50") > 1`, true},
+		{`decimal("0.99") < 1.0`, true},
+		{`toString(round(decimal("2.345"), 2))`, "2.35"},
+		{`toString(round(decimal("2.345"), 2, "half_even"))`, "2.34"},
+		{`toString(round(decimal("-2.341"), 2, "floor"))`, "-2.35"},
+		{`decimalRounding(2, "down"); toString(decimal("2") / 3)`, "0.66"},
+		{`decimalRounding(16, "half_up"); toInt(decimal("12.99"))`, 12},
+		{`decimalRounding(1001, "down")`, &object.Error{Message: "first argument to `decimalRounding` must be an INTEGER from 0 to 1000, got 1001"}},
+		{`round(decimal("1.5"), 1001)`, &object.Error{Message: "second argument to `round` must be an INTEGER from 0 to 1000, got 1001"}},
+		{`jsonStringify({"total": decimal("10.35"), "id": 99999999999999999999})`, `{"id":99999999999999999999,"total":10.35}`},
+		{`jsonStringify([1, "a", true, {1, 2}])`, `[1,"a",true,[1,2]]`},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+				var count << fct(n) { var i << 0; while (i < n) { yield i; i << i + 1; } };
+				var total << 0;
+				for (x in count(4)) { total << total + x; }
+				total
+			`,
+			expected: 6,
+		},
+		{
+			input: `
+				var gen << fct() { yield 1; yield 2; };
+				var g << gen();
+				next(g)["value"] + next(g)["value"]
+			`,
+			expected: 3,
+		},
+		{
+			input: `
+				var gen << fct() { yield 1; };
+				var g << gen();
+				next(g);
+				next(g)["done"]
+			`,
+			expected: true,
+		},
+		{
+			input: `
+				var naturals << fct() { var i << 0; while (true) { yield i; i << i + 1; } };
+				take(naturals(), 3)
+			`,
+			expected: []int{0, 1, 2},
+		},
+		{
+			input: `
+				var gen << fct(prefix) { for (x in [1, 2]) { yield prefix + toString(x); } };
+				toArray(gen("a"))[1]
+			`,
+			expected: "a2",
+		},
+		{
+			input: `
+				var seen << 0;
+				var gen << fct() { seen << seen + 1; yield seen; };
+				var g << gen();
+				seen
+			`,
+			expected: 0,
 		},
+		{
+			input: `
+				var inner << fct() { yield 1; yield 2; };
+				var outer << fct() { for (x in inner()) { yield x * 10; } };
+				sum(outer())
+			`,
+			expected: 30,
+		},
+		{"var gen << fct() { yield 5; yield 7; }; first(gen())", 5},
+		{"var gen << fct() { yield 5; yield 7; }; max(gen())", 7},
+		{"var gen << fct() { yield 5; yield 7; }; sizeOf(gen())", 2},
+		{"var gen << fct() { yield 5; yield 7; }; indexOf(gen(), 7)", 1},
+		{"var it << iter([1, 2]); next(it); next(it)[\"value\"]", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	program := parse(`var gen << fct() { yield 1; yield 1 + "a"; }; toArray(gen())`)
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err := vm.Run()
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	errObj, ok := vm.LastPoppedStackElem().(*object.Error)
+	if !ok {
+		t.Fatalf("expected error, got %T", vm.LastPoppedStackElem())
+	}
+	if errObj.Message != "unsupported types for binary operation: INTEGER STRING" {
+		t.Errorf("wrong error message: %q", errObj.Message)
+	}
+}
+
+func TestCall(t *testing.T) {
+	program := parse(`var base << 10; var add << fct(a, b) { a + b + base }; add`)
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	result, err := vm.Call(vm.LastPoppedStackElem(), &object.Integer{Value: 1}, &object.Integer{Value: 2})
+	if err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	testIntegerObject(13, result)
+
+	if _, err := vm.Call(vm.LastPoppedStackElem()); err == nil {
+		t.Errorf("expected an error for a wrong number of arguments")
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []vmTestCase{
+		{`assertEqual([1, {"a": 2}], [1, {"a": 2}])`, Null},
+		{`assertEqual(1, 1.0)`, Null},
+		{
+			`assertEqual([1, 2], [1, 3], "numbers")`,
+			&object.Error{Message: "assertion failed: numbers: values are not equal\n  [1]: got 2, want 3"},
+		},
+		{`assertTrue(1 < 2)`, Null},
+		{`assertTrue(1)`, &object.Error{Message: "assertion failed: expected true, got 1"}},
+		{`assertThrows(fct() { 1 + "a" })`, "unsupported types for binary operation: INTEGER STRING"},
+		{`assertThrows(fct() { sizeOf(1) })`, "argument to `sizeOf` not supported, got INTEGER"},
+		{`assertThrows(fct() { 1 })`, &object.Error{Message: "assertion failed: expected an error, got 1"}},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestRunUnmarshaledBytecode(t *testing.T) {
+	program := parse(`
+enum Color { Red, Green }
+var adder << fct(x) { fct(y) { x + y } };
+var gen << fct() { yield 2.5; yield 4; };
+var c << Color.Green;
+[adder(1)(2), toArray(gen()), {"color": c}["color"] == Color.Green]`)
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	data, err := comp.Bytecode().MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %s", err)
+	}
+	bytecode, err := compiler.UnmarshalBytecode(data)
+	if err != nil {
+		t.Fatalf("UnmarshalBytecode: %s", err)
+	}
+
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+
+	if got := vm.LastPoppedStackElem().Inspect(); got != "[3, [2.5, 4], true]" {
+		t.Errorf("wrong result: %s", got)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits Limits
+		want   error
+	}{
+		{`while (true) { 1 }`, Limits{Steps: 1000}, ErrStepLimit},
+		{`var f << fct(n) { f(n + 1) }; f(0)`, Limits{Frames: 50}, ErrFrameLimit},
+		{`var f << fct(n) { 1 + f(n + 1) }; f(0)`, Limits{Stack: 40}, ErrStackLimit},
+		{`var s << "ab"; while (true) { s << s + s }`, Limits{Allocation: 1000}, ErrAllocationLimit},
+		{`var x << bigint("3"); while (true) { x << x * x }`, Limits{Steps: 100000, Allocation: 1000}, ErrAllocationLimit},
+		{`var d << decimal("1.5"); while (true) { d << d * d }`, Limits{Steps: 100000, Allocation: 1000}, ErrAllocationLimit},
+		{`var a << fct() { assertThrows(fct() { while (true) {} }) }; a(); while (true) {}`, Limits{Steps: 500}, ErrStepLimit},
+		{`var total << 0; while (total < 10) { total << total + 1 } total`, Limits{Steps: 1000, Frames: 5, Stack: 10, Allocation: 10}, nil},
 	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetHook(tt.limits.Hook(context.Background()))
+		err := vm.Run()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%q: wrong error. want=%v, got=%v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestLimitsContext(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse(`while (true) { 1 }`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	vm := New(comp.Bytecode())
+	vm.SetHook(Limits{}.Hook(ctx))
+	if err := vm.Run(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestOptimizedPrograms(t *testing.T) {
+	tests := []vmTestCase{
+		{"fct() { return 1; 2; }()", 1},
+		{"fct() { var a << 1; return a; var b << 2; b }()", 1},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 }", Null},
+		{"if (false) { var y << 1; } var z << 2; z", 2},
+		{"var x << 0; while (false) { x << 1; } x", 0},
+		{"fct() { var i << 0; while (true) { i << i + 1; if (i > 2) { return i; } } }()", 3},
+		{`"zum" + "bra" == "zumbra"`, true},
+		{"!(1 == 2) and 2.5 > 2", true},
+		{"-(2 * 3)", -6},
+		{"var a << true; var b << false; if (a) { if (b) { 1 } else { 2 } } else { 3 }", 2},
+		{"var total << 0; for (x in [1, 2, 3]) { total << total + if (x > 1) { x } else { 10 }; } total", 15},
+	}
+
+	runVmTests(t, tests)
+}
+
+// TestSpecialisedInstructions runs the operands the -O 2 instructions do
+// not take a fast path for.
+func TestSpecialisedInstructions(t *testing.T) {
+	tests := []vmTestCase{
+		{"fct(x) { x + 1 }(1.5) > 2", true},
+		{"fct(x) { x - 1 }(-128)", -129},
+		{"toString(fct(x) { x + 1 }(9223372036854775807))", "9223372036854775808"},
+		{"fct(x) { x + 1 }(9223372036854775807 + 1) > 9223372036854775807", true},
+		{`fct(s) { if (s == "a") { 1 } else { 2 } }("a")`, 1},
+		{"fct(f) { if (f < 2) { 1 } else { 2 } }(1.5)", 1},
+		{"fct(n) { if (n != 3) { 1 } else { 2 } }(3)", 2},
+		{"fct(n) { var i << 0; while (i < n) { i << i + 1; } i }(1500)", 1500},
+		{"fct() { 1 }() + fct(a) { a }(2) + fct(a, b) { a + b }(3, 4) + fct(a, b, c) { c }(5, 6, 7)", 17},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestOptimizedRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-1.5", "unsupported type for negation: FLOAT"},
+		{`"a" - "b"`, "unknown string operator: 3"},
+		{"1.5 % 2", "unknown float operator: 6"},
+		{`fct(s) { s + 1 }("a")`, "unsupported types for binary operation: STRING INTEGER"},
+		{`fct(s) { s < 1 }("a")`, "unknown operator: 12 (STRING INTEGER)"},
+		{`fct(s) { if (s < 1) { 1 } else { 2 } }("a")`, "unknown operator: 12 (STRING INTEGER)"},
+		{"fct(a) { a }(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{"1 / 0", "division by zero"},
+		{"5 % 0", "division by zero"},
+		{"fct(n) { 10 / n }(0)", "division by zero"},
+		{"fct(n) { 10 % n }(0)", "division by zero"},
+	}
+
+	for level := compiler.O0; level <= compiler.MaxOptimization; level++ {
+		for _, tt := range tests {
+			comp := compiler.New()
+			comp.SetOptimization(level)
+			if err := comp.Compile(parse(tt.input)); err != nil {
+				t.Fatalf("compiler error: %s", err)
+			}
+
+			err := New(comp.Bytecode()).Run()
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("%q at -O%d: wrong error. want=%q, got=%v", tt.input, level, tt.expected, err)
+			}
+		}
+	}
+}
+
+func TestDeepRecursion(t *testing.T) {
+	tests := []vmTestCase{
+		{"var count << fct(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } }; count(20000)", 20000},
+		{"var sum << fct(n, a, b, c) { if (n == 0) { a + b + c } else { sum(n - 1, a, b, c + 1) } }; sum(5000, 1, 2, 0)", 5003},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestStackOverflow(t *testing.T) {
+	tests := []struct {
+		input     string
+		maxFrames int
+	}{
+		{"var forever << fct(n) { forever(n + 1) }; forever(0)", MaxFrames},
+		{"var count << fct(n) { if (n == 0) { 0 } else { 1 + count(n - 1) } }; count(100)", 50},
+		{"var g << fct() { for (x in g()) { yield x; } yield 1; }; for (y in g()) { y; }", 100},
+	}
+
+	for _, tt := range tests {
+		comp := compiler.New()
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(comp.Bytecode())
+		vm.SetMaxFrames(tt.maxFrames)
+		err := vm.Run()
+		if !errors.Is(err, ErrStackOverflow) {
+			t.Errorf("%q: want a stack overflow, got %v", tt.input, err)
+			continue
+		}
+		want := fmt.Sprintf("stack overflow: calls nested more than %d deep", tt.maxFrames)
+		if err.Error() != want {
+			t.Errorf("%q: wrong error. want=%q, got=%q", tt.input, want, err)
+		}
+	}
+}
+
+func TestWideOperands(t *testing.T) {
+	var globals, elements strings.Builder
+	for i := 0; i <= 65540; i++ {
+		fmt.Fprintf(&globals, "var g%d << %d;\n", i, i)
+		fmt.Fprintf(&elements, "%d, ", i)
+	}
+	array := "[" + strings.TrimSuffix(elements.String(), ", ") + "]"
+
+	var locals strings.Builder
+	terms := []string{}
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&locals, "var l%d << %d; ", i, i)
+		terms = append(terms, fmt.Sprintf("l%d", i))
+	}
+
+	tests := []vmTestCase{
+		// Global and constant indexes past 65535.
+		{globals.String() + "g65540 + g3", 65543},
+		// Constant indexes and an element count past 65535.
+		{"var xs << " + array + "; sizeOf(xs)", 65541},
+		{"var xs << " + array + "; var total << 0; for (x in xs) { total << total + x; } total", 65540 * 65541 / 2},
+		// Jumps past 65535.
+		{"fct(n) { if (n < 1) { " + strings.Repeat("n; ", 25000) + "n } else { 5 } }(3)", 5},
+		{"fct(n) { if (n < 1) { " + strings.Repeat("n; ", 25000) + "n } else { 5 } }(0)", 0},
+		{"var i << 0; while (i < 3) { " + strings.Repeat("i; ", 25000) + "i << i + 1; } i", 3},
+		// Local and free variable indexes and a free variable count past 255.
+		{"fct() { " + locals.String() + "l299 }()", 299},
+		{"fct() { " + locals.String() + "l299 << l299 + 1; l299 + l3 }()", 303},
+		{"fct() { " + locals.String() + "if (l299 == 299) { l256 } else { 0 } }()", 256},
+		{"fct() { " + locals.String() + "fct() { " + strings.Join(terms, " + ") + " } }()()", 299 * 300 / 2},
+	}
+
 	runVmTests(t, tests)
 }
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"zumbra/compiler"
+	"zumbra/object/builtins"
+	"zumbra/project"
+	"zumbra/watcher"
+)
+
+const (
+	watchInterval = 300 * time.Millisecond
+	// watchSettle is how long a change is given to finish, as editors
+	// often write a file in several steps.
+	watchSettle = 100 * time.Millisecond
+	// watchGrace is how long a program gets to stop before it is killed.
+	watchGrace = 5 * time.Second
+)
+
+// watchEnv marks a program started by the watcher. It holds the loopback
+// address the program reports the files it serves to.
+const watchEnv = "ZUMBRA_WATCH"
+
+// watchRun implements `zumbra run -watch`: it runs filename in a child
+// process, passing it args, and runs it again whenever the program, the
+// files it imports or serves, or its project files change. Errors are
+// printed and the watcher waits for the next change.
+func watchRun(filename string, args []string) bool {
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Printf("Error when trying to find the zumbra executable: %s\n", err)
+		return false
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	w := watcher.New()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	// used outlives each program, so reports still in flight when one
+	// stops do not block.
+	used := make(chan string, 64)
+	reports, err := listenForReports(used)
+	if err != nil {
+		fmt.Printf("[watch] could not listen for the files the program serves: %s\n", err)
+		return false
+	}
+	defer reports.Close()
+
+	for {
+		w.Add(watchedProjectFiles(filename)...)
+
+		var child *exec.Cmd
+		exited := make(chan error, 1)
+
+		if comp := compileForWatch(filename); comp != nil {
+			w.Add(comp.Imports()...)
+			child, err = startWatched(self, filename, args, reports.Addr().String(), exited)
+			if err != nil {
+				fmt.Printf("[watch] could not start the program: %s\n", err)
+			}
+		}
+
+	wait:
+		for {
+			select {
+			case path := <-used:
+				w.Add(path)
+
+			case err := <-exited:
+				child = nil
+				if err != nil {
+					fmt.Printf("[watch] program exited: %s; waiting for changes\n", err)
+				} else {
+					fmt.Println("[watch] program finished; waiting for changes")
+				}
+
+			case <-interrupt:
+				stopWatched(child, exited)
+				return true
+
+			case <-ticker.C:
+				changed := w.Changed()
+				if len(changed) == 0 {
+					continue
+				}
+				time.Sleep(watchSettle)
+				w.Changed()
+
+				stopWatched(child, exited)
+				fmt.Printf("[watch] %s changed, restarting\n", relative(changed[0]))
+				break wait
+			}
+		}
+	}
+}
+
+// watchedProjectFiles returns the entry file and, in a project, its
+// manifest and env files.
+func watchedProjectFiles(filename string) []string {
+	files := []string{filename}
+	m, err := project.Find(filepath.Dir(filename))
+	if err != nil || m == nil {
+		return files
+	}
+
+	files = append(files, filepath.Join(m.Dir, project.ManifestFile))
+	for _, env := range m.EnvFiles {
+		files = append(files, m.Path(env))
+	}
+	return files
+}
+
+// compileForWatch compiles filename to report errors before starting it.
+// Unlike compileFile it does not exit when the file cannot be read, as
+// editors may briefly remove it while saving.
+func compileForWatch(filename string) *compiler.Compiler {
+	if _, err := os.Stat(filename); err != nil {
+		fmt.Printf("[watch] %s\n", err)
+		return nil
+	}
+	comp, _ := compileFile(filename)
+	return comp
+}
+
+// listenForReports accepts the connections of the programs the watcher
+// starts and sends the paths they report to used.
+func listenForReports(used chan<- string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					used <- scanner.Text()
+				}
+				conn.Close()
+			}()
+		}
+	}()
+	return ln, nil
+}
+
+func startWatched(self, filename string, args []string, reports string, exited chan<- error) (*exec.Cmd, error) {
+	cmd := exec.Command(self, append(append([]string{"run"}, args...), filename)...)
+	cmd.Env = append(os.Environ(), watchEnv+"="+reports)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	return cmd, nil
+}
+
+// stopWatched asks the program to stop and kills it when it does not in
+// time.
+func stopWatched(child *exec.Cmd, exited <-chan error) {
+	if child == nil {
+		return
+	}
+
+	if err := child.Process.Signal(syscall.SIGTERM); err != nil {
+		child.Process.Kill()
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(watchGrace):
+		child.Process.Kill()
+		<-exited
+	}
+}
+
+// setupWatchedProgram is called in programs started by the watcher. It
+// reports the files they serve and shuts their servers down gracefully when
+// the watcher stops them.
+func setupWatchedProgram() {
+	reports := os.Getenv(watchEnv)
+	if reports == "" {
+		return
+	}
+
+	if report, err := net.Dial("tcp", reports); err == nil {
+		builtins.FileUsed = func(path string) {
+			if abs, err := filepath.Abs(path); err == nil {
+				fmt.Fprintln(report, abs)
+			}
+		}
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM)
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), watchGrace)
+		defer cancel()
+		builtins.ShutdownServers(ctx)
+		os.Exit(0)
+	}()
+}
+
+func relative(path string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(cwd, path); err == nil {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,97 @@
+// Package watcher notices when files change by comparing their size and
+// modification time between calls, which works the same on every system and
+// needs no dependencies. Directories are watched with everything under them.
+package watcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+type Watcher struct {
+	paths map[string]bool
+	// files holds the stamp of every file under the watched paths. Missing
+	// paths are kept with a zero stamp, so creating them is a change.
+	files map[string]stamp
+}
+
+func New() *Watcher {
+	return &Watcher{paths: map[string]bool{}, files: map[string]stamp{}}
+}
+
+// Add watches paths from their current state on.
+func (w *Watcher) Add(paths ...string) {
+	for _, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil || w.paths[path] {
+			continue
+		}
+		w.paths[path] = true
+		scan(path, w.files)
+	}
+}
+
+// Paths returns the watched paths, sorted.
+func (w *Watcher) Paths() []string {
+	paths := []string{}
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Changed returns the files that were created, modified or removed since
+// the last call, or since they were added.
+func (w *Watcher) Changed() []string {
+	current := map[string]stamp{}
+	for path := range w.paths {
+		scan(path, current)
+	}
+
+	changed := []string{}
+	for file, s := range current {
+		if old, ok := w.files[file]; !ok || old != s {
+			changed = append(changed, file)
+		}
+	}
+	for file := range w.files {
+		if _, ok := current[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+
+	w.files = current
+	sort.Strings(changed)
+	return changed
+}
+
+func scan(path string, files map[string]stamp) {
+	info, err := os.Stat(path)
+	if err != nil {
+		files[path] = stamp{}
+		return
+	}
+	if !info.IsDir() {
+		files[path] = stamp{info.Size(), info.ModTime()}
+		return
+	}
+
+	filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[file] = stamp{info.Size(), info.ModTime()}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.zum")
+	static := filepath.Join(dir, "static")
+	missing := filepath.Join(dir, "later.zum")
+
+	write := func(path, content string) {
+		t.Helper()
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(file, "show(1);")
+	write(filepath.Join(static, "css", "site.css"), "body {}")
+
+	w := New()
+	w.Add(file, static, missing, file)
+
+	if got := w.Paths(); !reflect.DeepEqual(got, []string{missing, file, static}) {
+		t.Errorf("wrong paths: %v", got)
+	}
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Fatalf("nothing changed yet, got %v", changed)
+	}
+
+	// Same size, so only the modification time tells.
+	write(file, "show(2);")
+	later := time.Now().Add(time.Second)
+	os.Chtimes(file, later, later)
+	if changed := w.Changed(); !reflect.DeepEqual(changed, []string{file}) {
+		t.Errorf("expected %s to change, got %v", file, changed)
+	}
+	if changed := w.Changed(); len(changed) != 0 {
+		t.Errorf("changes reported twice: %v", changed)
+	}
+
+	write(filepath.Join(static, "app.js"), "")
+	write(missing, "")
+	os.Remove(filepath.Join(static, "css", "site.css"))
+
+	want := []string{missing, filepath.Join(static, "app.js"), filepath.Join(static, "css", "site.css")}
+	if changed := w.Changed(); !reflect.DeepEqual(changed, want) {
+		t.Errorf("wrong changes.\nwant=%v\ngot=%v", want, changed)
+	}
+}
@@ -0,0 +1,154 @@
+package zumbra
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"zumbra/object"
+	"zumbra/vm"
+)
+
+// ToObject converts a Go value to a Zumbra value. It accepts nil, bools,
+// integers, floats, strings, *big.Int, slices and arrays, maps with string,
+// integer or bool keys, functions of type object.BuiltinFunction and
+// object.Object values, which are returned as they are.
+func ToObject(v interface{}) (object.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return vm.Null, nil
+	case object.Object:
+		return v, nil
+	case bool:
+		if v {
+			return vm.True, nil
+		}
+		return vm.False, nil
+	case string:
+		return &object.String{Value: v}, nil
+	case *big.Int:
+		return object.NewBigInt(v), nil
+	case object.BuiltinFunction:
+		return &object.Builtin{Fn: v}, nil
+	case func(args ...object.Object) object.Object:
+		return &object.Builtin{Fn: v}, nil
+	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: value.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := value.Uint()
+		if n > math.MaxInt64 {
+			return object.NewBigInt(new(big.Int).SetUint64(n)), nil
+		}
+		return &object.Integer{Value: int64(n)}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: value.Float()}, nil
+
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, value.Len())
+		for i := range elements {
+			element, err := ToObject(value.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		pairs := map[object.DictKey]object.DictPair{}
+		iter := value.MapRange()
+		for iter.Next() {
+			key, err := ToObject(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			dictable, ok := key.(object.Dictable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as dict key: %s", key.Type())
+			}
+			val, err := ToObject(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			pairs[dictable.DictKey()] = object.DictPair{Key: key, Value: val}
+		}
+		return &object.Dict{Pairs: pairs}, nil
+
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return vm.Null, nil
+		}
+		return ToObject(value.Elem().Interface())
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to a Zumbra value", v)
+}
+
+// FromObject converts a Zumbra value to a Go value: NULL to nil, INTEGER to
+// int64, FLOAT to float64, STRING to string, BOOLEAN to bool, BIGINT to
+// *big.Int, ARRAY and SET to []interface{} and DICT to map[string]interface{}
+// when all its keys are strings or map[interface{}]interface{} otherwise.
+// Enum values become their "Enum.Name" string. Other values, like
+// functions, are returned as they are.
+func FromObject(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil
+	case *object.Integer:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *object.EnumValue:
+		return obj.Inspect()
+
+	case *object.Array:
+		return fromObjects(obj.Elements)
+	case *object.Set:
+		return fromObjects(obj.Values())
+
+	case *object.Dict:
+		stringKeys := true
+		for _, pair := range obj.Pairs {
+			if _, ok := pair.Key.(*object.String); !ok {
+				stringKeys = false
+			}
+		}
+
+		if stringKeys {
+			m := make(map[string]interface{}, len(obj.Pairs))
+			for _, pair := range obj.Pairs {
+				m[pair.Key.(*object.String).Value] = FromObject(pair.Value)
+			}
+			return m
+		}
+
+		m := make(map[interface{}]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			m[FromObject(pair.Key)] = FromObject(pair.Value)
+		}
+		return m
+	}
+
+	return obj
+}
+
+func fromObjects(objects []object.Object) []interface{} {
+	values := make([]interface{}, len(objects))
+	for i, obj := range objects {
+		values[i] = FromObject(obj)
+	}
+	return values
+}
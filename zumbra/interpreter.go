@@ -0,0 +1,252 @@
+// Package zumbra embeds the Zumbra language in Go programs. An Interpreter
+// keeps the globals of everything it evaluated, so a host can load a script
+// once and then call its functions, passing Go values in and getting Go
+// values back with ToObject and FromObject.
+//
+// Interpreters are independent of each other: each has its own globals, the
+// builtins registered on it, and what the builtins remember between calls,
+// like the database connection, the routes and servers, the env vars loaded
+// with dotenvLoad and the JWT key.
+//
+// An interpreter with a Sandbox runs untrusted code: builtins outside its
+// capabilities are left out, and runs stop with an error when they go over
+// its limits or their context is done.
+package zumbra
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"zumbra/compiler"
+	"zumbra/lexer"
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/parser"
+	"zumbra/vm"
+)
+
+type Options struct {
+	// Dir is where imports are resolved from. It defaults to the working
+	// directory.
+	Dir string
+	// ImportPaths are searched, in order, for imports not found in Dir.
+	ImportPaths []string
+	// Stdout receives what show prints. It defaults to os.Stdout.
+	Stdout io.Writer
+	// Sandbox restricts the code the interpreter runs. Nil means no
+	// restrictions.
+	Sandbox *Sandbox
+}
+
+type Sandbox struct {
+	// Allow lists the capabilities whose builtins stay available. Without
+	// Filesystem, import statements are not allowed either.
+	Allow []builtins.Capability
+	// Limits apply to each call to Eval or Call separately. Going over them
+	// fails the call with an error wrapping vm.ErrStepLimit and the like.
+	Limits vm.Limits
+	// Timeout bounds each call to Eval or Call.
+	Timeout time.Duration
+}
+
+// Interpreter runs Zumbra source. It is not safe for concurrent use.
+type Interpreter struct {
+	dir         string
+	importPaths []string
+	sandbox     *Sandbox
+	state       *builtins.State
+
+	constants   []object.Object
+	globals     []object.Object
+	symbolTable *compiler.SymbolTable
+}
+
+func NewInterpreter(opts Options) *Interpreter {
+	dir := opts.Dir
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	i := &Interpreter{
+		dir:         dir,
+		importPaths: opts.ImportPaths,
+		sandbox:     opts.Sandbox,
+		state:       builtins.NewState(),
+		constants:   []object.Object{},
+		globals:     make([]object.Object, vm.GlobalSize),
+		symbolTable: compiler.NewSymbolTable(),
+	}
+	for index, b := range builtins.Builtins {
+		if opts.Sandbox != nil && !builtins.Allowed(b.Name, opts.Sandbox.Allow) {
+			continue
+		}
+		i.symbolTable.DefineBuiltin(index, b.Name)
+	}
+	for name, b := range i.state.Builtins() {
+		if opts.Sandbox == nil || builtins.Allowed(name, opts.Sandbox.Allow) {
+			i.define(name, b)
+		}
+	}
+
+	if opts.Stdout != nil {
+		i.define("show", builtins.ShowTo(opts.Stdout))
+	}
+	return i
+}
+
+// RegisterBuiltin makes fn callable as name in the code this interpreter
+// runs from now on. It replaces a stock builtin of the same name. fn reports
+// errors by returning an *object.Error, as made by builtins.NewError.
+func (i *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	i.define(name, &object.Builtin{Fn: fn})
+}
+
+// ShutdownServers stops the servers the interpreter's programs started,
+// letting requests in flight finish until ctx is done.
+func (i *Interpreter) ShutdownServers(ctx context.Context) error {
+	return i.state.ShutdownServers(ctx)
+}
+
+// Set defines the global name with the value of v, converted by ToObject.
+func (i *Interpreter) Set(name string, v interface{}) error {
+	obj, err := ToObject(v)
+	if err != nil {
+		return err
+	}
+	i.define(name, obj)
+	return nil
+}
+
+// Get returns the value of the global name.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	symbol, ok := i.symbolTable.Resolve(name)
+	if !ok || symbol.Scope != compiler.GlobalScope || symbol.Index >= len(i.globals) {
+		return nil, false
+	}
+	obj := i.globals[symbol.Index]
+	return obj, obj != nil
+}
+
+func (i *Interpreter) define(name string, obj object.Object) {
+	symbol := i.symbolTable.Define(name)
+	if symbol.Index >= len(i.globals) {
+		i.globals = append(i.globals, make([]object.Object, symbol.Index+1-len(i.globals))...)
+	}
+	i.globals[symbol.Index] = obj
+}
+
+// Eval runs source and returns the value of its last expression statement,
+// or NULL. Definitions stay visible to later calls to Eval and Call. An
+// error value the program ends with is returned as a Go error.
+func (i *Interpreter) Eval(source string) (object.Object, error) {
+	return i.EvalContext(context.Background(), source)
+}
+
+// EvalContext is like Eval, but stops the program when ctx is done.
+func (i *Interpreter) EvalContext(ctx context.Context, source string) (object.Object, error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parse error: %s", strings.Join(p.Errors(), "; "))
+	}
+
+	// Compiling into a copy keeps the interpreter usable when the source
+	// does not compile.
+	symbolTable := i.symbolTable.Copy()
+	comp := compiler.NewWithStateAndDir(symbolTable, i.constants, i.dir)
+	comp.SetImportPaths(i.importPaths)
+	if i.sandbox != nil && !allows(i.sandbox.Allow, builtins.Filesystem) {
+		comp.DisallowImports()
+	}
+	if err := comp.Compile(program); err != nil {
+		return nil, fmt.Errorf("compile error: %s", err)
+	}
+
+	bytecode := comp.Bytecode()
+	i.symbolTable, i.constants = symbolTable, bytecode.Constants
+
+	machine := vm.NewWithGlobalsStore(bytecode, i.globals)
+	i.globals = machine.Globals()
+	defer i.limit(ctx, machine)()
+	if err := machine.Run(); err != nil {
+		return nil, err
+	}
+
+	result := machine.LastPoppedStackElem()
+	if result == nil {
+		return vm.Null, nil
+	}
+	return result, errorValue(result)
+}
+
+// Call calls the global function fnName with args converted by ToObject.
+func (i *Interpreter) Call(fnName string, args ...interface{}) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext is like Call, but stops the function when ctx is done.
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...interface{}) (object.Object, error) {
+	fn, ok := i.Get(fnName)
+	if !ok {
+		symbol, builtin := i.symbolTable.Resolve(fnName)
+		if !builtin || symbol.Scope != compiler.BuiltinScope {
+			return nil, fmt.Errorf("undefined function %s", fnName)
+		}
+		fn = builtins.Builtins[symbol.Index].Builtin
+	}
+
+	objects := make([]object.Object, len(args))
+	for n, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d to %s: %s", n+1, fnName, err)
+		}
+		objects[n] = obj
+	}
+
+	machine := vm.NewWithGlobalsStore(&compiler.Bytecode{Constants: i.constants}, i.globals)
+	defer i.limit(ctx, machine)()
+	result, err := machine.Call(fn, objects...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fnName, err)
+	}
+	return result, errorValue(result)
+}
+
+// limit makes machine stop at the sandbox's limits or when ctx is done. The
+// returned function releases the sandbox's timeout.
+func (i *Interpreter) limit(ctx context.Context, machine *vm.VM) context.CancelFunc {
+	cancel := context.CancelFunc(func() {})
+	limits := vm.Limits{}
+	if i.sandbox != nil {
+		limits = i.sandbox.Limits
+		if i.sandbox.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, i.sandbox.Timeout)
+		}
+	}
+
+	if ctx.Done() != nil || limits != (vm.Limits{}) {
+		machine.SetHook(limits.Hook(ctx))
+	}
+	return cancel
+}
+
+func allows(allow []builtins.Capability, c builtins.Capability) bool {
+	for _, a := range allow {
+		if a == c {
+			return true
+		}
+	}
+	return false
+}
+
+func errorValue(obj object.Object) error {
+	if e, ok := obj.(*object.Error); ok {
+		return fmt.Errorf("%s", e.Message)
+	}
+	return nil
+}
@@ -0,0 +1,371 @@
+package zumbra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"zumbra/object"
+	"zumbra/object/builtins"
+	"zumbra/vm"
+)
+
+func TestEvalKeepsState(t *testing.T) {
+	i := NewInterpreter(Options{})
+
+	if _, err := i.Eval(`var limit << 10; var over << fct(n) { n > limit };`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+
+	result, err := i.Eval(`over(11)`)
+	if err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if FromObject(result) != true {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+
+	result, err = i.Call("over", 3)
+	if err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	if FromObject(result) != false {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	i := NewInterpreter(Options{})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`var x << ;`, "parse error"},
+		{`undefinedName`, "compile error: undefined variable undefinedName"},
+		{`1 + "a"`, "unsupported types"},
+		{`toInt("x")`, ""},
+	}
+
+	for _, tt := range tests {
+		_, err := i.Eval(tt.input)
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: wrong error %q", tt.input, err)
+		}
+	}
+
+	// A failed compilation leaves no half-defined names behind.
+	if _, err := i.Eval(`var a << 1; var b << missing;`); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := i.Eval(`var a << 2; a`); err != nil {
+		t.Errorf("eval error after a failed compilation: %s", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	i := NewInterpreter(Options{})
+	_, err := i.Eval(`var total << fct(items) { var sum << 0; for (item in items) { sum << sum + item["price"]; } sum };`)
+	if err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+
+	items := []map[string]interface{}{{"price": 3}, {"price": 4}}
+	result, err := i.Call("total", items)
+	if err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	if FromObject(result) != int64(7) {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+
+	if _, err := i.Call("nothing"); err == nil || err.Error() != "undefined function nothing" {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := i.Call("total"); err == nil || !strings.Contains(err.Error(), "wrong number of arguments") {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	result, err = i.Call("sizeOf", "four")
+	if err != nil {
+		t.Fatalf("call error: %s", err)
+	}
+	if FromObject(result) != int64(4) {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+}
+
+func TestRegisterBuiltinIsPerInterpreter(t *testing.T) {
+	a := NewInterpreter(Options{})
+	b := NewInterpreter(Options{})
+
+	a.RegisterBuiltin("double", func(args ...object.Object) object.Object {
+		n, ok := args[0].(*object.Integer)
+		if !ok {
+			return builtins.NewError("double wants an INTEGER")
+		}
+		return &object.Integer{Value: n.Value * 2}
+	})
+	a.RegisterBuiltin("sizeOf", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: -1}
+	})
+
+	result, err := a.Eval(`double(21) + sizeOf("abc")`)
+	if err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if FromObject(result) != int64(41) {
+		t.Errorf("wrong result: %s", result.Inspect())
+	}
+
+	if _, err := a.Eval(`double("x")`); err == nil || err.Error() != "double wants an INTEGER" {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	if _, err := b.Eval(`double(1)`); err == nil {
+		t.Error("double is defined in another interpreter")
+	}
+	result, err = b.Eval(`sizeOf("abc")`)
+	if err != nil || FromObject(result) != int64(3) {
+		t.Errorf("sizeOf was replaced in another interpreter: %v %v", result, err)
+	}
+}
+
+func TestBuiltinStateIsPerInterpreter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("INTERPRETER_KEY=a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewInterpreter(Options{Dir: dir})
+	b := NewInterpreter(Options{Dir: dir})
+	if _, err := a.Eval(`dotenvLoad("` + filepath.Join(dir, ".env") + `"); registerRoute("GET", "/", "a");`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+
+	if result, err := a.Call("dotenvGet", "INTERPRETER_KEY"); err != nil || FromObject(result) != "a" {
+		t.Errorf("wrong result: %v %v", result, err)
+	}
+	if result, _ := b.Call("dotenvGet", "INTERPRETER_KEY"); FromObject(result) != nil {
+		t.Errorf("env var loaded in another interpreter: %s", result.Inspect())
+	}
+	if result := builtins.GetBuiltinByName("dotenvGet").Fn(&object.String{Value: "INTERPRETER_KEY"}); result != nil {
+		t.Errorf("env var loaded in the process: %s", result.Inspect())
+	}
+	if err := a.ShutdownServers(context.Background()); err != nil {
+		t.Errorf("ShutdownServers: %s", err)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	i := NewInterpreter(Options{})
+	if err := i.Set("config", map[string]interface{}{"retries": 3, "hosts": []string{"a", "b"}}); err != nil {
+		t.Fatalf("set error: %s", err)
+	}
+
+	if _, err := i.Eval(`var hosts << config["hosts"];`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	hosts, ok := i.Get("hosts")
+	if !ok {
+		t.Fatal("hosts is not defined")
+	}
+	if got := FromObject(hosts); !reflect.DeepEqual(got, []interface{}{"a", "b"}) {
+		t.Errorf("wrong hosts: %#v", got)
+	}
+
+	if _, ok := i.Get("missing"); ok {
+		t.Error("missing is defined")
+	}
+	if err := i.Set("ch", make(chan int)); err == nil {
+		t.Error("expected an error converting a channel")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	if err := os.MkdirAll(lib, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(lib, "greet.zum"), []byte(`var greet << fct(name) { "hi " + name };`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	i := NewInterpreter(Options{Dir: dir, ImportPaths: []string{lib}, Stdout: &out})
+	if _, err := i.Eval(`import "greet.zum"
+show(greet("ana"));`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if out.String() != "hi ana\n" {
+		t.Errorf("wrong output: %q", out.String())
+	}
+}
+
+func TestConversions(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{int8(-3), int64(-3)},
+		{uint(7), int64(7)},
+		{1.5, 1.5},
+		{"text", "text"},
+		{huge, huge},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[string]bool{"on": true}, map[string]interface{}{"on": true}},
+		{map[int]string{1: "one"}, map[interface{}]interface{}{int64(1): "one"}},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.in)
+		if err != nil {
+			t.Errorf("ToObject(%#v): %s", tt.in, err)
+			continue
+		}
+		if got := FromObject(obj); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("round trip of %#v: got %#v", tt.in, got)
+		}
+	}
+
+	if _, err := ToObject(map[float64]int{1.5: 1}); err == nil {
+		t.Error("expected an error for float dict keys")
+	}
+	if _, err := ToObject(struct{}{}); err == nil {
+		t.Error("expected an error for a struct")
+	}
+}
+
+func TestSandboxCapabilities(t *testing.T) {
+	for _, c := range builtins.AllCapabilities {
+		found := false
+		for _, b := range builtins.Builtins {
+			if len(builtins.Requires(b.Name)) > 0 && builtins.Requires(b.Name)[0] == c {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("no builtin needs %s", c)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "lib.zum"), []byte(`var x << 1;`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	i := NewInterpreter(Options{Dir: dir, Sandbox: &Sandbox{Allow: []builtins.Capability{builtins.Network}}})
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`sendEmail`, "undefined variable sendEmail"},
+		{`mysqlDropTable`, "undefined variable mysqlDropTable"},
+		{`serveStatic`, "undefined variable serveStatic"},
+		{`dotenvLoad`, "undefined variable dotenvLoad"},
+		{`import "lib.zum"`, "imports are not allowed: lib.zum"},
+	}
+	for _, tt := range tests {
+		_, err := i.Eval(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: wrong error %v", tt.input, err)
+		}
+	}
+
+	for _, allowed := range []string{`server`, `registerRoute`, `sizeOf("ok")`} {
+		if _, err := i.Eval(allowed); err != nil {
+			t.Errorf("%q: %s", allowed, err)
+		}
+	}
+	if _, err := i.Call("sendEmail"); err == nil {
+		t.Error("sendEmail can be called from Go")
+	}
+}
+
+func TestSandboxWithoutCapabilities(t *testing.T) {
+	i := NewInterpreter(Options{Sandbox: &Sandbox{}})
+
+	for _, name := range []string{"get", "server", "input", "mysqlConnection", "sendWhatsapp"} {
+		if _, err := i.Eval(name); err == nil || !strings.Contains(err.Error(), "undefined variable "+name) {
+			t.Errorf("%s: wrong error %v", name, err)
+		}
+	}
+	if _, err := i.Eval(`sizeOf("ok")`); err != nil {
+		t.Errorf("sizeOf: %s", err)
+	}
+}
+
+func TestSandboxLeavesHostStateAlone(t *testing.T) {
+	host := NewInterpreter(Options{})
+	token, err := host.Call("jwtCreateToken", "ana", "host key", 1)
+	if err != nil {
+		t.Fatalf("jwtCreateToken: %s", err)
+	}
+	rounding := object.DefaultDecimalContext
+
+	untrusted := NewInterpreter(Options{Sandbox: &Sandbox{}})
+	if _, err := untrusted.Eval(`jwtCreateToken("mallory", "attacker", 1); test("t", fct() {}); assertTrue(false); 1;`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if _, err := untrusted.Eval(`decimalRounding(0, "down")`); err == nil || !strings.Contains(err.Error(), "undefined variable decimalRounding") {
+		t.Errorf("decimalRounding: wrong error %v", err)
+	}
+
+	user, err := host.Call("jwtVerifyToken", token)
+	if err != nil || FromObject(user) != "ana" {
+		t.Errorf("the host's token no longer verifies: %v %v", user, err)
+	}
+	if object.DefaultDecimalContext != rounding {
+		t.Errorf("decimal rounding changed to %+v", object.DefaultDecimalContext)
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	i := NewInterpreter(Options{Sandbox: &Sandbox{
+		Limits:  vm.Limits{Steps: 10000, Frames: 100},
+		Timeout: time.Minute,
+	}})
+
+	if _, err := i.Eval(`var spin << fct() { while (true) {} }; var deep << fct(n) { deep(n + 1) };`); err != nil {
+		t.Fatalf("eval error: %s", err)
+	}
+	if _, err := i.Eval(`spin()`); !errors.Is(err, vm.ErrStepLimit) {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := i.Call("deep", 0); !errors.Is(err, vm.ErrFrameLimit) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	// The budget is per call.
+	result, err := i.Eval(`var n << 0; while (n < 1000) { n << n + 1 } n`)
+	if err != nil || FromObject(result) != int64(1000) {
+		t.Errorf("wrong result: %v %v", result, err)
+	}
+
+	short := NewInterpreter(Options{Sandbox: &Sandbox{Allow: builtins.AllCapabilities, Timeout: 20 * time.Millisecond}})
+	if _, err := short.Eval(`while (true) {}`); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewInterpreter(Options{}).EvalContext(ctx, `while (true) {}`); !errors.Is(err, context.Canceled) {
+		t.Errorf("wrong error: %v", err)
+	}
+}